@@ -0,0 +1,193 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	"github.com/onsi/say"
+)
+
+// searching means fetching and parsing every payload, so we only look through this many of the most recent
+const MAX_SCAN = 200
+
+// Disco is anything we can hand an incoming e-mail to - i.e. SaturdayDisco and LunchtimeDisco
+type Disco interface {
+	HandleIncomingEmail(email mail.Email)
+}
+
+// Entry is an incoming e-mail as we stored it - the raw payload, parsed with today's code
+type Entry struct {
+	Key      string
+	StoredAt time.Time
+	Size     int64
+	Email    mail.Email
+	// set if the payload won't parse
+	Error error
+}
+
+type Results struct {
+	Entries []Entry
+	// how many payloads are in the archive
+	Stored int
+	// there are more matches past these
+	More bool
+}
+
+// Archive lets the boss browse the incoming e-mail mail.ParseIncomingEmail (and friends) stored for debugging: re-parsing it, handing it
+// to a disco again after a bug fix, and deleting it once it's old enough that we won't need it.
+type Archive struct {
+	db s3db.S3DBInt
+	w  io.Writer
+
+	routes map[string]Disco
+	lock   *sync.Mutex
+}
+
+func NewArchive(db s3db.S3DBInt, w io.Writer) *Archive {
+	return &Archive{
+		db:     db,
+		w:      w,
+		routes: map[string]Disco{},
+		lock:   &sync.Mutex{},
+	}
+}
+
+// Route lets Redispatch hand e-mail to disco by name
+func (a *Archive) Route(name string, disco Disco) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.routes[name] = disco
+}
+
+func (a *Archive) Discos() []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	out := []string{}
+	for name := range a.routes {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// list returns every stored payload, newest first
+func (a *Archive) list() ([]s3db.ObjectInfo, error) {
+	objects, err := a.db.ListObjects(mail.ARCHIVE_PREFIX)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].LastModified.After(objects[j].LastModified)
+	})
+	return objects, nil
+}
+
+// Search returns up to limit stored e-mails, newest first, skipping the first offset.  A query narrows things down to e-mails whose
+// sender, recipients, subject, body, or key contain it (ignoring case) - and, since that means parsing each payload, only looks at
+// the MAX_SCAN most recent.
+func (a *Archive) Search(query string, offset int, limit int) (Results, error) {
+	objects, err := a.list()
+	if err != nil {
+		return Results{}, err
+	}
+	results := Results{Stored: len(objects), Entries: []Entry{}}
+	query = strings.ToLower(strings.TrimSpace(query))
+	matched := 0
+	for i, object := range objects {
+		if query == "" {
+			// no need to fetch anything we aren't going to show
+			if i < offset {
+				continue
+			}
+		} else if i >= MAX_SCAN {
+			break
+		}
+		if len(results.Entries) == limit {
+			results.More = true
+			break
+		}
+		entry := a.entry(object)
+		if query != "" && !matches(entry, query) {
+			continue
+		}
+		matched += 1
+		if query != "" && matched <= offset {
+			continue
+		}
+		results.Entries = append(results.Entries, entry)
+	}
+	return results, nil
+}
+
+func matches(entry Entry, query string) bool {
+	email := entry.Email
+	for _, field := range []string{entry.Key, email.From.String(), email.To.String(), email.CC.String(), email.Subject, email.Text} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Archive) entry(object s3db.ObjectInfo) Entry {
+	entry := Entry{Key: object.Key, StoredAt: object.LastModified, Size: object.Size}
+	entry.Email, entry.Error = a.Reparse(object.Key)
+	return entry
+}
+
+// Reparse parses the payload stored under key with today's code
+func (a *Archive) Reparse(key string) (mail.Email, error) {
+	if !strings.HasPrefix(key, mail.ARCHIVE_PREFIX) {
+		return mail.Email{}, fmt.Errorf("%s isn't an archived e-mail", key)
+	}
+	data, err := a.db.FetchObject(key)
+	if err != nil {
+		return mail.Email{}, err
+	}
+	return mail.ParseStoredEmail(key, data)
+}
+
+// Redispatch re-parses the payload stored under key and hands it to the named disco, as if it had just arrived.  Both discos remember the
+// Message-IDs of the e-mails they've acted on since their weekly reset and ignore repeats, so this only does something for e-mails they
+// never got (or that failed to parse) - though an e-mail without a Message-ID, or one from before the last reset, will be acted on again.
+func (a *Archive) Redispatch(key string, name string) (mail.Email, error) {
+	a.lock.Lock()
+	disco, ok := a.routes[name]
+	a.lock.Unlock()
+	if !ok {
+		return mail.Email{}, fmt.Errorf("unknown disco: %s", name)
+	}
+	email, err := a.Reparse(key)
+	if err != nil {
+		return mail.Email{}, err
+	}
+	say.Fplni(a.w, 0, "{{green}}Archive: re-dispatching %q from %s to %s{{/}}", email.Subject, email.From, name)
+	disco.HandleIncomingEmail(email)
+	return email, nil
+}
+
+// Prune deletes every payload stored before cutoff and returns how many it deleted
+func (a *Archive) Prune(cutoff time.Time) (int, error) {
+	objects, err := a.list()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, object := range objects {
+		if !object.LastModified.Before(cutoff) {
+			continue
+		}
+		if err := a.db.DeleteObject(object.Key); err != nil {
+			return deleted, err
+		}
+		deleted += 1
+	}
+	say.Fplni(a.w, 0, "{{green}}Archive: deleted %d e-mails stored before %s{{/}}", deleted, cutoff.Format(time.RFC3339))
+	return deleted, nil
+}
@@ -0,0 +1,224 @@
+package archive_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/archive"
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+)
+
+var errBoom = errors.New("boom")
+
+type FakeDisco struct {
+	emails []mail.Email
+	lock   *sync.Mutex
+}
+
+func NewFakeDisco() *FakeDisco {
+	return &FakeDisco{lock: &sync.Mutex{}}
+}
+
+func (f *FakeDisco) HandleIncomingEmail(email mail.Email) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.emails = append(f.emails, email)
+}
+
+func (f *FakeDisco) Emails() []mail.Email {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.emails
+}
+
+func rawEmail(from string, subject string, body string) []byte {
+	return []byte(fmt.Sprintf("From: %s\r\nTo: saturday-disco@sedenverultimate.net\r\nSubject: %s\r\nMessage-ID: <%s@example.com>\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", from, subject, subject, body))
+}
+
+var _ = Describe("Archive", func() {
+	var db *s3db.FakeS3DB
+	var a *archive.Archive
+	var now time.Time
+
+	store := func(key string, data []byte, at time.Time) {
+		GinkgoHelper()
+		Ω(db.PutObject(mail.ARCHIVE_PREFIX+key, data)).Should(Succeed())
+		db.SetLastModified(mail.ARCHIVE_PREFIX+key, at)
+	}
+
+	keys := func(results archive.Results) []string {
+		out := []string{}
+		for _, entry := range results.Entries {
+			out = append(out, entry.Key)
+		}
+		return out
+	}
+
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+		a = archive.NewArchive(db, GinkgoWriter)
+		now = time.Date(2023, time.September, 24, 12, 0, 0, 0, clock.Timezone)
+
+		store("a", rawEmail("Player One <one@example.com>", "Count me in", "I'll be there +1"), now.Add(-3*time.Hour))
+		store("b", rawEmail("Player Two <two@example.com>", "Can't make it", "Sorry, out this week"), now.Add(-1*time.Hour))
+		store("c", rawEmail("Player Three <three@example.com>", "Rain?", "Is it going to rain?"), now.Add(-2*time.Hour))
+		Ω(db.PutObject("saturday-disco", []byte("{}"))).Should(Succeed())
+	})
+
+	Describe("searching", func() {
+		It("lists stored e-mails, newest first, parsed with today's code", func() {
+			results, err := a.Search("", 0, 10)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(results.Stored).Should(Equal(3))
+			Ω(results.More).Should(BeFalse())
+			Ω(keys(results)).Should(Equal([]string{"email/b", "email/c", "email/a"}))
+
+			entry := results.Entries[0]
+			Ω(entry.StoredAt).Should(BeTemporally("==", now.Add(-1*time.Hour)))
+			Ω(entry.Size).Should(BeNumerically(">", 0))
+			Ω(entry.Error).ShouldNot(HaveOccurred())
+			Ω(entry.Email.From).Should(Equal(mail.EmailAddress("Player Two <two@example.com>")))
+			Ω(entry.Email.Subject).Should(Equal("Can't make it"))
+			Ω(entry.Email.DebugKey).Should(Equal("email/b"))
+		})
+
+		It("pages", func() {
+			results, err := a.Search("", 0, 2)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(keys(results)).Should(Equal([]string{"email/b", "email/c"}))
+			Ω(results.More).Should(BeTrue())
+
+			results, err = a.Search("", 2, 2)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(keys(results)).Should(Equal([]string{"email/a"}))
+			Ω(results.More).Should(BeFalse())
+		})
+
+		It("matches the sender, recipients, subject, body, and key - ignoring case", func() {
+			for query, expected := range map[string][]string{
+				"two@example":       {"email/b"},
+				"PLAYER":            {"email/b", "email/c", "email/a"},
+				"rain":              {"email/c"},
+				"+1":                {"email/a"},
+				"email/a":           {"email/a"},
+				"saturday-disco@":   {"email/b", "email/c", "email/a"},
+				"nothing like this": {},
+			} {
+				results, err := a.Search(query, 0, 10)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(keys(results)).Should(Equal(expected), query)
+			}
+		})
+
+		It("pages through matches", func() {
+			results, err := a.Search("player", 1, 1)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(keys(results)).Should(Equal([]string{"email/c"}))
+			Ω(results.More).Should(BeTrue())
+		})
+
+		It("only searches the most recent e-mails", func() {
+			for i := 0; i < archive.MAX_SCAN; i++ {
+				store(fmt.Sprintf("new-%d", i), rawEmail("Someone <someone@example.com>", "Hi", "hello"), now.Add(time.Duration(i)*time.Minute))
+			}
+			results, err := a.Search("rain", 0, 10)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(results.Entries).Should(BeEmpty())
+			Ω(results.Stored).Should(Equal(archive.MAX_SCAN + 3))
+		})
+
+		It("still lists payloads that won't parse", func() {
+			store("broken", []byte("{not json"), now)
+			results, err := a.Search("", 0, 1)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(results.Entries[0].Key).Should(Equal("email/broken"))
+			Ω(results.Entries[0].Error).Should(HaveOccurred())
+		})
+
+		It("returns listing errors", func() {
+			db.SetFetchError(errBoom)
+			_, err := a.Search("", 0, 10)
+			Ω(err).Should(MatchError(errBoom))
+		})
+	})
+
+	Describe("re-parsing", func() {
+		It("parses the stored payload", func() {
+			email, err := a.Reparse("email/c")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(email.Subject).Should(Equal("Rain?"))
+			Ω(email.Text).Should(ContainSubstring("Is it going to rain?"))
+		})
+
+		It("refuses keys outside the archive", func() {
+			_, err := a.Reparse("saturday-disco")
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns fetch errors", func() {
+			_, err := a.Reparse("email/nope")
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("re-dispatching", func() {
+		var saturday, lunchtime *FakeDisco
+		BeforeEach(func() {
+			saturday, lunchtime = NewFakeDisco(), NewFakeDisco()
+			a.Route("saturday", saturday)
+			a.Route("lunchtime", lunchtime)
+		})
+
+		It("knows the discos", func() {
+			Ω(a.Discos()).Should(Equal([]string{"lunchtime", "saturday"}))
+		})
+
+		It("hands the re-parsed e-mail to the named disco", func() {
+			email, err := a.Redispatch("email/a", "saturday")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(email.Subject).Should(Equal("Count me in"))
+			Ω(saturday.Emails()).Should(ConsistOf(email))
+			Ω(lunchtime.Emails()).Should(BeEmpty())
+		})
+
+		It("refuses unknown discos", func() {
+			_, err := a.Redispatch("email/a", "sunday")
+			Ω(err).Should(MatchError("unknown disco: sunday"))
+		})
+
+		It("doesn't dispatch e-mail that won't parse", func() {
+			store("broken", []byte("{not json"), now)
+			_, err := a.Redispatch("email/broken", "saturday")
+			Ω(err).Should(HaveOccurred())
+			Ω(saturday.Emails()).Should(BeEmpty())
+		})
+	})
+
+	Describe("pruning", func() {
+		It("deletes payloads stored before the cutoff, and nothing else", func() {
+			deleted, err := a.Prune(now.Add(-90 * time.Minute))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(deleted).Should(Equal(2))
+
+			objects, err := db.ListObjects("")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(objects).Should(HaveLen(2))
+			Ω(objects[0].Key).Should(Equal("email/b"))
+			Ω(objects[1].Key).Should(Equal("saturday-disco"))
+		})
+
+		It("returns delete errors", func() {
+			db.SetPutError(errBoom)
+			deleted, err := a.Prune(now)
+			Ω(err).Should(MatchError(errBoom))
+			Ω(deleted).Should(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/say"
+)
+
+// the Pruner's Scheduler persists its timers under SCHEDULER_KEY
+const SCHEDULER_KEY = "archive-scheduler"
+const PRUNE_TIMER = "archive-prune"
+const PRUNE_INTERVAL = 24 * time.Hour
+
+// Pruner prunes the archive once a day.  The next prune lives on a persisted Scheduler, so restarting doesn't push it back a day - and a
+// prune that came due while we were down runs as soon as we're back.
+type Pruner struct {
+	archive   *Archive
+	scheduler clock.SchedulerInt
+	retention time.Duration
+	w         io.Writer
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewPruner(archive *Archive, scheduler clock.SchedulerInt, retention time.Duration, w io.Writer) *Pruner {
+	return &Pruner{
+		archive:   archive,
+		scheduler: scheduler,
+		retention: retention,
+		w:         w,
+	}
+}
+
+// Start prunes straight away the first time we ever run; after that the persisted timer decides
+func (p *Pruner) Start() error {
+	scheduled := false
+	for _, timer := range p.scheduler.Timers() {
+		scheduled = scheduled || timer.Name == PRUNE_TIMER
+	}
+	if !scheduled {
+		if err := p.scheduler.Schedule(PRUNE_TIMER, p.scheduler.Time()); err != nil {
+			return err
+		}
+	}
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+	p.done = make(chan struct{})
+	go p.run(ctx)
+	return nil
+}
+
+func (p *Pruner) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+	p.scheduler.Stop()
+}
+
+func (p *Pruner) run(ctx context.Context) {
+	defer close(p.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case timer := <-p.scheduler.C():
+			if timer.Name != PRUNE_TIMER {
+				continue
+			}
+			now := p.scheduler.Time()
+			if timer.Missed {
+				say.Fplni(p.w, 0, "{{yellow}}Archive: catching up on the prune we missed at %s{{/}}", timer.At.Format(time.RFC3339))
+			}
+			if _, err := p.archive.Prune(now.Add(-p.retention)); err != nil {
+				say.Fplni(p.w, 0, "{{red}}Archive: failed to prune archived e-mail: %s{{/}}", err.Error())
+			}
+			if err := p.scheduler.Schedule(PRUNE_TIMER, now.Add(PRUNE_INTERVAL)); err != nil {
+				say.Fplni(p.w, 0, "{{red}}Archive: failed to schedule the next prune: %s{{/}}", err.Error())
+			}
+		}
+	}
+}
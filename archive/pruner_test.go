@@ -0,0 +1,79 @@
+package archive_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/archive"
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+)
+
+var _ = Describe("Pruner", func() {
+	var db *s3db.FakeS3DB
+	var scheduler *clock.FakeScheduler
+	var pruner *archive.Pruner
+	var now time.Time
+
+	store := func(key string, at time.Time) {
+		GinkgoHelper()
+		Ω(db.PutObject(mail.ARCHIVE_PREFIX+key, rawEmail("Player <player@example.com>", key, "hi"))).Should(Succeed())
+		db.SetLastModified(mail.ARCHIVE_PREFIX+key, at)
+	}
+
+	stored := func() []string {
+		objects, err := db.ListObjects(mail.ARCHIVE_PREFIX)
+		Ω(err).ShouldNot(HaveOccurred())
+		out := []string{}
+		for _, object := range objects {
+			out = append(out, object.Key)
+		}
+		return out
+	}
+
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+		scheduler = clock.NewFakeScheduler()
+		now = time.Date(2023, time.September, 24, 12, 0, 0, 0, clock.Timezone)
+		scheduler.SetTime(now)
+		store("old", now.Add(-50*time.Hour))
+		store("recent", now.Add(-30*time.Hour))
+		pruner = archive.NewPruner(archive.NewArchive(db, GinkgoWriter), scheduler, 48*time.Hour, GinkgoWriter)
+	})
+
+	AfterEach(func() {
+		pruner.Stop()
+	})
+
+	It("prunes straight away the first time, then once a day", func() {
+		Ω(pruner.Start()).Should(Succeed())
+		Ω(scheduler.Timers()).Should(Equal(clock.Timers{{Name: archive.PRUNE_TIMER, At: now}}))
+
+		scheduler.Fire()
+		Eventually(stored).Should(ConsistOf(mail.ARCHIVE_PREFIX + "recent"))
+		Eventually(scheduler.Timers).Should(Equal(clock.Timers{{Name: archive.PRUNE_TIMER, At: now.Add(archive.PRUNE_INTERVAL)}}))
+
+		scheduler.Fire()
+		Eventually(stored).Should(BeEmpty())
+		Eventually(scheduler.Timers).Should(Equal(clock.Timers{{Name: archive.PRUNE_TIMER, At: now.Add(2 * archive.PRUNE_INTERVAL)}}))
+	})
+
+	It("leaves an already-scheduled prune alone", func() {
+		Ω(scheduler.Schedule(archive.PRUNE_TIMER, now.Add(time.Hour))).Should(Succeed())
+		Ω(pruner.Start()).Should(Succeed())
+		Ω(scheduler.Timers()).Should(Equal(clock.Timers{{Name: archive.PRUNE_TIMER, At: now.Add(time.Hour)}}))
+		Consistently(stored, "50ms").Should(HaveLen(2))
+	})
+
+	It("catches up on a prune it missed while it was down", func() {
+		Ω(scheduler.Schedule(archive.PRUNE_TIMER, now.Add(time.Hour))).Should(Succeed())
+		Ω(pruner.Start()).Should(Succeed())
+
+		scheduler.SimulateDowntime(now.Add(20 * time.Hour))
+		Eventually(stored).Should(BeEmpty())
+		Eventually(scheduler.Timers).Should(Equal(clock.Timers{{Name: archive.PRUNE_TIMER, At: now.Add(20*time.Hour + archive.PRUNE_INTERVAL)}}))
+	})
+})
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/mail"
+)
+
+// we keep a rolling window - plenty to answer "who did that?"
+const MAX_ENTRIES = 200
+
+type Entry struct {
+	Time   time.Time         `json:"time"`
+	Actor  mail.EmailAddress `json:"actor"`
+	Action string            `json:"action"`
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s - %s %s", e.Time.Format("Mon 1/2 3:04pm"), e.Actor, e.Action)
+}
+
+type Log []Entry
+
+func (l Log) Record(t time.Time, actor mail.EmailAddress, format string, args ...any) Log {
+	out := append(l.Dup(), Entry{
+		Time:   t,
+		Actor:  actor,
+		Action: fmt.Sprintf(format, args...),
+	})
+	if len(out) > MAX_ENTRIES {
+		out = out[len(out)-MAX_ENTRIES:]
+	}
+	return out
+}
+
+func (l Log) Last() (Entry, bool) {
+	if len(l) == 0 {
+		return Entry{}, false
+	}
+	return l[len(l)-1], true
+}
+
+// Recent returns the last n entries, most recent first
+func (l Log) Recent(n int) Log {
+	out := Log{}
+	for i := len(l) - 1; i >= 0 && len(out) < n; i-- {
+		out = append(out, l[i])
+	}
+	return out
+}
+
+func (l Log) Dup() Log {
+	out := make(Log, len(l))
+	copy(out, l)
+	return out
+}
+
+func (l Log) String() string {
+	out := &strings.Builder{}
+	for _, entry := range l {
+		out.WriteString("- " + entry.String() + "\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,53 @@
+package audit_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/audit"
+)
+
+var _ = Describe("Audit", func() {
+	var now time.Time
+	BeforeEach(func() {
+		now = time.Date(2023, time.September, 24, 10, 0, 0, 0, time.UTC)
+	})
+
+	It("records who did what, without mutating the original log", func() {
+		log := audit.Log{}
+		updated := log.Record(now, "Boss <boss@example.com>", "approved the %s", "invite")
+		Ω(log).Should(BeEmpty())
+		Ω(updated).Should(HaveLen(1))
+
+		last, ok := updated.Last()
+		Ω(ok).Should(BeTrue())
+		Ω(last.Actor).Should(Equal(updated[0].Actor))
+		Ω(last.Action).Should(Equal("approved the invite"))
+		Ω(last.String()).Should(Equal("Sun 9/24 10:00am - Boss <boss@example.com> approved the invite"))
+
+		_, ok = log.Last()
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("returns recent entries, most recent first", func() {
+		log := audit.Log{}.
+			Record(now, "a@example.com", "one").
+			Record(now, "b@example.com", "two").
+			Record(now, "c@example.com", "three")
+		recent := log.Recent(2)
+		Ω(recent).Should(HaveLen(2))
+		Ω(recent[0].Action).Should(Equal("three"))
+		Ω(recent[1].Action).Should(Equal("two"))
+	})
+
+	It("only keeps a rolling window of entries", func() {
+		log := audit.Log{}
+		for i := 0; i < audit.MAX_ENTRIES+10; i++ {
+			log = log.Record(now, "a@example.com", "action %d", i)
+		}
+		Ω(log).Should(HaveLen(audit.MAX_ENTRIES))
+		Ω(log[0].Action).Should(Equal("action 10"))
+	})
+})
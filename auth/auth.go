@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/signing"
+)
+
+const LOGIN_TOKEN_PURPOSE = "login"
+const LOGIN_TOKEN_TTL = 15 * time.Minute
+const SESSION_TOKEN_PURPOSE = "session"
+const SESSION_TTL = 30 * 24 * time.Hour
+const SESSION_COOKIE = "disco_session"
+
+var ErrNotAnOrganizer = errors.New("not an organizer")
+var ErrNoSession = errors.New("no session")
+
+type loginToken struct {
+	Address mail.EmailAddress `json:"address"`
+	Next    string            `json:"next"`
+}
+
+type sessionToken struct {
+	Address mail.EmailAddress `json:"address"`
+}
+
+// Authenticator hands out magic-link login tokens and turns them into signed session cookies.
+//
+// Sessions only record who you are - your role is looked up in the config on every request so that
+// removing someone from ORGANIZERS revokes their access immediately.
+type Authenticator struct {
+	signer signing.Signer
+	config config.Config
+}
+
+func NewAuthenticator(conf config.Config, signer signing.Signer) Authenticator {
+	return Authenticator{
+		signer: signer,
+		config: conf,
+	}
+}
+
+// LoginToken returns a short-lived token for address.  It fails with ErrNotAnOrganizer for strangers.
+func (a Authenticator) LoginToken(address mail.EmailAddress, next string, now time.Time) (config.Organizer, string, error) {
+	organizer, ok := a.config.AllOrganizers().Find(address)
+	if !ok {
+		return config.Organizer{}, "", ErrNotAnOrganizer
+	}
+	token, err := a.signer.Sign(LOGIN_TOKEN_PURPOSE, loginToken{Address: organizer.Address, Next: SafeNext(next)}, now.Add(LOGIN_TOKEN_TTL))
+	return organizer, token, err
+}
+
+// Login exchanges a login token for a session cookie and the path to send the organizer to
+func (a Authenticator) Login(token string, now time.Time) (*http.Cookie, string, error) {
+	var login loginToken
+	if err := a.signer.Verify(LOGIN_TOKEN_PURPOSE, token, now, &login); err != nil {
+		return nil, "", err
+	}
+	if _, ok := a.config.AllOrganizers().Find(login.Address); !ok {
+		return nil, "", ErrNotAnOrganizer
+	}
+	expiresAt := now.Add(SESSION_TTL)
+	session, err := a.signer.Sign(SESSION_TOKEN_PURPOSE, sessionToken{Address: login.Address}, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	return a.cookie(session, expiresAt), SafeNext(login.Next), nil
+}
+
+// Authenticate returns the organizer behind the request's session cookie
+func (a Authenticator) Authenticate(r *http.Request, now time.Time) (config.Organizer, error) {
+	cookie, err := r.Cookie(SESSION_COOKIE)
+	if err != nil {
+		return config.Organizer{}, ErrNoSession
+	}
+	var session sessionToken
+	if err := a.signer.Verify(SESSION_TOKEN_PURPOSE, cookie.Value, now, &session); err != nil {
+		return config.Organizer{}, err
+	}
+	organizer, ok := a.config.AllOrganizers().Find(session.Address)
+	if !ok {
+		return config.Organizer{}, ErrNotAnOrganizer
+	}
+	return organizer, nil
+}
+
+func (a Authenticator) LogoutCookie() *http.Cookie {
+	cookie := a.cookie("", time.Unix(0, 0))
+	cookie.MaxAge = -1
+	return cookie
+}
+
+func (a Authenticator) cookie(value string, expiresAt time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     SESSION_COOKIE,
+		Value:    value,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   a.config.IsPROD(),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// SafeNext only allows local redirects
+func SafeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/saturday/boss"
+	}
+	return next
+}
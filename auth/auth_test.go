@@ -0,0 +1,111 @@
+package auth_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/auth"
+	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/signing"
+)
+
+var _ = Describe("Auth", func() {
+	var conf config.Config
+	var authenticator auth.Authenticator
+	var now time.Time
+
+	requestWith := func(cookie *http.Cookie) *http.Request {
+		r, err := http.NewRequest(http.MethodGet, "/saturday/boss", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		return r
+	}
+
+	login := func(address string) *http.Cookie {
+		_, token, err := authenticator.LoginToken(mail.EmailAddress(address), "/lunchtime/boss", now)
+		Ω(err).ShouldNot(HaveOccurred())
+		cookie, next, err := authenticator.Login(token, now)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(next).Should(Equal("/lunchtime/boss"))
+		return cookie
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2023, time.September, 24, 10, 0, 0, 0, time.UTC)
+		conf = config.Config{
+			BossEmail: "Boss <boss@example.com>",
+			Organizers: config.Organizers{
+				{Address: "Helper <helper@example.com>", Role: config.RoleOrganizer},
+				{Address: "Lurker <lurker@example.com>", Role: config.RoleViewer},
+			},
+		}
+		authenticator = auth.NewAuthenticator(conf, signing.NewSigner("sekrit"))
+	})
+
+	It("logs organizers in via a login token and recognizes their session", func() {
+		cookie := login("boss@example.com")
+		Ω(cookie.HttpOnly).Should(BeTrue())
+
+		organizer, err := authenticator.Authenticate(requestWith(cookie), now.Add(time.Hour))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(organizer.Address).Should(Equal(conf.BossEmail))
+		Ω(organizer.Role).Should(Equal(config.RoleBoss))
+
+		organizer, err = authenticator.Authenticate(requestWith(login("HELPER@example.com")), now)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(organizer.Role).Should(Equal(config.RoleOrganizer))
+		Ω(organizer.Role.CanManage()).Should(BeTrue())
+
+		organizer, err = authenticator.Authenticate(requestWith(login("lurker@example.com")), now)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(organizer.Role.CanManage()).Should(BeFalse())
+	})
+
+	It("refuses to hand out login tokens to strangers", func() {
+		_, _, err := authenticator.LoginToken("stranger@example.com", "/", now)
+		Ω(err).Should(MatchError(auth.ErrNotAnOrganizer))
+	})
+
+	It("rejects expired login tokens", func() {
+		_, token, err := authenticator.LoginToken("boss@example.com", "/", now)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, _, err = authenticator.Login(token, now.Add(auth.LOGIN_TOKEN_TTL+time.Second))
+		Ω(err).Should(MatchError(signing.ErrExpiredToken))
+	})
+
+	It("doesn't accept login tokens as sessions", func() {
+		_, token, err := authenticator.LoginToken("boss@example.com", "/", now)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = authenticator.Authenticate(requestWith(&http.Cookie{Name: auth.SESSION_COOKIE, Value: token}), now)
+		Ω(err).Should(MatchError(signing.ErrInvalidToken))
+	})
+
+	It("rejects missing and expired sessions", func() {
+		_, err := authenticator.Authenticate(requestWith(nil), now)
+		Ω(err).Should(MatchError(auth.ErrNoSession))
+
+		_, err = authenticator.Authenticate(requestWith(login("boss@example.com")), now.Add(auth.SESSION_TTL+time.Second))
+		Ω(err).Should(MatchError(signing.ErrExpiredToken))
+	})
+
+	It("revokes sessions when an organizer is removed from the config", func() {
+		cookie := login("helper@example.com")
+		conf.Organizers = config.Organizers{}
+		authenticator = auth.NewAuthenticator(conf, signing.NewSigner("sekrit"))
+		_, err := authenticator.Authenticate(requestWith(cookie), now)
+		Ω(err).Should(MatchError(auth.ErrNotAnOrganizer))
+	})
+
+	It("only redirects locally", func() {
+		Ω(auth.SafeNext("/lunchtime/boss")).Should(Equal("/lunchtime/boss"))
+		Ω(auth.SafeNext("https://evil.example.com")).Should(Equal("/saturday/boss"))
+		Ω(auth.SafeNext("//evil.example.com")).Should(Equal("/saturday/boss"))
+		Ω(auth.SafeNext("")).Should(Equal("/saturday/boss"))
+	})
+})
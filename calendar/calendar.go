@@ -0,0 +1,143 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/mail"
+)
+
+const PRODID = "-//sedenverultimate.net//Disco//EN"
+const ICS_TIME_FORMAT = "20060102T150405Z"
+
+// Method says what a calendar is for: PUBLISH for feeds, REQUEST and CANCEL for invites sent by e-mail
+type Method string
+
+const (
+	MethodPublish Method = "PUBLISH"
+	MethodRequest Method = "REQUEST"
+	MethodCancel  Method = "CANCEL"
+)
+
+type Status string
+
+const (
+	StatusConfirmed Status = "CONFIRMED"
+	StatusCancelled Status = "CANCELLED"
+)
+
+// Event is a single game.  UID must stay the same across updates to the game, and Sequence must go up each time we send an update
+// so calendar apps know which version is newest.
+type Event struct {
+	UID         string
+	Sequence    int
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Location    string
+	Description string
+	URL         string
+	Status      Status
+	Organizer   mail.EmailAddress
+}
+
+func (e Event) IsZero() bool {
+	return e.UID == ""
+}
+
+type Calendar struct {
+	Name   string
+	Method Method
+	Events []Event
+}
+
+// Render returns the calendar in iCalendar (RFC 5545) format.  now is used to stamp the events.
+func (c Calendar) Render(now time.Time) []byte {
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + PRODID)
+	w.line("CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		w.line("METHOD:" + string(c.Method))
+	}
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	for _, event := range c.Events {
+		status := event.Status
+		if c.Method == MethodCancel {
+			status = StatusCancelled
+		}
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + event.UID)
+		w.line(fmt.Sprintf("SEQUENCE:%d", event.Sequence))
+		w.line("DTSTAMP:" + now.UTC().Format(ICS_TIME_FORMAT))
+		w.line("DTSTART:" + event.Start.UTC().Format(ICS_TIME_FORMAT))
+		w.line("DTEND:" + event.End.UTC().Format(ICS_TIME_FORMAT))
+		w.line("SUMMARY:" + escape(event.Summary))
+		if event.Location != "" {
+			w.line("LOCATION:" + escape(event.Location))
+		}
+		if event.Description != "" {
+			w.line("DESCRIPTION:" + escape(event.Description))
+		}
+		if event.URL != "" {
+			w.line("URL:" + event.URL)
+		}
+		if status != "" {
+			w.line("STATUS:" + string(status))
+		}
+		if event.Organizer != "" {
+			w.line(fmt.Sprintf("ORGANIZER;CN=%s:mailto:%s", quoteParam(event.Organizer.Name()), event.Organizer.Address()))
+		}
+		w.line("END:VEVENT")
+	}
+	w.line("END:VCALENDAR")
+	return []byte(w.String())
+}
+
+// Attachment renders the calendar as an e-mail attachment that calendar apps will offer to add (or remove)
+func (c Calendar) Attachment(filename string, now time.Time) mail.Attachment {
+	contentType := "text/calendar; charset=utf-8"
+	if c.Method != "" {
+		contentType = fmt.Sprintf("text/calendar; method=%s; charset=utf-8", c.Method)
+	}
+	return mail.Attachment{
+		Filename:    filename,
+		ContentType: contentType,
+		Content:     c.Render(now),
+	}
+}
+
+// lines are CRLF terminated and folded at 75 octets, per the spec
+type writer struct {
+	strings.Builder
+}
+
+func (w *writer) line(l string) {
+	for len(l) > 75 {
+		cut := 75
+		for cut > 0 && !isRuneStart(l[cut]) {
+			cut--
+		}
+		w.WriteString(l[:cut] + "\r\n")
+		l = " " + l[cut:]
+	}
+	w.WriteString(l + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func quoteParam(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "'") + `"`
+}
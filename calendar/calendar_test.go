@@ -0,0 +1,85 @@
+package calendar_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/calendar"
+	"github.com/onsi/disco/clock"
+)
+
+var _ = Describe("Calendar", func() {
+	var now time.Time
+	var event calendar.Event
+
+	BeforeEach(func() {
+		now = time.Date(2023, time.September, 28, 9, 0, 0, 0, clock.Timezone)
+		start := time.Date(2023, time.September, 30, 10, 0, 0, 0, clock.Timezone)
+		event = calendar.Event{
+			UID:         "saturday-2023-09-30@sedenverultimate.net",
+			Sequence:    1,
+			Start:       start,
+			End:         start.Add(2 * time.Hour),
+			Summary:     "Saturday Frisbee, at last",
+			Location:    "James Bible Park",
+			Description: "Bring a red shirt; a blue shirt\nand a white shirt",
+			URL:         "https://maps.app.goo.gl/P1vm2nkZdYLGZbxb9",
+			Status:      calendar.StatusConfirmed,
+			Organizer:   "Disco <saturday-disco@sedenverultimate.net>",
+		}
+	})
+
+	It("renders events in iCalendar format", func() {
+		ics := string(calendar.Calendar{Name: "Saturday Disco", Method: calendar.MethodRequest, Events: []calendar.Event{event}}.Render(now))
+		Ω(ics).Should(Equal(strings.Join([]string{
+			"BEGIN:VCALENDAR",
+			"VERSION:2.0",
+			"PRODID:-//sedenverultimate.net//Disco//EN",
+			"CALSCALE:GREGORIAN",
+			"METHOD:REQUEST",
+			"X-WR-CALNAME:Saturday Disco",
+			"BEGIN:VEVENT",
+			"UID:saturday-2023-09-30@sedenverultimate.net",
+			"SEQUENCE:1",
+			"DTSTAMP:20230928T150000Z",
+			"DTSTART:20230930T160000Z",
+			"DTEND:20230930T180000Z",
+			`SUMMARY:Saturday Frisbee\, at last`,
+			"LOCATION:James Bible Park",
+			`DESCRIPTION:Bring a red shirt\; a blue shirt\nand a white shirt`,
+			"URL:https://maps.app.goo.gl/P1vm2nkZdYLGZbxb9",
+			"STATUS:CONFIRMED",
+			`ORGANIZER;CN="Disco":mailto:saturday-disco@sedenverultimate.net`,
+			"END:VEVENT",
+			"END:VCALENDAR",
+			"",
+		}, "\r\n")))
+	})
+
+	It("marks events as cancelled when cancelling", func() {
+		ics := string(calendar.Calendar{Method: calendar.MethodCancel, Events: []calendar.Event{event}}.Render(now))
+		Ω(ics).Should(ContainSubstring("METHOD:CANCEL\r\n"))
+		Ω(ics).Should(ContainSubstring("STATUS:CANCELLED\r\n"))
+		Ω(ics).ShouldNot(ContainSubstring("X-WR-CALNAME"))
+	})
+
+	It("folds long lines without splitting characters", func() {
+		event.Description = strings.Repeat("🥏", 40)
+		ics := string(calendar.Calendar{Events: []calendar.Event{event}}.Render(now))
+		for _, line := range strings.Split(ics, "\r\n") {
+			Ω(len(line)).Should(BeNumerically("<=", 75))
+		}
+		unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+		Ω(unfolded).Should(ContainSubstring("DESCRIPTION:" + strings.Repeat("🥏", 40) + "\r\n"))
+	})
+
+	It("can be attached to an e-mail", func() {
+		attachment := calendar.Calendar{Method: calendar.MethodCancel, Events: []calendar.Event{event}}.Attachment("invite.ics", now)
+		Ω(attachment.Filename).Should(Equal("invite.ics"))
+		Ω(attachment.ContentType).Should(Equal("text/calendar; method=CANCEL; charset=utf-8"))
+		Ω(string(attachment.Content)).Should(HavePrefix("BEGIN:VCALENDAR\r\n"))
+	})
+})
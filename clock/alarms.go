@@ -0,0 +1,83 @@
+package clock
+
+import (
+	"sort"
+	"time"
+)
+
+// Alarms multiplexes any number of named alarms onto a single AlarmClockInt.  The underlying alarm is always set for the earliest one.
+// Alarms is not thread-safe - it's meant to be driven from a disco's dance loop.
+type Alarms struct {
+	clock  AlarmClockInt
+	alarms map[string]time.Time
+}
+
+func NewAlarms(clock AlarmClockInt) *Alarms {
+	return &Alarms{
+		clock:  clock,
+		alarms: map[string]time.Time{},
+	}
+}
+
+// Set (re)schedules the named alarm - a zero time clears it
+func (a *Alarms) Set(name string, t time.Time) {
+	if t.IsZero() {
+		delete(a.alarms, name)
+	} else {
+		a.alarms[name] = t
+	}
+	a.arm()
+}
+
+func (a *Alarms) Clear(name string) {
+	delete(a.alarms, name)
+	a.arm()
+}
+
+func (a *Alarms) ClearAll() {
+	a.alarms = map[string]time.Time{}
+	a.arm()
+}
+
+func (a *Alarms) Get(name string) time.Time {
+	return a.alarms[name]
+}
+
+// Names returns the pending alarms, earliest first
+func (a *Alarms) Names() []string {
+	names := []string{}
+	for name := range a.alarms {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if a.alarms[names[i]].Equal(a.alarms[names[j]]) {
+			return names[i] < names[j]
+		}
+		return a.alarms[names[i]].Before(a.alarms[names[j]])
+	})
+	return names
+}
+
+// Due removes and returns the alarms that have gone off, earliest first
+func (a *Alarms) Due() []string {
+	now := a.clock.Time()
+	due := []string{}
+	for _, name := range a.Names() {
+		if a.alarms[name].After(now) {
+			break
+		}
+		due = append(due, name)
+		delete(a.alarms, name)
+	}
+	a.arm()
+	return due
+}
+
+func (a *Alarms) arm() {
+	names := a.Names()
+	if len(names) == 0 {
+		a.clock.Stop()
+		return
+	}
+	a.clock.SetAlarm(a.alarms[names[0]])
+}
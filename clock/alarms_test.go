@@ -0,0 +1,73 @@
+package clock_test
+
+import (
+	"time"
+
+	"github.com/onsi/disco/clock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alarms", func() {
+	var fake *clock.FakeAlarmClock
+	var alarms *clock.Alarms
+	var now time.Time
+
+	BeforeEach(func() {
+		fake = clock.NewFakeAlarmClock()
+		now = time.Date(2023, time.September, 24, 0, 0, 0, 0, clock.Timezone)
+		fake.SetTime(now)
+		alarms = clock.NewAlarms(fake)
+	})
+
+	It("keeps the underlying alarm set for the earliest alarm", func() {
+		alarms.Set("later", now.Add(2*time.Hour))
+		alarms.Set("sooner", now.Add(time.Hour))
+		Ω(alarms.Names()).Should(Equal([]string{"sooner", "later"}))
+
+		go fake.Fire()
+		Eventually(fake.C()).Should(Receive(Equal(now.Add(time.Hour))))
+		Ω(alarms.Due()).Should(Equal([]string{"sooner"}))
+		Ω(alarms.Names()).Should(Equal([]string{"later"}))
+
+		go fake.Fire()
+		Eventually(fake.C()).Should(Receive(Equal(now.Add(2 * time.Hour))))
+		Ω(alarms.Due()).Should(Equal([]string{"later"}))
+		Ω(alarms.Names()).Should(BeEmpty())
+	})
+
+	It("returns every alarm that has gone off, earliest first", func() {
+		alarms.Set("b", now.Add(time.Hour))
+		alarms.Set("a", now.Add(time.Hour))
+		alarms.Set("c", now.Add(-time.Hour))
+		alarms.Set("d", now.Add(3*time.Hour))
+		fake.SetTime(now.Add(2 * time.Hour))
+		Ω(alarms.Due()).Should(Equal([]string{"c", "a", "b"}))
+		Ω(alarms.Names()).Should(Equal([]string{"d"}))
+	})
+
+	It("replaces alarms with the same name and clears alarms set to the zero time", func() {
+		alarms.Set("a", now.Add(time.Hour))
+		alarms.Set("a", now.Add(2*time.Hour))
+		Ω(alarms.Get("a")).Should(Equal(now.Add(2 * time.Hour)))
+		alarms.Set("b", now.Add(3*time.Hour))
+		alarms.Set("a", time.Time{})
+		Ω(alarms.Names()).Should(Equal([]string{"b"}))
+		alarms.Clear("b")
+		Ω(alarms.Names()).Should(BeEmpty())
+	})
+
+	It("works with the real alarm clock", func() {
+		c := clock.NewAlarmClock()
+		DeferCleanup(c.Stop)
+		alarms = clock.NewAlarms(c)
+		alarms.Set("later", time.Now().Add(300*time.Millisecond))
+		alarms.Set("sooner", time.Now().Add(50*time.Millisecond))
+		Eventually(c.C()).WithTimeout(200 * time.Millisecond).Should(Receive())
+		Ω(alarms.Due()).Should(Equal([]string{"sooner"}))
+		Eventually(c.C()).WithTimeout(500 * time.Millisecond).Should(Receive())
+		Ω(alarms.Due()).Should(Equal([]string{"later"}))
+		alarms.ClearAll()
+		Consistently(c.C()).WithTimeout(100 * time.Millisecond).ShouldNot(Receive())
+	})
+})
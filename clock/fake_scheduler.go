@@ -0,0 +1,98 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type FakeScheduler struct {
+	now    time.Time
+	timers Timers
+	c      chan Timer
+	lock   *sync.Mutex
+}
+
+func NewFakeScheduler() *FakeScheduler {
+	return &FakeScheduler{
+		timers: Timers{},
+		c:      make(chan Timer),
+		lock:   &sync.Mutex{},
+	}
+}
+
+func (f *FakeScheduler) SetTime(t time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = t
+}
+
+// Fire moves time forward to the earliest timer and sends it.  It blocks until the timer is received.
+func (f *FakeScheduler) Fire() {
+	f.lock.Lock()
+	if len(f.timers) == 0 {
+		f.lock.Unlock()
+		return
+	}
+	timer := f.timers[0]
+	f.timers = f.timers[1:]
+	f.now = timer.At
+	f.lock.Unlock()
+	f.c <- timer
+}
+
+// SimulateDowntime moves time forward to t and then sends every timer that came due in the meantime with Missed set - just like a Scheduler
+// replaying timers after a restart.  It blocks until they've all been received.
+func (f *FakeScheduler) SimulateDowntime(t time.Time) {
+	f.lock.Lock()
+	f.now = t
+	missed := Timers{}
+	for len(f.timers) > 0 && !f.timers[0].At.After(t) {
+		timer := f.timers[0]
+		timer.Missed = true
+		missed = append(missed, timer)
+		f.timers = f.timers[1:]
+	}
+	f.lock.Unlock()
+	for _, timer := range missed {
+		f.c <- timer
+	}
+}
+
+// SchedulerInt interface
+
+func (f *FakeScheduler) Time() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.now
+}
+
+func (f *FakeScheduler) C() <-chan Timer {
+	return f.c
+}
+
+func (f *FakeScheduler) Schedule(name string, at time.Time) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.timers = append(f.timers.without(name), Timer{Name: name, At: at})
+	f.timers.sort()
+	return nil
+}
+
+func (f *FakeScheduler) Cancel(name string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.timers = f.timers.without(name)
+	return nil
+}
+
+func (f *FakeScheduler) Timers() Timers {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append(Timers{}, f.timers...)
+}
+
+func (f *FakeScheduler) Stop() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.timers = Timers{}
+}
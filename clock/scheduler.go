@@ -0,0 +1,214 @@
+package clock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/onsi/disco/s3db"
+)
+
+// Timer is a named timer held by a Scheduler.  Missed is set on timers that came due while the process was down and are being replayed on startup.
+type Timer struct {
+	Name   string    `json:"name"`
+	At     time.Time `json:"at"`
+	Missed bool      `json:"missed,omitempty"`
+}
+
+type Timers []Timer
+
+func (t Timers) sort() {
+	sort.SliceStable(t, func(i, j int) bool {
+		if t[i].At.Equal(t[j].At) {
+			return t[i].Name < t[j].Name
+		}
+		return t[i].At.Before(t[j].At)
+	})
+}
+
+type SchedulerInt interface {
+	Time() time.Time
+	C() <-chan Timer
+	Schedule(name string, at time.Time) error
+	Cancel(name string) error
+	Timers() Timers
+	Stop()
+}
+
+// Scheduler holds any number of named timers and sends each one on C() when it comes due.  Timers are persisted to the db under key so that
+// timers that came due while the process was down are replayed (in order, with Missed set) as soon as the Scheduler starts back up.
+//
+// Unlike Alarms, Scheduler is safe to use from multiple goroutines.
+type Scheduler struct {
+	clock AlarmClockInt
+	db    s3db.S3DBInt
+	key   string
+
+	alarms  *Alarms
+	pending Timers // timers that have come due but haven't been received yet
+	c       chan Timer
+	wake    chan struct{}
+	lock    *sync.Mutex
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewScheduler loads any persisted timers and starts the scheduler.  Timers that should have fired while we were down are delivered first.
+func NewScheduler(clock AlarmClockInt, db s3db.S3DBInt, key string) (*Scheduler, error) {
+	s := &Scheduler{
+		clock:  clock,
+		db:     db,
+		key:    key,
+		alarms: NewAlarms(clock),
+		c:      make(chan Timer),
+		wake:   make(chan struct{}, 1),
+		lock:   &sync.Mutex{},
+		done:   make(chan struct{}),
+	}
+
+	data, err := db.FetchObject(key)
+	if err != nil && !errors.Is(err, s3db.ErrObjectNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		var timers Timers
+		if err := json.Unmarshal(data, &timers); err != nil {
+			return nil, err
+		}
+		now := clock.Time()
+		for _, timer := range timers {
+			timer.At = timer.At.In(Timezone)
+			if timer.Missed || !timer.At.After(now) {
+				timer.Missed = true
+				s.pending = append(s.pending, timer)
+			} else {
+				s.alarms.Set(timer.Name, timer.At)
+			}
+		}
+		s.pending.sort()
+	}
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	go s.run(ctx)
+	return s, nil
+}
+
+func (s *Scheduler) Time() time.Time {
+	return s.clock.Time()
+}
+
+func (s *Scheduler) C() <-chan Timer {
+	return s.c
+}
+
+// Schedule (re)sets the named timer and persists the change.  A timer that is already due but hasn't been received yet is replaced.
+func (s *Scheduler) Schedule(name string, at time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pending = s.pending.without(name)
+	s.alarms.Set(name, at)
+	s.poke()
+	return s.persist()
+}
+
+// Cancel clears the named timer, even if it has already come due but hasn't been received yet
+func (s *Scheduler) Cancel(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pending = s.pending.without(name)
+	s.alarms.Clear(name)
+	s.poke()
+	return s.persist()
+}
+
+// Timers returns every timer that has yet to be received, earliest first
+func (s *Scheduler) Timers() Timers {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.timers()
+}
+
+func (s *Scheduler) Stop() {
+	s.cancel()
+	<-s.done
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clock.Stop()
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	defer close(s.done)
+	for {
+		s.lock.Lock()
+		var out chan Timer
+		var next Timer
+		if len(s.pending) > 0 {
+			out, next = s.c, s.pending[0]
+		}
+		s.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-s.clock.C():
+			s.lock.Lock()
+			times := map[string]time.Time{}
+			for _, name := range s.alarms.Names() {
+				times[name] = s.alarms.Get(name)
+			}
+			for _, name := range s.alarms.Due() {
+				s.pending = append(s.pending, Timer{Name: name, At: times[name]})
+			}
+			s.lock.Unlock()
+		case out <- next:
+			s.lock.Lock()
+			// (the timer may have been rescheduled or cancelled while we were waiting to send it - in which case it's no longer at the front)
+			if len(s.pending) > 0 && s.pending[0] == next {
+				s.pending = s.pending[1:]
+			}
+			s.persist()
+			s.lock.Unlock()
+		}
+	}
+}
+
+// poke lets the run loop know that the pending timers have changed out from under it
+func (s *Scheduler) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) timers() Timers {
+	timers := Timers{}
+	timers = append(timers, s.pending...)
+	for _, name := range s.alarms.Names() {
+		timers = append(timers, Timer{Name: name, At: s.alarms.Get(name)})
+	}
+	return timers
+}
+
+func (s *Scheduler) persist() error {
+	data, err := json.Marshal(s.timers())
+	if err != nil {
+		return err
+	}
+	return s.db.PutObject(s.key, data)
+}
+
+func (t Timers) without(name string) Timers {
+	out := Timers{}
+	for _, timer := range t {
+		if timer.Name != name {
+			out = append(out, timer)
+		}
+	}
+	return out
+}
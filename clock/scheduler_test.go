@@ -0,0 +1,145 @@
+package clock_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/s3db"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var errBoom = errors.New("boom")
+
+var _ = Describe("Scheduler", func() {
+	var fake *clock.FakeAlarmClock
+	var db *s3db.FakeS3DB
+	var scheduler *clock.Scheduler
+	var now time.Time
+
+	BeforeEach(func() {
+		fake = clock.NewFakeAlarmClock()
+		db = s3db.NewFakeS3DB()
+		now = time.Date(2023, time.September, 24, 0, 0, 0, 0, clock.Timezone)
+		fake.SetTime(now)
+		var err error
+		scheduler, err = clock.NewScheduler(fake, db, "timers")
+		Ω(err).ShouldNot(HaveOccurred())
+		DeferCleanup(func() { scheduler.Stop() })
+	})
+
+	restart := func(at time.Time) {
+		GinkgoHelper()
+		scheduler.Stop()
+		fake = clock.NewFakeAlarmClock()
+		fake.SetTime(at)
+		var err error
+		scheduler, err = clock.NewScheduler(fake, db, "timers")
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	It("holds many named timers and sends each one when it comes due", func() {
+		Ω(scheduler.Schedule("later", now.Add(2*time.Hour))).Should(Succeed())
+		Ω(scheduler.Schedule("sooner", now.Add(time.Hour))).Should(Succeed())
+		Ω(scheduler.Schedule("cancelled", now.Add(90*time.Minute))).Should(Succeed())
+		Ω(scheduler.Cancel("cancelled")).Should(Succeed())
+		Ω(scheduler.Timers()).Should(Equal(clock.Timers{{Name: "sooner", At: now.Add(time.Hour)}, {Name: "later", At: now.Add(2 * time.Hour)}}))
+
+		go fake.Fire()
+		Eventually(scheduler.C()).Should(Receive(Equal(clock.Timer{Name: "sooner", At: now.Add(time.Hour)})))
+		go fake.Fire()
+		Eventually(scheduler.C()).Should(Receive(Equal(clock.Timer{Name: "later", At: now.Add(2 * time.Hour)})))
+		Eventually(scheduler.Timers).Should(BeEmpty())
+	})
+
+	It("replaces timers that are rescheduled", func() {
+		Ω(scheduler.Schedule("a", now.Add(time.Hour))).Should(Succeed())
+		Ω(scheduler.Schedule("a", now.Add(3*time.Hour))).Should(Succeed())
+		Ω(scheduler.Timers()).Should(Equal(clock.Timers{{Name: "a", At: now.Add(3 * time.Hour)}}))
+	})
+
+	It("persists timers and picks them back up on restart", func() {
+		Ω(scheduler.Schedule("a", now.Add(time.Hour))).Should(Succeed())
+		Ω(scheduler.Schedule("b", now.Add(2*time.Hour))).Should(Succeed())
+
+		restart(now.Add(30 * time.Minute))
+		Ω(scheduler.Timers()).Should(HaveLen(2))
+		Consistently(scheduler.C()).ShouldNot(Receive())
+		go fake.Fire()
+		Eventually(scheduler.C()).Should(Receive(HaveField("Name", "a")))
+	})
+
+	It("replays timers that came due while it was down, in order and marked as missed", func() {
+		Ω(scheduler.Schedule("b", now.Add(2*time.Hour))).Should(Succeed())
+		Ω(scheduler.Schedule("a", now.Add(time.Hour))).Should(Succeed())
+		Ω(scheduler.Schedule("c", now.Add(5*time.Hour))).Should(Succeed())
+
+		restart(now.Add(3 * time.Hour))
+		Eventually(scheduler.C()).Should(Receive(Equal(clock.Timer{Name: "a", At: now.Add(time.Hour), Missed: true})))
+		Eventually(scheduler.C()).Should(Receive(Equal(clock.Timer{Name: "b", At: now.Add(2 * time.Hour), Missed: true})))
+		Consistently(scheduler.C()).ShouldNot(Receive())
+		Ω(scheduler.Timers()).Should(HaveExactElements(HaveField("Name", "c")))
+
+		By("not replaying timers that have already been received")
+		restart(now.Add(4 * time.Hour))
+		Consistently(scheduler.C()).ShouldNot(Receive())
+		Ω(scheduler.Timers()).Should(HaveLen(1))
+	})
+
+	It("doesn't send timers that are cancelled before they're received", func() {
+		Ω(scheduler.Schedule("a", now.Add(time.Hour))).Should(Succeed())
+		restart(now.Add(2 * time.Hour))
+		Ω(scheduler.Cancel("a")).Should(Succeed())
+		Consistently(scheduler.C()).ShouldNot(Receive())
+		Ω(scheduler.Timers()).Should(BeEmpty())
+	})
+
+	It("fails to start if the persisted timers can't be fetched", func() {
+		db.SetFetchError(s3db.ErrObjectNotFound)
+		s, err := clock.NewScheduler(clock.NewFakeAlarmClock(), db, "timers")
+		Ω(err).ShouldNot(HaveOccurred())
+		s.Stop()
+
+		db.SetFetchError(errBoom)
+		_, err = clock.NewScheduler(clock.NewFakeAlarmClock(), db, "timers")
+		Ω(err).Should(MatchError(errBoom))
+	})
+
+	It("works with the real alarm clock", func() {
+		s, err := clock.NewScheduler(clock.NewAlarmClock(), s3db.NewFakeS3DB(), "timers")
+		Ω(err).ShouldNot(HaveOccurred())
+		DeferCleanup(s.Stop)
+		Ω(s.Schedule("later", time.Now().Add(300*time.Millisecond))).Should(Succeed())
+		Ω(s.Schedule("sooner", time.Now().Add(50*time.Millisecond))).Should(Succeed())
+		Eventually(s.C()).WithTimeout(200 * time.Millisecond).Should(Receive(HaveField("Name", "sooner")))
+		Eventually(s.C()).WithTimeout(500 * time.Millisecond).Should(Receive(HaveField("Name", "later")))
+	})
+
+	Describe("FakeScheduler", func() {
+		var f *clock.FakeScheduler
+
+		BeforeEach(func() {
+			f = clock.NewFakeScheduler()
+			f.SetTime(now)
+			f.Schedule("b", now.Add(2*time.Hour))
+			f.Schedule("a", now.Add(time.Hour))
+			f.Schedule("c", now.Add(5*time.Hour))
+		})
+
+		It("fires the earliest timer, moving time forward", func() {
+			go f.Fire()
+			Eventually(f.C()).Should(Receive(Equal(clock.Timer{Name: "a", At: now.Add(time.Hour)})))
+			Ω(f.Time()).Should(Equal(now.Add(time.Hour)))
+			Ω(f.Timers()).Should(HaveLen(2))
+		})
+
+		It("can simulate downtime", func() {
+			go f.SimulateDowntime(now.Add(3 * time.Hour))
+			Eventually(f.C()).Should(Receive(Equal(clock.Timer{Name: "a", At: now.Add(time.Hour), Missed: true})))
+			Eventually(f.C()).Should(Receive(Equal(clock.Timer{Name: "b", At: now.Add(2 * time.Hour), Missed: true})))
+			Ω(f.Time()).Should(Equal(now.Add(3 * time.Hour)))
+			Ω(f.Timers()).Should(Equal(clock.Timers{{Name: "c", At: now.Add(5 * time.Hour)}}))
+		})
+	})
+})
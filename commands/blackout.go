@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/mail"
+)
+
+// ParseBlackout understands the arguments to /blackout <start> [end] [silent] [announce] [reason...], where dates are M/D
+func ParseBlackout(args []string, disco string, setBy mail.EmailAddress, now time.Time) (config.Blackout, error) {
+	now = now.In(clock.Timezone)
+	usage := fmt.Errorf("invalid /blackout - try something like \"/blackout 11/23 11/26 announce Thanksgiving\"")
+	blackout := config.Blackout{Discos: []string{disco}, SetBy: setBy}
+	reason := []string{}
+	for i, arg := range args {
+		switch {
+		case len(reason) > 0:
+			reason = append(reason, arg)
+		case i == 0:
+			start, err := parseBlackoutDate(arg, now)
+			if err != nil {
+				return config.Blackout{}, err
+			}
+			blackout.Start = start
+		case i == 1 && monthDayRegex.MatchString(arg):
+			end, err := parseBlackoutDate(arg, now)
+			if err != nil {
+				return config.Blackout{}, err
+			}
+			if end < blackout.Start {
+				// 12/30 1/2 spans the new year
+				t, _ := time.Parse(config.BLACKOUT_DATE_FORMAT, end)
+				end = t.AddDate(1, 0, 0).Format(config.BLACKOUT_DATE_FORMAT)
+			}
+			blackout.End = end
+		case strings.EqualFold(arg, "silent"):
+			blackout.Silent = true
+		case strings.EqualFold(arg, "announce"):
+			blackout.Announce = true
+		default:
+			reason = append(reason, arg)
+		}
+	}
+	if blackout.Start == "" {
+		return config.Blackout{}, usage
+	}
+	blackout.Reason = strings.Join(reason, " ")
+	return blackout, nil
+}
+
+func parseBlackoutDate(arg string, now time.Time) (string, error) {
+	match := monthDayRegex.FindStringSubmatch(arg)
+	if match == nil {
+		return "", fmt.Errorf("invalid date for /blackout: %s - use M/D", arg)
+	}
+	month, _ := strconv.Atoi(match[1])
+	day, _ := strconv.Atoi(match[2])
+	date := time.Date(now.Year(), time.Month(month), day, 0, 0, 0, 0, clock.Timezone)
+	if month < 1 || month > 12 || date.Month() != time.Month(month) {
+		return "", fmt.Errorf("invalid date for /blackout: %s - use M/D", arg)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, clock.Timezone)
+	if date.Before(today) {
+		date = date.AddDate(1, 0, 0) // 1/3 in December means next year
+	}
+	return date.Format(config.BLACKOUT_DATE_FORMAT), nil
+}
@@ -0,0 +1,72 @@
+package commands_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/commands"
+	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/mail"
+)
+
+var _ = Describe("ParseBlackout", func() {
+	var now time.Time
+	var boss mail.EmailAddress
+
+	BeforeEach(func() {
+		now = time.Date(2023, time.September, 27, 10, 0, 0, 0, clock.Timezone)
+		boss = mail.EmailAddress("Boss <boss@example.com>")
+	})
+
+	parse := func(args string) (config.Blackout, error) {
+		return commands.ParseBlackout(strings.Fields(args), config.DiscoSaturday, boss, now)
+	}
+
+	It("parses the dates, flags and reason", func() {
+		blackout, err := parse("11/23 11/26 silent announce Thanksgiving weekend")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(blackout).Should(Equal(config.Blackout{
+			Start:    "2023-11-23",
+			End:      "2023-11-26",
+			Discos:   []string{config.DiscoSaturday},
+			Reason:   "Thanksgiving weekend",
+			Silent:   true,
+			Announce: true,
+			SetBy:    boss,
+		}))
+		Ω(blackout.String()).Should(Equal("11/23-11/26 (Thanksgiving weekend), silent, announced the week before"))
+	})
+
+	It("supports single days", func() {
+		blackout, err := parse("9/30 field maintenance")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(blackout.Start).Should(Equal("2023-09-30"))
+		Ω(blackout.EndDate()).Should(Equal("2023-09-30"))
+		Ω(blackout.Reason).Should(Equal("field maintenance"))
+		Ω(blackout.Silent).Should(BeFalse())
+	})
+
+	It("rolls dates over to next year", func() {
+		blackout, err := parse("12/30 1/2")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(blackout.Start).Should(Equal("2023-12-30"))
+		Ω(blackout.End).Should(Equal("2024-01-02"))
+
+		blackout, err = parse("1/2")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(blackout.Start).Should(Equal("2024-01-02"))
+	})
+
+	It("catches bad dates", func() {
+		_, err := parse("")
+		Ω(err).Should(MatchError(ContainSubstring("invalid /blackout - try something like")))
+		_, err = parse("thanksgiving")
+		Ω(err).Should(MatchError("invalid date for /blackout: thanksgiving - use M/D"))
+		_, err = parse("2/30")
+		Ω(err).Should(MatchError("invalid date for /blackout: 2/30 - use M/D"))
+	})
+})
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/mail"
+)
+
+// MAX_TEMPORARY_BOUNCES is how many temporary (4.x.x) failures we put up with before we give up on an address - a permanent failure
+// gives up right away
+const MAX_TEMPORARY_BOUNCES = 3
+
+// UndeliverableAddress is someone our e-mail has bounced for
+type UndeliverableAddress struct {
+	Address    mail.EmailAddress `json:"address"`
+	Reason     string            `json:"reason"`
+	Bounces    int               `json:"bounces"`
+	Permanent  bool              `json:"permanent"`
+	LastBounce time.Time         `json:"last_bounce"`
+}
+
+// Dead is true once we've stopped sending to the address
+func (u UndeliverableAddress) Dead() bool {
+	return u.Permanent || u.Bounces >= MAX_TEMPORARY_BOUNCES
+}
+
+func (u UndeliverableAddress) String() string {
+	status := "still trying"
+	if u.Dead() {
+		status = "no longer sending"
+	}
+	out := fmt.Sprintf("%s: bounced %d time(s), last on %s - %s", u.Address, u.Bounces, u.LastBounce.In(clock.Timezone).Format("Mon 1/2 3:04pm"), status)
+	if u.Reason != "" {
+		out += " (" + u.Reason + ")"
+	}
+	return out
+}
+
+type Undeliverable []UndeliverableAddress
+
+// Record notes a bounce for each of its failed recipients
+func (u Undeliverable) Record(bounce mail.Bounce, at time.Time) Undeliverable {
+	out := u.dup()
+	for _, recipient := range bounce.FailedRecipients {
+		found := false
+		for i := range out {
+			if out[i].Address.Equals(recipient) {
+				out[i].Bounces += 1
+				out[i].Permanent = out[i].Permanent || bounce.Permanent()
+				out[i].Reason, out[i].LastBounce = bounce.Reason(), at
+				found = true
+			}
+		}
+		if !found {
+			out = append(out, UndeliverableAddress{
+				Address:    recipient,
+				Reason:     bounce.Reason(),
+				Bounces:    1,
+				Permanent:  bounce.Permanent(),
+				LastBounce: at,
+			})
+		}
+	}
+	return out
+}
+
+func (u Undeliverable) IsDead(address mail.EmailAddress) bool {
+	for _, undeliverable := range u {
+		if undeliverable.Address.Equals(address) {
+			return undeliverable.Dead()
+		}
+	}
+	return false
+}
+
+// Remove forgets about address - e.g. because we've heard from them since, so their mailbox clearly works
+func (u Undeliverable) Remove(address mail.EmailAddress) (Undeliverable, bool) {
+	out := Undeliverable{}
+	found := false
+	for _, undeliverable := range u {
+		if undeliverable.Address.Equals(address) {
+			found = true
+		} else {
+			out = append(out, undeliverable)
+		}
+	}
+	if !found {
+		return u, false
+	}
+	return out, true
+}
+
+func (u Undeliverable) String() string {
+	out := &strings.Builder{}
+	for _, undeliverable := range u {
+		out.WriteString("- " + undeliverable.String() + "\n")
+	}
+	return out.String()
+}
+
+// Without drops the recipients our e-mail keeps bouncing for, and returns who it dropped.  It never drops the addresses in always - e.g.
+// the list and the boss: if those are bouncing the boss needs to fix it, and the bounce reports will keep saying so.
+func (u Undeliverable) Without(email mail.Email, always ...mail.EmailAddress) (mail.Email, mail.EmailAddresses) {
+	dropped := mail.EmailAddresses{}
+	if len(u) == 0 {
+		return email, dropped
+	}
+	keep := func(addresses mail.EmailAddresses) mail.EmailAddresses {
+		out := mail.EmailAddresses{}
+		for _, address := range addresses {
+			if u.IsDead(address) && !slices.ContainsFunc(always, address.Equals) {
+				dropped = append(dropped, address)
+				continue
+			}
+			out = append(out, address)
+		}
+		return out
+	}
+	email.To, email.CC = keep(email.To), keep(email.CC)
+	return email, dropped
+}
+
+// Deliverable is Without for a disco about to send: it logs who it dropped and returns false if there's nobody left to send to
+func (u Undeliverable) Deliverable(email mail.Email, logi func(uint, string, ...any), always ...mail.EmailAddress) (mail.Email, bool) {
+	email, dropped := u.Without(email, always...)
+	for _, address := range dropped {
+		logi(1, "{{yellow}}not sending to %s - our e-mail keeps bouncing{{/}}", address)
+	}
+	if len(email.To) == 0 && len(email.CC) == 0 {
+		logi(1, "{{yellow}}nobody left to send %q to{{/}}", email.Subject)
+		return email, false
+	}
+	return email, true
+}
+
+func (u Undeliverable) Dup() Undeliverable {
+	return u.dup()
+}
+
+func (u Undeliverable) dup() Undeliverable {
+	if u == nil {
+		return nil
+	}
+	out := make(Undeliverable, len(u))
+	copy(out, u)
+	return out
+}
@@ -0,0 +1,51 @@
+package commands_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/commands"
+	"github.com/onsi/disco/mail"
+)
+
+var _ = Describe("Undeliverable", func() {
+	var undeliverable commands.Undeliverable
+	var list, boss, dead, alive mail.EmailAddress
+	var logged []string
+	var logi func(uint, string, ...any)
+
+	BeforeEach(func() {
+		list = mail.EmailAddress("List <list@example.com>")
+		boss = mail.EmailAddress("Boss <boss@example.com>")
+		dead = mail.EmailAddress("Dead <dead@example.com>")
+		alive = mail.EmailAddress("Alive <alive@example.com>")
+		now := time.Date(2023, time.September, 24, 12, 0, 0, 0, clock.Timezone)
+		bounce := mail.Bounce{FailedRecipients: mail.EmailAddresses{"dead@example.com", "boss@example.com", "list@example.com"}, Status: "5.1.1"}
+		undeliverable = commands.Undeliverable{}.Record(bounce, now)
+		logged = []string{}
+		logi = func(_ uint, format string, args ...any) {
+			logged = append(logged, fmt.Sprintf(format, args...))
+		}
+	})
+
+	Describe("Deliverable", func() {
+		It("drops the dead addresses - but never the ones it's told to keep - and logs who it dropped", func() {
+			email := mail.E().WithTo(list, dead).AndCC(boss, alive).WithSubject("hi")
+			email, ok := undeliverable.Deliverable(email, logi, list, boss)
+			Ω(ok).Should(BeTrue())
+			Ω(email.To).Should(Equal(mail.EmailAddresses{list}))
+			Ω(email.CC).Should(Equal(mail.EmailAddresses{boss, alive}))
+			Ω(logged).Should(ConsistOf(ContainSubstring("not sending to Dead <dead@example.com>")))
+		})
+
+		It("returns false when there's nobody left", func() {
+			_, ok := undeliverable.Deliverable(mail.E().WithTo(dead).WithSubject("hi"), logi, list, boss)
+			Ω(ok).Should(BeFalse())
+			Ω(logged).Should(ContainElement(ContainSubstring(`nobody left to send "hi" to`)))
+		})
+	})
+})
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/clock"
+)
+
+// CATCH_UP_GRACE is how late an event can be on startup before we stop and ask the boss what to do, instead of just running it
+const CATCH_UP_GRACE = 30 * time.Minute
+
+// MissedTransition is something a disco would have done had it been running: moved From one state To another or - if ActionID is set -
+// run one of the boss's scheduled actions
+type MissedTransition[S ~string] struct {
+	At       time.Time `json:"at"`
+	From     S         `json:"from,omitempty"`
+	To       S         `json:"to,omitempty"`
+	ActionID int       `json:"action_id,omitempty"`
+	Action   string    `json:"action,omitempty"`
+}
+
+func (m MissedTransition[S]) String() string {
+	at := m.At.In(clock.Timezone).Format("Mon 1/2 3:04pm")
+	if m.ActionID != 0 {
+		return fmt.Sprintf("%s: scheduled #%d %s", at, m.ActionID, m.Action)
+	}
+	return fmt.Sprintf("%s: %s → %s", at, m.From, m.To)
+}
+
+// MissedTransitions is what a disco slept through.  While a disco holds a non-empty list it is paused: NextEvent and the scheduled actions
+// the list Holds stay unarmed until the boss says /resume (run them now, in order) or /skip (jump to where we'd be now without sending
+// any of it).  Any transition - including the one /skip makes - ends the catch-up and re-arms everything.
+type MissedTransitions[S ~string] []MissedTransition[S]
+
+func (m MissedTransitions[S]) String() string {
+	out := &strings.Builder{}
+	for i, transition := range m {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString("- " + transition.String())
+	}
+	return out.String()
+}
+
+// Transitions leaves out the missed scheduled actions
+func (m MissedTransitions[S]) Transitions() MissedTransitions[S] {
+	out := MissedTransitions[S]{}
+	for _, transition := range m {
+		if transition.ActionID == 0 {
+			out = append(out, transition)
+		}
+	}
+	return out
+}
+
+// ActionIDs are the missed scheduled actions - the discos hold on to these until the boss says whether to run them
+func (m MissedTransitions[S]) ActionIDs() []int {
+	out := []int{}
+	for _, transition := range m {
+		if transition.ActionID != 0 {
+			out = append(out, transition.ActionID)
+		}
+	}
+	return out
+}
+
+func (m MissedTransitions[S]) Holds(action DeferredAction) bool {
+	for _, id := range m.ActionIDs() {
+		if id == action.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m MissedTransitions[S]) Dup() MissedTransitions[S] {
+	if m == nil {
+		return nil
+	}
+	out := make(MissedTransitions[S], len(m))
+	copy(out, m)
+	return out
+}
+
+// CatchUp merges the transitions and scheduled actions a disco slept through, earliest first.  It returns nothing unless at least one of
+// them is more than CATCH_UP_GRACE late - if we were only down for a moment it's fine to just carry on.
+func CatchUp[S ~string](transitions MissedTransitions[S], actions DeferredActions, now time.Time) MissedTransitions[S] {
+	missed := transitions.Dup()
+	for _, action := range actions {
+		if !action.At.After(now) {
+			missed = append(missed, MissedTransition[S]{At: action.At, ActionID: action.ID, Action: action.CommandLine})
+		}
+	}
+	sort.SliceStable(missed, func(i, j int) bool {
+		return missed[i].At.Before(missed[j].At)
+	})
+	if len(missed) == 0 || now.Sub(missed[0].At) <= CATCH_UP_GRACE {
+		return nil
+	}
+	return missed
+}
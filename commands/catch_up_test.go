@@ -0,0 +1,40 @@
+package commands_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/commands"
+)
+
+type state string
+
+var _ = Describe("CatchUp", func() {
+	var now time.Time
+	var transitions commands.MissedTransitions[state]
+	BeforeEach(func() {
+		now = time.Date(2023, time.September, 27, 12, 0, 0, 0, clock.Timezone)
+		transitions = commands.MissedTransitions[state]{{At: now.Add(-10 * time.Minute), From: "pending", To: "invite_sent"}}
+	})
+
+	It("doesn't bother the boss if nothing is more than CATCH_UP_GRACE late", func() {
+		actions := commands.DeferredActions{{ID: 1, At: now.Add(-time.Minute), CommandLine: "/abort"}}
+		Ω(commands.CatchUp(transitions, actions, now)).Should(BeNil())
+	})
+
+	It("includes scheduled actions that came due, earliest first - and holds them until the boss decides", func() {
+		actions := commands.DeferredActions{
+			{ID: 1, At: now.Add(-6 * time.Hour), CommandLine: "/game-on"},
+			{ID: 2, At: now.Add(time.Hour), CommandLine: "/abort"},
+		}
+		missed := commands.CatchUp(transitions, actions, now)
+		Ω(missed.String()).Should(Equal("- Wed 9/27 6:00am: scheduled #1 /game-on\n- Wed 9/27 11:50am: pending → invite_sent"))
+		Ω(missed.Transitions()).Should(Equal(transitions))
+		Ω(missed.ActionIDs()).Should(Equal([]int{1}))
+		Ω(missed.Holds(actions[0])).Should(BeTrue())
+		Ω(missed.Holds(actions[1])).Should(BeFalse())
+	})
+})
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const DRY_RUN_FLAG = "--dry-run"
+
+// Spec describes a single admin command
+type Spec struct {
+	Name    string
+	Aliases []string
+	// Args documents the arguments, e.g. "<player> <count>"
+	Args    string
+	MinArgs int
+	// MaxArgs of -1 means there's no limit
+	MaxArgs int
+	// TakesBody commands consume every line below them - that content gets sent along with the e-mail
+	TakesBody   bool
+	Description string
+}
+
+func (s Spec) Usage() string {
+	if s.Args == "" {
+		return "/" + s.Name
+	}
+	return "/" + s.Name + " " + s.Args
+}
+
+func (s Spec) matches(name string) bool {
+	if s.Name == name {
+		return true
+	}
+	for _, alias := range s.Aliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Invocation is a single parsed command
+type Invocation struct {
+	Name   string
+	Args   []string
+	Body   string
+	DryRun bool
+	Line   string
+}
+
+func (i Invocation) RawArgs() string {
+	return strings.Join(i.Args, " ")
+}
+
+type Parser struct {
+	specs []Spec
+}
+
+var helpSpec = Spec{Name: "help", Description: "reply with this list of commands"}
+
+// NewParser returns a parser that understands specs (and /help)
+func NewParser(specs ...Spec) Parser {
+	return Parser{specs: append([]Spec{helpSpec}, specs...)}
+}
+
+// Parse extracts one command per line, stopping at the first line that isn't a command (e.g. a signature or quoted reply)
+func (p Parser) Parse(text string) ([]Invocation, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	invocations := []Invocation{}
+	for idx, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "/") {
+			if len(invocations) == 0 {
+				return nil, fmt.Errorf("invalid command: %s - commands start with /, send /help for a list of commands", line)
+			}
+			break
+		}
+		invocation, spec, err := p.parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if spec.TakesBody {
+			invocation.Body = strings.Trim(strings.Join(lines[idx+1:], "\n"), "\n")
+			invocations = append(invocations, invocation)
+			break
+		}
+		invocations = append(invocations, invocation)
+	}
+	if len(invocations) == 0 {
+		return nil, fmt.Errorf("no command found - send /help for a list of commands")
+	}
+	return invocations, nil
+}
+
+func (p Parser) parseLine(line string) (Invocation, Spec, error) {
+	fields := strings.Fields(line)
+	name := strings.TrimPrefix(fields[0], "/")
+	invocation := Invocation{Line: line, Args: []string{}}
+	for _, field := range fields[1:] {
+		if field == DRY_RUN_FLAG {
+			invocation.DryRun = true
+		} else {
+			invocation.Args = append(invocation.Args, field)
+		}
+	}
+
+	spec, ok := p.spec(name)
+	if !ok {
+		suggestions := p.Suggest(name)
+		if len(suggestions) > 0 {
+			return Invocation{}, Spec{}, fmt.Errorf("unknown command: /%s - did you mean %s?", name, strings.Join(suggestions, " or "))
+		}
+		return Invocation{}, Spec{}, fmt.Errorf("unknown command: /%s - send /help for a list of commands", name)
+	}
+	invocation.Name = spec.Name
+	if len(invocation.Args) < spec.MinArgs || (spec.MaxArgs >= 0 && len(invocation.Args) > spec.MaxArgs) {
+		return Invocation{}, Spec{}, fmt.Errorf("invalid arguments for /%s - usage: %s", spec.Name, spec.Usage())
+	}
+	return invocation, spec, nil
+}
+
+func (p Parser) spec(name string) (Spec, bool) {
+	for _, spec := range p.specs {
+		if spec.matches(name) {
+			return spec, true
+		}
+	}
+	return Spec{}, false
+}
+
+// Suggest returns the commands that look most like name
+func (p Parser) Suggest(name string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	candidates := []candidate{}
+	lowerName := strings.ToLower(name)
+	for _, spec := range p.specs {
+		best := -1
+		for _, n := range append([]string{spec.Name}, spec.Aliases...) {
+			lowerN := strings.ToLower(n)
+			d := editDistance(lowerName, lowerN)
+			if lowerName != "" && strings.HasPrefix(lowerN, lowerName) {
+				d = 0
+			}
+			if best == -1 || d < best {
+				best = d
+			}
+		}
+		if best <= max(1, len(spec.Name)/3) {
+			candidates = append(candidates, candidate{"/" + spec.Name, best})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	out := []string{}
+	for _, c := range candidates {
+		out = append(out, c.name)
+	}
+	return out
+}
+
+func (p Parser) Help() string {
+	out := &strings.Builder{}
+	for _, spec := range p.specs {
+		out.WriteString(spec.Usage())
+		for _, alias := range spec.Aliases {
+			out.WriteString(" | /" + alias)
+		}
+		out.WriteString(" - " + spec.Description + "\n")
+	}
+	out.WriteString("\nYou can send several commands in one e-mail, one per line.\n")
+	out.WriteString("Add " + DRY_RUN_FLAG + " to a command to see what I would do without actually doing it.")
+	return out.String()
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
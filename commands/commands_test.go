@@ -0,0 +1,90 @@
+package commands_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/commands"
+)
+
+var _ = Describe("Commands", func() {
+	var parser commands.Parser
+
+	BeforeEach(func() {
+		parser = commands.NewParser(
+			commands.Spec{Name: "status", Description: "get the status"},
+			commands.Spec{Name: "set", Args: "<player> <count>", MinArgs: 2, MaxArgs: -1, Description: "set a count"},
+			commands.Spec{Name: "approve", Aliases: []string{"yes", "shipit"}, MaxArgs: -1, TakesBody: true, Description: "approve"},
+			commands.Spec{Name: "delay", Args: "<hours>", MinArgs: 1, MaxArgs: 1, Description: "delay"},
+			commands.Spec{Name: "RESET-RESET-RESET", Description: "reset everything"},
+		)
+	})
+
+	It("parses a single command", func() {
+		invocations, err := parser.Parse("/status")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(invocations).Should(Equal([]commands.Invocation{{Name: "status", Args: []string{}, Line: "/status"}}))
+	})
+
+	It("parses one command per line, stopping at the first non-command line", func() {
+		invocations, err := parser.Parse("\n/set Jane Player <jane@example.com> 2\n\n/set bob@example.com 1\n/status\nThanks!\n/status\n\n> quoted")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(invocations).Should(HaveLen(3))
+		Ω(invocations[0].Name).Should(Equal("set"))
+		Ω(invocations[0].Args).Should(Equal([]string{"Jane", "Player", "<jane@example.com>", "2"}))
+		Ω(invocations[1].RawArgs()).Should(Equal("bob@example.com 1"))
+		Ω(invocations[2].Name).Should(Equal("status"))
+	})
+
+	It("resolves aliases and gives body-taking commands everything below them", func() {
+		invocations, err := parser.Parse("/shipit\n\nBring **cleats**\n/status")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(invocations).Should(HaveLen(1))
+		Ω(invocations[0].Name).Should(Equal("approve"))
+		Ω(invocations[0].Body).Should(Equal("Bring **cleats**\n/status"))
+	})
+
+	It("extracts the dry-run flag", func() {
+		invocations, err := parser.Parse("/delay 3 --dry-run")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(invocations[0].DryRun).Should(BeTrue())
+		Ω(invocations[0].Args).Should(Equal([]string{"3"}))
+	})
+
+	It("validates argument counts", func() {
+		_, err := parser.Parse("/delay")
+		Ω(err).Should(MatchError("invalid arguments for /delay - usage: /delay <hours>"))
+		_, err = parser.Parse("/status\n/delay 1 2")
+		Ω(err).Should(MatchError("invalid arguments for /delay - usage: /delay <hours>"))
+	})
+
+	It("suggests commands when it doesn't understand", func() {
+		_, err := parser.Parse("/stauts")
+		Ω(err).Should(MatchError("unknown command: /stauts - did you mean /status?"))
+		_, err = parser.Parse("/RESET-RESET-REST")
+		Ω(err).Should(MatchError("unknown command: /RESET-RESET-REST - did you mean /RESET-RESET-RESET?"))
+		_, err = parser.Parse("/ship")
+		Ω(err).Should(MatchError("unknown command: /ship - did you mean /approve?"))
+		_, err = parser.Parse("/frobnicate")
+		Ω(err).Should(MatchError("unknown command: /frobnicate - send /help for a list of commands"))
+	})
+
+	It("rejects e-mails that don't start with a command", func() {
+		_, err := parser.Parse("hey disco\n/status")
+		Ω(err).Should(MatchError(ContainSubstring("invalid command: hey disco")))
+		_, err = parser.Parse("  \n\n")
+		Ω(err).Should(MatchError(ContainSubstring("no command found")))
+	})
+
+	It("always understands /help and can describe every command", func() {
+		invocations, err := parser.Parse("/help")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(invocations[0].Name).Should(Equal("help"))
+
+		help := parser.Help()
+		Ω(help).Should(ContainSubstring("/help - reply with this list of commands\n"))
+		Ω(help).Should(ContainSubstring("/set <player> <count> - set a count\n"))
+		Ω(help).Should(ContainSubstring("/approve | /yes | /shipit - approve\n"))
+		Ω(help).Should(ContainSubstring("--dry-run"))
+	})
+})
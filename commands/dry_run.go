@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/disco/mail"
+)
+
+// DryRunSummary tells the boss what a command run with DRY_RUN_FLAG would have done: where the disco would have ended up and the
+// e-mails it would have sent
+func DryRunSummary[S ~string](from S, to S, emails []mail.Email) string {
+	out := &strings.Builder{}
+	if from == to {
+		fmt.Fprintf(out, "I would stay in the %s state.\n", from)
+	} else {
+		fmt.Fprintf(out, "I would move from the %s state to the %s state.\n", from, to)
+	}
+	if len(emails) == 0 {
+		out.WriteString("\nI wouldn't send any e-mails.")
+		return out.String()
+	}
+	fmt.Fprintf(out, "\nI would send %d e-mail(s):\n", len(emails))
+	for _, email := range emails {
+		fmt.Fprintf(out, "\n---\nTo: %s\n", email.To)
+		if len(email.CC) > 0 {
+			fmt.Fprintf(out, "CC: %s\n", email.CC)
+		}
+		fmt.Fprintf(out, "Subject: %s\n\n%s\n", email.Subject, strings.TrimSpace(email.Text))
+	}
+	return out.String()
+}
@@ -0,0 +1,28 @@
+package commands_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/commands"
+	"github.com/onsi/disco/mail"
+)
+
+type testState string
+
+var _ = Describe("DryRunSummary", func() {
+	It("says when nothing would happen", func() {
+		Ω(commands.DryRunSummary[testState]("pending", "pending", nil)).Should(Equal("I would stay in the pending state.\n\nI wouldn't send any e-mails."))
+	})
+
+	It("describes the transition and every e-mail that would go out", func() {
+		emails := []mail.Email{
+			mail.E().WithTo("Player <player@example.com>").WithSubject("Invite").WithBody("Come play!\n"),
+			mail.E().WithTo("list@example.com").AndCC("Boss <boss@example.com>").WithSubject("Cancelled").WithBody("No game."),
+		}
+		summary := commands.DryRunSummary[testState]("pending", "invite_sent", emails)
+		Ω(summary).Should(HavePrefix("I would move from the pending state to the invite_sent state.\n\nI would send 2 e-mail(s):\n"))
+		Ω(summary).Should(ContainSubstring("\n---\nTo: Player <player@example.com>\nSubject: Invite\n\nCome play!\n"))
+		Ω(summary).Should(ContainSubstring("\n---\nTo: list@example.com\nCC: Boss <boss@example.com>\nSubject: Cancelled\n\nNo game.\n"))
+	})
+})
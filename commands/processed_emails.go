@@ -0,0 +1,18 @@
+package commands
+
+import "slices"
+
+// ProcessedEmailIDs are the Message-IDs of the e-mails a disco has already acted on since its weekly reset - so an e-mail that reaches it
+// twice (a webhook retry, or the boss re-dispatching it from the archive) only counts once
+type ProcessedEmailIDs []string
+
+func (p ProcessedEmailIDs) Contains(id string) bool {
+	return slices.Contains(p, id)
+}
+
+func (p ProcessedEmailIDs) Dup() ProcessedEmailIDs {
+	if p == nil {
+		return nil
+	}
+	return slices.Clone(p)
+}
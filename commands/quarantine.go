@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/mail"
+)
+
+// QUARANTINE_SUBJECT_PREFIX marks the e-mail asking the boss to confirm a quarantined command - their reply comes back with Re: in front
+const QUARANTINE_SUBJECT_PREFIX = "[quarantine-confirmation-request]"
+
+var quarantineSubjectRegex = regexp.MustCompile(`^(?i:re):\s*` + regexp.QuoteMeta(QUARANTINE_SUBJECT_PREFIX) + `\s*#(\d+)`)
+
+// QuarantineReplyCommands are what the boss can say in reply to a confirmation request
+var QuarantineReplyCommands = NewParser(
+	Spec{Name: "confirm", Aliases: []string{"approve", "yes"}, Description: "run the quarantined commands as if they'd been authenticated"},
+	Spec{Name: "reject", Aliases: []string{"deny", "no"}, Description: "throw the quarantined commands away"},
+)
+
+// QuarantinedEmail is an admin command that arrived without passing DKIM/SPF alignment for its sender.  It waits for the boss to
+// confirm it actually came from who it says it did.
+type QuarantinedEmail struct {
+	ID    int        `json:"id"`
+	At    time.Time  `json:"at"`
+	Email mail.Email `json:"email"`
+}
+
+func (q QuarantinedEmail) Subject() string {
+	return fmt.Sprintf("%s #%d: %s", QUARANTINE_SUBJECT_PREFIX, q.ID, q.Email.Subject)
+}
+
+func (q QuarantinedEmail) String() string {
+	return fmt.Sprintf("#%d: %q from %s on %s", q.ID, q.Email.Subject, q.Email.From, q.At.In(clock.Timezone).Format("Mon 1/2 3:04pm"))
+}
+
+type Quarantine []QuarantinedEmail
+
+// Add assigns the e-mail the next free ID
+func (q Quarantine) Add(email QuarantinedEmail) (Quarantine, QuarantinedEmail) {
+	email.ID = 1
+	for _, existing := range q {
+		if existing.ID >= email.ID {
+			email.ID = existing.ID + 1
+		}
+	}
+	return append(q.Dup(), email), email
+}
+
+func (q Quarantine) Remove(id int) (Quarantine, QuarantinedEmail, bool) {
+	out := Quarantine{}
+	var removed QuarantinedEmail
+	found := false
+	for _, email := range q {
+		if email.ID == id {
+			removed, found = email, true
+		} else {
+			out = append(out, email)
+		}
+	}
+	return out, removed, found
+}
+
+func (q Quarantine) String() string {
+	out := &strings.Builder{}
+	for _, email := range q {
+		out.WriteString("- " + email.String() + "\n")
+	}
+	return out.String()
+}
+
+func (q Quarantine) Dup() Quarantine {
+	if q == nil {
+		return nil
+	}
+	out := make(Quarantine, len(q))
+	for i, email := range q {
+		email.Email = email.Email.Dup()
+		out[i] = email
+	}
+	return out
+}
+
+// ParseQuarantineReply pulls the quarantined e-mail's ID out of a reply to a confirmation request.  ok is false if this isn't such a reply.
+func ParseQuarantineReply(subject string) (id int, ok bool) {
+	match := quarantineSubjectRegex.FindStringSubmatch(subject)
+	if match == nil {
+		return 0, false
+	}
+	id, err := strconv.Atoi(match[1])
+	return id, err == nil
+}
@@ -0,0 +1,48 @@
+package commands_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/commands"
+	"github.com/onsi/disco/mail"
+)
+
+var _ = Describe("Quarantine", func() {
+	var at time.Time
+	BeforeEach(func() {
+		at = time.Date(2023, time.September, 27, 10, 0, 0, 0, clock.Timezone)
+	})
+
+	It("assigns IDs and removes e-mails by ID", func() {
+		var q commands.Quarantine
+		q, first := q.Add(commands.QuarantinedEmail{At: at, Email: mail.E().WithFrom("Boss <boss@example.com>").WithSubject("hey")})
+		q, second := q.Add(commands.QuarantinedEmail{At: at, Email: mail.E().WithFrom("Boss <boss@example.com>").WithSubject("again")})
+		Ω(first.ID).Should(Equal(1))
+		Ω(second.ID).Should(Equal(2))
+		Ω(second.Subject()).Should(Equal("[quarantine-confirmation-request] #2: again"))
+		Ω(q.String()).Should(Equal("- #1: \"hey\" from Boss <boss@example.com> on Wed 9/27 10:00am\n- #2: \"again\" from Boss <boss@example.com> on Wed 9/27 10:00am\n"))
+
+		q, removed, ok := q.Remove(1)
+		Ω(ok).Should(BeTrue())
+		Ω(removed.Email.Subject).Should(Equal("hey"))
+		Ω(q).Should(HaveLen(1))
+
+		_, _, ok = q.Remove(1)
+		Ω(ok).Should(BeFalse())
+	})
+
+	DescribeTable("recognizing replies to confirmation requests", func(subject string, expectedID int, expectedOK bool) {
+		id, ok := commands.ParseQuarantineReply(subject)
+		Ω(ok).Should(Equal(expectedOK))
+		Ω(id).Should(Equal(expectedID))
+	},
+		Entry(nil, "Re: [quarantine-confirmation-request] #3: hey", 3, true),
+		Entry(nil, "RE:[quarantine-confirmation-request] #12: hey", 12, true),
+		Entry("the request itself", "[quarantine-confirmation-request] #3: hey", 0, false),
+		Entry("other replies", "Re: [invite-approval-request] Can I send this week's invite?", 0, false),
+	)
+})
@@ -0,0 +1,290 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/mail"
+)
+
+type Condition string
+
+const (
+	ConditionNone         Condition = ""
+	ConditionIfQuorum     Condition = "if-quorum"
+	ConditionUnlessQuorum Condition = "unless-quorum"
+)
+
+// deferred actions without an explicit time run at 6am - the same time the discos wake up to do their own thing
+const DEFAULT_DEFERRED_HOUR = 6
+
+// DeferredAction is an admin command an organizer has scheduled to run later
+type DeferredAction struct {
+	ID          int               `json:"id"`
+	At          time.Time         `json:"at"`
+	Condition   Condition         `json:"condition,omitempty"`
+	CommandLine string            `json:"command_line"`
+	Body        string            `json:"body,omitempty"`
+	SetBy       mail.EmailAddress `json:"set_by"`
+}
+
+// Text is what the disco parses when the action runs - as if the organizer had e-mailed it then
+func (d DeferredAction) Text() string {
+	if d.Body == "" {
+		return d.CommandLine
+	}
+	return d.CommandLine + "\n" + d.Body
+}
+
+func (d DeferredAction) AlarmName() string {
+	return fmt.Sprintf("deferred-%d", d.ID)
+}
+
+func (d DeferredAction) String() string {
+	out := fmt.Sprintf("#%d: %s on %s", d.ID, d.CommandLine, d.At.In(clock.Timezone).Format("Mon 1/2 3:04pm"))
+	switch d.Condition {
+	case ConditionIfQuorum:
+		out += " if we have quorum"
+	case ConditionUnlessQuorum:
+		out += " unless we have quorum"
+	}
+	return out
+}
+
+type DeferredActions []DeferredAction
+
+// Add assigns the action the next free ID and keeps the list sorted by time
+func (d DeferredActions) Add(action DeferredAction) (DeferredActions, DeferredAction) {
+	action.ID = 1
+	for _, existing := range d {
+		if existing.ID >= action.ID {
+			action.ID = existing.ID + 1
+		}
+	}
+	out := append(d.dup(), action)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].At.Before(out[j].At)
+	})
+	return out, action
+}
+
+func (d DeferredActions) Find(id int) (DeferredAction, bool) {
+	for _, action := range d {
+		if action.ID == id {
+			return action, true
+		}
+	}
+	return DeferredAction{}, false
+}
+
+func (d DeferredActions) FindByAlarmName(name string) (DeferredAction, bool) {
+	for _, action := range d {
+		if action.AlarmName() == name {
+			return action, true
+		}
+	}
+	return DeferredAction{}, false
+}
+
+func (d DeferredActions) Remove(id int) DeferredActions {
+	out := DeferredActions{}
+	for _, action := range d {
+		if action.ID != id {
+			out = append(out, action)
+		}
+	}
+	return out
+}
+
+func (d DeferredActions) String() string {
+	out := &strings.Builder{}
+	for _, action := range d {
+		out.WriteString("- " + action.String() + " (set by " + action.SetBy.Name() + ")\n")
+	}
+	return out.String()
+}
+
+func (d DeferredActions) Dup() DeferredActions {
+	return d.dup()
+}
+
+func (d DeferredActions) dup() DeferredActions {
+	out := make(DeferredActions, len(d))
+	copy(out, d)
+	return out
+}
+
+// ParseSchedule parses the arguments to /schedule: <when> [if-quorum|unless-quorum] /<command> [args]
+// the scheduled command itself is validated with parser, so typos are caught now rather than when the action runs
+func ParseSchedule(parser Parser, args []string, body string, setBy mail.EmailAddress, now time.Time) (DeferredAction, error) {
+	idx := -1
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "/") {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return DeferredAction{}, fmt.Errorf("invalid /schedule - put the command to run after the time, e.g. /schedule friday 6pm if-quorum /game-on")
+	}
+	action := DeferredAction{
+		CommandLine: strings.Join(args[idx:], " "),
+		Body:        body,
+		SetBy:       setBy,
+	}
+	when := []string{}
+	for _, arg := range args[:idx] {
+		switch Condition(strings.ToLower(arg)) {
+		case ConditionIfQuorum, ConditionUnlessQuorum:
+			action.Condition = Condition(strings.ToLower(arg))
+		default:
+			when = append(when, arg)
+		}
+	}
+	var err error
+	action.At, err = ParseWhen(when, now)
+	if err != nil {
+		return DeferredAction{}, err
+	}
+
+	invocations, err := parser.Parse(action.Text())
+	if err != nil {
+		return DeferredAction{}, fmt.Errorf("invalid command to schedule: %w", err)
+	}
+	if len(invocations) != 1 {
+		return DeferredAction{}, fmt.Errorf("invalid command to schedule: you can only schedule one command at a time")
+	}
+	switch invocations[0].Name {
+	case "help", "schedule", "cancel", "undo":
+		return DeferredAction{}, fmt.Errorf("invalid command to schedule: /%s can't be scheduled", invocations[0].Name)
+	}
+	if invocations[0].DryRun {
+		return DeferredAction{}, fmt.Errorf("invalid command to schedule: add %s to /schedule itself to preview it", DRY_RUN_FLAG)
+	}
+	return action, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+var monthDayRegex = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})$`)
+var ordinalRegex = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)$`)
+var timeRegex = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+
+// ParseWhen understands things like "friday 6pm", "thu noon", "tomorrow 7:30am", "9/24 6am", "on the 24th" and "5pm".
+// Days without a time are at 6am, times without a day are the next time that time comes around.
+func ParseWhen(tokens []string, now time.Time) (time.Time, error) {
+	now = now.In(clock.Timezone)
+	invalid := fmt.Errorf("invalid time for /schedule: %s - try something like \"friday 6pm\", \"9/24 noon\" or \"the 24th\"", strings.Join(tokens, " "))
+
+	var date time.Time
+	hasDate, hasTime := false, false
+	hour, minute := DEFAULT_DEFERRED_HOUR, 0
+	var weekday time.Weekday
+	hasWeekday := false
+
+	words := []string{}
+	for _, token := range tokens {
+		token = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(token), ","))
+		switch token {
+		case "", "on", "the", "at", "next", "this":
+			continue
+		}
+		// join "6 pm" into "6pm"
+		if (token == "am" || token == "pm") && len(words) > 0 {
+			words[len(words)-1] += token
+			continue
+		}
+		words = append(words, token)
+	}
+	if len(words) == 0 {
+		return time.Time{}, invalid
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, clock.Timezone)
+	for _, word := range words {
+		if wd, ok := weekdays[word]; ok && !hasDate {
+			weekday, hasWeekday, hasDate = wd, true, true
+		} else if word == "today" && !hasDate {
+			date, hasDate = today, true
+		} else if word == "tomorrow" && !hasDate {
+			date, hasDate = today.AddDate(0, 0, 1), true
+		} else if match := monthDayRegex.FindStringSubmatch(word); match != nil && !hasDate {
+			month, _ := strconv.Atoi(match[1])
+			day, _ := strconv.Atoi(match[2])
+			if month < 1 || month > 12 || day < 1 || day > 31 {
+				return time.Time{}, invalid
+			}
+			date, hasDate = time.Date(now.Year(), time.Month(month), day, 0, 0, 0, 0, clock.Timezone), true
+			if date.Month() != time.Month(month) {
+				return time.Time{}, invalid
+			}
+			if date.Before(today) && time.Month(month) < now.Month() {
+				date = date.AddDate(1, 0, 0) // 1/3 in December means next year
+			}
+		} else if match := ordinalRegex.FindStringSubmatch(word); match != nil && !hasDate {
+			day, _ := strconv.Atoi(match[1])
+			if day < 1 || day > 31 {
+				return time.Time{}, invalid
+			}
+			date, hasDate = time.Date(now.Year(), now.Month(), day, 0, 0, 0, 0, clock.Timezone), true
+			if date.Before(today) || date.Day() != day {
+				date = time.Date(now.Year(), now.Month()+1, day, 0, 0, 0, 0, clock.Timezone)
+			}
+		} else if (word == "noon" || word == "midnight") && !hasTime {
+			hour, minute, hasTime = 12, 0, true
+			if word == "midnight" {
+				hour = 0
+			}
+		} else if match := timeRegex.FindStringSubmatch(word); match != nil && !hasTime && (match[2] != "" || match[3] != "") {
+			hour, _ = strconv.Atoi(match[1])
+			if match[2] != "" {
+				minute, _ = strconv.Atoi(match[2])
+			}
+			if hour > 23 || minute > 59 || (match[3] != "" && (hour == 0 || hour > 12)) {
+				return time.Time{}, invalid
+			}
+			if match[3] == "pm" && hour != 12 {
+				hour += 12
+			} else if match[3] == "am" && hour == 12 {
+				hour = 0
+			}
+			hasTime = true
+		} else {
+			return time.Time{}, invalid
+		}
+	}
+
+	var t time.Time
+	switch {
+	case hasWeekday:
+		daysAhead := (int(weekday) - int(now.Weekday()) + 7) % 7
+		t = time.Date(now.Year(), now.Month(), now.Day()+daysAhead, hour, minute, 0, 0, clock.Timezone)
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 7)
+		}
+	case hasDate:
+		t = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, clock.Timezone)
+	default:
+		t = time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, clock.Timezone)
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("invalid time for /schedule: %s is in the past", t.Format("Mon 1/2 3:04pm"))
+	}
+	return t, nil
+}
@@ -0,0 +1,125 @@
+package commands_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/commands"
+	"github.com/onsi/disco/mail"
+)
+
+var _ = Describe("Scheduling", func() {
+	var now time.Time
+	var boss mail.EmailAddress
+
+	at := func(month time.Month, day int, hour int, minute int) time.Time {
+		return time.Date(2023, month, day, hour, minute, 0, 0, clock.Timezone)
+	}
+
+	BeforeEach(func() {
+		now = at(time.September, 27, 10, 0) // a Wednesday
+		boss = mail.EmailAddress("Boss <boss@example.com>")
+	})
+
+	DescribeTable("ParseWhen", func(when string, expected time.Time) {
+		t, err := commands.ParseWhen(strings.Fields(when), now)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(t).Should(BeTemporally("==", expected))
+	},
+		Entry(nil, "friday 6pm", at(time.September, 29, 18, 0)),
+		Entry(nil, "Thu noon", at(time.September, 28, 12, 0)),
+		Entry(nil, "thursday at 12:30pm", at(time.September, 28, 12, 30)),
+		Entry(nil, "fri 6 pm", at(time.September, 29, 18, 0)),
+		Entry("today later", "wed 5pm", at(time.September, 27, 17, 0)),
+		Entry("today, but too early - so next week", "wed 9am", at(time.October, 4, 9, 0)),
+		Entry("a day with no time is at 6am", "saturday", at(time.September, 30, 6, 0)),
+		Entry(nil, "tomorrow 7:30am", at(time.September, 28, 7, 30)),
+		Entry(nil, "today 18:00", at(time.September, 27, 18, 0)),
+		Entry("a time with no day is the next time it comes around", "5pm", at(time.September, 27, 17, 0)),
+		Entry(nil, "8am", at(time.September, 28, 8, 0)),
+		Entry(nil, "10/2 6am", at(time.October, 2, 6, 0)),
+		Entry(nil, "on the 29th", at(time.September, 29, 6, 0)),
+		Entry("ordinals in the past roll over to next month", "the 24th", at(time.October, 24, 6, 0)),
+		Entry(nil, "12am tomorrow", at(time.September, 28, 0, 0)),
+	)
+
+	It("rolls dates in early months over to next year", func() {
+		now = at(time.December, 20, 10, 0)
+		t, err := commands.ParseWhen([]string{"1/3"}, now)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(t).Should(BeTemporally("==", time.Date(2024, time.January, 3, 6, 0, 0, 0, clock.Timezone)))
+	})
+
+	DescribeTable("invalid times", func(when string, expectedError string) {
+		_, err := commands.ParseWhen(strings.Fields(when), now)
+		Ω(err).Should(MatchError(ContainSubstring(expectedError)))
+	},
+		Entry(nil, "", "invalid time for /schedule"),
+		Entry(nil, "someday", "invalid time for /schedule: someday - try something like"),
+		Entry(nil, "friday 13pm", "invalid time for /schedule"),
+		Entry(nil, "2/30", "invalid time for /schedule"),
+		Entry(nil, "9/25 noon", "invalid time for /schedule: Mon 9/25 12:00pm is in the past"),
+		Entry(nil, "today 9am", "is in the past"),
+	)
+
+	Describe("ParseSchedule", func() {
+		var parser commands.Parser
+		BeforeEach(func() {
+			parser = commands.NewParser(
+				commands.Spec{Name: "game-on", TakesBody: true, Description: "game on"},
+				commands.Spec{Name: "undo", Description: "undo"},
+				commands.Spec{Name: "schedule", Args: "<when> /<command>", MinArgs: 2, MaxArgs: -1, TakesBody: true, Description: "schedule"},
+			)
+		})
+
+		It("parses the time, condition and command", func() {
+			action, err := commands.ParseSchedule(parser, strings.Fields("friday 6pm if-quorum /game-on"), "See you there!", boss, now)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(action).Should(Equal(commands.DeferredAction{
+				At:          at(time.September, 29, 18, 0),
+				Condition:   commands.ConditionIfQuorum,
+				CommandLine: "/game-on",
+				Body:        "See you there!",
+				SetBy:       boss,
+			}))
+			Ω(action.Text()).Should(Equal("/game-on\nSee you there!"))
+		})
+
+		It("validates the command", func() {
+			_, err := commands.ParseSchedule(parser, strings.Fields("friday 6pm /gameon"), "", boss, now)
+			Ω(err).Should(MatchError("invalid command to schedule: unknown command: /gameon - did you mean /game-on?"))
+			_, err = commands.ParseSchedule(parser, strings.Fields("friday 6pm"), "", boss, now)
+			Ω(err).Should(MatchError(ContainSubstring("put the command to run after the time")))
+			_, err = commands.ParseSchedule(parser, strings.Fields("friday 6pm /undo"), "", boss, now)
+			Ω(err).Should(MatchError("invalid command to schedule: /undo can't be scheduled"))
+			_, err = commands.ParseSchedule(parser, strings.Fields("someday /game-on"), "", boss, now)
+			Ω(err).Should(MatchError(ContainSubstring("invalid time for /schedule: someday")))
+		})
+	})
+
+	Describe("DeferredActions", func() {
+		It("assigns IDs, sorts by time, and removes actions", func() {
+			actions := commands.DeferredActions{}
+			actions, a := actions.Add(commands.DeferredAction{At: at(time.September, 29, 18, 0), CommandLine: "/game-on", Condition: commands.ConditionIfQuorum, SetBy: boss})
+			actions, b := actions.Add(commands.DeferredAction{At: at(time.September, 28, 12, 0), CommandLine: "/status", SetBy: boss})
+			Ω(a.ID).Should(Equal(1))
+			Ω(b.ID).Should(Equal(2))
+			Ω(actions.String()).Should(Equal("- #2: /status on Thu 9/28 12:00pm (set by Boss)\n- #1: /game-on on Fri 9/29 6:00pm if we have quorum (set by Boss)\n"))
+
+			found, ok := actions.FindByAlarmName(a.AlarmName())
+			Ω(ok).Should(BeTrue())
+			Ω(found).Should(Equal(a))
+
+			actions = actions.Remove(2)
+			_, ok = actions.Find(2)
+			Ω(ok).Should(BeFalse())
+			actions, c := actions.Add(commands.DeferredAction{At: at(time.September, 28, 12, 0), CommandLine: "/status", SetBy: boss})
+			Ω(c.ID).Should(Equal(2))
+			Ω(actions).Should(HaveLen(2))
+		})
+	})
+})
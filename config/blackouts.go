@@ -0,0 +1,215 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/mail"
+)
+
+const BLACKOUT_DATE_FORMAT = "2006-01-02"
+
+// Blackout is a stretch of days with no games - holidays and the like.  Dates are inclusive and in BLACKOUT_DATE_FORMAT, which sorts, so
+// they can be compared as strings without worrying about time zones.
+type Blackout struct {
+	// ID is only set on blackouts added by e-mail - blackouts from the calendar file can only be changed by editing the file
+	ID     int      `json:"id,omitempty"`
+	Start  string   `json:"start"`
+	End    string   `json:"end,omitempty"`
+	Discos []string `json:"discos,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+	// Silent skips the week without telling the list, instead of sending the no-invitation e-mail
+	Silent bool `json:"silent,omitempty"`
+	// Announce mentions the blackout in the previous week's e-mails
+	Announce bool              `json:"announce,omitempty"`
+	SetBy    mail.EmailAddress `json:"set_by,omitempty"`
+}
+
+func (b Blackout) IsZero() bool {
+	return b.Start == ""
+}
+
+func (b Blackout) EndDate() string {
+	if b.End == "" {
+		return b.Start
+	}
+	return b.End
+}
+
+// AppliesTo is true if the blackout covers disco.  Blackouts without any discos cover all of them.
+func (b Blackout) AppliesTo(disco string) bool {
+	if len(b.Discos) == 0 {
+		return true
+	}
+	for _, d := range b.Discos {
+		if d == disco {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlaps is true if any day from start to end (inclusive) is blacked out
+func (b Blackout) Overlaps(start string, end string) bool {
+	return b.Start <= end && b.EndDate() >= start
+}
+
+// Covers is true if every day from start to end (inclusive) is blacked out
+func (b Blackout) Covers(start string, end string) bool {
+	return b.Start <= start && b.EndDate() >= end
+}
+
+func (b Blackout) Dates() string {
+	start, _ := time.Parse(BLACKOUT_DATE_FORMAT, b.Start)
+	end, _ := time.Parse(BLACKOUT_DATE_FORMAT, b.EndDate())
+	if start.Equal(end) {
+		return start.Format("1/2")
+	}
+	return start.Format("1/2") + "-" + end.Format("1/2")
+}
+
+func (b Blackout) String() string {
+	out := b.Dates()
+	if b.ID > 0 {
+		out = fmt.Sprintf("#%d: %s", b.ID, out)
+	}
+	if b.Reason != "" {
+		out += " (" + b.Reason + ")"
+	}
+	if b.Silent {
+		out += ", silent"
+	}
+	if b.Announce {
+		out += ", announced the week before"
+	}
+	return out
+}
+
+type Blackouts []Blackout
+
+// Find returns the first blackout for disco that overlaps start to end
+func (b Blackouts) Find(disco string, start string, end string) (Blackout, bool) {
+	for _, blackout := range b {
+		if blackout.AppliesTo(disco) && blackout.Overlaps(start, end) {
+			return blackout, true
+		}
+	}
+	return Blackout{}, false
+}
+
+// Covering returns the first blackout for disco that covers every day from start to end
+func (b Blackouts) Covering(disco string, start string, end string) (Blackout, bool) {
+	for _, blackout := range b {
+		if blackout.AppliesTo(disco) && blackout.Covers(start, end) {
+			return blackout, true
+		}
+	}
+	return Blackout{}, false
+}
+
+// Upcoming returns the blackouts for disco that haven't ended as of today
+func (b Blackouts) Upcoming(disco string, today string) Blackouts {
+	out := Blackouts{}
+	for _, blackout := range b {
+		if blackout.AppliesTo(disco) && blackout.EndDate() >= today {
+			out = append(out, blackout)
+		}
+	}
+	return out
+}
+
+// Add gives the blackout the next ID
+func (b Blackouts) Add(blackout Blackout) (Blackouts, Blackout) {
+	for _, existing := range b {
+		if existing.ID >= blackout.ID {
+			blackout.ID = existing.ID + 1
+		}
+	}
+	if blackout.ID == 0 {
+		blackout.ID = 1
+	}
+	return append(b.Dup(), blackout), blackout
+}
+
+func (b Blackouts) Remove(id int) (Blackouts, Blackout, bool) {
+	out := Blackouts{}
+	var removed Blackout
+	found := false
+	for _, blackout := range b {
+		if blackout.ID == id {
+			removed, found = blackout, true
+		} else {
+			out = append(out, blackout)
+		}
+	}
+	return out, removed, found
+}
+
+func (b Blackouts) Dup() Blackouts {
+	if b == nil {
+		return nil
+	}
+	out := make(Blackouts, len(b))
+	copy(out, b)
+	return out
+}
+
+func (b Blackouts) String() string {
+	lines := []string{}
+	for _, blackout := range b {
+		lines = append(lines, "- "+blackout.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// BLACKOUTS_FILE points at a JSON calendar, e.g. [{"start": "2024-11-30", "discos": ["saturday"], "reason": "Thanksgiving", "announce": true}]
+// It defaults to blackouts.json, which is fine to leave out.
+func loadBlackouts() Blackouts {
+	path, explicit := os.LookupEnv("BLACKOUTS_FILE")
+	if !explicit {
+		path = "blackouts.json"
+	}
+	blackouts := Blackouts{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return blackouts
+	} else if err != nil {
+		panic(fmt.Sprintf("invalid BLACKOUTS_FILE: %s", err.Error()))
+	}
+	blackouts, err = ParseBlackouts(data)
+	if err != nil {
+		panic(fmt.Sprintf("invalid BLACKOUTS_FILE: %s", err.Error()))
+	}
+	return blackouts
+}
+
+func ParseBlackouts(data []byte) (Blackouts, error) {
+	blackouts := Blackouts{}
+	if err := json.Unmarshal(data, &blackouts); err != nil {
+		return nil, err
+	}
+	for i, blackout := range blackouts {
+		if blackout.ID != 0 {
+			return nil, fmt.Errorf("blackout %s: ids are only for blackouts added by e-mail", blackout.Start)
+		}
+		if _, err := time.Parse(BLACKOUT_DATE_FORMAT, blackout.Start); err != nil {
+			return nil, fmt.Errorf("blackout %d: invalid start %q - use YYYY-MM-DD", i, blackout.Start)
+		}
+		if _, err := time.Parse(BLACKOUT_DATE_FORMAT, blackout.EndDate()); err != nil {
+			return nil, fmt.Errorf("blackout %s: invalid end %q - use YYYY-MM-DD", blackout.Start, blackout.End)
+		}
+		if blackout.EndDate() < blackout.Start {
+			return nil, fmt.Errorf("blackout %s: ends before it starts", blackout.Start)
+		}
+		for _, disco := range blackout.Discos {
+			if disco != DiscoSaturday && disco != DiscoLunchtime {
+				return nil, fmt.Errorf("blackout %s: unknown disco %q", blackout.Start, disco)
+			}
+		}
+	}
+	return blackouts, nil
+}
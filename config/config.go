@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/onsi/disco/mail"
 )
@@ -12,16 +15,34 @@ type Config struct {
 	SaturdayDiscoList   mail.EmailAddress
 	LunchtimeDiscoEmail mail.EmailAddress
 	LunchtimeDiscoList  mail.EmailAddress
+	Organizers          Organizers
+	Blackouts           Blackouts
 
 	Port            string
 	Env             string
 	ForwardEmailKey string
 	GmailUser       string
 	GmailPassword   string
+	MailRoutes      []mail.TransportConfig
 
 	IncomingSaturdayEmailGUID  string
 	IncomingLunchtimeEmailGUID string
+	IncomingWebhookSecret      string
+	// when set we also accept mail directly over SMTP on this port, instead of (or as well as) via forwardemail's webhooks
+	SMTPPort            string
+	SMTPHostname        string
+	SMTPMaxMessageBytes int64
+	// when set we also poll this IMAP server (host:port, over TLS) for incoming mail
+	IMAPAddress      string
+	IMAPUser         string
+	IMAPPassword     string
+	IMAPPollInterval time.Duration
+	// incoming e-mail we've archived for longer than this is deleted when the boss applies the retention policy
+	EmailRetention time.Duration
+	// admin commands must pass DKIM/SPF alignment for the sender's domain - the rest are quarantined until the boss confirms them
+	RequireAuthenticatedAdmins bool
 	OpenAIKey                  string
+	SigningSecret              string
 
 	AWSAccessKey string
 	AWSSecretKey string
@@ -46,7 +67,18 @@ func LoadConfig() Config {
 		GmailPassword:              os.Getenv("GMAIL_PASSWORD"),
 		IncomingSaturdayEmailGUID:  os.Getenv("INCOMING_SATURDAY_EMAIL_GUID"),
 		IncomingLunchtimeEmailGUID: os.Getenv("INCOMING_LUNCHTIME_EMAIL_GUID"),
+		IncomingWebhookSecret:      os.Getenv("INCOMING_WEBHOOK_SECRET"),
+		SMTPPort:                   os.Getenv("SMTP_PORT"),
+		SMTPHostname:               os.Getenv("SMTP_HOSTNAME"),
+		SMTPMaxMessageBytes:        loadSMTPMaxMessageBytes(),
+		IMAPAddress:                os.Getenv("IMAP_ADDRESS"),
+		IMAPUser:                   withDefault(os.Getenv("IMAP_USER"), os.Getenv("GMAIL_USER")),
+		IMAPPassword:               withDefault(os.Getenv("IMAP_PASSWORD"), os.Getenv("GMAIL_PASSWORD")),
+		IMAPPollInterval:           loadIMAPPollInterval(),
+		EmailRetention:             loadEmailRetention(),
+		RequireAuthenticatedAdmins: os.Getenv("ALLOW_UNAUTHENTICATED_ADMINS") != "true",
 		OpenAIKey:                  os.Getenv("OPEN_AI_KEY"),
+		SigningSecret:              os.Getenv("SIGNING_SECRET"),
 		AWSAccessKey:               os.Getenv("AWS_ACCESS_KEY"),
 		AWSSecretKey:               os.Getenv("AWS_SECRET_KEY"),
 		AWSRegion:                  os.Getenv("AWS_REGION"),
@@ -57,5 +89,54 @@ func LoadConfig() Config {
 		SaturdayDiscoList:   mail.EmailAddress(os.Getenv("SATURDAY_DISCO_LIST")),
 		LunchtimeDiscoEmail: mail.EmailAddress(os.Getenv("LUNCHTIME_DISCO_EMAIL")),
 		LunchtimeDiscoList:  mail.EmailAddress(os.Getenv("LUNCHTIME_DISCO_LIST")),
+		Organizers:          loadOrganizers(),
+		Blackouts:           loadBlackouts(),
+		MailRoutes:          loadMailRoutes(os.Getenv("FORWARD_EMAIL_KEY"), os.Getenv("GMAIL_USER"), os.Getenv("GMAIL_PASSWORD")),
 	}
 }
+
+// SMTP_MAX_MESSAGE_BYTES is optional - 0 means the SMTP server's default
+func loadSMTPMaxMessageBytes() int64 {
+	raw := os.Getenv("SMTP_MAX_MESSAGE_BYTES")
+	if raw == "" {
+		return 0
+	}
+	out, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || out < 0 {
+		panic(fmt.Sprintf("invalid SMTP_MAX_MESSAGE_BYTES: %q", raw))
+	}
+	return out
+}
+
+// IMAP_POLL_INTERVAL is a Go duration, e.g. 30s - 0 means the poller's default
+func loadIMAPPollInterval() time.Duration {
+	raw := os.Getenv("IMAP_POLL_INTERVAL")
+	if raw == "" {
+		return 0
+	}
+	out, err := time.ParseDuration(raw)
+	if err != nil || out < 0 {
+		panic(fmt.Sprintf("invalid IMAP_POLL_INTERVAL: %q", raw))
+	}
+	return out
+}
+
+// EMAIL_RETENTION_DAYS defaults to 90
+func loadEmailRetention() time.Duration {
+	raw := os.Getenv("EMAIL_RETENTION_DAYS")
+	if raw == "" {
+		return 90 * 24 * time.Hour
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days <= 0 {
+		panic(fmt.Sprintf("invalid EMAIL_RETENTION_DAYS: %q", raw))
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func withDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/onsi/disco/mail"
+)
+
+// MAIL_ROUTES is a JSON array mapping sender addresses to transports, e.g.
+//
+//	[{"from": "@sedenverultimate.net", "transport": "forwardemail", "key": "..."},
+//	 {"from": "onsijoe@gmail.com", "transport": "smtp", "host": "smtp.gmail.com", "port": 587, "security": "starttls", "username": "onsijoe@gmail.com", "password": "..."},
+//	 {"from": "*", "transport": "maildir", "dir": "./outgoing"}]
+//
+// Routes are tried in order.  Without MAIL_ROUTES we send @sedenverultimate.net e-mail via forwardemail and GMAIL_USER's e-mail via gmail.
+func loadMailRoutes(forwardEmailKey, gmailUser, gmailPassword string) []mail.TransportConfig {
+	raw := os.Getenv("MAIL_ROUTES")
+	if raw == "" {
+		return defaultMailRoutes(forwardEmailKey, gmailUser, gmailPassword)
+	}
+	routes := []mail.TransportConfig{}
+	if err := json.Unmarshal([]byte(raw), &routes); err != nil {
+		panic(fmt.Sprintf("invalid MAIL_ROUTES: %s", err.Error()))
+	}
+	for _, route := range routes {
+		if _, err := mail.NewTransport(route); err != nil {
+			panic(fmt.Sprintf("invalid MAIL_ROUTES: %s for %q", err.Error(), route.From))
+		}
+	}
+	return routes
+}
+
+func defaultMailRoutes(forwardEmailKey, gmailUser, gmailPassword string) []mail.TransportConfig {
+	routes := []mail.TransportConfig{{
+		From:      "@sedenverultimate.net",
+		Transport: mail.TransportForwardEmail,
+		Key:       forwardEmailKey,
+	}}
+	if gmailUser != "" {
+		routes = append(routes, mail.TransportConfig{
+			From:      gmailUser,
+			Transport: mail.TransportSMTP,
+			Host:      "smtp.gmail.com",
+			Port:      587,
+			Security:  mail.SMTPSecurityStartTLS,
+			Username:  gmailUser,
+			Password:  gmailPassword,
+		})
+	}
+	return routes
+}
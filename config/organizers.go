@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/onsi/disco/mail"
+)
+
+type Role string
+
+const (
+	RoleBoss      Role = "boss"
+	RoleOrganizer Role = "organizer"
+	RoleViewer    Role = "viewer"
+)
+
+// CanManage is true for roles that are allowed to make changes, not just look
+func (r Role) CanManage() bool {
+	return r == RoleBoss || r == RoleOrganizer
+}
+
+const (
+	DiscoSaturday  = "saturday"
+	DiscoLunchtime = "lunchtime"
+)
+
+type Organizer struct {
+	Address mail.EmailAddress `json:"address"`
+	Role    Role              `json:"role"`
+	// Discos lists the discos this organizer has access to.  Empty means all of them.
+	Discos []string `json:"discos"`
+}
+
+func (o Organizer) CanView(disco string) bool {
+	if len(o.Discos) == 0 {
+		return true
+	}
+	for _, d := range o.Discos {
+		if d == disco {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Organizer) CanManage(disco string) bool {
+	return o.Role.CanManage() && o.CanView(disco)
+}
+
+type Organizers []Organizer
+
+func (o Organizers) Find(address mail.EmailAddress) (Organizer, bool) {
+	for _, organizer := range o {
+		if organizer.Address.Equals(address) {
+			return organizer, true
+		}
+	}
+	return Organizer{}, false
+}
+
+// Managers returns the organizers who can make changes to disco
+func (o Organizers) Managers(disco string) Organizers {
+	out := Organizers{}
+	for _, organizer := range o {
+		if organizer.CanManage(disco) {
+			out = append(out, organizer)
+		}
+	}
+	return out
+}
+
+func (o Organizers) Includes(address mail.EmailAddress) bool {
+	_, ok := o.Find(address)
+	return ok
+}
+
+func (o Organizers) Addresses() mail.EmailAddresses {
+	out := mail.EmailAddresses{}
+	for _, organizer := range o {
+		out = append(out, organizer.Address)
+	}
+	return out
+}
+
+// IsAdminEmail is true if an organizer sent email to disco with nobody but other organizers on it.  Approval requests go to every
+// organizer, so a reply-all to one is still a command - but anything that copies the list or a player is a conversation.
+func (o Organizers) IsAdminEmail(email mail.Email, disco mail.EmailAddress) bool {
+	if !o.Includes(email.From) || !email.IncludesRecipient(disco) {
+		return false
+	}
+	for _, recipient := range email.Recipients() {
+		if !recipient.Equals(disco) && !o.Includes(recipient) {
+			return false
+		}
+	}
+	return true
+}
+
+// Except returns all organizers other than address
+func (o Organizers) Except(address mail.EmailAddress) Organizers {
+	out := Organizers{}
+	for _, organizer := range o {
+		if !organizer.Address.Equals(address) {
+			out = append(out, organizer)
+		}
+	}
+	return out
+}
+
+// AllOrganizers always includes the boss, who has access to every disco
+func (c Config) AllOrganizers() Organizers {
+	out := Organizers{{Address: c.BossEmail, Role: RoleBoss}}
+	for _, organizer := range c.Organizers {
+		if !organizer.Address.Equals(c.BossEmail) {
+			out = append(out, organizer)
+		}
+	}
+	return out
+}
+
+// ORGANIZERS is a JSON array, e.g. [{"address": "Jane <jane@example.com>", "role": "organizer", "discos": ["saturday"]}]
+func loadOrganizers() Organizers {
+	organizers := Organizers{}
+	raw := os.Getenv("ORGANIZERS")
+	if raw == "" {
+		return organizers
+	}
+	if err := json.Unmarshal([]byte(raw), &organizers); err != nil {
+		panic(fmt.Sprintf("invalid ORGANIZERS: %s", err.Error()))
+	}
+	for _, organizer := range organizers {
+		for _, disco := range organizer.Discos {
+			if disco != DiscoSaturday && disco != DiscoLunchtime {
+				panic(fmt.Sprintf("invalid ORGANIZERS: unknown disco %q for %s", disco, organizer.Address))
+			}
+		}
+		if organizer.Role != RoleBoss && organizer.Role != RoleOrganizer && organizer.Role != RoleViewer {
+			panic(fmt.Sprintf("invalid ORGANIZERS: unknown role %q for %s", organizer.Role, organizer.Address))
+		}
+	}
+	return organizers
+}
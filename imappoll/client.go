@@ -0,0 +1,220 @@
+package imappoll
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const TIMEOUT = time.Minute
+
+// Mailbox is the little bit of IMAP the Poller needs
+type Mailbox interface {
+	UIDValidity() uint32
+	// SearchUnseen returns the UIDs of unseen messages with UIDs >= since
+	SearchUnseen(since uint32) ([]uint32, error)
+	// Fetch returns the raw message without marking it as seen
+	Fetch(uid uint32) ([]byte, error)
+	MarkSeen(uid uint32) error
+	Logout() error
+}
+
+type Dialer func() (Mailbox, error)
+
+// IMAPDialer connects over TLS (i.e. port 993), logs in, and selects the INBOX
+func IMAPDialer(addr string, username string, password string) Dialer {
+	return func() (Mailbox, error) {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: TIMEOUT}, "tcp", addr, &tls.Config{ServerName: host})
+		if err != nil {
+			return nil, err
+		}
+		client, err := NewClient(conn)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if err := client.Login(username, password); err != nil {
+			client.Logout()
+			return nil, err
+		}
+		if err := client.Select("INBOX"); err != nil {
+			client.Logout()
+			return nil, err
+		}
+		return client, nil
+	}
+}
+
+// response is an untagged response - any literals ({n}\r\n...) it carried are pulled out into literals
+type response struct {
+	text     string
+	literals [][]byte
+}
+
+// Client is a minimal IMAP4rev1 client: just enough to read an inbox
+type Client struct {
+	conn        net.Conn
+	reader      *bufio.Reader
+	tag         int
+	uidValidity uint32
+}
+
+var literalRegex = regexp.MustCompile(`\{(\d+)\}$`)
+var uidValidityRegex = regexp.MustCompile(`(?i)\[UIDVALIDITY (\d+)\]`)
+var fetchUIDRegex = regexp.MustCompile(`(?i)\bUID (\d+)`)
+
+// NewClient reads the server's greeting off conn
+func NewClient(conn net.Conn) (*Client, error) {
+	c := &Client{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+	c.conn.SetDeadline(time.Now().Add(TIMEOUT))
+	greeting, err := c.readResponse()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(strings.ToUpper(greeting.text), "* OK") {
+		return nil, fmt.Errorf("unexpected IMAP greeting: %s", greeting.text)
+	}
+	return c, nil
+}
+
+func (c *Client) Login(username string, password string) error {
+	_, err := c.command("LOGIN %s %s", quote(username), quote(password))
+	return err
+}
+
+func (c *Client) Select(mailbox string) error {
+	responses, err := c.command("SELECT %s", quote(mailbox))
+	if err != nil {
+		return err
+	}
+	for _, response := range responses {
+		if match := uidValidityRegex.FindStringSubmatch(response.text); match != nil {
+			validity, _ := strconv.ParseUint(match[1], 10, 32)
+			c.uidValidity = uint32(validity)
+		}
+	}
+	return nil
+}
+
+func (c *Client) UIDValidity() uint32 {
+	return c.uidValidity
+}
+
+func (c *Client) SearchUnseen(since uint32) ([]uint32, error) {
+	responses, err := c.command("UID SEARCH UID %d:* UNSEEN", max(since, 1))
+	if err != nil {
+		return nil, err
+	}
+	uids := []uint32{}
+	for _, response := range responses {
+		fields := strings.Fields(response.text)
+		if len(fields) < 2 || !strings.EqualFold(fields[1], "SEARCH") {
+			continue
+		}
+		for _, field := range fields[2:] {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("malformed IMAP search response: %s", response.text)
+			}
+			// n:* always matches the last message, even if its UID is less than n
+			if uint32(uid) >= since {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+func (c *Client) Fetch(uid uint32) ([]byte, error) {
+	responses, err := c.command("UID FETCH %d (UID BODY.PEEK[])", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, response := range responses {
+		match := fetchUIDRegex.FindStringSubmatch(response.text)
+		if match == nil || match[1] != strconv.FormatUint(uint64(uid), 10) || len(response.literals) == 0 {
+			continue
+		}
+		return response.literals[0], nil
+	}
+	return nil, fmt.Errorf("IMAP server returned no message for UID %d", uid)
+}
+
+func (c *Client) MarkSeen(uid uint32) error {
+	_, err := c.command(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+func (c *Client) Logout() error {
+	_, err := c.command("LOGOUT")
+	c.conn.Close()
+	return err
+}
+
+// command sends a tagged command and collects the untagged responses until the server completes it
+func (c *Client) command(format string, args ...any) ([]response, error) {
+	c.tag += 1
+	tag := fmt.Sprintf("D%03d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(TIMEOUT))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+	responses := []response{}
+	for {
+		r, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(r.text, tag+" ") {
+			responses = append(responses, r)
+			continue
+		}
+		status := strings.TrimPrefix(r.text, tag+" ")
+		if strings.HasPrefix(strings.ToUpper(status), "OK") {
+			return responses, nil
+		}
+		command, _, _ := strings.Cut(format, " ")
+		return nil, fmt.Errorf("IMAP %s failed: %s", command, status)
+	}
+}
+
+func (c *Client) readResponse() (response, error) {
+	out := response{}
+	text := &strings.Builder{}
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return response{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		text.WriteString(line)
+		match := literalRegex.FindStringSubmatch(line)
+		if match == nil {
+			out.text = text.String()
+			return out, nil
+		}
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return response{}, err
+		}
+		literal := make([]byte, n)
+		if _, err := io.ReadFull(c.reader, literal); err != nil {
+			return response{}, err
+		}
+		out.literals = append(out.literals, literal)
+	}
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
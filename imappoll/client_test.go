@@ -0,0 +1,109 @@
+package imappoll_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/imappoll"
+)
+
+// scriptedServer answers each tagged command with the canned response for its verb, recording what it was sent
+func scriptedServer(conn net.Conn, responses map[string]string, received chan<- string) {
+	defer GinkgoRecover()
+	defer conn.Close()
+	fmt.Fprintf(conn, "* OK IMAP4rev1 ready\r\n")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		received <- line
+		tag, command, _ := strings.Cut(line, " ")
+		verb := strings.Fields(command)[0]
+		if verb == "UID" {
+			verb = "UID " + strings.Fields(command)[1]
+		}
+		response, ok := responses[verb]
+		if !ok {
+			fmt.Fprintf(conn, "%s BAD unknown command\r\n", tag)
+			continue
+		}
+		fmt.Fprintf(conn, "%s%s OK done\r\n", response, tag)
+		if verb == "LOGOUT" {
+			return
+		}
+	}
+}
+
+var _ = Describe("Client", func() {
+	var client *imappoll.Client
+	var received chan string
+	var responses map[string]string
+
+	BeforeEach(func() {
+		received = make(chan string, 100)
+		message := "From: a@example.com\r\nSubject: {hi}\r\n\r\nI'm in!\r\n"
+		responses = map[string]string{
+			"LOGIN":      "",
+			"SELECT":     "* 3 EXISTS\r\n* OK [UIDVALIDITY 1234] UIDs valid\r\n",
+			"UID SEARCH": "* SEARCH 4 9 12\r\n",
+			"UID FETCH":  fmt.Sprintf("* 2 FETCH (UID 9 BODY[] {%d}\r\n%s)\r\n", len(message), message),
+			"UID STORE":  "",
+			"LOGOUT":     "* BYE\r\n",
+		}
+	})
+
+	JustBeforeEach(func() {
+		clientConn, serverConn := net.Pipe()
+		go scriptedServer(serverConn, responses, received)
+		var err error
+		client, err = imappoll.NewClient(clientConn)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("logs in, selects, searches, fetches, and marks messages as seen", func() {
+		Ω(client.Login("disco@example.com", `pa"ss\word`)).Should(Succeed())
+		Ω(received).Should(Receive(Equal(`D001 LOGIN "disco@example.com" "pa\"ss\\word"`)))
+
+		Ω(client.Select("INBOX")).Should(Succeed())
+		Ω(client.UIDValidity()).Should(Equal(uint32(1234)))
+
+		uids, err := client.SearchUnseen(5)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(uids).Should(Equal([]uint32{9, 12}), "4 is returned by the server because n:* always includes the last message, but it's before 5")
+
+		data, err := client.Fetch(9)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(data)).Should(Equal("From: a@example.com\r\nSubject: {hi}\r\n\r\nI'm in!\r\n"))
+
+		Ω(client.MarkSeen(9)).Should(Succeed())
+		Ω(client.Logout()).Should(Succeed())
+
+		Ω(received).Should(Receive(Equal(`D002 SELECT "INBOX"`)))
+		Ω(received).Should(Receive(Equal(`D003 UID SEARCH UID 5:* UNSEEN`)))
+		Ω(received).Should(Receive(Equal(`D004 UID FETCH 9 (UID BODY.PEEK[])`)))
+		Ω(received).Should(Receive(Equal(`D005 UID STORE 9 +FLAGS.SILENT (\Seen)`)))
+	})
+
+	Context("when the server refuses a command", func() {
+		BeforeEach(func() {
+			delete(responses, "LOGIN")
+		})
+
+		It("returns an error", func() {
+			Ω(client.Login("disco@example.com", "wrong")).Should(MatchError(ContainSubstring("IMAP LOGIN failed: BAD")))
+		})
+	})
+
+	It("errors when the server doesn't return the message", func() {
+		_, err := client.Fetch(10)
+		Ω(err).Should(MatchError("IMAP server returned no message for UID 10"))
+	})
+})
@@ -0,0 +1,229 @@
+package imappoll
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	netmail "net/mail"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	"github.com/onsi/say"
+)
+
+const KEY = "imap-poller"
+
+const DEFAULT_INTERVAL = time.Minute
+
+// Disco is anything we can hand an incoming e-mail to - i.e. SaturdayDisco and LunchtimeDisco
+type Disco interface {
+	HandleIncomingEmail(email mail.Email)
+}
+
+// Checkpoint is how far through the mailbox we've got.  UIDs are only meaningful for a given UIDValidity - if the server changes it
+// we start over.
+type Checkpoint struct {
+	UIDValidity uint32    `json:"uid_validity"`
+	LastUID     uint32    `json:"last_uid"`
+	Processed   int       `json:"processed"`
+	LastPoll    time.Time `json:"last_poll"`
+}
+
+// Poller periodically checks an IMAP mailbox (e.g. the gmail account) for unseen messages addressed to a disco, hands them to that
+// disco, and marks them as seen.  Messages that aren't for a disco are left unseen - it may well be someone's personal inbox.
+//
+// As with the SMTP server, we don't trust the Authentication-Results on messages we pick up this way: admin commands will be
+// quarantined for the boss to confirm on the dashboard (their e-mailed confirmations arrive this way too, so can't be trusted either).
+type Poller struct {
+	dial     Dialer
+	interval time.Duration
+	db       s3db.S3DBInt
+	clock    clock.AlarmClockInt
+	w        io.Writer
+
+	routes     map[string]Disco
+	checkpoint Checkpoint
+	lock       *sync.Mutex
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewPoller(dial Dialer, interval time.Duration, db s3db.S3DBInt, alarmClock clock.AlarmClockInt, w io.Writer) (*Poller, error) {
+	if interval <= 0 {
+		interval = DEFAULT_INTERVAL
+	}
+	p := &Poller{
+		dial:     dial,
+		interval: interval,
+		db:       db,
+		clock:    alarmClock,
+		w:        w,
+
+		routes: map[string]Disco{},
+		lock:   &sync.Mutex{},
+	}
+	data, err := db.FetchObject(KEY)
+	if err != nil && !errors.Is(err, s3db.ErrObjectNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &p.checkpoint); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Route delivers mail sent to address - directly, or via a list - to disco
+func (p *Poller) Route(address mail.EmailAddress, disco Disco) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.routes[strings.ToLower(address.Address())] = disco
+}
+
+func (p *Poller) Checkpoint() Checkpoint {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.checkpoint
+}
+
+// Start polls right away and then every interval until Stop is called
+func (p *Poller) Start() {
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+	p.done = make(chan struct{})
+	p.clock.SetAlarm(p.clock.Time())
+	go p.run(ctx)
+}
+
+func (p *Poller) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+	p.clock.Stop()
+}
+
+func (p *Poller) run(ctx context.Context) {
+	defer close(p.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.clock.C():
+			if err := p.Poll(); err != nil {
+				say.Fplni(p.w, 0, "{{red}}IMAP Poller: failed to poll: %s{{/}}", err.Error())
+			}
+			p.clock.SetAlarm(p.clock.Time().Add(p.interval))
+		}
+	}
+}
+
+// Poll makes one pass through the mailbox's unseen messages.  The checkpoint is persisted after each message so a failure part way
+// through doesn't lead to anyone getting an e-mail handled twice.
+func (p *Poller) Poll() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	mailbox, err := p.dial()
+	if err != nil {
+		return err
+	}
+	defer mailbox.Logout()
+
+	if mailbox.UIDValidity() != p.checkpoint.UIDValidity {
+		if p.checkpoint.UIDValidity != 0 {
+			say.Fplni(p.w, 0, "{{yellow}}IMAP Poller: UIDVALIDITY changed from %d to %d - starting over{{/}}", p.checkpoint.UIDValidity, mailbox.UIDValidity())
+		}
+		p.checkpoint = Checkpoint{UIDValidity: mailbox.UIDValidity(), Processed: p.checkpoint.Processed}
+	}
+	p.checkpoint.LastPoll = p.clock.Time()
+
+	uids, err := mailbox.SearchUnseen(p.checkpoint.LastUID + 1)
+	if err != nil {
+		return err
+	}
+	slices.Sort(uids)
+	for _, uid := range uids {
+		if uid <= p.checkpoint.LastUID {
+			continue
+		}
+		data, err := mailbox.Fetch(uid)
+		if err != nil {
+			return err
+		}
+		if p.deliver(uid, data) {
+			if err := mailbox.MarkSeen(uid); err != nil {
+				return err
+			}
+			p.checkpoint.Processed += 1
+		}
+		p.checkpoint.LastUID = uid
+		if err := p.persist(); err != nil {
+			return err
+		}
+	}
+	return p.persist()
+}
+
+// deliver returns true if the message was for one of our discos
+func (p *Poller) deliver(uid uint32, data []byte) bool {
+	discos := p.discosFor(data)
+	if len(discos) == 0 {
+		return false
+	}
+	email, err := mail.ParseRawEmail(p.db, data, p.w)
+	if err != nil {
+		// there's no point trying again - we mark it as seen so it stops showing up and leave the raw e-mail for debugging
+		say.Fplni(p.w, 0, "{{red}}IMAP Poller: failed to parse message %d: %s{{/}}", uid, err.Error())
+		return true
+	}
+	email.Authentication = mail.AuthenticationResults{}
+	say.Fplni(p.w, 0, "{{green}}IMAP Poller: picked up %q from %s{{/}}", email.Subject, email.From)
+	for _, disco := range discos {
+		disco.HandleIncomingEmail(email)
+	}
+	return true
+}
+
+// discosFor looks for disco (and list) addresses among the recipients - including Delivered-To, since messages that come in via a list
+// or a Bcc don't name us in To or Cc
+func (p *Poller) discosFor(data []byte) []Disco {
+	message, err := netmail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	discos := []Disco{}
+	for _, key := range []string{"Delivered-To", "X-Original-To", "To", "Cc"} {
+		for _, value := range message.Header[key] {
+			addresses, err := netmail.ParseAddressList(value)
+			if err != nil {
+				continue
+			}
+			for _, address := range addresses {
+				disco, ok := p.routes[strings.ToLower(address.Address)]
+				if ok && !slices.Contains(discos, disco) {
+					discos = append(discos, disco)
+				}
+			}
+		}
+	}
+	return discos
+}
+
+func (p *Poller) persist() error {
+	data, err := json.Marshal(p.checkpoint)
+	if err != nil {
+		return err
+	}
+	return p.db.PutObject(KEY, data)
+}
@@ -0,0 +1,13 @@
+package imappoll_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestImappoll(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Imappoll Suite")
+}
@@ -0,0 +1,225 @@
+package imappoll_test
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/imappoll"
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+)
+
+var errBoom = errors.New("boom")
+
+type fakeDisco struct {
+	emails []mail.Email
+	lock   *sync.Mutex
+}
+
+func newFakeDisco() *fakeDisco {
+	return &fakeDisco{lock: &sync.Mutex{}}
+}
+
+func (f *fakeDisco) HandleIncomingEmail(email mail.Email) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.emails = append(f.emails, email)
+}
+
+func (f *fakeDisco) Emails() []mail.Email {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]mail.Email{}, f.emails...)
+}
+
+type fakeMailbox struct {
+	uidValidity uint32
+	messages    map[uint32][]byte
+	seen        map[uint32]bool
+	fetchErr    error
+	loggedOut   int
+	lock        *sync.Mutex
+}
+
+func (f *fakeMailbox) UIDValidity() uint32 { return f.uidValidity }
+
+func (f *fakeMailbox) SearchUnseen(since uint32) ([]uint32, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	uids := []uint32{}
+	for uid := range f.messages {
+		if uid >= since && !f.seen[uid] {
+			uids = append(uids, uid)
+		}
+	}
+	return uids, nil
+}
+
+func (f *fakeMailbox) Fetch(uid uint32) ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.fetchErr != nil {
+		return nil, f.fetchErr
+	}
+	return f.messages[uid], nil
+}
+
+func (f *fakeMailbox) MarkSeen(uid uint32) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.seen[uid] = true
+	return nil
+}
+
+func (f *fakeMailbox) Logout() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.loggedOut += 1
+	return nil
+}
+
+func (f *fakeMailbox) Seen(uid uint32) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.seen[uid]
+}
+
+func fixture(name string) []byte {
+	GinkgoHelper()
+	data, err := os.ReadFile("../mail/fixtures/" + name)
+	Ω(err).ShouldNot(HaveOccurred())
+	return data
+}
+
+var _ = Describe("Poller", func() {
+	var db *s3db.FakeS3DB
+	var fakeClock *clock.FakeAlarmClock
+	var mailbox *fakeMailbox
+	var dialErr error
+	var saturday, lunchtime *fakeDisco
+	var poller *imappoll.Poller
+	var now time.Time
+
+	build := func() {
+		GinkgoHelper()
+		var err error
+		poller, err = imappoll.NewPoller(func() (imappoll.Mailbox, error) {
+			if dialErr != nil {
+				return nil, dialErr
+			}
+			return mailbox, nil
+		}, time.Minute, db, fakeClock, GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		poller.Route("Saturday Disco <saturday-disco@sedenverultimate.net>", saturday)
+		poller.Route("saturday-se-denver-ultimate@googlegroups.com", saturday)
+		poller.Route("Lunchtime Disco <lunchtime-disco@sedenverultimate.net>", lunchtime)
+	}
+
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+		now = time.Date(2023, time.September, 24, 10, 0, 0, 0, clock.Timezone)
+		fakeClock = clock.NewFakeAlarmClock()
+		fakeClock.SetTime(now)
+		dialErr = nil
+		mailbox = &fakeMailbox{
+			uidValidity: 7,
+			messages: map[uint32][]byte{
+				3: fixture("raw_multipart_alternative.eml"),
+				4: []byte("From: friend@example.com\r\nTo: boss@example.com\r\nSubject: dinner?\r\n\r\nwant to get dinner?\r\n"),
+				5: fixture("raw_mailing_list.eml"),
+			},
+			seen: map[uint32]bool{},
+			lock: &sync.Mutex{},
+		}
+		saturday, lunchtime = newFakeDisco(), newFakeDisco()
+		build()
+	})
+
+	It("hands unseen messages to the disco they're addressed to, directly or via a list, and marks them as seen", func() {
+		Ω(poller.Poll()).Should(Succeed())
+		emails := saturday.Emails()
+		Ω(emails).Should(HaveLen(2))
+		Ω(emails[0].Subject).Should(Equal("Re: Saturday Bible Park Frisbee 🥏"))
+		Ω(emails[0].Authentication.IsZero()).Should(BeTrue(), "we never trust authentication results from mail picked up this way")
+		Ω(emails[1].From).Should(Equal(mail.EmailAddress("Onsi Fakhouri <onsijoe@gmail.com>")))
+		Ω(lunchtime.Emails()).Should(BeEmpty())
+
+		Ω(mailbox.Seen(3)).Should(BeTrue())
+		Ω(mailbox.Seen(5)).Should(BeTrue())
+		Ω(mailbox.loggedOut).Should(Equal(1))
+	})
+
+	It("leaves messages that aren't for a disco alone", func() {
+		Ω(poller.Poll()).Should(Succeed())
+		Ω(mailbox.Seen(4)).Should(BeFalse())
+		Ω(poller.Checkpoint()).Should(Equal(imappoll.Checkpoint{UIDValidity: 7, LastUID: 5, Processed: 2, LastPoll: now}))
+	})
+
+	It("doesn't pick up the same message twice, even if it's still unseen", func() {
+		Ω(poller.Poll()).Should(Succeed())
+		mailbox.seen = map[uint32]bool{}
+		Ω(poller.Poll()).Should(Succeed())
+		Ω(saturday.Emails()).Should(HaveLen(2))
+	})
+
+	It("persists its checkpoint so a restart carries on where it left off", func() {
+		Ω(poller.Poll()).Should(Succeed())
+		mailbox.seen = map[uint32]bool{}
+		mailbox.messages[6] = fixture("raw_iso_8859_1.eml")
+
+		saturday = newFakeDisco()
+		build()
+		Ω(poller.Checkpoint().LastUID).Should(Equal(uint32(5)))
+		Ω(poller.Poll()).Should(Succeed())
+		Ω(saturday.Emails()).Should(HaveLen(1))
+		Ω(saturday.Emails()[0].Subject).Should(Equal("Ça marche"))
+	})
+
+	It("starts over when the mailbox's UIDVALIDITY changes", func() {
+		Ω(poller.Poll()).Should(Succeed())
+		mailbox.uidValidity = 8
+		mailbox.messages = map[uint32][]byte{1: fixture("raw_iso_8859_1.eml")}
+		Ω(poller.Poll()).Should(Succeed())
+		Ω(saturday.Emails()).Should(HaveLen(3))
+		Ω(poller.Checkpoint()).Should(Equal(imappoll.Checkpoint{UIDValidity: 8, LastUID: 1, Processed: 3, LastPoll: now}))
+	})
+
+	It("marks messages it can't parse as seen so they don't keep coming back", func() {
+		mailbox.messages = map[uint32][]byte{9: []byte("To: saturday-disco@sedenverultimate.net\r\nFrom: a@example.com\r\nContent-Type: image/png\r\n\r\nxyz\r\n")}
+		Ω(poller.Poll()).Should(Succeed())
+		Ω(mailbox.Seen(9)).Should(BeTrue())
+		Ω(saturday.Emails()).Should(BeEmpty())
+	})
+
+	It("stops and tries again next time when the mailbox misbehaves", func() {
+		mailbox.fetchErr = errBoom
+		Ω(poller.Poll()).Should(MatchError(errBoom))
+		Ω(poller.Checkpoint().LastUID).Should(BeZero())
+		Ω(mailbox.loggedOut).Should(Equal(1))
+
+		mailbox.fetchErr = nil
+		Ω(poller.Poll()).Should(Succeed())
+		Ω(saturday.Emails()).Should(HaveLen(2))
+
+		dialErr = errBoom
+		Ω(poller.Poll()).Should(MatchError(errBoom))
+	})
+
+	It("polls on start and then every interval", func() {
+		poller.Start()
+		DeferCleanup(poller.Stop)
+		fakeClock.Fire()
+		Eventually(saturday.Emails).Should(HaveLen(2))
+
+		mailbox.messages[6] = fixture("raw_iso_8859_1.eml")
+		fakeClock.Fire()
+		Eventually(saturday.Emails).Should(HaveLen(3))
+		Ω(poller.Checkpoint().LastPoll).Should(Equal(now.Add(time.Minute)))
+	})
+})
@@ -5,6 +5,7 @@ document.addEventListener('submit', (e) => {
         wantsSaturday: document.querySelector("#saturday").checked,
         wantsLunchtime: document.querySelector("#lunchtime").checked,
         message: document.querySelector("#message").value,
+        website: document.querySelector("#website").value,
     }
     fetch(form.action, {
         method: form.method,
@@ -14,7 +15,7 @@ document.addEventListener('submit', (e) => {
         body: JSON.stringify(data),
     }).then((res) => {
         if (!res.ok) throw new Error("Error subscribing");
-        document.querySelector("#subscribe").outerHTML = "<div class='subscribe success'>Thanks for your interest!  We'll be in touch soon.</div>";
+        document.querySelector("#subscribe").outerHTML = "<div class='subscribe success'>Thanks for your interest!  Check your inbox for a link to confirm your subscription.</div>";
     }).catch((err) => {
         document.querySelector("#subscribe").outerHTML = "<div class='subscribe fail'>Sorry, there was an error getting you subscribed. Please try again later.</div>";
     })
@@ -137,7 +137,7 @@ class LunchtimeBoss {
 
         m.request({
             method: "POST",
-            url: "/lunchtime/" + data.bossGuid,
+            url: "/lunchtime/boss",
             body: body,
         }).then((res) => {
             this.successSendMessage = "Got it, thanks! Reloading..."
@@ -149,12 +149,44 @@ class LunchtimeBoss {
         })
     }
 
+    submitQuarantine(q, confirmed) {
+        this.successQuarantineMessage = ""
+        this.failureQuarantineMessage = ""
+        m.request({
+            method: "POST",
+            url: "/lunchtime/boss",
+            body: {
+                commandType: "quarantine_reply",
+                quarantineID: q.id,
+                confirmed: confirmed,
+            },
+        }).then((res) => {
+            this.successQuarantineMessage = "Got it, thanks! Reloading..."
+            setTimeout(() => {
+                location.reload()
+            }, 1000);
+        }).catch((err) => {
+            this.failureQuarantineMessage = "Whoops, something went wrong. Only the boss can confirm or reject quarantined commands."
+        })
+    }
+
+    quarantined(q) {
+        return m(".quarantined",
+            m(".audit-entry", m("span.bold", q.description)),
+            m(".audit-entry", q.text),
+            m(".button-row",
+                m("button", { onclick: () => this.submitQuarantine(q, true) }, "Confirm"),
+                m("button.red", { onclick: () => this.submitQuarantine(q, false) }, "Reject"),
+            ),
+        )
+    }
+
     submitGames() {
         this.successSetGamesMessage = ""
         this.failureSetGamesMessage = ""
         m.request({
             method: "POST",
-            url: "/lunchtime/" + data.bossGuid,
+            url: "/lunchtime/boss",
             body: {
                 commandType: "set_games",
                 participant: this.currentParticipant,
@@ -328,6 +360,23 @@ class LunchtimeBoss {
                 onclick: () => this.submitGames(),
             }, "Submit"),
 
+            data.quarantine.length > 0 && m("h3", "🔒 Quarantined Commands"),
+            data.quarantine.length > 0 && m(".info", "These admin commands couldn't be authenticated.  Only the boss can confirm or reject them."),
+            this.successQuarantineMessage ? m(".message.success.full-width", this.successQuarantineMessage) : null,
+            this.failureQuarantineMessage ? m(".message.failure.full-width", this.failureQuarantineMessage) : null,
+            data.quarantine.length > 0 && m(".audit-log", data.quarantine.map(q => this.quarantined(q))),
+
+            data.deferred.length > 0 && m("h3", "Scheduled Actions"),
+            data.deferred.length > 0 && m(".audit-log", data.deferred.map(action => m(".audit-entry", action))),
+
+            data.blackouts.length > 0 && m("h3", "Upcoming Blackouts"),
+            data.blackouts.length > 0 && m(".audit-log", data.blackouts.map(blackout => m(".audit-entry", blackout))),
+
+            data.auditLog.length > 0 && m("h3", "Recent Admin Actions"),
+            data.auditLog.length > 0 && m(".audit-log", data.auditLog.map(entry => m(".audit-entry",
+                m("span.meta", new Date(entry.time).toLocaleString()), " ",
+                m("span.bold", entry.actor), " ", entry.action,
+            ))),
         ]
     }
 }
@@ -0,0 +1,130 @@
+import m from "mithril"
+
+let data = window.DATA
+
+class MailArchive {
+    oninit() {
+        this.query = data.query
+        this.successMessage = ""
+        this.failureMessage = ""
+        this.reparsed = {}
+    }
+
+    search(offset) {
+        let params = new URLSearchParams()
+        if (this.query) {
+            params.set("q", this.query)
+        }
+        if (offset > 0) {
+            params.set("offset", offset)
+        }
+        location.search = params.toString()
+    }
+
+    submit(body) {
+        this.successMessage = ""
+        this.failureMessage = ""
+        return m.request({
+            method: "POST",
+            url: "/mail/archive",
+            body: body,
+        }).catch((err) => {
+            this.failureMessage = err.response || "Whoops, something went wrong. Please try again later."
+            throw err
+        })
+    }
+
+    reparse(entry) {
+        this.submit({ action: "reparse", key: entry.key }).then((email) => {
+            this.reparsed[entry.key] = email
+        })
+    }
+
+    dispatch(entry, disco) {
+        if (!confirm(`Hand this e-mail to the ${disco} disco as if it had just arrived?`)) {
+            return
+        }
+        this.submit({ action: "dispatch", key: entry.key, disco: disco }).then((email) => {
+            this.successMessage = `Sent "${email.subject}" to the ${disco} disco.`
+        })
+    }
+
+    prune() {
+        if (!confirm(`Delete every e-mail stored more than ${data.retention} days ago?`)) {
+            return
+        }
+        this.submit({ action: "prune" }).then((res) => {
+            this.successMessage = `Deleted ${res.deleted} e-mail(s). Reloading...`
+            setTimeout(() => {
+                location.reload()
+            }, 1000);
+        })
+    }
+
+    email(email) {
+        return [
+            m(".meta", `${email.from} to ${email.to}`, email.cc ? `, cc ${email.cc}` : ""),
+            m(".meta", email.date, " - ", email.messageID),
+            email.isBounce && m(".meta", "Bounce: ", email.bounce || "for an unknown recipient"),
+            email.autoReply && m(".meta", "Auto-reply: ", email.autoReply),
+            m(".text", email.text),
+        ]
+    }
+
+    entry(entry) {
+        let email = this.reparsed[entry.key] || entry.email
+        return m(".participant", { class: entry.error ? "zero" : "" },
+            m(".header",
+                m(".name", email ? email.subject : entry.key),
+                m(".count", new Date(entry.storedAt).toLocaleString()),
+            ),
+            m(".relevant-email",
+                m(".meta", `${entry.key} (${entry.size} bytes)`, this.reparsed[entry.key] ? " - re-parsed" : ""),
+                entry.error && m(".meta", "Failed to parse: ", entry.error),
+                email && this.email(email),
+            ),
+            m(".button-row",
+                m("button", { onclick: () => this.reparse(entry) }, "Re-parse"),
+                data.discos.map(disco => m("button", { onclick: () => this.dispatch(entry, disco) }, `Send to ${disco}`)),
+            ),
+        )
+    }
+
+    view() {
+        let first = data.offset + 1
+        let last = data.offset + data.entries.length
+        return [
+            m("h2", "🪩 ", m("span.green", "Incoming Mail Archive")),
+            m(".info", `${data.stored} e-mail(s) stored. We keep them for ${data.retention} days.`, m("a", { href: "/mail/boss" }, " Back to stuck mail.")),
+            m("form", {
+                onsubmit: (e) => {
+                    e.preventDefault()
+                    this.search(0)
+                }
+            },
+                m("input[type=text]", {
+                    placeholder: "Search senders, recipients, subjects, and bodies",
+                    value: this.query,
+                    oninput: (e) => { this.query = e.target.value },
+                }),
+                m(".button-row",
+                    m("button[type=submit]", "Search"),
+                    m("button.red[type=button]", { onclick: () => this.prune() }, `Delete E-mail Older Than ${data.retention} Days`),
+                ),
+            ),
+            data.query && m(".info", `Searching the ${data.maxScan} most recent e-mails.`),
+            data.error && m(".message.failure.full-width", "Failed to list the archive: ", data.error),
+            this.successMessage ? m(".message.success.full-width", this.successMessage) : null,
+            this.failureMessage ? m(".message.failure.full-width", this.failureMessage) : null,
+            data.entries.length == 0 && m(".info", "No e-mail found."),
+            data.entries.length > 0 && m(".info", `Showing ${first}-${last}`),
+            m(".participants", data.entries.map(entry => this.entry(entry))),
+            m(".button-row",
+                data.offset > 0 && m("button", { onclick: () => this.search(Math.max(0, data.offset - data.pageSize)) }, "Newer"),
+                data.more && m("button", { onclick: () => this.search(data.offset + data.pageSize) }, "Older"),
+            ),
+        ]
+    }
+}
+
+m.mount(document.querySelector("#content"), MailArchive)
@@ -0,0 +1,64 @@
+import m from "mithril"
+
+let data = window.DATA
+
+class MailBoss {
+    oninit() {
+        this.successMessage = ""
+        this.failureMessage = ""
+    }
+
+    submit(action, message) {
+        this.successMessage = ""
+        this.failureMessage = ""
+        m.request({
+            method: "POST",
+            url: "/mail/boss",
+            body: { action: action, id: message.id },
+        }).then((res) => {
+            this.successMessage = "Got it, thanks! Reloading..."
+            setTimeout(() => {
+                location.reload()
+            }, 1000);
+        }).catch((err) => {
+            this.failureMessage = "Whoops, something went wrong. Please try again later."
+        })
+    }
+
+    message(message) {
+        let dead = message.status == "dead"
+        return m(".participant", { class: dead ? "zero" : "" },
+            m(".header",
+                m(".name", message.subject),
+                m(".count", dead ? "dead" : `${message.attempts}/${data.maxAttempts}`),
+            ),
+            m(".relevant-email",
+                m(".meta", `${message.from} to ${message.to}, queued ${new Date(message.enqueuedAt).toLocaleString()}`),
+                m(".meta", "Last error: ", message.lastError),
+                !dead && m(".meta", "Next attempt: ", new Date(message.nextAttempt).toLocaleString()),
+                m(".text", message.text),
+            ),
+            m(".button-row",
+                m("button", { onclick: () => this.submit("retry", message) }, "Retry Now"),
+                m("button.red", { onclick: () => this.submit("discard", message) }, "Discard"),
+            ),
+        )
+    }
+
+    view() {
+        return [
+            m("h2", "🪩 ", m("span.green", "Stuck Mail")),
+            data.messages.length == 0 && m(".info", "Nothing is stuck - every e-mail has gone out."),
+            data.messages.length > 0 && m(".info", "These e-mails failed to send.  Pending ones are retried automatically; dead ones have run out of attempts."),
+            this.successMessage ? m(".message.success.full-width", this.successMessage) : null,
+            this.failureMessage ? m(".message.failure.full-width", this.failureMessage) : null,
+            m(".participants", data.messages.map(message => this.message(message))),
+
+            m("h3", "Incoming E-mail"),
+            m(".info", data.incoming),
+            m(".info", m("a", { href: "/mail/archive" }, "Browse the incoming e-mail archive")),
+        ]
+    }
+}
+
+m.mount(document.querySelector("#content"), MailBoss)
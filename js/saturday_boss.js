@@ -0,0 +1,263 @@
+import m from "mithril"
+import { EmailAddress } from "./email.js"
+
+let data = window.DATA
+data.participants.forEach(p => {
+    p.address = EmailAddress.fromEmail(p.address)
+})
+
+const approvalCommandTypes = {
+    "requested_invite_approval": "requested_invite_approval_reply",
+    "requested_badger_approval": "requested_badger_approval_reply",
+    "requested_game_on_approval": "requested_game_on_approval_reply",
+    "requested_no_game_approval": "requested_no_game_approval_reply",
+}
+
+class SaturdayBoss {
+    oninit() {
+        this.additionalContent = ""
+        this.selectedMessage = null
+        this.delay = 1
+        this.expandedParticipant = null
+        this.setCountAddress = null
+        this.setCount = 1
+    }
+
+    get pendingApproval() {
+        return approvalCommandTypes[data.state]
+    }
+
+    get showGameOn() {
+        return data.state != "game_on_sent" && data.state != "reminder_sent"
+    }
+
+    get showNoGame() {
+        return data.state != "no_game_sent" && data.state != "no_invite_sent"
+    }
+
+    get showAbort() {
+        return data.state != "abort"
+    }
+
+    toggleSelectedMessage(message) {
+        this.selectedMessage = (this.selectedMessage == message) ? null : message
+    }
+
+    get showMessageSendForm() {
+        return !!this.selectedMessage
+    }
+
+    get showAdditionalContent() {
+        return this.selectedMessage == "Approve" || this.selectedMessage == "Deny" || this.selectedMessage == "Game On" || this.selectedMessage == "No Game"
+    }
+
+    get showDelayPicker() {
+        return this.selectedMessage == "Delay"
+    }
+
+    get setCountAddressIsValid() {
+        if (!this.setCountAddress) return false
+        return this.setCountAddress.isValid
+    }
+
+    submit(body, onSuccess, onFailure) {
+        m.request({
+            method: "POST",
+            url: "/saturday/boss",
+            body: body,
+        }).then((res) => {
+            onSuccess("Got it, thanks! Reloading...")
+            setTimeout(() => {
+                location.reload()
+            }, 1000);
+        }).catch((err) => {
+            onFailure("Whoops, something went wrong. Please try again later.")
+        })
+    }
+
+    sendMessage() {
+        this.successSendMessage = ""
+        this.failureSendMessage = ""
+        let body = {
+            additionalContent: this.additionalContent
+        }
+        if (this.selectedMessage == "Approve") {
+            body.commandType = this.pendingApproval
+            body.approved = true
+        } else if (this.selectedMessage == "Deny") {
+            body.commandType = this.pendingApproval
+            body.approved = false
+        } else if (this.selectedMessage == "Delay") {
+            body.commandType = this.pendingApproval
+            body.delay = parseInt(this.delay)
+            body.additionalContent = ""
+        } else if (this.selectedMessage == "Game On") {
+            body.commandType = "admin_game_on"
+        } else if (this.selectedMessage == "No Game") {
+            body.commandType = "admin_no_game"
+        } else if (this.selectedMessage == "Abort") {
+            body.commandType = "admin_abort"
+            body.additionalContent = ""
+        } else if (this.selectedMessage == "Reset") {
+            body.commandType = "admin_reset"
+            body.additionalContent = ""
+        }
+        this.submit(body, (msg) => this.successSendMessage = msg, (msg) => this.failureSendMessage = msg)
+    }
+
+    submitCount() {
+        this.successSetCountMessage = ""
+        this.failureSetCountMessage = ""
+        this.submit({
+            commandType: "admin_set_count",
+            emailAddress: this.setCountAddress.string,
+            count: parseInt(this.setCount),
+        }, (msg) => this.successSetCountMessage = msg, (msg) => this.failureSetCountMessage = msg)
+    }
+
+    submitQuarantine(q, approved) {
+        this.successQuarantineMessage = ""
+        this.failureQuarantineMessage = ""
+        this.submit({
+            commandType: "quarantine_reply",
+            quarantineID: q.id,
+            approved: approved,
+        }, (msg) => this.successQuarantineMessage = msg, (msg) => this.failureQuarantineMessage = msg)
+    }
+
+    quarantined(q) {
+        return m(".participant",
+            m(".header", m(".name", q.description)),
+            m(".relevant-email", m(".text", q.text)),
+            m(".button-row",
+                m("button", { onclick: () => this.submitQuarantine(q, true) }, "Confirm"),
+                m("button.red", { onclick: () => this.submitQuarantine(q, false) }, "Reject"),
+            ),
+        )
+    }
+
+    messageButton(id, label, message, klass) {
+        return m("button" + klass + "#" + id, {
+            class: this.selectedMessage && this.selectedMessage != message ? "dim" : "",
+            onclick: () => this.toggleSelectedMessage(message)
+        }, label)
+    }
+
+    participant(p) {
+        let expanded = this.expandedParticipant == p
+        return m(".participant", {
+            class: p.count == 0 ? "zero" : "",
+            onclick: () => {
+                this.expandedParticipant = expanded ? null : p
+                this.setCountAddress = p.address
+                this.setCount = p.count
+            }
+        },
+            m(".header",
+                m(".name", p.address.string),
+                m(".count", p.count),
+            ),
+            expanded && p.relevantEmails.map(e => m(".relevant-email",
+                m(".meta", `${e.from} on ${e.date}: ${e.subject}`),
+                m(".text", e.text),
+            )),
+        )
+    }
+
+    view() {
+        return [
+            m("h2", "🪩 ", m("span.green", "Saturday"), " (", data.gameDate, " at ", data.gameTime, ")"),
+            m("h3", "Current State: ", m("span.bold.green", data.state.toUpperCase())),
+            m(".info", "Next Event: ", m("span.bold", data.nextEvent)),
+            m(".info", "Forecast: ", data.forecast),
+            m(".info", "Total Count: ", m("span.bold", data.count), data.hasQuorum ? " 🎉" : " (no quorum yet)"),
+            data.awayStatus && m(".info.bold", "🏝️ ", data.awayStatus),
+
+            m("h3", "Take Action"),
+            this.pendingApproval && m(".info", "I'm waiting for your approval.  I want to..."),
+            this.pendingApproval && m(".button-row",
+                this.messageButton("approve", "Approve", "Approve", ""),
+                this.messageButton("deny", "Deny", "Deny", ".red"),
+                this.messageButton("delay", "Delay", "Delay", ".blue"),
+            ),
+            m(".info", "Or, take over and..."),
+            m(".button-row",
+                this.showGameOn && this.messageButton("game-on", "Send Game On", "Game On", ""),
+                this.showNoGame && this.messageButton("no-game", "Send No Game", "No Game", ".red"),
+                this.showAbort && this.messageButton("abort", "Abort", "Abort", ".blue"),
+                this.messageButton("reset", "Reset", "Reset", ".red"),
+            ),
+            this.showAdditionalContent && m("textarea#additional-content.full-width", {
+                placeholder: "Additional Content (optional)\nThis is sent on top of the canned message.",
+                rows: 3,
+                maxLength: 1000,
+                value: this.additionalContent,
+                onchange: (e) => {
+                    this.additionalContent = e.target.value
+                }
+            }),
+            this.showDelayPicker && m(".button-row",
+                m(".info", "Delay by"),
+                m("input#delay", {
+                    type: "number",
+                    min: 1,
+                    value: this.delay,
+                    onchange: (e) => this.delay = e.target.value,
+                }),
+                m(".info", "hours"),
+            ),
+            this.selectedMessage == "Reset" && m(".info.bold", "This drops all the data for this week.  Beware!"),
+            this.successSendMessage ? m(".message.success.full-width", this.successSendMessage) : null,
+            this.failureSendMessage ? m(".message.failure.full-width", this.failureSendMessage) : null,
+            this.showMessageSendForm && m(".button-row",
+                m("button.confirm-message", {
+                    onclick: () => this.sendMessage(),
+                }, "Confirm " + this.selectedMessage),
+            ),
+
+            m("h3", "Participants"),
+            m(".participants", data.participants.map(p => this.participant(p))),
+            m(".button-row",
+                m("input#set-count-address", {
+                    type: "text",
+                    class: (this.setCountAddress && !this.setCountAddressIsValid) ? "invalid" : "",
+                    value: this.setCountAddress ? this.setCountAddress.string : "",
+                    placeholder: "Participant: First Last <email@example.com>",
+                    onchange: (e) => this.setCountAddress = EmailAddress.fromEmail(e.target.value),
+                }),
+                m("input#set-count", {
+                    type: "number",
+                    min: 0,
+                    value: this.setCount,
+                    onchange: (e) => this.setCount = e.target.value,
+                }),
+                m("button#submit-count", {
+                    disabled: !this.setCountAddressIsValid,
+                    onclick: () => this.submitCount(),
+                }, "Set Count"),
+            ),
+            this.successSetCountMessage ? m(".message.success.full-width", this.successSetCountMessage) : null,
+            this.failureSetCountMessage ? m(".message.failure.full-width", this.failureSetCountMessage) : null,
+
+            data.quarantine.length > 0 && m("h3", "🔒 Quarantined Commands"),
+            data.quarantine.length > 0 && m(".info", "These admin commands couldn't be authenticated.  Only the boss can confirm or reject them."),
+            this.successQuarantineMessage ? m(".message.success.full-width", this.successQuarantineMessage) : null,
+            this.failureQuarantineMessage ? m(".message.failure.full-width", this.failureQuarantineMessage) : null,
+            data.quarantine.length > 0 && m(".participants", data.quarantine.map(q => this.quarantined(q))),
+
+            data.deferred.length > 0 && m("h3", "Scheduled Actions"),
+            data.deferred.length > 0 && m(".audit-log", data.deferred.map(action => m(".audit-entry", action))),
+
+            data.blackouts.length > 0 && m("h3", "Upcoming Blackouts"),
+            data.blackouts.length > 0 && m(".audit-log", data.blackouts.map(blackout => m(".audit-entry", blackout))),
+
+            data.auditLog.length > 0 && m("h3", "Recent Admin Actions"),
+            data.auditLog.length > 0 && m(".audit-log", data.auditLog.map(entry => m(".audit-entry",
+                m("span.meta", new Date(entry.time).toLocaleString()), " ",
+                m("span.bold", entry.actor), " ", entry.action,
+            ))),
+        ]
+    }
+}
+
+m.mount(document.querySelector("#content"), SaturdayBoss)
@@ -0,0 +1,69 @@
+package lunchtimedisco
+
+import (
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/calendar"
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/mail"
+)
+
+const GAME_DURATION = time.Hour
+const CALENDAR_FILENAME = "lunchtime-frisbee.ics"
+
+// gameStartTime is when the called game starts - taking the boss' adjusted time (e.g. 12:30pm) into account if we can make sense of it
+func gameStartTime(snapshot LunchtimeDiscoSnapshot) time.Time {
+	start := snapshot.T.Add(DT[snapshot.GameOnGameKey]).In(clock.Timezone)
+	adjusted := strings.ToLower(strings.ReplaceAll(snapshot.GameOnAdjustedTime, " ", ""))
+	for _, layout := range []string{"3:04pm", "3pm", "15:04"} {
+		if t, err := time.Parse(layout, adjusted); err == nil {
+			return time.Date(start.Year(), start.Month(), start.Day(), t.Hour(), t.Minute(), 0, 0, clock.Timezone)
+		}
+	}
+	return start
+}
+
+// calendarEvent describes this week's game to calendar apps.  There's one game a week, so the UID is derived from the week - that way
+// calling a different game after a no-game moves the event rather than adding another one.
+func calendarEvent(snapshot LunchtimeDiscoSnapshot, organizer mail.EmailAddress, sequence int) calendar.Event {
+	start := snapshot.CalledGameStart
+	if snapshot.GameOnGameKey != "" {
+		start = gameStartTime(snapshot)
+	}
+	return calendar.Event{
+		UID:       "lunchtime-" + snapshot.T.In(clock.Timezone).Format(config.BLACKOUT_DATE_FORMAT) + "@sedenverultimate.net",
+		Sequence:  sequence,
+		Start:     start,
+		End:       start.Add(GAME_DURATION),
+		Summary:   "Lunchtime Bible Park Frisbee",
+		Location:  "James Bible Park",
+		URL:       "https://maps.app.goo.gl/P1vm2nkZdYLGZbxb9",
+		Status:    calendar.StatusConfirmed,
+		Organizer: organizer,
+	}
+}
+
+// calendarInvite is attached to game-on e-mails (and to no-game e-mails that follow one)
+func (s *LunchtimeDisco) calendarInvite(method calendar.Method) mail.Attachment {
+	return calendar.Calendar{
+		Method: method,
+		Events: []calendar.Event{calendarEvent(s.LunchtimeDiscoSnapshot, s.config.LunchtimeDiscoEmail, s.CalendarSequence)},
+	}.Attachment(CALENDAR_FILENAME, s.alarmClock.Time())
+}
+
+// CalendarFeed lists this week's game once it's been called - or cancelled after being called - for folks who subscribe to /calendar/lunchtime.ics
+func (s *LunchtimeDisco) CalendarFeed() calendar.Calendar {
+	feed := calendar.Calendar{Name: "Lunchtime Bible Park Frisbee", Method: calendar.MethodPublish, Events: []calendar.Event{}}
+	snapshot := s.GetSnapshot()
+	if snapshot.CalendarSequence == 0 {
+		return feed
+	}
+	event := calendarEvent(snapshot, s.config.LunchtimeDiscoEmail, snapshot.CalendarSequence-1)
+	if snapshot.State != StateGameOnSent && snapshot.State != StateReminderSent {
+		event.Status = calendar.StatusCancelled
+	}
+	feed.Events = append(feed.Events, event)
+	return feed
+}
@@ -0,0 +1,36 @@
+package lunchtimedisco
+
+import (
+	"time"
+
+	"github.com/onsi/disco/commands"
+)
+
+// MissedTransition is a transition Lunchtime Disco would have made (or a scheduled action it would have run) had it been running
+type MissedTransition = commands.MissedTransition[LunchtimeDiscoState]
+
+type MissedTransitions = commands.MissedTransitions[LunchtimeDiscoState]
+
+// missedTransitions only counts the reminder: a missed morning ping just goes to the organizers, and the alarm sends it once we're up
+func (s *LunchtimeDisco) missedTransitions(now time.Time) MissedTransitions {
+	missed := MissedTransitions{}
+	if s.State == StateGameOnSent && !s.NextEvent.IsZero() && !s.NextEvent.After(now) {
+		missed = append(missed, MissedTransition{At: s.NextEvent, From: StateGameOnSent, To: StateReminderSent})
+	}
+	return missed
+}
+
+// resumeMissedTransitions is /resume: the held actions, then the reminder if it's still due
+func (s *LunchtimeDisco) resumeMissedTransitions() {
+	actionIDs := s.CatchUp.ActionIDs()
+	s.CatchUp = nil
+	for _, id := range actionIDs {
+		if action, ok := s.Deferred.Find(id); ok {
+			s.performDeferredAction(action.AlarmName())
+		}
+	}
+	if !s.NextEvent.IsZero() && !s.NextEvent.After(s.alarmClock.Time()) {
+		s.performNextEvent()
+	}
+	s.syncAlarms()
+}
@@ -6,12 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"text/template"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/onsi/disco/audit"
+	"github.com/onsi/disco/calendar"
 	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/commands"
 	"github.com/onsi/disco/config"
 	"github.com/onsi/disco/mail"
 	"github.com/onsi/disco/s3db"
@@ -36,6 +41,7 @@ const day = 24 * time.Hour
 const RETRY_DELAY = 5 * time.Minute
 
 const KEY = "lunchtime-disco"
+const NEXT_EVENT_ALARM = "next-event"
 const PARTICIPANTS_KEY = "lunchtime-participants"
 
 type LunchtimeDiscoState string
@@ -58,13 +64,28 @@ type CommandType string
 const (
 	CommandCaptureThreadEmail CommandType = "capture_thread_email"
 
-	CommandAdminBadger   CommandType = "admin_badger"
-	CommandAdminGameOn   CommandType = "admin_game_on"
-	CommandAdminNoGame   CommandType = "admin_no_game"
-	CommandAdminInvite   CommandType = "admin_invite"
-	CommandAdminNoInvite CommandType = "admin_no_invite"
+	CommandAdminBadger     CommandType = "admin_badger"
+	CommandAdminGameOn     CommandType = "admin_game_on"
+	CommandAdminNoGame     CommandType = "admin_no_game"
+	CommandAdminInvite     CommandType = "admin_invite"
+	CommandAdminNoInvite   CommandType = "admin_no_invite"
+	CommandAdminStatus     CommandType = "admin_status"
+	CommandAdminHelp       CommandType = "admin_help"
+	CommandAdminInvalid    CommandType = "admin_invalid"
+	CommandAdminBatch      CommandType = "admin_batch"
+	CommandAdminSchedule   CommandType = "admin_schedule"
+	CommandAdminCancel     CommandType = "admin_cancel"
+	CommandAdminResume     CommandType = "admin_resume"
+	CommandAdminSkip       CommandType = "admin_skip"
+	CommandAdminBlackout   CommandType = "admin_blackout"
+	CommandAdminUnblackout CommandType = "admin_unblackout"
+	CommandAdminQuarantine CommandType = "admin_quarantine"
+
+	CommandQuarantineReply CommandType = "quarantine_reply"
 
 	CommandSetGames CommandType = "set_games"
+
+	CommandBounce CommandType = "bounce"
 )
 
 type Command struct {
@@ -78,25 +99,57 @@ type Command struct {
 	GameOnGameKey      string `json:"gameOnGameKey"`
 	GameOnAdjustedTime string `json:"gameOnAdjustedTime"`
 
+	// the organizer who issued the command from the dashboard or by e-mail
+	Actor mail.EmailAddress `json:"-"`
+	// commands sent together in one e-mail, one per line
+	Batch []Command `json:"-"`
+	// set by --dry-run: reply with what would happen instead of doing it
+	DryRun bool `json:"-"`
+	// for /schedule and /cancel
+	Deferred   commands.DeferredAction `json:"-"`
+	DeferredID int                     `json:"-"`
+	// for /blackout and /unblackout
+	Blackout   config.Blackout `json:"-"`
+	BlackoutID int             `json:"-"`
+	// for the boss' reply to a quarantine confirmation request - or their click on the dashboard
+	QuarantineID int  `json:"quarantineID"`
+	Confirmed    bool `json:"confirmed"`
+
 	Email mail.Email
 	Error error
 }
 
 type LunchtimeDiscoSnapshot struct {
-	GUID               string                `json:"guid"`
-	BossGUID           string                `json:"boss_guid"`
-	ThreadEmail        mail.Email            `json:"thread_email"`
-	State              LunchtimeDiscoState   `json:"state"`
-	Participants       LunchtimeParticipants `json:"participants"`
-	NextEvent          time.Time             `json:"next_event"`
-	T                  time.Time             `json:"reference_time"`
-	GameOnGameKey      string                `json:"game_on_game_key"`
-	GameOnAdjustedTime string                `json:"game_on_adjusted_time"`
+	GUID               string                   `json:"guid"`
+	ThreadEmail        mail.Email               `json:"thread_email"`
+	State              LunchtimeDiscoState      `json:"state"`
+	Participants       LunchtimeParticipants    `json:"participants"`
+	NextEvent          time.Time                `json:"next_event"`
+	T                  time.Time                `json:"reference_time"`
+	GameOnGameKey      string                   `json:"game_on_game_key"`
+	GameOnAdjustedTime string                   `json:"game_on_adjusted_time"`
+	AuditLog           audit.Log                `json:"audit_log"`
+	Deferred           commands.DeferredActions `json:"deferred"`
+	// so an e-mail that reaches us twice (e.g. re-dispatched from the archive) doesn't count twice
+	ProcessedEmailIDs commands.ProcessedEmailIDs `json:"processed_email_ids,omitempty"`
+	// see commands.MissedTransitions
+	CatchUp MissedTransitions `json:"catch_up,omitempty"`
+	// blackouts added by e-mail - the rest come from the calendar file in config
+	Blackouts config.Blackouts `json:"blackouts"`
+	// how many calendar updates (invites and cancellations) we've sent for this week's game
+	CalendarSequence int `json:"calendar_sequence,omitempty"`
+	// the sequence for mail.TransitionKey - it survives resets
+	SentEmails int `json:"sent_emails,omitempty"`
+	// when the game we sent a calendar invite for starts - so we can still cancel it after no-game clears GameOnGameKey
+	CalledGameStart time.Time `json:"called_game_start,omitempty"`
+	// unauthenticated admin commands waiting for the boss to confirm them
+	Quarantine commands.Quarantine `json:"quarantine,omitempty"`
+	// addresses our e-mail has bounced for - unlike the participants, these carry over from week to week
+	Undeliverable commands.Undeliverable `json:"undeliverable,omitempty"`
 }
 
 func (s LunchtimeDiscoSnapshot) dup() LunchtimeDiscoSnapshot {
 	return LunchtimeDiscoSnapshot{
-		BossGUID:           s.BossGUID,
 		GUID:               s.GUID,
 		ThreadEmail:        s.ThreadEmail.Dup(),
 		State:              s.State,
@@ -105,6 +158,16 @@ func (s LunchtimeDiscoSnapshot) dup() LunchtimeDiscoSnapshot {
 		T:                  s.T,
 		GameOnGameKey:      s.GameOnGameKey,
 		GameOnAdjustedTime: s.GameOnAdjustedTime,
+		AuditLog:           s.AuditLog.Dup(),
+		Deferred:           s.Deferred.Dup(),
+		ProcessedEmailIDs:  s.ProcessedEmailIDs.Dup(),
+		CatchUp:            s.CatchUp.Dup(),
+		Blackouts:          s.Blackouts.Dup(),
+		CalendarSequence:   s.CalendarSequence,
+		CalledGameStart:    s.CalledGameStart,
+		SentEmails:         s.SentEmails,
+		Quarantine:         s.Quarantine.Dup(),
+		Undeliverable:      s.Undeliverable.Dup(),
 	}
 }
 
@@ -114,6 +177,7 @@ type LunchtimeDisco struct {
 	w                      io.Writer
 
 	alarmClock clock.AlarmClockInt
+	alarms     *clock.Alarms
 	outbox     mail.OutboxInt
 	forecaster weather.ForecasterInt
 	db         s3db.S3DBInt
@@ -123,6 +187,9 @@ type LunchtimeDisco struct {
 	config     config.Config
 	ctx        context.Context
 	cancel     func()
+
+	// out-of-office and other autoresponders we've dropped since we started - just for the logs
+	droppedAutoReplies atomic.Int64
 }
 
 type TemplateData struct {
@@ -131,16 +198,23 @@ type TemplateData struct {
 	NextEvent              string
 
 	GUID               string
-	BossGUID           string
 	WeekOf             string
 	Games              Games
 	GameOnGame         Game
 	GameOnAdjustedTime string
 	GameOff            bool
+	// set when next week is blacked out and the blackout should be announced
+	UpcomingBlackout  config.Blackout
+	UpcomingBlackouts config.Blackouts
 
 	Message string
 	Comment string
 	Error   error
+	// the e-mail we're asking the boss to confirm
+	Quarantined commands.QuarantinedEmail
+	// the bounce we're telling the boss about
+	Bounce        mail.Bounce
+	EmailDebugKey string
 }
 
 func (e TemplateData) GameOnGameFullStartTime() string {
@@ -183,12 +257,23 @@ func (e TemplateData) WithError(err error) TemplateData {
 	return e
 }
 
+func (e TemplateData) WithQuarantined(quarantined commands.QuarantinedEmail) TemplateData {
+	e.Quarantined = quarantined
+	return e
+}
+
+func (e TemplateData) WithBounce(bounce mail.Bounce, debugKey string) TemplateData {
+	e.Bounce = bounce
+	e.EmailDebugKey = debugKey
+	return e
+}
+
 func (e TemplateData) PickerURL() string {
 	return fmt.Sprintf("https://www.sedenverultimate.net/lunchtime/%s", e.GUID)
 }
 
 func (e TemplateData) BossURL() string {
-	return fmt.Sprintf("https://www.sedenverultimate.net/lunchtime/%s", e.BossGUID)
+	return "https://www.sedenverultimate.net/lunchtime/boss"
 }
 
 func (e TemplateData) JSONForPlayer() string {
@@ -225,9 +310,27 @@ func (e TemplateData) JSONForBoss() string {
 		})
 	}
 
+	deferred := []string{}
+	for _, action := range e.Deferred {
+		deferred = append(deferred, action.String())
+	}
+
+	blackouts := []string{}
+	for _, blackout := range e.UpcomingBlackouts {
+		blackouts = append(blackouts, blackout.String())
+	}
+
+	quarantine := []map[string]any{}
+	for _, quarantined := range e.Quarantine {
+		quarantine = append(quarantine, map[string]any{
+			"id":          quarantined.ID,
+			"description": quarantined.String(),
+			"text":        quarantined.Email.Text,
+		})
+	}
+
 	out, _ := json.Marshal(map[string]any{
 		"state":                   e.State,
-		"bossGuid":                e.BossGUID,
 		"weekOf":                  e.WeekOf,
 		"historicalParticipants":  e.HistoricalParticipants,
 		"participants":            e.Participants,
@@ -235,6 +338,10 @@ func (e TemplateData) JSONForBoss() string {
 		"gameOnGameKey":           e.GameOnGameKey,
 		"gameOnAdjustedTime":      e.GameOnAdjustedTime,
 		"gameOnGameFullStartTime": e.GameOnGameFullStartTime(),
+		"auditLog":                e.AuditLog.Recent(20),
+		"deferred":                deferred,
+		"blackouts":               blackouts,
+		"quarantine":              quarantine,
 	})
 	return string(out)
 }
@@ -242,6 +349,7 @@ func (e TemplateData) JSONForBoss() string {
 func NewLunchtimeDisco(config config.Config, w io.Writer, alarmClock clock.AlarmClockInt, outbox mail.OutboxInt, forecaster weather.ForecasterInt, db s3db.S3DBInt) (*LunchtimeDisco, error) {
 	lunchtimeDisco := &LunchtimeDisco{
 		alarmClock: alarmClock,
+		alarms:     clock.NewAlarms(alarmClock),
 		outbox:     outbox,
 		forecaster: forecaster,
 		db:         db,
@@ -276,12 +384,20 @@ func NewLunchtimeDisco(config config.Config, w io.Writer, alarmClock clock.Alarm
 			if nextSaturday.After(snapshot.T) {
 				startupMessage = "Backup is from a previous week.  Resetting."
 				lunchtimeDisco.logi(0, "{{red}}%s{{/}}", startupMessage)
+				lunchtimeDisco.AuditLog = snapshot.AuditLog
+				lunchtimeDisco.Deferred = snapshot.Deferred
+				lunchtimeDisco.Blackouts = snapshot.Blackouts
+				lunchtimeDisco.Quarantine = snapshot.Quarantine
+				lunchtimeDisco.SentEmails = snapshot.SentEmails
+				lunchtimeDisco.Undeliverable = snapshot.Undeliverable
+				lunchtimeDisco.pruneBlackouts()
 				lunchtimeDisco.reset()
+				lunchtimeDisco.syncAlarms()
 			} else {
 				startupMessage = "Backup is good.  Spinning up..."
 				lunchtimeDisco.logi(0, "{{green}}%s{{/}}", startupMessage)
 				lunchtimeDisco.LunchtimeDiscoSnapshot = snapshot
-				alarmClock.SetAlarm(snapshot.NextEvent)
+				lunchtimeDisco.syncAlarms()
 			}
 		}
 	}
@@ -308,13 +424,27 @@ func NewLunchtimeDisco(config config.Config, w io.Writer, alarmClock clock.Alarm
 	}
 
 	if err != nil {
-		outbox.SendEmail(lunchtimeDisco.emailForBoss("startup_error", TemplateData{
+		outbox.SendEmail(lunchtimeDisco.emailForOrganizers("startup_error", TemplateData{
 			Error: fmt.Errorf(startupMessage + "\n" + participantsMessage),
 		}))
 		return nil, err
 	}
 
-	outbox.SendEmail(lunchtimeDisco.emailForBoss("startup", lunchtimeDisco.emailData().WithMessage(startupMessage)))
+	if lastBackup != nil {
+		// rather than send a stale reminder, ask the boss
+		now := lunchtimeDisco.alarmClock.Time()
+		if missed := commands.CatchUp(lunchtimeDisco.missedTransitions(now), lunchtimeDisco.Deferred, now); len(missed) > 0 {
+			lunchtimeDisco.logi(0, "{{red}}I missed %d event(s) while I was down.  Pausing until the boss tells me how to proceed.{{/}}", len(missed))
+			startupMessage += fmt.Sprintf("\nI missed %d event(s) while I was down.  I've paused until you tell me how to proceed.", len(missed))
+			lunchtimeDisco.CatchUp = missed
+			lunchtimeDisco.syncAlarms()
+		}
+	}
+
+	outbox.SendEmail(lunchtimeDisco.emailForOrganizers("startup", lunchtimeDisco.emailData().WithMessage(startupMessage)))
+	if len(lunchtimeDisco.CatchUp) > 0 {
+		outbox.SendEmail(lunchtimeDisco.emailForOrganizers("catch_up", lunchtimeDisco.emailData()))
+	}
 
 	go lunchtimeDisco.dance()
 	return lunchtimeDisco, nil
@@ -341,11 +471,33 @@ func (s *LunchtimeDisco) HandleParticipant(participant LunchtimeParticipant) {
 	}()
 }
 
-// command from the Boss
-func (s *LunchtimeDisco) HandleCommand(command Command) {
+// command from an organizer's dashboard
+func (s *LunchtimeDisco) HandleCommand(actor mail.EmailAddress, command Command) error {
+	command.Actor = actor
+	if command.CommandType == CommandQuarantineReply {
+		// the dashboard is the one place the boss can confirm a quarantined command when e-mail can't be authenticated (e.g. over SMTP or IMAP)
+		if !actor.Equals(s.config.BossEmail) {
+			return fmt.Errorf("only the boss can confirm quarantined commands")
+		}
+		line := "/reject"
+		if command.Confirmed {
+			line = "/confirm"
+		}
+		// so the acknowledgement has someone to reply to
+		command.Email = mail.E().
+			WithFrom(actor).
+			WithTo(s.config.LunchtimeDiscoEmail).
+			WithSubject("Lunchtime Disco Dashboard").
+			WithBody(line)
+	}
 	go func() {
 		s.commandC <- command
 	}()
+	return nil
+}
+
+func (s *LunchtimeDisco) DroppedAutoReplies() int {
+	return int(s.droppedAutoReplies.Load())
 }
 
 func (s *LunchtimeDisco) GetSnapshot() LunchtimeDiscoSnapshot {
@@ -378,7 +530,6 @@ func (s *LunchtimeDisco) emailData() TemplateData {
 	}
 	return TemplateData{
 		GUID:                   s.GUID,
-		BossGUID:               s.BossGUID,
 		WeekOf:                 s.T.Add(-day * 5).Format("1/2"),
 		LunchtimeDiscoSnapshot: s.LunchtimeDiscoSnapshot,
 		Games:                  games,
@@ -386,6 +537,8 @@ func (s *LunchtimeDisco) emailData() TemplateData {
 		GameOnAdjustedTime:     s.GameOnAdjustedTime,
 		HistoricalParticipants: s.HistoricalParticipants,
 		GameOff:                s.State == StateNoInviteSent || s.State == StateNoGameSent,
+		UpcomingBlackout:       s.announcedBlackout(),
+		UpcomingBlackouts:      s.blackouts().Upcoming(config.DiscoLunchtime, blackoutDate(s.alarmClock.Time())),
 	}.WithNextEvent(s.NextEvent)
 }
 
@@ -401,16 +554,33 @@ func (s *LunchtimeDisco) emailBody(name string, data TemplateData) string {
 	return b.String()
 }
 
-func (s *LunchtimeDisco) emailForBoss(name string, data TemplateData) mail.Email {
+// organizers returns everyone who can run the lunchtime game
+func (s *LunchtimeDisco) organizers() config.Organizers {
+	return s.config.AllOrganizers().Managers(config.DiscoLunchtime)
+}
+
+func (s *LunchtimeDisco) emailForOrganizers(name string, data TemplateData) mail.Email {
 	e := mail.E().
 		WithFrom(s.config.LunchtimeDiscoEmail).
-		WithTo(s.config.BossEmail).
+		WithTo(s.organizers().Addresses()...).
 		WithSubject(s.emailSubject(name, data)).
 		WithBody(mail.Markdown(s.emailBody(name, data)))
 	return e
 }
 
 func (s *LunchtimeDisco) emailForList(name string, data TemplateData) mail.Email {
+	email := s.threadedEmailForList(name, data)
+	switch {
+	case name == "game_on":
+		email = email.WithAttachment(s.calendarInvite(calendar.MethodRequest))
+	case name == "no_game" && s.CalendarSequence > 0:
+		// folks may have already added the game to their calendars
+		email = email.WithAttachment(s.calendarInvite(calendar.MethodCancel))
+	}
+	return email
+}
+
+func (s *LunchtimeDisco) threadedEmailForList(name string, data TemplateData) mail.Email {
 	if s.ThreadEmail.MessageID == "" {
 		return mail.E().
 			WithFrom(s.config.BossEmail).
@@ -429,7 +599,7 @@ func (s *LunchtimeDisco) emailForList(name string, data TemplateData) mail.Email
 		} else {
 			email.Subject = "Re: " + s.ThreadEmail.Subject
 		}
-		email.InReplyTo = s.ThreadEmail.MessageID
+		email = email.InThreadOf(s.ThreadEmail)
 		return email
 	}
 }
@@ -443,7 +613,14 @@ func (s *LunchtimeDisco) dance() {
 			return
 		case <-s.alarmClock.C():
 			s.log("{{yellow}}alarm clock triggered{{/}}")
-			s.performNextEvent()
+			for _, name := range s.alarms.Due() {
+				if name != NEXT_EVENT_ALARM {
+					s.performDeferredAction(name)
+				} else if s.alarms.Get(NEXT_EVENT_ALARM).IsZero() {
+					// (a deferred action that ran first may have already moved us along and rescheduled the next event)
+					s.performNextEvent()
+				}
+			}
 			s.backup()
 		case command := <-s.commandC:
 			s.log("{{yellow}}received a command{{/}}")
@@ -490,20 +667,172 @@ func (s *LunchtimeDisco) storeHistoricalParticipants() {
 	s.log("{{green}}stored historical participants{{/}}")
 }
 
+var adminCommands = commands.NewParser(
+	commands.Spec{Name: "status", Description: "reply with the current status and the list of games"},
+	commands.Spec{Name: "invite", TakesBody: true, Description: "send the invite - anything below this line is included in the e-mail"},
+	commands.Spec{Name: "no-invite", TakesBody: true, Description: "send the no-invite e-mail - anything below this line is included in the e-mail"},
+	commands.Spec{Name: "badger", TakesBody: true, Description: "send the badger e-mail - anything below this line is included in the e-mail"},
+	commands.Spec{Name: "game-on", Args: "<game> [time]", MinArgs: 1, MaxArgs: -1, TakesBody: true, Description: "call the game (A-P, see /status), optionally at an adjusted time like 12:30pm - anything below this line is included in the e-mail"},
+	commands.Spec{Name: "no-game", TakesBody: true, Description: "send the no-game e-mail - anything below this line is included in the e-mail"},
+	commands.Spec{Name: "schedule", Args: "<when> /<command>", MinArgs: 2, MaxArgs: -1, TakesBody: true, Description: "run a command later, e.g. /schedule thursday noon /badger - anything below this line goes along with the command"},
+	commands.Spec{Name: "cancel", Args: "<id>", MinArgs: 1, MaxArgs: 1, Description: "cancel a scheduled command (/status lists them)"},
+	commands.Spec{Name: "blackout", Args: "<start> [end] [silent] [announce] [reason]", MinArgs: 1, MaxArgs: -1, Description: "no games from <start> to [end] (dates are M/D, inclusive) - weeks that are entirely blacked out get the no-invite e-mail with the reason, or nothing at all if silent.  announce mentions it the week before"},
+	commands.Spec{Name: "unblackout", Args: "<id>", MinArgs: 1, MaxArgs: 1, Description: "remove a blackout added by e-mail (/status lists them)"},
+	commands.Spec{Name: "resume", Description: "after downtime: pick up where I left off, running the event I missed right away"},
+	commands.Spec{Name: "skip", Description: "after downtime: move on without sending anything I missed"},
+)
+
 func (s *LunchtimeDisco) processEmail(email mail.Email) {
 	s.logi(0, "{{yellow}}Processing Email:{{/}}")
-	if email.From.Equals(s.config.BossEmail) && email.IncludesRecipient(s.config.LunchtimeDiscoList) {
+	if email.IsBounce() {
+		// bounces are auto-submitted too, so we have to catch them before we drop auto-replies - but we only believe the ones about
+		// e-mail we actually sent
+		if !mail.IsMessageIDFor(email.Bounce.OriginalMessageID, "lunchtime", s.config.LunchtimeDiscoEmail) {
+			s.logi(1, "{{red}}Ignoring a bounce for an e-mail I didn't send (%q){{/}}", email.Bounce.OriginalMessageID)
+			return
+		}
+		s.logi(1, "{{red}}This is a bounce{{/}}")
+		s.commandC <- Command{CommandType: CommandBounce, Email: email}
+		return
+	}
+	if email.IsAutoReply() {
+		s.logi(1, "{{gray}}Dropping an auto-reply (%s) - that's %d since I started{{/}}", email.AutoReply, s.droppedAutoReplies.Add(1))
+		return
+	}
+	isOrganizer := s.organizers().Includes(email.From)
+	isAdminCommand := s.organizers().IsAdminEmail(email, s.config.LunchtimeDiscoEmail)
+	quarantineID, isQuarantineReply := commands.ParseQuarantineReply(email.Subject)
+	isQuarantineReply = isAdminCommand && isQuarantineReply
+	if isOrganizer && email.IncludesRecipient(s.config.LunchtimeDiscoList) {
 		s.logi(1, "{{green}}This is a list email - harvesting the thread id{{/}}")
 		s.commandC <- Command{
 			CommandType: CommandCaptureThreadEmail,
 			Email:       email,
 		}
+	} else if isAdminCommand && !s.isAuthenticated(email) {
+		if isQuarantineReply {
+			// confirming a quarantined command with another unauthenticated e-mail would defeat the point - the boss can use the dashboard
+			s.logi(1, "{{red}}Ignoring an unauthenticated reply to a quarantine confirmation request: %s{{/}}", email.Authentication)
+			return
+		}
+		s.logi(1, "{{red}}This admin command failed authentication (%s) - quarantining it{{/}}", email.Authentication)
+		s.commandC <- Command{CommandType: CommandAdminQuarantine, Actor: email.From, Email: email}
+	} else if isQuarantineReply {
+		s.logi(1, "{{green}}This is a reply to a quarantine confirmation request{{/}}")
+		s.commandC <- s.quarantineReplyCommand(email, quarantineID)
+	} else if isAdminCommand {
+		s.logi(1, "{{green}}This is an admin command{{/}}")
+		c := s.adminCommand(email)
+		if c.Error != nil {
+			s.logi(1, "{{red}}unable to extract command from email: %s{{/}}", c.Error.Error())
+		}
+		s.commandC <- c
 	} else {
 		s.logi(1, "{{yellow}}Nothing to see here... move along.{{/}}")
 	}
 }
 
+// isAuthenticated is true if the e-mail passed DKIM/SPF alignment for its sender - or if we've been told not to care
+func (s *LunchtimeDisco) isAuthenticated(email mail.Email) bool {
+	return !s.config.RequireAuthenticatedAdmins || email.Authentication.AlignedWith(email.From)
+}
+
+// quarantineReplyCommand turns the boss' reply to a quarantine confirmation request into a command
+func (s *LunchtimeDisco) quarantineReplyCommand(email mail.Email, id int) Command {
+	if !email.From.Equals(s.config.BossEmail) {
+		return Command{CommandType: CommandAdminInvalid, Actor: email.From, Email: email, Error: fmt.Errorf("only the boss can confirm quarantined commands")}
+	}
+	invocations, err := commands.QuarantineReplyCommands.Parse(email.Text)
+	if err != nil {
+		return Command{CommandType: CommandAdminInvalid, Actor: email.From, Email: email, Error: err}
+	}
+	c := Command{CommandType: CommandQuarantineReply, Actor: email.From, Email: email, QuarantineID: id}
+	switch invocations[0].Name {
+	case "help":
+		c.CommandType = CommandAdminHelp
+		c.AdditionalContent = commands.QuarantineReplyCommands.Help()
+	case "confirm":
+		c.Confirmed = true
+	}
+	return c
+}
+
+// adminCommand turns an organizer's e-mail into the same commands the dashboard sends - or a batch of them if there's more than one line of commands
+func (s *LunchtimeDisco) adminCommand(email mail.Email) Command {
+	invocations, err := adminCommands.Parse(email.Text)
+	if err != nil {
+		return Command{CommandType: CommandAdminInvalid, Actor: email.From, Email: email, Error: err}
+	}
+	batch := Command{CommandType: CommandAdminBatch, Actor: email.From, Email: email}
+	for _, invocation := range invocations {
+		c := Command{Actor: email.From, Email: email, DryRun: invocation.DryRun, AdditionalContent: invocation.Body}
+		switch invocation.Name {
+		case "help":
+			c.CommandType = CommandAdminHelp
+			c.AdditionalContent = adminCommands.Help()
+		case "status":
+			c.CommandType = CommandAdminStatus
+		case "invite":
+			c.CommandType = CommandAdminInvite
+		case "blackout":
+			c.CommandType = CommandAdminBlackout
+			c.Blackout, c.Error = commands.ParseBlackout(invocation.Args, config.DiscoLunchtime, email.From, s.alarmClock.Time())
+		case "unblackout":
+			c.CommandType = CommandAdminUnblackout
+			c.BlackoutID, err = strconv.Atoi(strings.TrimPrefix(invocation.Args[0], "#"))
+			if err != nil {
+				c.Error = fmt.Errorf("invalid id for /unblackout command: %s", invocation.Args[0])
+			}
+		case "resume":
+			c.CommandType = CommandAdminResume
+		case "skip":
+			c.CommandType = CommandAdminSkip
+		case "no-invite":
+			c.CommandType = CommandAdminNoInvite
+		case "badger":
+			c.CommandType = CommandAdminBadger
+		case "game-on":
+			c.CommandType = CommandAdminGameOn
+			c.GameOnGameKey = strings.ToUpper(invocation.Args[0])
+			c.GameOnAdjustedTime = strings.Join(invocation.Args[1:], " ")
+			if !ValidGameKeys[c.GameOnGameKey] {
+				c.Error = fmt.Errorf("invalid game for /game-on: %s - must be one of %s", invocation.Args[0], strings.Join(GameKeys, ", "))
+			}
+		case "no-game":
+			c.CommandType = CommandAdminNoGame
+		case "schedule":
+			c.CommandType = CommandAdminSchedule
+			c.Deferred, c.Error = commands.ParseSchedule(adminCommands, invocation.Args, invocation.Body, email.From, s.alarmClock.Time())
+			if c.Error == nil && c.Deferred.Condition != commands.ConditionNone {
+				c.Error = fmt.Errorf("invalid /schedule - Lunchtime Disco doesn't track quorum, so %s isn't supported", c.Deferred.Condition)
+			}
+		case "cancel":
+			c.CommandType = CommandAdminCancel
+			c.DeferredID, err = strconv.Atoi(strings.TrimPrefix(invocation.Args[0], "#"))
+			if err != nil {
+				c.Error = fmt.Errorf("invalid id for /cancel command: %s", invocation.Args[0])
+			}
+		}
+		if c.Error != nil {
+			// don't run half a batch
+			return Command{CommandType: CommandAdminInvalid, Actor: email.From, Email: email, Error: c.Error}
+		}
+		batch.DryRun = batch.DryRun || c.DryRun
+		batch.Batch = append(batch.Batch, c)
+	}
+	if len(batch.Batch) == 1 {
+		return batch.Batch[0]
+	}
+	return batch
+}
+
 func (s *LunchtimeDisco) transitionTo(state LunchtimeDiscoState) {
+	if state == StatePending {
+		if blackout, ok := s.blackout(); ok && blackout.Silent {
+			s.logi(1, "{{yellow}}this week is blacked out (%s) - skipping it quietly{{/}}", blackout)
+			state = StateNoInviteSent
+		}
+	}
 	switch state {
 	case StatePending, StateInviteSent:
 		if s.NextEvent.IsZero() {
@@ -516,9 +845,21 @@ func (s *LunchtimeDisco) transitionTo(state LunchtimeDiscoState) {
 	case StateNoInviteSent, StateNoGameSent, StateReminderSent:
 		s.NextEvent = s.T.Add(2 * time.Hour) //Saturday, 12pm is when we reset
 	}
+	if state == StateGameOnSent || (state == StateNoGameSent && s.CalendarSequence > 0) {
+		// we've just sent a calendar invite or cancellation
+		s.CalendarSequence += 1
+	}
+	if state == StateGameOnSent {
+		s.CalledGameStart = gameStartTime(s.LunchtimeDiscoSnapshot)
+	}
 	s.State = state
+	if len(s.CatchUp) > 0 {
+		// moving again ends the catch-up
+		s.CatchUp = nil
+		s.syncAlarms()
+	}
 	if !s.NextEvent.IsZero() {
-		s.alarmClock.SetAlarm(s.NextEvent)
+		s.alarms.Set(NEXT_EVENT_ALARM, s.NextEvent)
 	}
 }
 
@@ -526,8 +867,14 @@ func (s *LunchtimeDisco) performNextEvent() {
 	data := s.emailData()
 	switch s.State {
 	case StatePending, StateInviteSent:
+		if blackout, ok := s.blackout(); ok && s.State == StatePending {
+			s.logi(1, "{{yellow}}this week is blacked out (%s) - sending the no-invitation e-mail{{/}}", blackout)
+			s.sendEmail(s.emailForList("no_invitation", data.WithMessage("%s", blackout.Reason)),
+				StateNoInviteSent, s.retryNextEventErrorHandler)
+			return
+		}
 		s.logi(1, "{{coral}}sending boss the morning ping{{/}}")
-		s.sendEmail(s.emailForBoss("monitor", data), s.State, s.retryNextEventErrorHandler)
+		s.sendEmail(s.emailForOrganizers("monitor", data), s.State, s.retryNextEventErrorHandler)
 	case StateGameOnSent:
 		s.sendEmail(s.emailForList("reminder", data), StateReminderSent, s.retryNextEventErrorHandler)
 	case StateNoInviteSent, StateNoGameSent, StateReminderSent:
@@ -536,7 +883,57 @@ func (s *LunchtimeDisco) performNextEvent() {
 }
 
 func (s *LunchtimeDisco) handleCommand(command Command) {
+	// dashboard commands don't come with a message id
+	if command.Email.MessageID != "" {
+		if s.ProcessedEmailIDs.Contains(command.Email.MessageID) {
+			s.logi(1, "{{coral}}I've already processed this email (id: %s).  Ignoring.{{/}}", command.Email.MessageID)
+			return
+		}
+		defer func() {
+			s.ProcessedEmailIDs = append(s.ProcessedEmailIDs, command.Email.MessageID)
+		}()
+	}
+	if command.Email.From != "" && !command.Email.IsBounce() {
+		var heardFrom bool
+		if s.Undeliverable, heardFrom = s.Undeliverable.Remove(command.Email.From); heardFrom {
+			s.logi(1, "{{green}}heard from %s - I'll start sending to them again{{/}}", command.Email.From)
+		}
+	}
+	s.performCommand(command)
+}
+
+// performCommand runs the command - or, for a dry run, reports what it would have done
+func (s *LunchtimeDisco) performCommand(command Command) {
+	if command.DryRun {
+		s.dryRun(command)
+		return
+	}
+	s.dispatchCommand(command)
+}
+
+// dryRun runs the command against a scratch outbox, then puts everything back and tells the organizer what would have happened
+func (s *LunchtimeDisco) dryRun(command Command) {
+	s.logi(1, "{{yellow}}dry run - nothing will actually change{{/}}")
+	snapshot := s.LunchtimeDiscoSnapshot.dup()
+	outbox := s.outbox
+	preview := mail.NewFakeOutbox()
+	s.outbox = preview
+
+	s.dispatchCommand(command)
+	finalState := s.State
+
+	s.outbox = outbox
+	s.LunchtimeDiscoSnapshot = snapshot
+	s.syncAlarms()
+
+	s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+		s.emailBody("dry_run", s.emailData().WithMessage(commands.DryRunSummary(snapshot.State, finalState, preview.Emails())))))
+}
+
+func (s *LunchtimeDisco) dispatchCommand(command Command) {
 	switch command.CommandType {
+	case CommandBounce:
+		s.handleBounce(command)
 	case CommandCaptureThreadEmail:
 		if s.ThreadEmail.IsZero() {
 			s.logi(1, "{{green}}capturing thread email{{/}}")
@@ -544,10 +941,12 @@ func (s *LunchtimeDisco) handleCommand(command Command) {
 		}
 	case CommandAdminBadger:
 		s.logi(1, "{{red}}boss has asked me to badger{{/}}")
+		s.recordAdminAction(command.Actor, "sent the badger")
 		s.sendEmailWithNoTransition((s.emailForList("badger",
 			s.emailData().WithMessage(command.AdditionalContent))))
 	case CommandAdminGameOn:
 		s.logi(1, "{{green}}boss has asked me to send game-on{{/}}")
+		s.recordAdminAction(command.Actor, "sent game on")
 		s.GameOnGameKey = command.GameOnGameKey
 		s.GameOnAdjustedTime = command.GameOnAdjustedTime
 		s.sendEmail(s.emailForList("game_on",
@@ -555,6 +954,7 @@ func (s *LunchtimeDisco) handleCommand(command Command) {
 			StateGameOnSent, s.replyWithFailureErrorHandler)
 	case CommandAdminNoGame:
 		s.logi(1, "{{red}}boss has asked me to send no-game{{/}}")
+		s.recordAdminAction(command.Actor, "sent no game")
 		s.GameOnGameKey = ""
 		s.GameOnAdjustedTime = ""
 		s.sendEmail(s.emailForList("no_game",
@@ -562,45 +962,232 @@ func (s *LunchtimeDisco) handleCommand(command Command) {
 			StateNoGameSent, s.replyWithFailureErrorHandler)
 	case CommandAdminInvite:
 		s.logi(1, "{{green}}boss has asked me to send the invite out{{/}}")
+		s.recordAdminAction(command.Actor, "sent the invite")
 		s.sendEmail(s.emailForList("invitation",
 			s.emailData().WithMessage(command.AdditionalContent)),
 			StateInviteSent, s.replyWithFailureErrorHandler)
 	case CommandAdminNoInvite:
 		s.logi(1, "{{red}}boss has asked me to send the no-invite email{{/}}")
+		s.recordAdminAction(command.Actor, "sent no invite")
 		s.sendEmail(s.emailForList("no_invitation",
 			s.emailData().WithMessage(command.AdditionalContent)),
 			StateNoInviteSent, s.replyWithFailureErrorHandler)
+	case CommandAdminStatus:
+		s.logi(1, "{{green}}boss is asking for status{{/}}")
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+			mail.Markdown(s.emailBody("status", s.emailData()))))
+	case CommandAdminBlackout:
+		var blackout config.Blackout
+		s.Blackouts, blackout = s.Blackouts.Add(command.Blackout)
+		s.logi(1, "{{green}}boss added a blackout: %s{{/}}", blackout)
+		s.recordAdminAction(command.Actor, "added blackout "+blackout.String())
+		if s.State == StatePending {
+			// in case this week is now a silent blackout
+			s.transitionTo(StatePending)
+		}
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+			s.emailBody("acknowledge_blackout", s.emailData().WithMessage("added %s", blackout))))
+	case CommandAdminUnblackout:
+		var blackout config.Blackout
+		var ok bool
+		s.Blackouts, blackout, ok = s.Blackouts.Remove(command.BlackoutID)
+		if !ok {
+			s.logi(1, "{{red}}boss asked me to remove a blackout that doesn't exist: #%d{{/}}", command.BlackoutID)
+			s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+				s.emailBody("invalid_admin_email",
+					s.emailData().WithError(fmt.Errorf("there's no blackout #%d - /status lists them (blackouts from the calendar file can't be removed by e-mail)", command.BlackoutID)))))
+			return
+		}
+		s.logi(1, "{{yellow}}boss removed a blackout: %s{{/}}", blackout)
+		s.recordAdminAction(command.Actor, "removed blackout "+blackout.String())
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+			s.emailBody("acknowledge_blackout", s.emailData().WithMessage("removed %s", blackout))))
+	case CommandAdminResume, CommandAdminSkip:
+		if len(s.CatchUp) == 0 {
+			s.logi(1, "{{red}}boss asked me to catch up, but I'm not behind{{/}}")
+			s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+				s.emailBody("invalid_admin_email",
+					s.emailData().WithError(fmt.Errorf("I haven't missed any events - there's nothing to catch up on")))))
+			return
+		}
+		message := ""
+		if command.CommandType == CommandAdminResume {
+			s.logi(1, "{{green}}boss asked me to pick up where I left off{{/}}")
+			s.recordAdminAction(command.Actor, fmt.Sprintf("resumed after missing %d event(s)", len(s.CatchUp)))
+			s.resumeMissedTransitions()
+			message = "I've picked up where I left off."
+		} else {
+			s.logi(1, "{{green}}boss asked me to skip the events I missed{{/}}")
+			s.recordAdminAction(command.Actor, fmt.Sprintf("skipped %d missed event(s)", len(s.CatchUp)))
+			for _, id := range s.CatchUp.ActionIDs() {
+				s.Deferred = s.Deferred.Remove(id)
+			}
+			if transitions := s.CatchUp.Transitions(); len(transitions) > 0 {
+				s.transitionTo(transitions[len(transitions)-1].To)
+			} else {
+				s.CatchUp = nil
+				s.syncAlarms()
+			}
+			message = "I've moved on without sending anything I missed."
+		}
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+			s.emailBody("acknowledge_catch_up", s.emailData().WithMessage(message))))
+	case CommandAdminHelp:
+		s.logi(1, "{{green}}boss is asking for help{{/}}")
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+			s.emailBody("admin_help", s.emailData().WithMessage(command.AdditionalContent))))
+	case CommandAdminInvalid:
+		s.logi(1, "{{red}}boss sent me an invalid command{{/}}")
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+			s.emailBody("invalid_admin_email", s.emailData().WithError(command.Error))))
+	case CommandAdminSchedule:
+		var action commands.DeferredAction
+		s.Deferred, action = s.Deferred.Add(command.Deferred)
+		s.logi(1, "{{green}}boss scheduled %s{{/}}", action)
+		s.alarms.Set(action.AlarmName(), action.At)
+		s.recordAdminAction(command.Actor, "scheduled "+action.String())
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+			s.emailBody("acknowledge_schedule", s.emailData().WithMessage(action.String()))))
+	case CommandAdminCancel:
+		action, ok := s.Deferred.Find(command.DeferredID)
+		if !ok {
+			s.logi(1, "{{red}}boss asked me to cancel a scheduled action that doesn't exist: #%d{{/}}", command.DeferredID)
+			s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+				s.emailBody("invalid_admin_email",
+					s.emailData().WithError(fmt.Errorf("there's no scheduled action #%d - /status lists them", command.DeferredID)))))
+			return
+		}
+		s.logi(1, "{{yellow}}boss cancelled %s{{/}}", action)
+		s.Deferred = s.Deferred.Remove(action.ID)
+		s.alarms.Clear(action.AlarmName())
+		s.recordAdminAction(command.Actor, "cancelled "+action.String())
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+			s.emailBody("acknowledge_cancel", s.emailData().WithMessage(action.String()))))
+	case CommandAdminQuarantine:
+		var quarantined commands.QuarantinedEmail
+		s.Quarantine, quarantined = s.Quarantine.Add(commands.QuarantinedEmail{At: s.alarmClock.Time(), Email: command.Email})
+		s.logi(1, "{{red}}quarantined %s - asking the boss to confirm it{{/}}", quarantined)
+		s.sendEmailWithNoTransition(mail.E().
+			WithFrom(s.config.LunchtimeDiscoEmail).
+			WithTo(s.config.BossEmail).
+			WithSubject(quarantined.Subject()).
+			WithBody(s.emailBody("quarantine_confirmation_request", s.emailData().WithQuarantined(quarantined))))
+	case CommandQuarantineReply:
+		var quarantined commands.QuarantinedEmail
+		var ok bool
+		s.Quarantine, quarantined, ok = s.Quarantine.Remove(command.QuarantineID)
+		if !ok {
+			s.logi(1, "{{red}}boss replied about a quarantined e-mail that doesn't exist: #%d{{/}}", command.QuarantineID)
+			s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+				s.emailBody("invalid_admin_email",
+					s.emailData().WithError(fmt.Errorf("there's no quarantined e-mail #%d - it may have already been confirmed or rejected", command.QuarantineID)))))
+			return
+		}
+		if !command.Confirmed {
+			s.logi(1, "{{yellow}}boss rejected quarantined e-mail %s{{/}}", quarantined)
+			s.recordAdminAction(command.Actor, "rejected quarantined e-mail "+quarantined.String())
+			s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+				s.emailBody("acknowledge_quarantine", s.emailData().WithMessage("I threw away #%d.", quarantined.ID))))
+			return
+		}
+		s.logi(1, "{{green}}boss confirmed quarantined e-mail %s{{/}}", quarantined)
+		s.recordAdminAction(command.Actor, "confirmed quarantined e-mail "+quarantined.String())
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.LunchtimeDiscoEmail,
+			s.emailBody("acknowledge_quarantine", s.emailData().WithMessage("I'm running #%d now.", quarantined.ID))))
+		// not handleCommand: we recorded this e-mail's Message-ID when we quarantined it, so that would ignore it
+		s.performCommand(s.adminCommand(quarantined.Email))
+	case CommandAdminBatch:
+		s.logi(1, "{{green}}boss sent %d commands{{/}}", len(command.Batch))
+		for _, c := range command.Batch {
+			s.dispatchCommand(c)
+		}
 	case CommandSetGames:
 		s.logi(1, "{{green}}I've been asked to set games{{/}}")
 		s.Participants = s.Participants.AddOrUpdate(command.Participant)
 		s.HistoricalParticipants = s.HistoricalParticipants.AddOrUpdate(command.Participant.Address)
-		s.sendEmailWithNoTransition(s.emailForBoss("acknowledge_set_games", s.emailData().
+		s.sendEmailWithNoTransition(s.emailForOrganizers("acknowledge_set_games", s.emailData().
 			WithMessage(command.Participant.GamesAckMessage()).
 			WithComment(command.Participant.Comments)))
 	}
 }
 
+// syncAlarms re-arms NextEvent and the deferred actions (bar any CatchUp holds) - e.g. after a dry run puts the snapshot back
+func (s *LunchtimeDisco) syncAlarms() {
+	s.alarms.ClearAll()
+	if !s.NextEvent.IsZero() && len(s.CatchUp) == 0 {
+		s.alarms.Set(NEXT_EVENT_ALARM, s.NextEvent)
+	}
+	for _, action := range s.Deferred {
+		if !s.CatchUp.Holds(action) {
+			s.alarms.Set(action.AlarmName(), action.At)
+		}
+	}
+}
+
+// performDeferredAction runs a scheduled command as though the organizer who scheduled it had just e-mailed it in
+func (s *LunchtimeDisco) performDeferredAction(name string) {
+	action, ok := s.Deferred.FindByAlarmName(name)
+	if !ok {
+		return
+	}
+	s.Deferred = s.Deferred.Remove(action.ID)
+	email := mail.E().
+		WithFrom(action.SetBy).
+		WithTo(s.config.LunchtimeDiscoEmail).
+		WithSubject("Scheduled: " + action.CommandLine).
+		WithBody(action.Text())
+	email.Date = s.alarmClock.Time().Format("Mon, 2 Jan 2006 15:04:05 -0700")
+
+	if !s.organizers().Includes(action.SetBy) {
+		skipReason := fmt.Sprintf("%s is no longer an organizer", action.SetBy)
+		s.logi(1, "{{yellow}}skipping scheduled action %s - %s{{/}}", action, skipReason)
+		s.recordAdminAction(s.config.LunchtimeDiscoEmail, fmt.Sprintf("skipped scheduled action %s - %s", action, skipReason))
+		s.sendEmailWithNoTransition(email.Reply(s.config.LunchtimeDiscoEmail,
+			s.emailBody("deferred_skipped", s.emailData().WithMessage("%s - %s", action, skipReason))))
+		return
+	}
+
+	s.logi(1, "{{green}}running scheduled action %s{{/}}", action)
+	s.recordAdminAction(action.SetBy, "ran scheduled action "+action.String())
+	s.dispatchCommand(s.adminCommand(email))
+}
+
+func (s *LunchtimeDisco) recordAdminAction(actor mail.EmailAddress, action string) {
+	s.AuditLog = s.AuditLog.Record(s.alarmClock.Time(), actor, "%s", action)
+	entry, _ := s.AuditLog.Last()
+	s.logi(2, "{{gray}}audit: %s{{/}}", entry)
+}
+
 func (s *LunchtimeDisco) retryNextEventErrorHandler(email mail.Email, err error) {
 	s.outbox.SendEmail(mail.Email{
 		From:    s.config.LunchtimeDiscoEmail,
-		To:      []mail.EmailAddress{s.config.BossEmail},
+		To:      s.organizers().Addresses(),
 		Subject: "Help!",
 		Text:    fmt.Sprintf("Saturday Disco failed to send an e-mail during an event transition.\n\n%s\n\nTrying to send:\n\n%s\n\nPlease help!", err.Error(), email.String()),
 	})
-	s.alarmClock.SetAlarm(s.alarmClock.Time().Add(RETRY_DELAY))
+	// NextEvent has to move too - otherwise syncAlarms (or a restart) would put the alarm straight back on the event we just failed
+	s.NextEvent = s.alarmClock.Time().Add(RETRY_DELAY)
+	s.alarms.Set(NEXT_EVENT_ALARM, s.NextEvent)
 }
 
 func (s *LunchtimeDisco) replyWithFailureErrorHandler(email mail.Email, err error) {
 	s.logi(1, "{{red}}failed while handling a command: %s{{/}}", err.Error())
 	s.outbox.SendEmail(mail.Email{
 		From:    s.config.LunchtimeDiscoEmail,
-		To:      []mail.EmailAddress{s.config.BossEmail},
+		To:      s.organizers().Addresses(),
 		Subject: "Help!",
 		Text:    fmt.Sprintf("Saturday Disco failed while trying to handle a command.\n\n%s\n\nTrying to send:\n\n%s\n\nPlease help!", err.Error(), email.String()),
 	})
 }
 
 func (s *LunchtimeDisco) sendEmail(email mail.Email, successState LunchtimeDiscoState, onFailure func(mail.Email, error)) {
+	email = email.WithIdempotencyKey(mail.TransitionKey("lunchtime", s.T, s.SentEmails, string(successState)))
+	email, deliverable := s.Undeliverable.Deliverable(email, s.logi, s.config.LunchtimeDiscoList, s.config.BossEmail)
+	if !deliverable {
+		s.SentEmails += 1
+		s.transitionTo(successState)
+		return
+	}
 	err := s.outbox.SendEmail(email)
 	if err != nil {
 		s.logi(1, "{{red}}failed to send e-mail: %s{{/}}", err.Error())
@@ -610,11 +1197,16 @@ func (s *LunchtimeDisco) sendEmail(email mail.Email, successState LunchtimeDisco
 
 		onFailure(email, err)
 	} else {
+		s.SentEmails += 1
 		s.transitionTo(successState)
 	}
 }
 
 func (s *LunchtimeDisco) sendEmailWithNoTransition(email mail.Email) {
+	email, deliverable := s.Undeliverable.Deliverable(email, s.logi, s.config.LunchtimeDiscoList, s.config.BossEmail)
+	if !deliverable {
+		return
+	}
 	err := s.outbox.SendEmail(email)
 	if err != nil {
 		s.logi(1, "{{red}}failed to send e-mail: %s{{/}}", err.Error())
@@ -622,16 +1214,64 @@ func (s *LunchtimeDisco) sendEmailWithNoTransition(email mail.Email) {
 	}
 }
 
+// handleBounce keeps track of who our e-mail is bouncing for and tells the boss
+func (s *LunchtimeDisco) handleBounce(command Command) {
+	bounce := command.Email.Bounce
+	if bounce.Delayed {
+		s.logi(1, "{{yellow}}got a delivery delay notification - the mail server is still trying, so ignoring it{{/}}")
+		return
+	}
+	s.logi(1, "{{red}}an e-mail bounced for %s: %s{{/}}", bounce.FailedRecipients, bounce.Reason())
+	s.Undeliverable = s.Undeliverable.Record(bounce, s.alarmClock.Time())
+	s.sendEmailWithNoTransition(command.Email.Forward(s.config.LunchtimeDiscoEmail, s.config.BossEmail,
+		s.emailBody("bounce_report", s.emailData().WithBounce(bounce, command.Email.DebugKey))))
+}
+
+func blackoutDate(t time.Time) string {
+	return t.In(clock.Timezone).Format(config.BLACKOUT_DATE_FORMAT)
+}
+
+// blackouts combines the calendar file with the blackouts added by e-mail
+func (s *LunchtimeDisco) blackouts() config.Blackouts {
+	return append(s.config.Blackouts.Dup(), s.Blackouts...)
+}
+
+// pruneBlackouts forgets the blackouts added by e-mail that are over
+func (s *LunchtimeDisco) pruneBlackouts() {
+	s.Blackouts = s.Blackouts.Upcoming(config.DiscoLunchtime, blackoutDate(s.alarmClock.Time()))
+}
+
+// blackoutFor returns the blackout covering every game day in the week ending t, if any.  A holiday on just one of the days doesn't call off the week.
+func (s *LunchtimeDisco) blackoutFor(t time.Time) (config.Blackout, bool) {
+	return s.blackouts().Covering(config.DiscoLunchtime, blackoutDate(t.Add(DT["A"])), blackoutDate(t.Add(DT["P"])))
+}
+
+// blackout returns the blackout covering this week's games, if any
+func (s *LunchtimeDisco) blackout() (config.Blackout, bool) {
+	return s.blackoutFor(s.T)
+}
+
+// announcedBlackout returns the blackout covering next week's games, if it should be announced this week
+func (s *LunchtimeDisco) announcedBlackout() config.Blackout {
+	blackout, ok := s.blackoutFor(s.T.AddDate(0, 0, 7))
+	if !ok || !blackout.Announce {
+		return config.Blackout{}
+	}
+	return blackout
+}
+
 func (s *LunchtimeDisco) reset() {
-	s.alarmClock.Stop()
+	s.alarms.Clear(NEXT_EVENT_ALARM)
 	s.State = StateInvalid
 	s.GUID = uuid.New().String()
-	s.BossGUID = uuid.New().String()
 	s.ThreadEmail = mail.Email{}
 	s.Participants = LunchtimeParticipants{}
 	s.NextEvent = time.Time{}
 	s.T = clock.NextSaturdayAt10(s.alarmClock.Time())
 	s.GameOnGameKey = ""
 	s.GameOnAdjustedTime = ""
+	s.ProcessedEmailIDs = commands.ProcessedEmailIDs{}
+	s.CalendarSequence = 0
+	s.CalledGameStart = time.Time{}
 	s.transitionTo(StatePending)
 }
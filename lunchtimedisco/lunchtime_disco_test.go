@@ -4,13 +4,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/onsi/disco/calendar"
 	clockpkg "github.com/onsi/disco/clock"
 	"github.com/onsi/disco/config"
 	"github.com/onsi/disco/lunchtimedisco"
@@ -18,6 +22,7 @@ import (
 	"github.com/onsi/disco/s3db"
 	"github.com/onsi/disco/server"
 	"github.com/onsi/disco/weather"
+	"github.com/onsi/disco/webhook"
 
 	. "github.com/onsi/disco/lunchtimedisco"
 )
@@ -91,13 +96,17 @@ var _ = Describe("LunchtimeDisco", func() {
 		conf.BossEmail = mail.EmailAddress("Boss <boss@example.com>")
 		conf.LunchtimeDiscoEmail = mail.EmailAddress("Disco <lunchtime-disco@sedenverultimate.net>")
 		conf.LunchtimeDiscoList = mail.EmailAddress("southeast-denver-lunchtime-ultimate@googlegroups.com")
+		conf.RequireAuthenticatedAdmins = false
 		playerEmail = mail.EmailAddress("John Player <player@example.com>")
 		playerName = "John Player"
 
 		now = time.Date(2023, time.September, 24, 0, 0, 0, 0, clockpkg.Timezone) // a Sunday
 		clock.SetTime(now)
 		weekOf = "9/25"
+	})
 
+	// the disco starts after every BeforeEach has had its say about conf
+	JustBeforeEach(func() {
 		var err error
 		disco, err = NewLunchtimeDisco(conf, GinkgoWriter, clock, outbox, forecaster, db)
 		Ω(err).ShouldNot(HaveOccurred())
@@ -108,15 +117,57 @@ var _ = Describe("LunchtimeDisco", func() {
 		conf.Port = fmt.Sprintf("99%02d", GinkgoParallelProcess())
 		e := echo.New()
 		e.Logger.SetOutput(GinkgoWriter)
-		s := server.NewServer(e, "../", conf, outbox, db, nil, disco)
+		s := server.NewServer(e, "../", conf, outbox, nil, nil, webhook.NewGuard("", false, db, GinkgoWriter), db, nil, disco)
 		go s.Start()
 		DeferCleanup(e.Shutdown, NodeTimeout(10*time.Second))
 
 		indexURL = fmt.Sprintf("http://localhost:%s", conf.Port)
 		persistentPlayerURL = fmt.Sprintf("%s/lunchtime/%s", indexURL, disco.GUID)
 		playerURL = fmt.Sprintf("%s/lunchtime/%s?reset", indexURL, disco.GUID)
-		bossURL = fmt.Sprintf("%s/lunchtime/%s", indexURL, disco.BossGUID)
+		bossURL = fmt.Sprintf("%s/lunchtime/boss", indexURL)
 		Eventually(http.Get).WithArguments(indexURL).Should(HaveField("StatusCode", http.StatusOK))
+
+		//log the browser in as the boss via a magic link
+		Ω(http.PostForm(indexURL+"/login", url.Values{"email": {"boss@example.com"}, "next": {"/lunchtime/boss"}})).Should(HaveField("StatusCode", http.StatusOK))
+		Eventually(le).Should(HaveSubject("Your Disco login link"))
+		Ω(le()).Should(BeSentTo(conf.BossEmail))
+		token := regexp.MustCompile(`/login/(\S+)`).FindStringSubmatch(le().Text)[1]
+		outbox.Clear()
+		b.Navigate(indexURL + "/login/" + token)
+		Eventually(b.Location).Should(Equal(bossURL))
+	})
+
+	Describe("boss authentication", func() {
+		var client *http.Client
+		BeforeEach(func() {
+			client = &http.Client{
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+		})
+
+		It("sends strangers to the login page", func() {
+			resp, err := client.Get(bossURL)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(http.StatusSeeOther))
+			Ω(resp.Header.Get("Location")).Should(Equal("/login?next=%2Flunchtime%2Fboss"))
+
+			resp, err = client.Post(bossURL, "application/json", bytes.NewBufferString(`{"commandType":"admin_badger"}`))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(http.StatusUnauthorized))
+		})
+
+		It("doesn't send login links to strangers", func() {
+			Ω(http.PostForm(indexURL+"/login", url.Values{"email": {"stranger@example.com"}})).Should(HaveField("StatusCode", http.StatusOK))
+			Consistently(outbox.Emails, "100ms").Should(BeEmpty())
+		})
+
+		It("rejects bogus login links", func() {
+			resp, err := client.Get(indexURL + "/login/bogus")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(http.StatusBadRequest))
+		})
 	})
 
 	Describe("the scheduler", func() {
@@ -127,7 +178,7 @@ var _ = Describe("LunchtimeDisco", func() {
 			Ω(le()).Should(BeFrom(conf.LunchtimeDiscoEmail))
 			Ω(le()).Should(BeSentTo(conf.BossEmail))
 			Ω(le()).Should(HaveText(ContainSubstring("Here's the latest on the lunchtime game.")))
-			Ω(le()).Should(HaveText(ContainSubstring("Dashboard: https://www.sedenverultimate.net/lunchtime/" + disco.GetSnapshot().BossGUID)))
+			Ω(le()).Should(HaveText(ContainSubstring("Dashboard: https://www.sedenverultimate.net/lunchtime/boss")))
 			outbox.Clear()
 
 			signUpPlayer(playerName, playerEmail.Address(), "", []string{"A"})
@@ -147,7 +198,7 @@ var _ = Describe("LunchtimeDisco", func() {
 		})
 
 		Context("when the invite is sent", func() {
-			BeforeEach(func() {
+			JustBeforeEach(func() {
 				sendInvite()
 			})
 
@@ -166,7 +217,7 @@ var _ = Describe("LunchtimeDisco", func() {
 		})
 
 		Context("when no-invite is sent", func() {
-			BeforeEach(func() {
+			JustBeforeEach(func() {
 				//first we get the monitor e-mail
 				clock.Fire()
 				Eventually(le).Should(HaveSubject("Lunchtime Monitor: " + weekOf))
@@ -190,7 +241,7 @@ var _ = Describe("LunchtimeDisco", func() {
 		})
 
 		Context("when no-game is called", func() {
-			BeforeEach(func() {
+			JustBeforeEach(func() {
 				sendInvite()
 				b.Navigate(bossURL)
 				Eventually("#no-game").Should(b.Click())
@@ -210,7 +261,7 @@ var _ = Describe("LunchtimeDisco", func() {
 		})
 
 		Context("when game is called", func() {
-			BeforeEach(func() {
+			JustBeforeEach(func() {
 				sendInvite()
 				b.Navigate(bossURL)
 				Eventually("#game-on").Should(b.Click())
@@ -234,6 +285,16 @@ var _ = Describe("LunchtimeDisco", func() {
 				Eventually(disco.GetSnapshot).Should(HaveState(StatePending))
 				Ω(le()).Should(BeZero())
 			})
+
+			It("keeps counting sent e-mails across the reset, so no two e-mails ever share an idempotency key", func() {
+				sent := disco.GetSnapshot().SentEmails
+				Ω(sent).Should(BeNumerically(">", 0))
+				clock.Fire()
+				Eventually(disco.GetSnapshot).Should(HaveState(StateReminderSent))
+				clock.Fire()
+				Eventually(disco.GetSnapshot).Should(HaveState(StatePending))
+				Ω(disco.GetSnapshot().SentEmails).Should(Equal(sent + 1))
+			})
 		})
 	})
 
@@ -282,6 +343,7 @@ var _ = Describe("LunchtimeDisco", func() {
 			Ω(le()).Should(BeSentTo(conf.LunchtimeDiscoList, conf.BossEmail))
 
 			Ω(le().InReplyTo).Should(Equal(threadId))
+			Ω(le().References).Should(Equal([]string{threadId}))
 			Ω(le()).Should(HaveText(ContainSubstring("GAME ON for Wednesday 9/27 at 10:00am")))
 		})
 	})
@@ -449,7 +511,7 @@ var _ = Describe("LunchtimeDisco", func() {
 	})
 
 	Describe("allowing the boss to see and modify who has signed up (via web)", func() {
-		BeforeEach(func() {
+		JustBeforeEach(func() {
 			sendInvite()
 			signUpPlayer(playerName, playerEmail.Address(), "I'm in", []string{"A", "B", "C"})
 			signUpPlayer("Bob Player", "bob@example.com", "", []string{"A", "E"})
@@ -669,7 +731,7 @@ var _ = Describe("LunchtimeDisco", func() {
 	})
 
 	Describe("boss sending a badger", func() {
-		BeforeEach(func() {
+		JustBeforeEach(func() {
 			sendInvite()
 		})
 
@@ -715,7 +777,7 @@ var _ = Describe("LunchtimeDisco", func() {
 	})
 
 	Describe("boss calling game", func() {
-		BeforeEach(func() {
+		JustBeforeEach(func() {
 			sendInvite()
 			signUpPlayer(playerName, playerEmail.Address(), "I'm in", []string{"A", "B", "C"})
 			signUpPlayer("Bob Player", "bob@example.com", "", []string{"A", "B"})
@@ -745,7 +807,7 @@ var _ = Describe("LunchtimeDisco", func() {
 		})
 
 		Context("with the standard time", func() {
-			BeforeEach(func() {
+			JustBeforeEach(func() {
 				b.Click("#game-option-B")
 				Eventually("#game-option-B").Should(b.HaveClass("selected"))
 				Ω("#additional-content").Should(b.SetValue("Yum **YUM**"))
@@ -784,7 +846,7 @@ var _ = Describe("LunchtimeDisco", func() {
 		})
 
 		Context("with a time override", func() {
-			BeforeEach(func() {
+			JustBeforeEach(func() {
 				b.Click("#game-option-B")
 				Eventually("#game-option-B").Should(b.HaveClass("selected"))
 				Ω("#override-start-time").Should(b.SetValue("11:15AM"))
@@ -822,7 +884,7 @@ var _ = Describe("LunchtimeDisco", func() {
 	})
 
 	Describe("boss calling no game", func() {
-		BeforeEach(func() {
+		JustBeforeEach(func() {
 			sendInvite()
 		})
 
@@ -866,4 +928,415 @@ var _ = Describe("LunchtimeDisco", func() {
 			Eventually(".status.lunchtime").Should(b.HaveClass("game-off"))
 		})
 	})
+
+	Describe("boss sending commands by e-mail", func() {
+		var bossToDisco = func(body string) {
+			GinkgoHelper()
+			disco.HandleIncomingEmail(mail.E().
+				WithFrom(conf.BossEmail).
+				WithTo(conf.LunchtimeDiscoEmail).
+				WithSubject("hey").
+				WithBody(body))
+		}
+
+		It("replies to /status with the list of games", func() {
+			disco.HandleParticipant(lunchtimedisco.LunchtimeParticipant{Address: playerEmail, GameKeys: []string{"K"}})
+			Eventually(le).Should(HaveSubject("Set Games - " + playerEmail.String() + ": K"))
+			outbox.Clear()
+
+			bossToDisco("/status")
+			Eventually(le).Should(HaveSubject("Re: hey"))
+			Ω(le()).Should(BeSentTo(conf.BossEmail))
+			Ω(le()).Should(HaveText(ContainSubstring("Current State: pending")))
+			Ω(le()).Should(HaveText(ContainSubstring("- K - 1 - Thursday 9/28 at 12:00pm")))
+		})
+
+		It("replies to /help with the list of commands", func() {
+			bossToDisco("/help")
+			Eventually(le).Should(HaveSubject("Re: hey"))
+			Ω(le()).Should(HaveText(ContainSubstring("/game-on <game> [time] - call the game")))
+		})
+
+		It("sends the invite", func() {
+			bossToDisco("/invite\nLets do it **again**.")
+			Eventually(le).Should(HaveSubject("Lunchtime Bible Park Frisbee - Week of " + weekOf))
+			Ω(le()).Should(BeFrom(conf.BossEmail))
+			Ω(le()).Should(BeSentTo(conf.LunchtimeDiscoList, conf.BossEmail))
+			Ω(le()).Should(HaveHTML(ContainSubstring(`Lets do it <strong>again</strong>.`)))
+			Ω(disco.GetSnapshot()).Should(HaveState(StateInviteSent))
+			entry, _ := disco.GetSnapshot().AuditLog.Last()
+			Ω(entry.Actor).Should(Equal(conf.BossEmail))
+			Ω(entry.Action).Should(Equal("sent the invite"))
+		})
+
+		It("only acts on an e-mail once, even if it arrives again", func() {
+			email := mail.E().
+				WithFrom(conf.BossEmail).
+				WithTo(conf.LunchtimeDiscoEmail).
+				WithSubject("hey").
+				WithBody("/invite")
+			email.MessageID = "<redispatched@example.com>"
+			disco.HandleIncomingEmail(email)
+			Eventually(le).Should(HaveSubject("Lunchtime Bible Park Frisbee - Week of " + weekOf))
+			Ω(disco.GetSnapshot().ProcessedEmailIDs).Should(ConsistOf("<redispatched@example.com>"))
+			outbox.Clear()
+
+			disco.HandleIncomingEmail(email)
+			Consistently(outbox.Emails).Should(BeEmpty())
+			Ω(disco.GetSnapshot()).Should(HaveState(StateInviteSent))
+		})
+
+		It("calls the game, with an optional adjusted time", func() {
+			disco.HandleParticipant(lunchtimedisco.LunchtimeParticipant{Address: playerEmail, GameKeys: []string{"K"}})
+			Eventually(le).ShouldNot(BeZero())
+			outbox.Clear()
+
+			bossToDisco("/game-on k 12:30pm\nYum **YUM**")
+			Eventually(le).Should(HaveSubject("GAME ON! Thursday 9/28 at 12:30pm"))
+			Ω(le()).Should(BeSentTo(conf.LunchtimeDiscoList, conf.BossEmail))
+			Ω(le()).Should(HaveHTML(ContainSubstring(`Yum <strong>YUM</strong>`)))
+			Ω(le()).Should(HaveHTML(ContainSubstring(`<strong>Who</strong>: John`)))
+			Ω(disco.GetSnapshot()).Should(HaveState(StateGameOnSent))
+			Ω(disco.GetSnapshot().GameOnGameKey).Should(Equal("K"))
+		})
+
+		It("sends no-game", func() {
+			bossToDisco("/no-game")
+			Eventually(le).Should(HaveSubject("No Lunchtime Game This Week"))
+			Ω(disco.GetSnapshot()).Should(HaveState(StateNoGameSent))
+		})
+
+		It("runs several commands in one e-mail", func() {
+			bossToDisco("/status\n/invite\nLets do it **again**.")
+			Eventually(outbox.Emails).Should(HaveLen(2))
+			Ω(outbox.Emails()[0]).Should(HaveSubject("Re: hey"))
+			Ω(outbox.Emails()[1]).Should(HaveSubject("Lunchtime Bible Park Frisbee - Week of " + weekOf))
+			Ω(disco.GetSnapshot()).Should(HaveState(StateInviteSent))
+		})
+
+		It("previews commands with --dry-run", func() {
+			bossToDisco("/no-invite --dry-run")
+			Eventually(le).Should(HaveSubject("Re: hey"))
+			Ω(outbox.Emails()).Should(HaveLen(1))
+			Ω(le()).Should(HaveText(ContainSubstring("I would move from the pending state to the no_invite_sent state.")))
+			Ω(le()).Should(HaveText(ContainSubstring("Subject: No Lunchtime Bible Park Frisbee This Week")))
+			Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+		})
+
+		It("replies with an error, and a suggestion, for invalid commands", func() {
+			bossToDisco("/invit")
+			Eventually(le).Should(HaveText(ContainSubstring("unknown command: /invit - did you mean /invite")))
+			bossToDisco("/game-on Z")
+			Eventually(le).Should(HaveText(ContainSubstring("invalid game for /game-on: Z")))
+			Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+		})
+
+		It("schedules commands for later, and lets the boss cancel them", func() {
+			bossToDisco("/schedule today 3am /invite\nLets do it **again**.")
+			Eventually(le).Should(HaveText(ContainSubstring("Got it - I've scheduled #1: /invite on Sun 9/24 3:00am")))
+			bossToDisco("/schedule today 4am /no-game")
+			Eventually(le).Should(HaveText(ContainSubstring("#2: /no-game on Sun 9/24 4:00am")))
+			bossToDisco("/cancel #2")
+			Eventually(le).Should(HaveText(ContainSubstring("Got it - I've cancelled #2: /no-game")))
+			Ω(disco.GetSnapshot().Deferred).Should(HaveLen(1))
+			outbox.Clear()
+
+			clock.Fire()
+			Eventually(le).Should(HaveSubject("Lunchtime Bible Park Frisbee - Week of " + weekOf))
+			Ω(le()).Should(BeFrom(conf.BossEmail))
+			Ω(le()).Should(HaveHTML(ContainSubstring(`Lets do it <strong>again</strong>.`)))
+			Ω(disco.GetSnapshot()).Should(HaveState(StateInviteSent))
+			Ω(disco.GetSnapshot().Deferred).Should(BeEmpty())
+			entry, _ := disco.GetSnapshot().AuditLog.Last()
+			Ω(entry.Action).Should(Equal("sent the invite"))
+		})
+
+		It("rejects quorum conditions, which Lunchtime Disco doesn't track", func() {
+			bossToDisco("/schedule today 3am if-quorum /no-game")
+			Eventually(le).Should(HaveText(ContainSubstring("if-quorum isn't supported")))
+			Ω(disco.GetSnapshot().Deferred).Should(BeEmpty())
+		})
+
+		Describe("catching up after downtime", func() {
+			JustBeforeEach(func() {
+				bossToDisco("/game-on K")
+				Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
+				disco.Stop()
+
+				clock.SetTime(time.Date(2023, time.September, 28, 15, 0, 0, 0, clockpkg.Timezone)) // after the reminder should have gone out
+				outbox.Clear()
+				var err error
+				disco, err = NewLunchtimeDisco(conf, GinkgoWriter, clock, outbox, forecaster, db)
+				Ω(err).ShouldNot(HaveOccurred())
+				DeferCleanup(disco.Stop)
+			})
+
+			It("pauses instead of sending a stale reminder, and asks the boss how to proceed", func() {
+				Ω(outbox.Emails()).Should(HaveLen(2))
+				Ω(outbox.Emails()[0]).Should(HaveText(ContainSubstring("I missed 1 event(s) while I was down.  I've paused until you tell me how to proceed.")))
+				Ω(le()).Should(HaveSubject("LunchtimeDisco Missed Some Events"))
+				Ω(le()).Should(HaveText(ContainSubstring("- Thu 9/28 6:00am: gameonsent → remindersent")))
+				Ω(disco.GetSnapshot()).Should(HaveState(StateGameOnSent))
+			})
+
+			It("sends the reminder on /resume", func() {
+				outbox.Clear()
+				bossToDisco("/resume")
+				Eventually(disco.GetSnapshot).Should(HaveState(StateReminderSent))
+				Ω(outbox.Emails()[0]).Should(HaveSubject("Reminder: GAME ON TODAY! Thursday 9/28 at 12:00pm"))
+				Ω(le()).Should(HaveText(HavePrefix("Got it - I've picked up where I left off.")))
+			})
+
+			It("moves on without sending the reminder on /skip", func() {
+				outbox.Clear()
+				bossToDisco("/skip")
+				Eventually(disco.GetSnapshot).Should(HaveState(StateReminderSent))
+				Ω(outbox.Emails()).Should(HaveLen(1))
+				Ω(le()).Should(HaveText(HavePrefix("Got it - I've moved on without sending anything I missed.")))
+			})
+		})
+
+		Describe("bounces", func() {
+			var bounceFor = func(originalMessageID string, recipients ...mail.EmailAddress) mail.Email {
+				email := mail.E().WithFrom("Mail Delivery System <MAILER-DAEMON@mx1.forwardemail.net>").WithTo(conf.LunchtimeDiscoEmail).
+					WithSubject("Undelivered Mail Returned to Sender").WithBody("I'm sorry to have to inform you that your message could not be delivered.")
+				email.AutoReply = "Auto-Submitted: auto-replied"
+				email.Bounce = mail.Bounce{FailedRecipients: recipients, Status: "5.1.1", Diagnostic: "550 no such user", OriginalMessageID: originalMessageID}
+				return email
+			}
+
+			It("tells the boss who an e-mail we sent bounced for - even though bounces are auto-submitted", func() {
+				disco.HandleIncomingEmail(bounceFor(mail.MessageIDFor("lunchtime/2023-09-30/0/invite_sent", conf.LunchtimeDiscoEmail), playerEmail))
+				Eventually(le).Should(HaveSubject("Fwd: Undelivered Mail Returned to Sender"))
+				Ω(le()).Should(BeSentTo(conf.BossEmail))
+				Ω(le()).Should(HaveText(ContainSubstring("It couldn't be delivered to:\n\n- John Player <player@example.com>")))
+				Ω(le()).Should(HaveText(ContainSubstring("The mail server said: 5.1.1 550 no such user")))
+				Ω(disco.GetSnapshot().Undeliverable.IsDead(playerEmail)).Should(BeTrue())
+				Ω(disco.DroppedAutoReplies()).Should(Equal(0))
+
+				outbox.Clear()
+				bossToDisco("/status")
+				Eventually(le).Should(HaveText(ContainSubstring("📭 E-mail has bounced for:\n- John Player <player@example.com>: bounced 1 time(s)")))
+			})
+
+			It("ignores bounces for e-mails it didn't send, so nobody can get a player cut off with a forged one", func() {
+				disco.HandleIncomingEmail(bounceFor("<made-up@example.com>", playerEmail))
+				disco.HandleIncomingEmail(bounceFor("", playerEmail))
+				disco.HandleIncomingEmail(bounceFor(mail.MessageIDFor("saturday/2023-09-30/0/invite_sent", conf.LunchtimeDiscoEmail), playerEmail))
+				Consistently(le).Should(BeZero())
+				Ω(disco.GetSnapshot().Undeliverable).Should(BeEmpty())
+			})
+		})
+
+		Describe("catching up on scheduled actions after downtime", func() {
+			JustBeforeEach(func() {
+				bossToDisco("/game-on K")
+				Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
+				bossToDisco("/schedule wednesday noon /no-game")
+				Eventually(disco.GetSnapshot).Should(HaveField("Deferred", HaveLen(1)))
+				disco.Stop()
+
+				clock.SetTime(time.Date(2023, time.September, 27, 15, 0, 0, 0, clockpkg.Timezone)) // after the scheduled /no-game, before the reminder
+				outbox.Clear()
+				var err error
+				disco, err = NewLunchtimeDisco(conf, GinkgoWriter, clock, outbox, forecaster, db)
+				Ω(err).ShouldNot(HaveOccurred())
+				DeferCleanup(disco.Stop)
+			})
+
+			It("holds on to the action and pauses instead of running it late", func() {
+				Ω(outbox.Emails()).Should(HaveLen(2))
+				Ω(outbox.Emails()[0]).Should(HaveText(ContainSubstring("I missed 1 event(s) while I was down.  I've paused until you tell me how to proceed.")))
+				Ω(le()).Should(HaveText(ContainSubstring("- Wed 9/27 12:00pm: scheduled #1 /no-game")))
+				Ω(disco.GetSnapshot()).Should(HaveState(StateGameOnSent))
+				Ω(disco.GetSnapshot().Deferred).Should(HaveLen(1))
+			})
+
+			It("runs the action on /resume", func() {
+				outbox.Clear()
+				bossToDisco("/resume")
+				Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
+				Ω(disco.GetSnapshot().Deferred).Should(BeEmpty())
+				Ω(disco.GetSnapshot().CatchUp).Should(BeEmpty())
+			})
+
+			It("drops the action on /skip", func() {
+				outbox.Clear()
+				bossToDisco("/skip")
+				Eventually(le).Should(HaveText(HavePrefix("Got it - I've moved on without sending anything I missed.")))
+				Ω(disco.GetSnapshot()).Should(HaveState(StateGameOnSent))
+				Ω(disco.GetSnapshot().Deferred).Should(BeEmpty())
+				Ω(disco.GetSnapshot().CatchUp).Should(BeEmpty())
+			})
+		})
+
+		Describe("calendar invites", func() {
+			It("attaches an invite to the game-on e-mail and a cancellation to a no-game e-mail that follows it", func() {
+				Ω(disco.CalendarFeed().Events).Should(BeEmpty())
+
+				bossToDisco("/game-on K 12:30pm")
+				Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
+				Ω(le()).Should(HaveSubject("GAME ON! Thursday 9/28 at 12:30pm"))
+				Ω(le().Attachments).Should(HaveLen(1))
+				Ω(le().Attachments[0].ContentType).Should(Equal("text/calendar; method=REQUEST; charset=utf-8"))
+				Ω(string(le().Attachments[0].Content)).Should(And(
+					ContainSubstring("SEQUENCE:0\r\n"),
+					ContainSubstring("DTSTART:20230928T183000Z\r\n"),
+					ContainSubstring("DTEND:20230928T193000Z\r\n"),
+				))
+				Ω(disco.CalendarFeed().Events).Should(HaveExactElements(HaveField("Status", calendar.StatusConfirmed)))
+
+				bossToDisco("/no-game")
+				Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
+				Ω(le().Attachments).Should(HaveLen(1))
+				Ω(string(le().Attachments[0].Content)).Should(And(
+					ContainSubstring("METHOD:CANCEL\r\n"),
+					ContainSubstring("SEQUENCE:1\r\n"),
+					ContainSubstring("DTSTART:20230928T183000Z\r\n"),
+				))
+				Ω(disco.CalendarFeed().Events).Should(HaveExactElements(HaveField("Status", calendar.StatusCancelled)))
+			})
+
+			It("serves the calendar feed", func() {
+				bossToDisco("/game-on K")
+				Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
+				resp, err := http.Get(indexURL + "/calendar/lunchtime.ics")
+				Ω(err).ShouldNot(HaveOccurred())
+				defer resp.Body.Close()
+				Ω(resp.Header.Get("Content-Type")).Should(Equal("text/calendar; charset=utf-8"))
+				Ω(io.ReadAll(resp.Body)).Should(And(
+					ContainSubstring("METHOD:PUBLISH\r\n"),
+					ContainSubstring("DTSTART:20230928T180000Z\r\n"),
+					ContainSubstring("STATUS:CONFIRMED\r\n"),
+				))
+			})
+		})
+
+		Describe("blacking out weeks", func() {
+			It("sends the no-invitation e-mail with the reason instead of the morning ping", func() {
+				bossToDisco("/blackout 9/25 9/29 Field is closed")
+				Eventually(le).Should(HaveText(ContainSubstring("Got it - I've added #1: 9/25-9/29 (Field is closed)")))
+				Ω(le()).Should(HaveText(ContainSubstring("Upcoming blackouts:\n- #1: 9/25-9/29 (Field is closed)")))
+
+				outbox.Clear()
+				clock.Fire()
+				Eventually(le).Should(HaveSubject("No Lunchtime Bible Park Frisbee This Week"))
+				Ω(le()).Should(BeSentTo(conf.LunchtimeDiscoList))
+				Ω(le()).Should(HaveText(HavePrefix("Field is closed")))
+				Ω(disco.GetSnapshot()).Should(HaveState(StateNoInviteSent))
+			})
+
+			It("skips the week without sending anything if the blackout is silent", func() {
+				bossToDisco("/blackout 9/26 9/29 silent")
+				Eventually(disco.GetSnapshot).Should(HaveState(StateNoInviteSent))
+			})
+
+			It("doesn't call off the week for a holiday on just one day", func() {
+				bossToDisco("/blackout 9/27 silent")
+				Eventually(le).Should(HaveText(ContainSubstring("Got it - I've added #1: 9/27, silent")))
+				outbox.Clear()
+				clock.Fire()
+				Eventually(le).Should(HaveSubject("Lunchtime Monitor: " + weekOf))
+				Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+			})
+
+			It("announces blackouts in the previous week's e-mails, and lets the boss remove them", func() {
+				bossToDisco("/blackout 10/3 10/6 announce Fall break")
+				Eventually(le).Should(HaveText(ContainSubstring("Got it - I've added #1")))
+				bossToDisco("/invite")
+				Eventually(le).Should(HaveSubject("Lunchtime Bible Park Frisbee - Week of " + weekOf))
+				Ω(le()).Should(HaveText(ContainSubstring("Heads up: there's no lunchtime game next week (10/3-10/6) - Fall break.")))
+
+				bossToDisco("/unblackout 1")
+				Eventually(le).Should(HaveText(ContainSubstring("Got it - I've removed #1: 10/3-10/6 (Fall break)")))
+				Ω(disco.GetSnapshot().Blackouts).Should(BeEmpty())
+			})
+		})
+
+		Describe("authenticating admin commands", func() {
+			authenticated := func(m mail.Email) mail.Email {
+				m.Authentication = mail.AuthenticationResults{
+					AuthServID: "mx1.forwardemail.net",
+					Results: []mail.AuthenticationResult{
+						{Method: "dmarc", Result: "pass", Properties: map[string]string{"header.from": "example.com"}},
+					},
+				}
+				return m
+			}
+
+			BeforeEach(func() {
+				conf.RequireAuthenticatedAdmins = true
+			})
+
+			JustBeforeEach(func() {
+				bossToDisco("/invite")
+				Eventually(le).Should(HaveSubject("[quarantine-confirmation-request] #1: hey"))
+				Ω(le()).Should(BeSentTo(conf.BossEmail))
+				Ω(le()).Should(HaveText(ContainSubstring("It claims to be from Boss <boss@example.com>, but our mail server reported: no authentication results")))
+				Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+			})
+
+			It("runs the quarantined command once the boss confirms it", func() {
+				disco.HandleIncomingEmail(authenticated(le().ReplyWithoutQuote(conf.BossEmail, "/confirm")))
+				Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+				Ω(le()).Should(HaveSubject("Lunchtime Bible Park Frisbee - Week of " + weekOf))
+				Ω(disco.GetSnapshot().Quarantine).Should(BeEmpty())
+			})
+
+			It("runs a confirmed command even though it has already seen the quarantined e-mail's Message-ID", func() {
+				email := mail.E().
+					WithFrom(conf.BossEmail).
+					WithTo(conf.LunchtimeDiscoEmail).
+					WithSubject("again").
+					WithBody("/invite")
+				email.MessageID = "<quarantined@example.com>"
+				disco.HandleIncomingEmail(email)
+				Eventually(le).Should(HaveSubject("[quarantine-confirmation-request] #2: again"))
+
+				disco.HandleIncomingEmail(authenticated(le().ReplyWithoutQuote(conf.BossEmail, "/confirm")))
+				Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+				Ω(le()).Should(HaveSubject("Lunchtime Bible Park Frisbee - Week of " + weekOf))
+				Ω(disco.GetSnapshot().Quarantine).Should(HaveLen(1))
+			})
+
+			It("throws the quarantined command away if the boss rejects it", func() {
+				disco.HandleIncomingEmail(authenticated(le().ReplyWithoutQuote(conf.BossEmail, "/reject")))
+				Eventually(le).Should(HaveText(ContainSubstring("Got it - I threw away #1.")))
+				Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+				Ω(disco.GetSnapshot().Quarantine).Should(BeEmpty())
+			})
+
+			It("lets the boss confirm from the dashboard instead", func() {
+				Ω(le()).Should(HaveText(ContainSubstring("confirm or reject it on the dashboard instead: https://www.sedenverultimate.net/lunchtime/boss")))
+				Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandQuarantineReply, QuarantineID: 1, Confirmed: true})).Should(Succeed())
+				Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+				Ω(disco.GetSnapshot().Quarantine).Should(BeEmpty())
+				Ω(outbox.Emails()).Should(ContainElement(SatisfyAll(BeSentTo(conf.BossEmail), HaveText(ContainSubstring("Got it - I'm running #1 now.")))))
+			})
+
+			It("only lets the boss confirm from the dashboard", func() {
+				Ω(disco.HandleCommand(playerEmail, Command{CommandType: CommandQuarantineReply, QuarantineID: 1, Confirmed: true})).Should(MatchError("only the boss can confirm quarantined commands"))
+				Consistently(disco.GetSnapshot, "100ms").Should(HaveState(StatePending))
+				Ω(disco.GetSnapshot().Quarantine).Should(HaveLen(1))
+			})
+
+			It("ignores confirmations that aren't authenticated either", func() {
+				request := le()
+				outbox.Clear()
+				disco.HandleIncomingEmail(request.ReplyWithoutQuote(conf.BossEmail, "/confirm"))
+				Consistently(le).Should(BeZero())
+				Ω(disco.GetSnapshot().Quarantine).Should(HaveLen(1))
+			})
+		})
+
+		It("ignores commands from people who aren't organizers", func() {
+			disco.HandleIncomingEmail(mail.E().
+				WithFrom(playerEmail).
+				WithTo(conf.LunchtimeDiscoEmail).
+				WithSubject("hey").
+				WithBody("/invite"))
+			Consistently(le).Should(BeZero())
+			Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+		})
+	})
 })
@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attachment is a file sent along with an e-mail, e.g. a calendar invite.  Attachments with a CID are inline: they aren't offered as
+// downloads and, instead, the HTML body refers to them via cid:<CID>
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+	CID         string
+}
+
+func (a Attachment) IsInline() bool {
+	return a.CID != ""
+}
+
+func (a Attachment) String() string {
+	out := fmt.Sprintf("%s (%s, %d bytes)", a.Filename, a.ContentType, len(a.Content))
+	if a.IsInline() {
+		out += " inline as cid:" + a.CID
+	}
+	return out
+}
+
+type Attachments []Attachment
+
+func (a Attachments) dup() Attachments {
+	if a == nil {
+		return nil
+	}
+	out := make(Attachments, len(a))
+	for i, attachment := range a {
+		attachment.Content = append([]byte{}, attachment.Content...)
+		out[i] = attachment
+	}
+	return out
+}
+
+// Named returns the first attachment with the given filename
+func (a Attachments) Named(filename string) (Attachment, bool) {
+	for _, attachment := range a {
+		if attachment.Filename == filename {
+			return attachment, true
+		}
+	}
+	return Attachment{}, false
+}
+
+func (a Attachments) String() string {
+	out := []string{}
+	for _, attachment := range a {
+		out = append(out, attachment.String())
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,164 @@
+package mail
+
+import (
+	"fmt"
+	"strings"
+)
+
+// we only believe Authentication-Results stamped by our own mail servers - anything else could have been written by the sender
+const TRUSTED_AUTHSERV_DOMAIN = "forwardemail.net"
+
+// AuthenticationResult is one method's verdict from an Authentication-Results header, e.g. dkim=pass header.d=gmail.com
+type AuthenticationResult struct {
+	Method     string            `json:"method"`
+	Result     string            `json:"result"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+func (r AuthenticationResult) IsPass() bool {
+	return r.Result == "pass"
+}
+
+// AuthenticationResults is what our mail server made of the sender's DKIM, SPF, and DMARC
+type AuthenticationResults struct {
+	AuthServID string                 `json:"authserv_id,omitempty"`
+	Results    []AuthenticationResult `json:"results,omitempty"`
+}
+
+func (a AuthenticationResults) IsZero() bool {
+	return a.AuthServID == "" && len(a.Results) == 0
+}
+
+func (a AuthenticationResults) dup() AuthenticationResults {
+	out := AuthenticationResults{AuthServID: a.AuthServID}
+	for _, result := range a.Results {
+		properties := map[string]string{}
+		for k, v := range result.Properties {
+			properties[k] = v
+		}
+		result.Properties = properties
+		out.Results = append(out.Results, result)
+	}
+	return out
+}
+
+func (a AuthenticationResults) String() string {
+	if a.IsZero() {
+		return "no authentication results"
+	}
+	verdicts := []string{}
+	for _, result := range a.Results {
+		verdicts = append(verdicts, result.Method+"="+result.Result)
+	}
+	return fmt.Sprintf("%s (by %s)", strings.Join(verdicts, " "), a.AuthServID)
+}
+
+// AlignedWith is true if a passing DMARC, DKIM, or SPF result vouches for address's domain (or a parent/child domain of it - i.e. relaxed alignment)
+func (a AuthenticationResults) AlignedWith(address EmailAddress) bool {
+	domain := domainOf(address.Address())
+	if domain == "" {
+		return false
+	}
+	for _, result := range a.Results {
+		if !result.IsPass() {
+			continue
+		}
+		var candidates []string
+		switch result.Method {
+		case "dmarc":
+			candidates = []string{result.Properties["header.from"]}
+		case "dkim":
+			candidates = []string{result.Properties["header.d"], domainOf(result.Properties["header.i"])}
+		case "spf":
+			candidates = []string{domainOf(result.Properties["smtp.mailfrom"])}
+		}
+		for _, candidate := range candidates {
+			if domainsAlign(domain, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func domainOf(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		address = address[i+1:]
+	}
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+func domainsAlign(a, b string) bool {
+	b = strings.ToLower(strings.TrimSpace(b))
+	if a == "" || b == "" {
+		return false
+	}
+	return a == b || strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)
+}
+
+// ParseAuthenticationResults parses an (Arc-)Authentication-Results header per RFC 8601, e.g.
+//
+//	Authentication-Results: mx1.forwardemail.net; dkim=pass header.d=gmail.com; spf=pass (comment) smtp.mailfrom=a@gmail.com
+func ParseAuthenticationResults(header string) (AuthenticationResults, error) {
+	if name, value, ok := strings.Cut(header, ":"); ok && strings.HasSuffix(strings.ToLower(name), "authentication-results") {
+		header = value
+	}
+	header = stripHeaderComments(strings.NewReplacer("\r\n", " ", "\n", " ", "\t", " ").Replace(header))
+
+	out := AuthenticationResults{}
+	for _, clause := range strings.Split(header, ";") {
+		fields := strings.Fields(clause)
+		if len(fields) == 0 {
+			continue
+		}
+		if out.AuthServID == "" {
+			// ARC headers lead with the instance number
+			if strings.HasPrefix(fields[0], "i=") {
+				continue
+			}
+			out.AuthServID = strings.ToLower(fields[0])
+			continue
+		}
+		method, result, ok := strings.Cut(fields[0], "=")
+		if !ok {
+			// "none" - no methods were checked
+			continue
+		}
+		r := AuthenticationResult{
+			Method:     strings.ToLower(method),
+			Result:     strings.ToLower(result),
+			Properties: map[string]string{},
+		}
+		for _, field := range fields[1:] {
+			if key, value, ok := strings.Cut(field, "="); ok {
+				r.Properties[strings.ToLower(key)] = strings.Trim(value, `"`)
+			}
+		}
+		out.Results = append(out.Results, r)
+	}
+	if out.AuthServID == "" {
+		return AuthenticationResults{}, fmt.Errorf("malformed authentication results: %s", header)
+	}
+	return out, nil
+}
+
+func (a AuthenticationResults) isTrusted() bool {
+	return a.AuthServID == TRUSTED_AUTHSERV_DOMAIN || strings.HasSuffix(a.AuthServID, "."+TRUSTED_AUTHSERV_DOMAIN)
+}
+
+// stripHeaderComments drops (parenthesized comments), which can nest
+func stripHeaderComments(header string) string {
+	out := &strings.Builder{}
+	depth := 0
+	for _, r := range header {
+		switch {
+		case r == '(':
+			depth += 1
+		case r == ')' && depth > 0:
+			depth -= 1
+		case depth == 0:
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,60 @@
+package mail_test
+
+import (
+	"github.com/onsi/disco/mail"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuthenticationResults", func() {
+	parse := func(header string) mail.AuthenticationResults {
+		GinkgoHelper()
+		results, err := mail.ParseAuthenticationResults(header)
+		Ω(err).ShouldNot(HaveOccurred())
+		return results
+	}
+
+	It("parses folded headers, ignoring comments", func() {
+		results := parse("Authentication-Results: mx1.forwardemail.net;\r\n dkim=pass header.i=@gmail.com header.s=20230601;\r\n spf=pass (mx1.forwardemail.net: domain of (nested) a@gmail.com) smtp.mailfrom=a@gmail.com\r\n smtp.helo=mail.google.com;\r\n dmarc=fail (p=NONE) header.from=gmail.com")
+		Ω(results.AuthServID).Should(Equal("mx1.forwardemail.net"))
+		Ω(results.Results).Should(HaveLen(3))
+		Ω(results.Results[0]).Should(Equal(mail.AuthenticationResult{Method: "dkim", Result: "pass", Properties: map[string]string{"header.i": "@gmail.com", "header.s": "20230601"}}))
+		Ω(results.Results[1].Properties).Should(Equal(map[string]string{"smtp.mailfrom": "a@gmail.com", "smtp.helo": "mail.google.com"}))
+		Ω(results.Results[2].IsPass()).Should(BeFalse())
+	})
+
+	It("skips the instance number on ARC headers", func() {
+		results := parse("ARC-Authentication-Results: i=1; mx2.forwardemail.net; spf=pass smtp.mailfrom=a@gmail.com")
+		Ω(results.AuthServID).Should(Equal("mx2.forwardemail.net"))
+		Ω(results.Results).Should(HaveLen(1))
+	})
+
+	It("handles servers that checked nothing", func() {
+		results := parse("Authentication-Results: mx1.forwardemail.net; none")
+		Ω(results.Results).Should(BeEmpty())
+		Ω(results.AlignedWith("a@gmail.com")).Should(BeFalse())
+	})
+
+	It("errors when there's no authserv-id", func() {
+		_, err := mail.ParseAuthenticationResults("Authentication-Results:   ")
+		Ω(err).Should(HaveOccurred())
+	})
+
+	DescribeTable("alignment", func(header string, address string, expected bool) {
+		Ω(parse(header).AlignedWith(mail.EmailAddress(address))).Should(Equal(expected))
+	},
+		Entry("dmarc pass", "mx1.forwardemail.net; dmarc=pass header.from=example.com", "Boss <boss@example.com>", true),
+		Entry("dkim pass via header.d", "mx1.forwardemail.net; dkim=pass header.d=example.com", "boss@example.com", true),
+		Entry("dkim pass via header.i", "mx1.forwardemail.net; dkim=pass header.i=@example.com", "boss@example.com", true),
+		Entry("spf pass", "mx1.forwardemail.net; spf=pass smtp.mailfrom=bounces@example.com", "boss@example.com", true),
+		Entry("relaxed alignment with a subdomain", "mx1.forwardemail.net; dkim=pass header.d=mail.example.com", "boss@example.com", true),
+		Entry("passes for someone else's domain", "mx1.forwardemail.net; dkim=pass header.d=attacker.com; spf=pass smtp.mailfrom=x@attacker.com", "boss@example.com", false),
+		Entry("lookalike domains don't align", "mx1.forwardemail.net; dkim=pass header.d=notexample.com", "boss@example.com", false),
+		Entry("failures", "mx1.forwardemail.net; dkim=fail header.d=example.com; spf=softfail smtp.mailfrom=boss@example.com; dmarc=fail header.from=example.com", "boss@example.com", false),
+	)
+
+	It("is never aligned when there are no results", func() {
+		Ω(mail.AuthenticationResults{}.AlignedWith("boss@example.com")).Should(BeFalse())
+		Ω(mail.AuthenticationResults{}.String()).Should(Equal("no authentication results"))
+	})
+})
@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"regexp"
+	"strings"
+)
+
+// the subjects out-of-office responders use - in the languages our players' mail clients speak
+var autoReplySubjectRegex = regexp.MustCompile(`(?i)^\s*(out of (the )?office|automatic reply|auto[- ]?reply|auto[- ]?response|autoresponder|away from (my|the) (desk|office)|vacation (reply|response)|abwesenheitsnotiz|automatische antwort|réponse automatique|respuesta automática|resposta automática|risposta automatica)($|[\s:!.,-])`)
+
+// detectAutoReply returns why we think a machine sent the e-mail on someone's behalf (an out-of-office, a vacation responder...), or
+// "" if it looks like a person wrote it
+func detectAutoReply(subject string, headers []forwardEmailHeader) string {
+	isList := false
+	for _, header := range headers {
+		if header.Key == "list-id" {
+			isList = true
+		}
+	}
+	for _, header := range headers {
+		value := strings.ToLower(headerValue(header))
+		switch header.Key {
+		case "auto-submitted":
+			// RFC 3834: anything but "no" means no human was involved
+			if value != "" && value != "no" {
+				return "Auto-Submitted: " + value
+			}
+		case "x-autoreply", "x-autorespond":
+			return header.Line
+		case "precedence":
+			// mailing lists mark everything they send as bulk or list - including real replies from real players
+			if !isList && (value == "bulk" || value == "junk" || value == "auto_reply") {
+				return "Precedence: " + value
+			}
+		}
+	}
+	if autoReplySubjectRegex.MatchString(subject) {
+		return "Subject: " + subject
+	}
+	return ""
+}
+
+func (e Email) IsAutoReply() bool {
+	return e.AutoReply != ""
+}
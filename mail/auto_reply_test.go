@@ -0,0 +1,63 @@
+package mail_test
+
+import (
+	"encoding/json"
+
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Detecting auto-replies", func() {
+	var db *s3db.FakeS3DB
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+	})
+
+	parse := func(headers string) mail.Email {
+		GinkgoHelper()
+		email, err := mail.ParseRawEmail(db, []byte("From: player@example.com\r\nTo: saturday-disco@sedenverultimate.net\r\n"+headers+"\r\nI'm out until Monday."), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		return email
+	}
+
+	DescribeTable("spotting autoresponders", func(headers string, expected string) {
+		email := parse(headers)
+		Ω(email.AutoReply).Should(Equal(expected))
+		Ω(email.IsAutoReply()).Should(Equal(expected != ""))
+	},
+		Entry("Auto-Submitted", "Subject: Re: Saturday Bible Park Frisbee\r\nAuto-Submitted: auto-replied\r\n", "Auto-Submitted: auto-replied"),
+		Entry("Auto-Submitted: no", "Subject: Re: Saturday Bible Park Frisbee\r\nAuto-Submitted: no\r\n", ""),
+		Entry("X-Autoreply", "Subject: Re: Saturday Bible Park Frisbee\r\nX-Autoreply: yes\r\n", "X-Autoreply: yes"),
+		Entry("Precedence: bulk", "Subject: Re: Saturday Bible Park Frisbee\r\nPrecedence: bulk\r\n", "Precedence: bulk"),
+		Entry("Precedence: bulk from a mailing list", "Subject: Re: Saturday Bible Park Frisbee\r\nPrecedence: bulk\r\nList-Id: <saturday.googlegroups.com>\r\n", ""),
+		Entry("Outlook", "Subject: Automatic reply: Saturday Bible Park Frisbee\r\n", "Subject: Automatic reply: Saturday Bible Park Frisbee"),
+		Entry("Out of Office", "Subject: Out of Office\r\n", "Subject: Out of Office"),
+		Entry("German", "Subject: Abwesenheitsnotiz: Saturday Bible Park Frisbee\r\n", "Subject: Abwesenheitsnotiz: Saturday Bible Park Frisbee"),
+		Entry("Spanish", "Subject: Respuesta automática: Saturday Bible Park Frisbee\r\n", "Subject: Respuesta automática: Saturday Bible Park Frisbee"),
+		Entry("a player", "Subject: Re: Saturday Bible Park Frisbee\r\n", ""),
+		Entry("a player who's out of the office", "Subject: Re: Out of office on Saturday\r\n", ""),
+	)
+
+	It("reads forwardemail's header lines too", func() {
+		data, err := json.Marshal(map[string]any{
+			"from":        map[string]any{"value": []map[string]string{{"address": "player@example.com"}}},
+			"subject":     "Re: Saturday Bible Park Frisbee",
+			"text":        "I'm out until Monday.",
+			"headerLines": []map[string]string{{"key": "auto-submitted", "line": "Auto-Submitted: auto-replied"}},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		email, err := mail.ParseIncomingEmail(db, data, GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.AutoReply).Should(Equal("Auto-Submitted: auto-replied"))
+	})
+
+	It("leaves the e-mails we get from real players alone", func() {
+		for _, fixture := range []string{"email_from_ios.json", "reply_from_gmail_app.json", "reply_from_ios_mail.json", "mailing_list_reply_all.email", "mailing_list_reply_disco.email"} {
+			email, err := mail.ParseIncomingEmail(db, loadEmailFixture(fixture), GinkgoWriter)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(email.IsAutoReply()).Should(BeFalse(), fixture)
+		}
+	})
+})
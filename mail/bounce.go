@@ -0,0 +1,169 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// Bounce is what we could make of a delivery status notification - a mail server telling us an e-mail we sent didn't arrive
+type Bounce struct {
+	// who the e-mail couldn't be delivered to - empty if the notification didn't say in a way we understand
+	FailedRecipients EmailAddresses
+	// the enhanced status code (e.g. 5.1.1) and whatever the receiving server had to say for itself
+	Status     string
+	Diagnostic string
+	// the notification only reports a delay - the server is still trying
+	Delayed bool
+	// the Message-ID of the e-mail that bounced - the discos only believe bounces for e-mails they actually sent
+	OriginalMessageID string
+}
+
+func (b Bounce) IsZero() bool {
+	return len(b.FailedRecipients) == 0 && b.Status == "" && b.Diagnostic == "" && !b.Delayed
+}
+
+// Permanent is true unless the server said the failure was temporary (a 4.x.x status).  Notifications that don't give a status are
+// almost always for hard bounces.
+func (b Bounce) Permanent() bool {
+	return !strings.HasPrefix(b.Status, "4")
+}
+
+func (b Bounce) Reason() string {
+	return strings.TrimSpace(b.Status + " " + b.Diagnostic)
+}
+
+func (b Bounce) dup() Bounce {
+	if b.FailedRecipients != nil {
+		b.FailedRecipients = b.FailedRecipients.dup()
+	}
+	return b
+}
+
+func (e Email) IsBounce() bool {
+	return !e.Bounce.IsZero()
+}
+
+var mailerDaemonRegex = regexp.MustCompile(`(?i)^(mailer-daemon|postmaster|mail-daemon)@`)
+var bounceSubjectRegex = regexp.MustCompile(`(?i)(undeliver|undelivered mail|delivery (status notification|failure|has failed|incomplete)|mail delivery (failed|failure|subsystem)|returned mail|failure notice|could not be delivered|address not found)`)
+var dsnPrefixRegex = regexp.MustCompile(`^[a-zA-Z0-9-]+;\s*`)
+var returnedMessageIDRegex = regexp.MustCompile(`(?im)^message-id:\s*(<[^<>\s]+>)`)
+
+// detectBounce decides whether an incoming e-mail is a delivery status notification.  Only mail servers send those - from a null
+// sender (Return-Path: <>) or a mailer daemon - so anything else is never a bounce, however it's dressed up: anyone can add an
+// X-Failed-Recipients header.  Beyond that we look for, in order of how much we trust them: an RFC 3464 message/delivery-status report,
+// an X-Failed-Recipients header (exim, gmail), and finally a mailer daemon sending us something with a bounce-y subject.
+func detectBounce(email Email, headers []forwardEmailHeader, body string) Bounce {
+	isReport, isNullSender := false, false
+	for _, header := range headers {
+		switch header.Key {
+		case "content-type":
+			mediaType, params, err := mime.ParseMediaType(headerValue(header))
+			isReport = err == nil && mediaType == "multipart/report" && strings.EqualFold(params["report-type"], "delivery-status")
+		case "return-path":
+			isNullSender = headerValue(header) == "<>"
+		}
+	}
+	if !isNullSender && !mailerDaemonRegex.MatchString(email.From.Address()) {
+		return Bounce{}
+	}
+
+	bounce := deliveryStatusFrom(email.Subject, headers, email.Attachments, isReport)
+	if !bounce.IsZero() {
+		bounce.OriginalMessageID = returnedMessageID(email, body)
+	}
+	return bounce
+}
+
+// deliveryStatusFrom works out who the e-mail bounced for, and why
+func deliveryStatusFrom(subject string, headers []forwardEmailHeader, attachments Attachments, isReport bool) Bounce {
+	for _, attachment := range attachments {
+		if attachment.ContentType == "message/delivery-status" || attachment.ContentType == "message/global-delivery-status" {
+			if bounce := parseDeliveryStatus(attachment.Content); !bounce.IsZero() {
+				return bounce
+			}
+		}
+	}
+
+	bounce := Bounce{}
+	for _, header := range headers {
+		if header.Key != "x-failed-recipients" {
+			continue
+		}
+		for _, address := range strings.Split(headerValue(header), ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				bounce.FailedRecipients = append(bounce.FailedRecipients, EmailAddress(address))
+			}
+		}
+	}
+	if bounce.IsZero() && (isReport || bounceSubjectRegex.MatchString(subject)) {
+		// we know it bounced, just not for whom - the boss will have to read it
+		bounce.Diagnostic = subject
+	}
+	return bounce
+}
+
+// returnedMessageID finds the Message-ID of the e-mail that bounced: in the headers the mail server sent back (as a message/rfc822 or
+// text/rfc822-headers part, or pasted into the body), or failing that in the bounce's own In-Reply-To and References
+func returnedMessageID(email Email, body string) string {
+	for _, attachment := range email.Attachments {
+		switch attachment.ContentType {
+		case "message/rfc822", "text/rfc822-headers", "message/rfc822-headers", "message/global", "message/global-headers":
+			if match := returnedMessageIDRegex.FindStringSubmatch(string(attachment.Content)); match != nil {
+				return match[1]
+			}
+		}
+	}
+	if match := returnedMessageIDRegex.FindStringSubmatch(body); match != nil {
+		return match[1]
+	}
+	if thread := email.Thread(); len(thread) > 0 {
+		return thread[0]
+	}
+	return ""
+}
+
+// parseDeliveryStatus reads the per-recipient fields of a message/delivery-status part - a block of per-message fields followed by
+// a block for each recipient, separated by blank lines
+func parseDeliveryStatus(content []byte) Bounce {
+	bounce := Bounce{}
+	blocks := bytes.Split(bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")), []byte("\n\n"))
+	for _, block := range blocks {
+		reader := io.MultiReader(bytes.NewReader(bytes.TrimSpace(block)), strings.NewReader("\n\n"))
+		fields, err := textproto.NewReader(bufio.NewReader(reader)).ReadMIMEHeader()
+		if err != nil && len(fields) == 0 {
+			continue
+		}
+		action := strings.ToLower(strings.TrimSpace(fields.Get("Action")))
+		recipient := dsnPrefixRegex.ReplaceAllString(strings.TrimSpace(fields.Get("Final-Recipient")), "")
+		if recipient == "" {
+			recipient = dsnPrefixRegex.ReplaceAllString(strings.TrimSpace(fields.Get("Original-Recipient")), "")
+		}
+		switch action {
+		case "failed":
+			if recipient != "" {
+				bounce.FailedRecipients = append(bounce.FailedRecipients, EmailAddress(recipient))
+			}
+			if bounce.Status == "" {
+				bounce.Status = strings.TrimSpace(fields.Get("Status"))
+				bounce.Diagnostic = dsnPrefixRegex.ReplaceAllString(strings.Join(strings.Fields(fields.Get("Diagnostic-Code")), " "), "")
+			}
+		case "delayed":
+			bounce.Delayed = true
+		}
+	}
+	if len(bounce.FailedRecipients) > 0 || bounce.Status != "" {
+		bounce.Delayed = false
+	}
+	return bounce
+}
+
+// headerValue strips the key off a header line and unfolds it
+func headerValue(header forwardEmailHeader) string {
+	_, value, _ := strings.Cut(header.Line, ":")
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,97 @@
+package mail_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Detecting bounces", func() {
+	var db *s3db.FakeS3DB
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+	})
+
+	parse := func(fixture string) mail.Email {
+		GinkgoHelper()
+		email, err := mail.ParseRawEmail(db, loadEmailFixture(fixture), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		return email
+	}
+
+	It("reads the failed recipients out of a delivery status report", func() {
+		email := parse("raw_bounce_dsn.eml")
+		Ω(email.IsBounce()).Should(BeTrue())
+		Ω(email.Bounce.FailedRecipients).Should(Equal(mail.EmailAddresses{"gone@example.com", "also-gone@example.com"}))
+		Ω(email.Bounce.Status).Should(Equal("5.1.1"))
+		Ω(email.Bounce.Diagnostic).Should(Equal("550 5.1.1 <gone@example.com>: Recipient address rejected: User unknown"))
+		Ω(email.Bounce.Permanent()).Should(BeTrue())
+		Ω(email.Bounce.Delayed).Should(BeFalse())
+		Ω(email.Bounce.OriginalMessageID).Should(Equal("<saturday.2023-09-30.2.invite_sent@sedenverultimate.net>"))
+	})
+
+	It("notices when the report is just a delay", func() {
+		email := parse("raw_bounce_delayed.eml")
+		Ω(email.IsBounce()).Should(BeTrue())
+		Ω(email.Bounce.Delayed).Should(BeTrue())
+		Ω(email.Bounce.FailedRecipients).Should(BeEmpty())
+	})
+
+	It("uses the X-Failed-Recipients header", func() {
+		email := parse("raw_bounce_exim.eml")
+		Ω(email.IsBounce()).Should(BeTrue())
+		Ω(email.Bounce.FailedRecipients).Should(Equal(mail.EmailAddresses{"gone@example.org"}))
+		Ω(email.Bounce.Permanent()).Should(BeTrue())
+		Ω(email.Bounce.OriginalMessageID).Should(Equal("<saturday.2023-09-30.2.invite_sent@sedenverultimate.net>"))
+	})
+
+	It("recognizes a mailer daemon's bounce even when it doesn't say who bounced", func() {
+		email := parse("raw_bounce_heuristic.eml")
+		Ω(email.IsBounce()).Should(BeTrue())
+		Ω(email.Bounce.FailedRecipients).Should(BeEmpty())
+		Ω(email.Bounce.Reason()).Should(Equal("Undeliverable: Saturday Bible Park Frisbee 9/30"))
+		Ω(email.Bounce.OriginalMessageID).Should(Equal("<lunchtime.2023-09-30.1.invite_sent@sedenverultimate.net>"))
+	})
+
+	It("doesn't mistake a player talking about bounces for a bounce", func() {
+		email, err := mail.ParseRawEmail(db, []byte("From: player@example.com\r\nSubject: Undeliverable?\r\n\r\nI never got the invite!"), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.IsBounce()).Should(BeFalse())
+		Ω(parse("raw_multipart_alternative.eml").IsBounce()).Should(BeFalse())
+	})
+
+	It("doesn't believe bounces that don't come from a mail server, however they're dressed up", func() {
+		forged := "From: player@example.com\r\nSubject: Undelivered Mail Returned to Sender\r\nX-Failed-Recipients: victim@example.com\r\n\r\nSorry."
+		email, err := mail.ParseRawEmail(db, []byte(forged), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.IsBounce()).Should(BeFalse())
+
+		email, err = mail.ParseRawEmail(db, []byte("Return-Path: <>\r\n"+forged), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.Bounce.FailedRecipients).Should(Equal(mail.EmailAddresses{"victim@example.com"}))
+	})
+
+	It("works with forwardemail's payloads too", func() {
+		status := "Reporting-MTA: dns; mx1.forwardemail.net\r\n\r\nFinal-Recipient: rfc822; gone@example.com\r\nAction: failed\r\nStatus: 5.1.1\r\n"
+		data, err := json.Marshal(map[string]any{
+			"from":        map[string]any{"value": []map[string]string{{"address": "MAILER-DAEMON@mx1.forwardemail.net", "name": "Mail Delivery System"}}},
+			"to":          map[string]any{"value": []map[string]string{{"address": "saturday-disco@sedenverultimate.net"}}},
+			"subject":     "Undelivered Mail Returned to Sender",
+			"messageId":   "<bounce@mx1.forwardemail.net>",
+			"inReplyTo":   "<saturday.2023-09-30.2.invite_sent@sedenverultimate.net>",
+			"text":        "I'm sorry to have to inform you that your message could not be delivered.",
+			"headerLines": []map[string]string{{"key": "content-type", "line": "Content-Type: multipart/report; report-type=delivery-status;\r\n boundary=\"abc\""}},
+			"attachments": []map[string]string{{"contentType": "message/delivery-status", "content": base64.StdEncoding.EncodeToString([]byte(status))}},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		email, err := mail.ParseIncomingEmail(db, data, GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.Bounce.FailedRecipients).Should(Equal(mail.EmailAddresses{"gone@example.com"}))
+		Ω(email.Bounce.Status).Should(Equal("5.1.1"))
+		Ω(email.Bounce.OriginalMessageID).Should(Equal("<saturday.2023-09-30.2.invite_sent@sedenverultimate.net>"))
+	})
+})
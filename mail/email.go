@@ -16,7 +16,11 @@ type Markdown string
 type Email struct {
 	MessageID string
 	InReplyTo string
-	DebugKey  string
+	// the thread's Message-IDs, oldest first
+	References []string
+	DebugKey   string
+	// IdempotencyKey, if set, makes sure a durable outbox only ever delivers this e-mail once
+	IdempotencyKey string
 
 	From    EmailAddress
 	To      EmailAddresses
@@ -26,13 +30,25 @@ type Email struct {
 
 	Text string
 	HTML string
+
+	Attachments Attachments
+
+	// what our mail server made of the sender's DKIM/SPF/DMARC - only set on incoming e-mail
+	Authentication AuthenticationResults
+	// set when the incoming e-mail is a delivery status notification for something we sent
+	Bounce Bounce
+	// why we think the incoming e-mail came from an out-of-office or other autoresponder - empty if a person sent it
+	AutoReply string
 }
 
 func (e Email) Dup() Email {
 	return Email{
-		MessageID: e.MessageID,
-		InReplyTo: e.InReplyTo,
-		DebugKey:  e.DebugKey,
+		MessageID:  e.MessageID,
+		InReplyTo:  e.InReplyTo,
+		References: append([]string(nil), e.References...),
+		DebugKey:   e.DebugKey,
+
+		IdempotencyKey: e.IdempotencyKey,
 
 		From:    e.From,
 		To:      e.To.dup(),
@@ -42,6 +58,12 @@ func (e Email) Dup() Email {
 
 		Text: e.Text,
 		HTML: e.HTML,
+
+		Attachments: e.Attachments.dup(),
+
+		Authentication: e.Authentication.dup(),
+		Bounce:         e.Bounce.dup(),
+		AutoReply:      e.AutoReply,
 	}
 }
 
@@ -50,7 +72,11 @@ func (e Email) IsZero() bool {
 }
 
 func (e Email) String() string {
-	return fmt.Sprintf("From: %s on %s\nTo: %s\nCC: %s\nSubject: %s\nDebug Key: %s\n\n%s", e.From, e.Date, e.To, e.CC, e.Subject, e.DebugKey, e.Text)
+	out := fmt.Sprintf("From: %s on %s\nTo: %s\nCC: %s\nSubject: %s\nDebug Key: %s\n\n%s", e.From, e.Date, e.To, e.CC, e.Subject, e.DebugKey, e.Text)
+	if len(e.Attachments) > 0 {
+		out += "\n\nAttachments: " + e.Attachments.String()
+	}
+	return out
 }
 
 func stripMarkdown(md Markdown) string {
@@ -134,6 +160,13 @@ func (e Email) AndCC(cc ...EmailAddress) Email {
 	return e
 }
 
+// WithIdempotencyKey also derives the Message-ID from the key, so we can tell which of our e-mails a reply or bounce is about
+func (e Email) WithIdempotencyKey(key string) Email {
+	e.IdempotencyKey = key
+	e.MessageID = MessageIDFor(key, e.From)
+	return e
+}
+
 func (e Email) WithSubject(subject string) Email {
 	e.Subject = subject
 	return e
@@ -154,6 +187,17 @@ func (e Email) WithBody(body any) Email {
 	return e
 }
 
+func (e Email) WithAttachment(attachment Attachment) Email {
+	e.Attachments = append(e.Attachments.dup(), attachment)
+	return e
+}
+
+// WithInlineAttachment attaches content that the HTML body refers to as cid:<cid>, e.g. <img src="cid:map">
+func (e Email) WithInlineAttachment(cid string, attachment Attachment) Email {
+	attachment.CID = cid
+	return e.WithAttachment(attachment)
+}
+
 func replySubject(subject string) string {
 	if strings.HasPrefix(subject, "Re: ") {
 		return subject
@@ -169,19 +213,20 @@ func (e Email) Forward(from EmailAddress, to EmailAddress, body any) Email {
 		Subject: "Fwd: " + e.Subject,
 		Text:    text,
 		HTML:    html,
+
+		Attachments: e.Attachments.dup(),
 	}
 }
 
 func (e Email) Reply(from EmailAddress, body any) Email {
 	text, html := synthesizeReplyBodies(e, body)
 	return Email{
-		InReplyTo: e.MessageID,
-		From:      from,
-		To:        EmailAddresses{e.From},
-		Subject:   replySubject(e.Subject),
-		Text:      text,
-		HTML:      html,
-	}
+		From:    from,
+		To:      EmailAddresses{e.From},
+		Subject: replySubject(e.Subject),
+		Text:    text,
+		HTML:    html,
+	}.InThreadOf(e)
 }
 
 func (e Email) ReplyAll(from EmailAddress, body any) Email {
@@ -198,23 +243,21 @@ func (e Email) ReplyAll(from EmailAddress, body any) Email {
 		}
 	}
 	return Email{
-		InReplyTo: e.MessageID,
-		From:      from,
-		To:        EmailAddresses{e.From},
-		CC:        ccs,
-		Subject:   replySubject(e.Subject),
-		Text:      text,
-		HTML:      html,
-	}
+		From:    from,
+		To:      EmailAddresses{e.From},
+		CC:      ccs,
+		Subject: replySubject(e.Subject),
+		Text:    text,
+		HTML:    html,
+	}.InThreadOf(e)
 }
 
 func (e Email) ReplyWithoutQuote(from EmailAddress, body any) Email {
 	return Email{
-		InReplyTo: e.MessageID,
-		From:      from,
-		To:        EmailAddresses{e.From},
-		Subject:   replySubject(e.Subject),
-	}.WithBody(body)
+		From:    from,
+		To:      EmailAddresses{e.From},
+		Subject: replySubject(e.Subject),
+	}.InThreadOf(e).WithBody(body)
 }
 func (e Email) ReplyAllWithoutQuote(from EmailAddress, body any) Email {
 	ccs := EmailAddresses{}
@@ -229,12 +272,11 @@ func (e Email) ReplyAllWithoutQuote(from EmailAddress, body any) Email {
 		}
 	}
 	return Email{
-		InReplyTo: e.MessageID,
-		From:      from,
-		To:        EmailAddresses{e.From},
-		CC:        ccs,
-		Subject:   replySubject(e.Subject),
-	}.WithBody(body)
+		From:    from,
+		To:      EmailAddresses{e.From},
+		CC:      ccs,
+		Subject: replySubject(e.Subject),
+	}.InThreadOf(e).WithBody(body)
 }
 
 func (e Email) Recipients() EmailAddresses {
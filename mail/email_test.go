@@ -81,6 +81,35 @@ var _ = Describe("Email", func() {
 		Ω(email.CC[0]).Should(Equal(EmailAddress("onemore@example.com")))
 	})
 
+	It("can carry attachments", func() {
+		email := E().WithFrom("onsijoe@gmail.com").WithTo("player@example.com").AndCC("another@example.com").WithAttachment(Attachment{
+			Filename:    "invite.ics",
+			ContentType: "text/calendar; charset=utf-8",
+			Content:     []byte("BEGIN:VCALENDAR"),
+		})
+		withTwo := email.WithAttachment(Attachment{Filename: "notes.txt", ContentType: "text/plain", Content: []byte("hi")})
+		Ω(email.Attachments).Should(HaveLen(1))
+		Ω(withTwo.Attachments).Should(HaveLen(2))
+
+		clone := withTwo.Dup()
+		Ω(clone).Should(Equal(withTwo))
+		clone.Attachments[0].Content[0] = 'X'
+		Ω(string(withTwo.Attachments[0].Content)).Should(Equal("BEGIN:VCALENDAR"))
+	})
+
+	It("can carry inline attachments that the HTML body refers to by CID", func() {
+		email := E().WithBody(Markdown("![the field](cid:field-map)")).WithInlineAttachment("field-map", Attachment{
+			Filename:    "map.png",
+			ContentType: "image/png",
+			Content:     []byte{0x89, 0x50, 0x4e, 0x47},
+		})
+		Ω(email.HTML).Should(ContainSubstring(`src="cid:field-map"`))
+		attachment, ok := email.Attachments.Named("map.png")
+		Ω(ok).Should(BeTrue())
+		Ω(attachment.IsInline()).Should(BeTrue())
+		Ω(email.String()).Should(HaveSuffix("Attachments: map.png (image/png, 4 bytes) inline as cid:field-map"))
+	})
+
 	Describe("Replying to e-mails", func() {
 		var email Email
 		BeforeEach(func() {
@@ -107,6 +136,14 @@ var _ = Describe("Email", func() {
 			Ω(email.HTML).Should(Equal("<p>Got <strong>your</strong> message.</p>\n\n<p><em>Thanks!</em></p>\n\n<div><blockquote type=\"cite\">On Sun, 24 Sep 2023 13:48:58 -0600, onsijoe@gmail.com wrote:<br><br></blockquote></div>\n<blockquote type=\"cite\"><div>My **original** text<br>Is _here_!</div></blockquote>\n"))
 		})
 
+		It("threads the reply onto the original", func() {
+			email.References = []string{"<root-id>"}
+			reply := email.Reply("disco@sedenverultimate.net", "Got it")
+			Ω(reply.References).Should(Equal([]string{"<root-id>", "<original-id>"}))
+			Ω(reply.ReplyAll("onsijoe@gmail.com", "Thanks").References).Should(Equal([]string{"<root-id>", "<original-id>"}), "the reply has no Message-ID of its own yet")
+			Ω(email.References).Should(Equal([]string{"<root-id>"}), "the original is untouched")
+		})
+
 		It("doesn't double up the Res", func() {
 			email = email.Reply("disco@sedenverultimate.net", "A").Reply("foo@example.com", "B")
 			Ω(email.Subject).Should(Equal("Re: Original Subject"))
@@ -181,5 +218,11 @@ var _ = Describe("Email", func() {
 			Ω(email.Text).Should(Equal("Check this out.\n\nOn Sun, 24 Sep 2023 13:48:58 -0600, onsijoe@gmail.com wrote:\n> My **original** text\n> Is _here_!"))
 			Ω(email.HTML).Should(Equal("<p>Check <strong>this</strong> out.</p>\n\n<div><blockquote type=\"cite\">On Sun, 24 Sep 2023 13:48:58 -0600, onsijoe@gmail.com wrote:<br><br></blockquote></div>\n<blockquote type=\"cite\"><div>My **original** text<br>Is _here_!</div></blockquote>\n"))
 		})
+
+		It("forwards attachments along with the e-mail", func() {
+			email = email.WithAttachment(Attachment{Filename: "roster.txt", ContentType: "text/plain", Content: []byte("Onsi")})
+			forwarded := email.Forward("disco@sedenverultimate.net", "thirdparty@example.com", "FYI")
+			Ω(forwarded.Attachments).Should(Equal(email.Attachments))
+		})
 	})
 })
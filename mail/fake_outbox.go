@@ -33,7 +33,10 @@ func (o *FakeOutbox) SendEmail(email Email) error {
 	defer o.lock.Unlock()
 	say.Fpln(o.w, "Sending email:")
 	say.Fplni(o.w, 1, "%s", email)
-	email.MessageID = uuid.New().String()
+	email.Attachments = email.Attachments.dup()
+	if email.MessageID == "" {
+		email.MessageID = uuid.New().String()
+	}
 	o.emails = append(o.emails, email)
 	return o.err
 }
@@ -0,0 +1,168 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type SMTPMessage struct {
+	Username string
+	From     string
+	To       []string
+	Data     []byte
+}
+
+func (m SMTPMessage) Parse() (*netmail.Message, error) {
+	return netmail.ReadMessage(bytes.NewReader(m.Data))
+}
+
+// FakeSMTPServer is a bare-bones SMTP server for integration tests.  It accepts any credentials, doesn't offer STARTTLS, and remembers everything it receives.
+type FakeSMTPServer struct {
+	listener net.Listener
+	messages []SMTPMessage
+	lock     *sync.Mutex
+	wg       *sync.WaitGroup
+}
+
+func NewFakeSMTPServer() (*FakeSMTPServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &FakeSMTPServer{
+		listener: listener,
+		lock:     &sync.Mutex{},
+		wg:       &sync.WaitGroup{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+func (s *FakeSMTPServer) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+func (s *FakeSMTPServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// TransportConfig routes from to this server
+func (s *FakeSMTPServer) TransportConfig(from string) TransportConfig {
+	return TransportConfig{
+		From:      from,
+		Transport: TransportSMTP,
+		Host:      s.Host(),
+		Port:      s.Port(),
+		Security:  SMTPSecurityNone,
+	}
+}
+
+func (s *FakeSMTPServer) Messages() []SMTPMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]SMTPMessage{}, s.messages...)
+}
+
+func (s *FakeSMTPServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *FakeSMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *FakeSMTPServer) handle(conn net.Conn) {
+	c := textproto.NewConn(conn)
+	defer c.Close()
+	message := SMTPMessage{}
+	c.PrintfLine("220 localhost fake disco smtp")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			c.PrintfLine("250-localhost")
+			c.PrintfLine("250-8BITMIME")
+			c.PrintfLine("250 AUTH PLAIN")
+		case "HELO":
+			c.PrintfLine("250 localhost")
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if strings.ToUpper(mechanism) != "PLAIN" {
+				c.PrintfLine("504 unrecognized authentication type")
+				continue
+			}
+			if initial == "" {
+				c.PrintfLine("334 ")
+				if initial, err = c.ReadLine(); err != nil {
+					return
+				}
+			}
+			decoded, err := base64.StdEncoding.DecodeString(initial)
+			parts := strings.Split(string(decoded), "\x00")
+			if err != nil || len(parts) != 3 {
+				c.PrintfLine("501 malformed auth input")
+				continue
+			}
+			message.Username = parts[1]
+			c.PrintfLine("235 authenticated")
+		case "MAIL":
+			message.From = smtpPath(arg)
+			c.PrintfLine("250 ok")
+		case "RCPT":
+			message.To = append(message.To, smtpPath(arg))
+			c.PrintfLine("250 ok")
+		case "DATA":
+			c.PrintfLine("354 go ahead")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			message.Data = data
+			s.lock.Lock()
+			s.messages = append(s.messages, message)
+			s.lock.Unlock()
+			message = SMTPMessage{Username: message.Username}
+			c.PrintfLine("250 ok: queued as " + strconv.Itoa(len(s.Messages())))
+		case "RSET":
+			message = SMTPMessage{Username: message.Username}
+			c.PrintfLine("250 ok")
+		case "NOOP":
+			c.PrintfLine("250 ok")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// smtpPath pulls the address out of FROM:<a@b.com> SIZE=123
+func smtpPath(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	path, _, _ = strings.Cut(strings.TrimSpace(path), " ")
+	return strings.Trim(path, "<>")
+}
@@ -1,11 +1,8 @@
 package mail
 
 import (
-	"context"
 	"fmt"
 	"io"
-	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
@@ -18,76 +15,55 @@ type OutboxInt interface {
 	SendEmail(Email) error
 }
 
-type Outbox struct {
-	forwardEmailKey string
-	gmailUser       string
-	gmailPassword   string
+// Route sends e-mail from matching sender addresses via Transport.  From can be an exact address (onsijoe@gmail.com), a domain (@sedenverultimate.net) or * to match everything.
+type Route struct {
+	From      string
+	Transport Transport
 }
 
-func NewOutbox(forwardEmailKey, gmailUser, gmailPassword string) Outbox {
-	return Outbox{
-		forwardEmailKey: forwardEmailKey,
-		gmailUser:       gmailUser,
-		gmailPassword:   gmailPassword,
+func (r Route) Matches(from EmailAddress) bool {
+	switch {
+	case r.From == "*":
+		return true
+	case strings.HasPrefix(r.From, "@"):
+		return strings.HasSuffix(strings.ToLower(from.Address()), strings.ToLower(r.From))
+	default:
+		return from.Equals(EmailAddress(r.From))
 	}
 }
 
-func (o Outbox) SendEmail(email Email) error {
-	if strings.Contains(email.From.String(), "@sedenverultimate.net") {
-		return o.sendViaForwardEmail(email)
-	} else if email.From.Equals(EmailAddress(o.gmailUser)) {
-		return o.sendViaGmail(email)
-	} else {
-		return fmt.Errorf("unknown e-mail address: %s", email.From)
-	}
+// Outbox hands each e-mail to the transport of the first route that matches its sender
+type Outbox struct {
+	routes []Route
 }
 
-func (o Outbox) sendViaForwardEmail(email Email) error {
-	form := url.Values{}
-	form.Add("from", email.From.String())
-	for _, to := range email.To {
-		form.Add("to", to.String())
-	}
-	for _, cc := range email.CC {
-		form.Add("cc", cc.String())
-	}
-	if email.Subject != "" {
-		form.Add("subject", email.Subject)
-	}
-	if email.InReplyTo != "" {
-		form.Add("inReplyTo", email.InReplyTo)
-	}
-	if email.Text != "" {
-		form.Add("text", email.Text)
-	}
-	if email.HTML != "" {
-		form.Add("html", email.HTML)
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_TIMEOUT)
-	defer cancel()
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.forwardemail.net/v1/emails", strings.NewReader(form.Encode()))
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.SetBasicAuth(o.forwardEmailKey, "")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		issue, err := io.ReadAll(resp.Body)
+func NewOutbox(configs []TransportConfig) (Outbox, error) {
+	routes := []Route{}
+	for _, config := range configs {
+		transport, err := NewTransport(config)
 		if err != nil {
-			return err
+			return Outbox{}, fmt.Errorf("invalid route for %s: %w", config.From, err)
 		}
-		return fmt.Errorf("failed to send e-mail: %d - %s", resp.StatusCode, string(issue))
+		routes = append(routes, Route{From: config.From, Transport: transport})
 	}
-	return nil
+	return NewOutboxWithRoutes(routes...), nil
 }
 
-func (o Outbox) sendViaGmail(email Email) error {
-	d := gomail.NewDialer("smtp.gmail.com", 587, o.gmailUser, o.gmailPassword)
+func NewOutboxWithRoutes(routes ...Route) Outbox {
+	return Outbox{routes: routes}
+}
+
+func (o Outbox) SendEmail(email Email) error {
+	for _, route := range o.routes {
+		if route.Matches(email.From) {
+			return route.Transport.Send(email)
+		}
+	}
+	return fmt.Errorf("unknown e-mail address: %s", email.From)
+}
+
+// buildMessage renders the e-mail as a MIME message for the transports that speak SMTP (or write files)
+func buildMessage(email Email) *gomail.Message {
 	m := gomail.NewMessage()
 	m.SetHeader("From", email.From.String())
 	if (len(email.To)) > 0 {
@@ -97,9 +73,34 @@ func (o Outbox) sendViaGmail(email Email) error {
 		m.SetHeader("Cc", email.CC.Strings()...)
 	}
 	m.SetHeader("Subject", email.Subject)
+	if email.MessageID != "" {
+		m.SetHeader("Message-ID", email.MessageID)
+	}
+	if email.InReplyTo != "" {
+		m.SetHeader("In-Reply-To", email.InReplyTo)
+	}
+	if len(email.References) > 0 {
+		m.SetHeader("References", strings.Join(email.References, " "))
+	} else if email.InReplyTo != "" {
+		m.SetHeader("References", email.InReplyTo)
+	}
 	m.SetBody("text/plain", email.Text)
 	if email.HTML != "" {
 		m.AddAlternative("text/html", email.HTML)
 	}
-	return d.DialAndSend(m)
+	for _, attachment := range email.Attachments {
+		content := attachment.Content
+		header := map[string][]string{"Content-Type": {attachment.ContentType}}
+		copyFunc := gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(content)
+			return err
+		})
+		if attachment.IsInline() {
+			header["Content-ID"] = []string{"<" + attachment.CID + ">"}
+			m.Embed(attachment.Filename, gomail.SetHeader(header), copyFunc)
+		} else {
+			m.Attach(attachment.Filename, gomail.SetHeader(header), copyFunc)
+		}
+	}
+	return m
 }
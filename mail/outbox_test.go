@@ -17,7 +17,9 @@ var _ = Describe("Outbox", func() {
 			Skip("Skipping outbox tests, set TEST_OUTBOX=true to run")
 		}
 		c := config.LoadConfig()
-		outbox = mail.NewOutbox(c.ForwardEmailKey, c.GmailUser, c.GmailPassword)
+		var err error
+		outbox, err = mail.NewOutbox(c.MailRoutes)
+		Ω(err).ShouldNot(HaveOccurred())
 	})
 
 	It("can send a multipart e-mail from onsi's gmail account", func() {
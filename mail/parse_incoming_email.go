@@ -1,15 +1,14 @@
 package mail
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
-	"math"
-	"regexp"
 	"strings"
 
 	"github.com/google/uuid"
-	strip "github.com/grokify/html-strip-tags-go"
 	"github.com/onsi/say"
 )
 
@@ -42,60 +41,121 @@ type forwardEmailHeader struct {
 	Line string `json:"line"`
 }
 
-type forwardEmailModel struct {
-	From      forwardEmailAddressField `json:"from"`
-	ReplyTo   forwardEmailAddressField `json:"replyTo"`
-	To        forwardEmailAddressField `json:"to"`
-	CC        forwardEmailAddressField `json:"cc"`
-	Subject   string                   `json:"subject"`
-	MessageID string                   `json:"messageId"`
-	Text      string                   `json:"text"`
-	HTML      any                      `json:"html"`
-	Headers   []forwardEmailHeader     `json:"headerLines"`
-}
-
-var emailRegex = `[a-zA-Z0-9._-]+@[a-zA-Z0-9._-]+\.[a-zA-Z0-9_-]+`
-var replyRegexes = []*regexp.Regexp{
-	regexp.MustCompile(`(?m)^>.*`),
-	regexp.MustCompile(`(?m)^\s*On.*(\s?).*@.*wrote:`),
-	regexp.MustCompile(`(?m)^\s*On.*@.*(\s?).*wrote:`),
-	regexp.MustCompile(`(?im)-+\s*(original|forwarded)\s+message\s*-+\s*$`),
-	regexp.MustCompile(`(?im)From:\s*` + emailRegex),
-	regexp.MustCompile(`(?im)` + emailRegex + `\s+wrote:`),
-}
-
-func ExtractTopMostPortionFromHTML(htmlBody string) string {
-	i := strings.Index(htmlBody, "<blockquote")
-	if i >= 0 {
-		htmlBody = htmlBody[:i]
-	}
-	htmlBody = strings.ReplaceAll(htmlBody, "<br", "\n<br")
-	txtBody := strip.StripTags(htmlBody)
-	return ExtractTopMostPortion(txtBody)
-
-}
-
-func ExtractTopMostPortion(fullBody string) string {
-	winner := math.MaxInt
-	for _, regex := range replyRegexes {
-		index := regex.FindStringIndex(fullBody)
-		if index != nil && index[0] < winner {
-			winner = index[0]
+// forwardemail hands us attachment content as a serialized node Buffer ({"type":"Buffer","data":[...]}) but we also accept base64 strings.
+// Content we can't decode doesn't fail the whole e-mail - we note it in err and the attachment gets dropped.
+type forwardEmailAttachmentContent struct {
+	data []byte
+	err  error
+}
+
+func (c *forwardEmailAttachmentContent) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if json.Unmarshal(data, &encoded) == nil {
+		c.data, c.err = base64.StdEncoding.DecodeString(encoded)
+		if c.err != nil {
+			c.err = fmt.Errorf("invalid attachment content: %w", c.err)
 		}
+		return nil
+	}
+	buffer := struct {
+		Type string `json:"type"`
+		Data []int  `json:"data"`
+	}{}
+	if err := json.Unmarshal(data, &buffer); err != nil {
+		c.err = fmt.Errorf("invalid attachment content: %w", err)
+		return nil
+	}
+	c.data = make([]byte, len(buffer.Data))
+	for i, b := range buffer.Data {
+		if b < 0 || b > 255 {
+			c.data, c.err = nil, fmt.Errorf("invalid attachment content: %d isn't a byte", b)
+			return nil
+		}
+		c.data[i] = byte(b)
+	}
+	return nil
+}
+
+type forwardEmailAttachment struct {
+	Filename           string                        `json:"filename"`
+	ContentType        string                        `json:"contentType"`
+	ContentDisposition string                        `json:"contentDisposition"`
+	CID                string                        `json:"cid"`
+	Content            forwardEmailAttachmentContent `json:"content"`
+}
+
+func (a forwardEmailAttachment) asAttachment() Attachment {
+	out := Attachment{
+		Filename:    a.Filename,
+		ContentType: a.ContentType,
+		Content:     a.Content.data,
 	}
-	if winner == math.MaxInt {
-		return fullBody
+	if a.ContentDisposition == "inline" {
+		out.CID = a.CID
 	}
-	return fullBody[:winner]
+	return out
+}
+
+// forwardemail hands us References as a string when there's one Message-ID and an array when there are more
+type forwardEmailReferences []string
+
+func (r *forwardEmailReferences) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*r = parseMessageIDs(single)
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("invalid references: %w", err)
+	}
+	*r = parseMessageIDs(strings.Join(multiple, " "))
+	return nil
+}
+
+type forwardEmailModel struct {
+	From       forwardEmailAddressField `json:"from"`
+	ReplyTo    forwardEmailAddressField `json:"replyTo"`
+	To         forwardEmailAddressField `json:"to"`
+	CC         forwardEmailAddressField `json:"cc"`
+	Subject    string                   `json:"subject"`
+	MessageID  string                   `json:"messageId"`
+	InReplyTo  string                   `json:"inReplyTo"`
+	References forwardEmailReferences   `json:"references"`
+	Text       string                   `json:"text"`
+	HTML       any                      `json:"html"`
+	Headers    []forwardEmailHeader     `json:"headerLines"`
+
+	Attachments []forwardEmailAttachment `json:"attachments"`
 }
 
 type S3DBInt interface {
 	PutObject(key string, data []byte) error
 }
 
-func ParseIncomingEmail(db S3DBInt, data []byte, debug io.Writer) (Email, error) {
-	//upload e-mail to S3 so we can debug?
-	debugKey := "email/" + uuid.New().String()
+// authenticationResultsFrom finds the results our mail server prepended.  Only the topmost header counts: anything below it came from
+// the sender, who can write whatever they like.  We don't fall back to ARC-Authentication-Results: we'd have to verify the ARC seal
+// to know our server wrote it, so a message without an Authentication-Results header simply isn't authenticated.
+func authenticationResultsFrom(headers []forwardEmailHeader) AuthenticationResults {
+	for _, header := range headers {
+		if header.Key != "authentication-results" {
+			continue
+		}
+		results, err := ParseAuthenticationResults(header.Line)
+		if err == nil && results.isTrusted() {
+			return results
+		}
+		break
+	}
+	return AuthenticationResults{}
+}
+
+// ARCHIVE_PREFIX is where storeForDebugging keeps incoming e-mail
+const ARCHIVE_PREFIX = "email/"
+
+// storeForDebugging uploads the raw e-mail to S3 so we can debug it later
+func storeForDebugging(db S3DBInt, data []byte, debug io.Writer) string {
+	debugKey := ARCHIVE_PREFIX + uuid.New().String()
 	say.Fplni(debug, 1, "Email Debugging:  Storing raw email in S3 with key %s", debugKey)
 	go func() {
 		err := db.PutObject(debugKey, data)
@@ -103,7 +163,42 @@ func ParseIncomingEmail(db S3DBInt, data []byte, debug io.Writer) (Email, error)
 			say.Fplni(debug, 2, "{{red}}Email Debugging:  Failed to store key %s{{/}}", debugKey)
 		}
 	}()
+	return debugKey
+}
+
+// senderFrom picks out who actually sent the e-mail - mailing lists send as themselves and put the real sender in Reply-To
+func senderFrom(froms []EmailAddress, replyTos []EmailAddress) (EmailAddress, error) {
+	if len(froms) == 0 {
+		return "", fmt.Errorf("no from address found")
+	}
+	if strings.Contains(froms[0].Address(), "googlegroups.com") {
+		if len(replyTos) == 0 {
+			return "", fmt.Errorf("from address included googlegroups.com, but no reply-to found")
+		}
+		return replyTos[0], nil
+	}
+	return froms[0], nil
+}
+
+func ParseIncomingEmail(db S3DBInt, data []byte, debug io.Writer) (Email, error) {
+	return parseForwardEmail(storeForDebugging(db, data, debug), data)
+}
+
+// ParseStoredEmail re-parses an e-mail storeForDebugging kept under key - either a forwardemail webhook payload or a raw message that
+// came in over SMTP or IMAP.  We didn't trust the authentication results on the raw messages the first time round, so we don't now.
+func ParseStoredEmail(key string, data []byte) (Email, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseForwardEmail(key, data)
+	}
+	email, err := parseRawEmail(key, data)
+	if err != nil {
+		return Email{}, err
+	}
+	email.Authentication = AuthenticationResults{}
+	return email, nil
+}
 
+func parseForwardEmail(debugKey string, data []byte) (Email, error) {
 	model := forwardEmailModel{}
 	err := json.Unmarshal(data, &model)
 	if err != nil {
@@ -112,29 +207,23 @@ func ParseIncomingEmail(db S3DBInt, data []byte, debug io.Writer) (Email, error)
 	out := Email{
 		DebugKey: debugKey,
 	}
-	froms := model.From.asEmailAddresses()
-	if len(froms) == 0 {
-		return Email{}, fmt.Errorf("no from address found")
-	}
-	out.From = froms[0]
-	if strings.Contains(out.From.Address(), "googlegroups.com") {
-		replyTos := model.ReplyTo.asEmailAddresses()
-		if len(replyTos) == 0 {
-			return Email{}, fmt.Errorf("from address included googlegroups.com, but no reply-to found")
-		} else {
-			out.From = replyTos[0]
-		}
+	out.From, err = senderFrom(model.From.asEmailAddresses(), model.ReplyTo.asEmailAddresses())
+	if err != nil {
+		return Email{}, err
 	}
 	out.To = model.To.asEmailAddresses()
 	out.CC = model.CC.asEmailAddresses()
 	out.Subject = model.Subject
 	out.MessageID = model.MessageID
+	out.InReplyTo = model.InReplyTo
+	out.References = model.References
 
 	for _, header := range model.Headers {
 		if header.Key == "date" {
 			out.Date = strings.TrimPrefix(header.Line, "Date: ")
 		}
 	}
+	out.Authentication = authenticationResultsFrom(model.Headers)
 
 	if model.Text != "" {
 		out.Text = ExtractTopMostPortion(model.Text)
@@ -148,5 +237,15 @@ func ParseIncomingEmail(db S3DBInt, data []byte, debug io.Writer) (Email, error)
 			return Email{}, fmt.Errorf("no content found in email")
 		}
 	}
+
+	for _, attachment := range model.Attachments {
+		if attachment.Content.err != nil {
+			// better to lose one attachment than the whole e-mail
+			continue
+		}
+		out.Attachments = append(out.Attachments, attachment.asAttachment())
+	}
+	out.Bounce = detectBounce(out, model.Headers, model.Text)
+	out.AutoReply = detectAutoReply(out.Subject, model.Headers)
 	return out, nil
 }
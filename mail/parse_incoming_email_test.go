@@ -1,6 +1,7 @@
 package mail_test
 
 import (
+	"encoding/json"
 	"os"
 
 	"github.com/onsi/disco/mail"
@@ -71,6 +72,64 @@ var _ = Describe("ParseIncomingEmail", func() {
 		})
 	})
 
+	Describe("extracting attachments", func() {
+		It("decodes attachments and keeps the CID of inline attachments", func() {
+			email, err := mail.ParseIncomingEmail(db, loadEmailFixture("email_with_attachments.json"), GinkgoWriter)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(email.Subject).Should(Equal("Roster attached"))
+			Ω(email.Attachments).Should(Equal(mail.Attachments{
+				{Filename: "roster.txt", ContentType: "text/plain", Content: []byte("Onsi\nJane\n")},
+				{Filename: "map.png", ContentType: "image/png", Content: []byte{0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a}, CID: "field-map@disco"},
+			}))
+		})
+
+		It("drops attachments it can't decode, but keeps the e-mail", func() {
+			model := map[string]any{}
+			Ω(json.Unmarshal(loadEmailFixture("email_with_attachments.json"), &model)).Should(Succeed())
+			model["attachments"] = append(model["attachments"].([]any),
+				map[string]any{"contentType": "text/plain", "filename": "bad-base64.txt", "content": "not base64!"},
+				map[string]any{"contentType": "text/plain", "filename": "bad-buffer.txt", "content": map[string]any{"type": "Buffer", "data": []any{1, "two"}}},
+				map[string]any{"contentType": "text/plain", "filename": "out-of-range.txt", "content": map[string]any{"type": "Buffer", "data": []any{256}}},
+			)
+			data, err := json.Marshal(model)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			email, err := mail.ParseIncomingEmail(db, data, GinkgoWriter)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(email.Subject).Should(Equal("Roster attached"))
+			Ω(email.Attachments).Should(HaveLen(2))
+			roster, found := email.Attachments.Named("roster.txt")
+			Ω(found).Should(BeTrue())
+			Ω(roster.Content).Should(Equal([]byte("Onsi\nJane\n")))
+			_, found = email.Attachments.Named("bad-base64.txt")
+			Ω(found).Should(BeFalse())
+		})
+
+		It("has no attachments when there are none", func() {
+			email, err := mail.ParseIncomingEmail(db, loadEmailFixture("email_from_ios.json"), GinkgoWriter)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(email.Attachments).Should(BeEmpty())
+		})
+	})
+
+	Describe("extracting authentication results", func() {
+		It("extracts the results our mail server prepended", func() {
+			email, err := mail.ParseIncomingEmail(db, loadEmailFixture("email_from_ios.json"), GinkgoWriter)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(email.Authentication.AuthServID).Should(Equal("mx1.forwardemail.net"))
+			Ω(email.Authentication.String()).Should(Equal("dkim=pass spf=pass dmarc=pass bimi=skipped (by mx1.forwardemail.net)"))
+			Ω(email.Authentication.AlignedWith(email.From)).Should(BeTrue())
+			Ω(email.Authentication.AlignedWith("Boss <boss@example.com>")).Should(BeFalse())
+		})
+
+		It("doesn't fall back to the ARC results when there's no Authentication-Results header - nothing checks the seal", func() {
+			email, err := mail.ParseIncomingEmail(db, loadEmailFixture("html_only_ios_email.json"), GinkgoWriter)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(email.Authentication).Should(BeZero())
+			Ω(email.Authentication.AlignedWith("example-user@gmail.com")).Should(BeFalse())
+		})
+	})
+
 	Describe("extracting bodies", func() {
 		It("only extracts the text portion, ignoring HTML, and it grabs everything if this email is not a reply", func() {
 			email, err := mail.ParseIncomingEmail(db, loadEmailFixture("email_from_ios.json"), GinkgoWriter)
@@ -90,6 +149,8 @@ var _ = Describe("ParseIncomingEmail", func() {
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(email.Text).Should(Equal("And this is another rely… from the *Gmail App*.\n"))
 				Ω(email.HTML).Should(BeZero())
+				Ω(email.InReplyTo).Should(Equal("<4C72D641-DF9D-4515-86F2-291C4DB41031@gmail.com>"))
+				Ω(email.References).Should(Equal([]string{"<C81E9CFE-81FC-477B-A3EA-1F6AB18870B4@gmail.com>", "<4C72D641-DF9D-4515-86F2-291C4DB41031@gmail.com>"}))
 			})
 		})
 		Context("when the e-mail is a forward", func() {
@@ -112,9 +173,46 @@ var _ = Describe("ParseIncomingEmail", func() {
 			It("grabs the html and strips out all the tags", func() {
 				email, err := mail.ParseIncomingEmail(db, loadEmailFixture("html_only_ios_email.json"), GinkgoWriter)
 				Ω(err).ShouldNot(HaveOccurred())
-				Ω(email.Text).Should(Equal("I’m in\nSent from my iPhone\n"))
+				Ω(email.Text).Should(Equal("I’m in\n"), "the mobile signature is stripped too")
 				Ω(email.HTML).Should(BeZero())
 			})
 		})
 	})
 })
+
+var _ = Describe("ParseStoredEmail", func() {
+	var db *s3db.FakeS3DB
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+	})
+
+	It("re-parses forwardemail payloads just as they were parsed the first time", func() {
+		data := loadEmailFixture("reply_from_gmail_app.json")
+		original, err := mail.ParseIncomingEmail(db, data, GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Eventually(db.FetchObject).WithArguments(original.DebugKey).Should(Equal(data))
+
+		reparsed, err := mail.ParseStoredEmail(original.DebugKey, data)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(reparsed).Should(Equal(original))
+	})
+
+	It("re-parses raw messages, without trusting their authentication results", func() {
+		data := loadEmailFixture("raw_multipart_alternative.eml")
+		original, err := mail.ParseRawEmail(db, data, GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(original.Authentication.AuthServID).ShouldNot(BeZero())
+
+		reparsed, err := mail.ParseStoredEmail(original.DebugKey, data)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(reparsed.DebugKey).Should(Equal(original.DebugKey))
+		Ω(reparsed.Text).Should(Equal(original.Text))
+		Ω(reparsed.Authentication).Should(BeZero())
+	})
+
+	It("doesn't store anything", func() {
+		_, err := mail.ParseStoredEmail(mail.ARCHIVE_PREFIX+"abc", loadEmailFixture("email_from_ios.json"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Consistently(func() ([]s3db.ObjectInfo, error) { return db.ListObjects("") }, "50ms").Should(BeEmpty())
+	})
+})
@@ -0,0 +1,204 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+
+// rawEmailParts accumulates what we find as we walk a MIME tree
+type rawEmailParts struct {
+	text        string
+	html        string
+	attachments Attachments
+}
+
+// ParseRawEmail parses a raw RFC 5322 message - as handed to us by an SMTP listener, a maildir, or any provider that doesn't pre-parse
+// e-mail the way forwardemail does - into the same Email ParseIncomingEmail produces.
+func ParseRawEmail(db S3DBInt, data []byte, debug io.Writer) (Email, error) {
+	return parseRawEmail(storeForDebugging(db, data, debug), data)
+}
+
+func parseRawEmail(debugKey string, data []byte) (Email, error) {
+	message, err := netmail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return Email{}, err
+	}
+	header := message.Header
+	out := Email{
+		DebugKey: debugKey,
+	}
+	out.From, err = senderFrom(rawAddresses(header, "From"), rawAddresses(header, "Reply-To"))
+	if err != nil {
+		return Email{}, err
+	}
+	out.To = rawAddresses(header, "To")
+	out.CC = rawAddresses(header, "Cc")
+	out.Subject = decodeHeader(header.Get("Subject"))
+	out.MessageID = strings.TrimSpace(header.Get("Message-Id"))
+	if inReplyTo := parseMessageIDs(header.Get("In-Reply-To")); len(inReplyTo) > 0 {
+		out.InReplyTo = inReplyTo[0]
+	}
+	if references := parseMessageIDs(header.Get("References")); len(references) > 0 {
+		out.References = references
+	}
+	out.Date = header.Get("Date")
+	out.Authentication = authenticationResultsFrom(rawHeaderLines(header, "Authentication-Results"))
+
+	parts := &rawEmailParts{}
+	if err := parts.walk(textproto.MIMEHeader(header), message.Body); err != nil {
+		return Email{}, err
+	}
+	if parts.text != "" {
+		out.Text = ExtractTopMostPortion(parts.text)
+	} else if parts.html != "" {
+		out.Text = ExtractTopMostPortionFromHTML(parts.html)
+	} else {
+		return Email{}, fmt.Errorf("no content found in email")
+	}
+	out.Attachments = parts.attachments
+	out.Bounce = detectBounce(out, rawHeaderLines(header, "Content-Type", "X-Failed-Recipients", "Return-Path"), parts.text)
+	out.AutoReply = detectAutoReply(out.Subject, rawHeaderLines(header, "Auto-Submitted", "X-Autoreply", "X-Autorespond", "Precedence", "List-Id"))
+	return out, nil
+}
+
+func rawAddresses(header netmail.Header, key string) []EmailAddress {
+	out := []EmailAddress{}
+	if header.Get(key) == "" {
+		return out
+	}
+	parser := netmail.AddressParser{WordDecoder: wordDecoder}
+	addresses, err := parser.ParseList(header.Get(key))
+	if err != nil {
+		return out
+	}
+	for _, address := range addresses {
+		name := address.Name
+		if strings.EqualFold(name, address.Address) {
+			// "a@b.com" <a@b.com> - forwardemail drops the redundant name and so do we
+			name = ""
+		}
+		out = append(out, forwardEmailAddress{Address: address.Address, Name: name}.asEmailAddress())
+	}
+	return out
+}
+
+// rawHeaderLines presents the headers the way forwardemail does - lower-case keys, full lines, topmost first
+func rawHeaderLines(header netmail.Header, keys ...string) []forwardEmailHeader {
+	out := []forwardEmailHeader{}
+	for _, key := range keys {
+		for _, value := range header[textproto.CanonicalMIMEHeaderKey(key)] {
+			out = append(out, forwardEmailHeader{Key: strings.ToLower(key), Line: key + ": " + value})
+		}
+	}
+	return out
+}
+
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// walk descends through multipart bodies, keeping the first plain text and HTML bodies and collecting everything else as attachments
+func (p *rawEmailParts) walk(header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045: no (or a broken) Content-Type means plain US-ASCII text
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("malformed multipart body: %w", err)
+			}
+			if err := p.walk(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("failed to decode %s part: %w", mediaType, err)
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	isBody := disposition != "attachment" && filename == ""
+	if isBody && mediaType == "text/plain" && p.text == "" {
+		p.text, err = decodeCharset(params["charset"], content)
+		return err
+	}
+	if isBody && mediaType == "text/html" && p.html == "" {
+		p.html, err = decodeCharset(params["charset"], content)
+		return err
+	}
+
+	attachment := Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		Content:     content,
+	}
+	if disposition == "inline" || disposition == "" {
+		// parts of a multipart/related body often skip the Content-Disposition and just carry a Content-ID
+		attachment.CID = strings.Trim(header.Get("Content-Id"), "<> ")
+	}
+	p.attachments = append(p.attachments, attachment)
+	return nil
+}
+
+func transferDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		// 7bit, 8bit, binary
+		return body
+	}
+}
+
+func decodeCharset(label string, content []byte) (string, error) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" || label == "utf-8" || label == "us-ascii" {
+		return normalizeNewlines(string(content)), nil
+	}
+	reader, err := charset.NewReaderLabel(label, bytes.NewReader(content))
+	if err != nil {
+		return "", fmt.Errorf("unsupported charset %s: %w", label, err)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return normalizeNewlines(string(decoded)), nil
+}
+
+// MIME bodies use CRLF line endings - everything downstream (reply stripping, commands) expects \n
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
@@ -0,0 +1,138 @@
+package mail_test
+
+import (
+	"encoding/json"
+
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseRawEmail", func() {
+	var db *s3db.FakeS3DB
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+	})
+
+	DescribeTable("it agrees with ParseIncomingEmail on the raw messages forwardemail hands us", func(fixture string) {
+		data := loadEmailFixture(fixture)
+		var forwardEmail struct {
+			Raw string `json:"raw"`
+		}
+		Ω(json.Unmarshal(data, &forwardEmail)).Should(Succeed())
+
+		expected, err := mail.ParseIncomingEmail(db, data, GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		actual, err := mail.ParseRawEmail(db, []byte(forwardEmail.Raw), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		expected.DebugKey, actual.DebugKey = "", ""
+		Ω(actual).Should(Equal(expected))
+	},
+		Entry(nil, "email_from_ios.json"),
+		Entry(nil, "email_with_multiple_to_and_cc.json"),
+		Entry(nil, "reply_from_gmail_app.json"),
+		Entry(nil, "reply_from_ios_mail.json"),
+		Entry(nil, "mailing_list_reply_all.email"),
+		Entry(nil, "mailing_list_reply_disco.email"),
+	)
+
+	It("stores the raw version of the email in the db for future debugging", func() {
+		raw := loadEmailFixture("raw_multipart_alternative.eml")
+		email, err := mail.ParseRawEmail(db, raw, GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Eventually(db.FetchObject).WithArguments(email.DebugKey).Should(Equal(raw))
+	})
+
+	It("ignores ARC-Authentication-Results, which any sender can forge", func() {
+		raw := "ARC-Authentication-Results: i=1; mx1.forwardemail.net; dkim=pass header.d=example.com; dmarc=pass header.from=example.com\r\n" +
+			"ARC-Seal: i=1; a=rsa-sha256; d=forwardemail.net; s=default; cv=none; b=Zm9yZ2Vk\r\n" +
+			"From: Boss <boss@example.com>\r\n" +
+			"To: saturday-disco@sedenverultimate.net\r\n" +
+			"Subject: hey\r\n" +
+			"Content-Type: text/plain\r\n" +
+			"\r\n" +
+			"/RESET-RESET-RESET\r\n"
+		email, err := mail.ParseRawEmail(db, []byte(raw), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.Authentication).Should(BeZero())
+		Ω(email.Authentication.AlignedWith(email.From)).Should(BeFalse())
+	})
+
+	Describe("a multipart/alternative, quoted-printable reply", func() {
+		var email mail.Email
+		BeforeEach(func() {
+			var err error
+			email, err = mail.ParseRawEmail(db, loadEmailFixture("raw_multipart_alternative.eml"), GinkgoWriter)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("decodes encoded-word headers", func() {
+			Ω(email.From).Should(Equal(mail.EmailAddress("Onsi Fakhouri 🥏 <onsijoe@gmail.com>")))
+			Ω(email.Subject).Should(Equal("Re: Saturday Bible Park Frisbee 🥏"))
+		})
+
+		It("extracts the recipients and identifiers", func() {
+			Ω(email.To).Should(ConsistOf(mail.EmailAddress("saturday-disco@sedenverultimate.net"), mail.EmailAddress("Onsi Fakhouri <onsijoe@gmail.com>")))
+			Ω(email.CC).Should(ConsistOf(mail.EmailAddress("Onsi Fakhouri <onsijoe+foo@gmail.com>")))
+			Ω(email.MessageID).Should(Equal("<CAFmhaLZbzzxfNCkuqmC4vNY0wPtgJ@mail.gmail.com>"))
+			Ω(email.Date).Should(Equal("Sat, 23 Sep 2023 16:47:41 -0600"))
+		})
+
+		It("only trusts the topmost authentication results", func() {
+			Ω(email.Authentication.String()).Should(Equal("dkim=pass spf=pass dmarc=pass (by mx1.forwardemail.net)"))
+			Ω(email.Authentication.AlignedWith(email.From)).Should(BeTrue())
+			Ω(email.Authentication.AlignedWith("boss@example.com")).Should(BeFalse())
+		})
+
+		It("decodes the plain text body and strips the quoted reply", func() {
+			Ω(email.Text).Should(Equal("I'm in! Bringing a friend — so that's 2.\n"))
+			Ω(email.HTML).Should(BeZero())
+			Ω(email.Attachments).Should(BeEmpty())
+		})
+	})
+
+	It("decodes base64 bodies and non-UTF-8 charsets", func() {
+		email, err := mail.ParseRawEmail(db, loadEmailFixture("raw_iso_8859_1.eml"), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.From).Should(Equal(mail.EmailAddress("Renée Joueur <renee@example.com>")))
+		Ω(email.Subject).Should(Equal("Ça marche"))
+		Ω(email.Text).Should(Equal("Je serai là samedi. Ça marche!\n"))
+	})
+
+	It("walks nested multiparts, keeping inline parts' CIDs and collecting attachments", func() {
+		email, err := mail.ParseRawEmail(db, loadEmailFixture("raw_with_attachments.eml"), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.Text).Should(Equal("Here's the field map and the waiver."))
+		Ω(email.Attachments).Should(HaveLen(2))
+		Ω(email.Attachments[0]).Should(Equal(mail.Attachment{Filename: "map.png", ContentType: "image/png", Content: []byte{0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d}, CID: "map@disco"}))
+		Ω(email.Attachments[1].Filename).Should(Equal("waiver.pdf"))
+		Ω(email.Attachments[1].ContentType).Should(Equal("application/pdf"))
+		Ω(email.Attachments[1].CID).Should(BeZero())
+		Ω(string(email.Attachments[1].Content)).Should(HavePrefix("%PDF-1.4 pretend this is a waiver"))
+	})
+
+	It("falls back to the HTML body when there's no plain text", func() {
+		email, err := mail.ParseRawEmail(db, loadEmailFixture("raw_html_only.eml"), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.Text).Should(Equal("Count me in\nfor Thursday\n"))
+	})
+
+	It("uses the reply-to address when the e-mail came via a mailing list", func() {
+		email, err := mail.ParseRawEmail(db, loadEmailFixture("raw_mailing_list.eml"), GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(email.From).Should(Equal(mail.EmailAddress("Onsi Fakhouri <onsijoe@gmail.com>")))
+		Ω(email.Text).Should(Equal("I'm in!\n"))
+	})
+
+	It("errors when there's no content", func() {
+		_, err := mail.ParseRawEmail(db, []byte("From: a@example.com\r\nSubject: hi\r\nContent-Type: image/png\r\n\r\nxyz"), GinkgoWriter)
+		Ω(err).Should(MatchError("no content found in email"))
+	})
+
+	It("errors on garbage", func() {
+		_, err := mail.ParseRawEmail(db, []byte("not an e-mail"), GinkgoWriter)
+		Ω(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"html"
+	"math"
+	"regexp"
+
+	strip "github.com/grokify/html-strip-tags-go"
+)
+
+// the patterns come from real clients - see fixtures/replies
+var emailRegex = `[a-zA-Z0-9._-]+@[a-zA-Z0-9._-]+\.[a-zA-Z0-9_-]+`
+var replyRegexes = []*regexp.Regexp{
+	// quoted text
+	regexp.MustCompile(`(?m)^>.*`),
+	// On <date>, <someone> wrote: - often wrapped over two lines
+	regexp.MustCompile(`(?m)^\s*On.*(\s?).*@.*wrote:`),
+	regexp.MustCompile(`(?m)^\s*On.*@.*(\s?).*wrote:`),
+	regexp.MustCompile(`(?m)^\s*On\s.*\swrote:\s*$`),
+	// ...and its translations: Le ... a écrit, Am ... schrieb, El ... escribió, Em ... escreveu, Il ... ha scritto, Op ... schreef
+	regexp.MustCompile(`(?m)^\s*(Le|Am|El|Em|Il|Op)\s([^\n]|\n[^\n]){0,300}?\s(a\s+écrit|schrieb|escribió|escreveu|ha\s+scritto|schreef)([^\n]|\n[^\n]){0,200}?:[ \t]*$`),
+	regexp.MustCompile(`(?im)-+\s*(original|forwarded)\s+message\s*-+\s*$`),
+	regexp.MustCompile(`(?im)From:\s*` + emailRegex),
+	regexp.MustCompile(`(?im)` + emailRegex + `\s+wrote:`),
+	// Outlook's header block - From: Someone / Sent: ... - and the line it sometimes draws above it
+	regexp.MustCompile(`(?im)^\s*\*?From:\*?\s.*\n\s*\*?(Sent|Date):\*?\s`),
+	regexp.MustCompile(`(?m)^\s*_{10,}\s*$`),
+	// signatures: the "-- " separator and the ones mobile clients add
+	regexp.MustCompile(`(?m)^-- ?$`),
+	regexp.MustCompile(`(?im)^\s*(sent from my\s|sent from (outlook|mail for windows|yahoo mail)\b|get outlook for (ios|android)\b)`),
+}
+
+// the HTML equivalents: quotes and signatures, as marked up by gmail, outlook, yahoo, and apple mail
+var htmlQuoteRegex = regexp.MustCompile(`(?i)<blockquote|<(div|span)[^>]*\sclass="[^"]*(\b(gmail_quote|gmail_attr|gmail_signature|gmail_signature_prefix)|yahoo_quoted)\b|<[a-z]+[^>]*\sid="(appendonsend|divRplyFwdMsg|ms-outlook-mobile-signature|Signature)"`)
+var htmlLineBreakRegex = regexp.MustCompile(`(?i)(<br|</div>|</p>)`)
+
+func ExtractTopMostPortionFromHTML(htmlBody string) string {
+	if index := htmlQuoteRegex.FindStringIndex(htmlBody); index != nil {
+		htmlBody = htmlBody[:index[0]]
+	}
+	htmlBody = htmlLineBreakRegex.ReplaceAllString(htmlBody, "\n$1")
+	txtBody := html.UnescapeString(strip.StripTags(htmlBody))
+	return ExtractTopMostPortion(txtBody)
+}
+
+// ExtractTopMostPortion cuts an e-mail body off at the first thing that looks like quoted text or a signature, so the interpreter
+// (which only sees the first USER_MESSAGE_CUTOFF characters) gets what the sender actually wrote
+func ExtractTopMostPortion(fullBody string) string {
+	winner := math.MaxInt
+	for _, regex := range replyRegexes {
+		index := regex.FindStringIndex(fullBody)
+		if index != nil && index[0] < winner {
+			winner = index[0]
+		}
+	}
+	if winner == math.MaxInt {
+		return fullBody
+	}
+	return fullBody[:winner]
+}
@@ -0,0 +1,52 @@
+package mail_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/onsi/disco/mail"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// each fixtures/replies/<client>.txt (or .html) is a real client's reply, paired with <client>.txt.expected: what we should keep
+func replyFixtureEntries() []TableEntry {
+	paths, _ := filepath.Glob("./fixtures/replies/*.*")
+	entries := []TableEntry{}
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".expected") {
+			continue
+		}
+		entries = append(entries, Entry(filepath.Base(path), path))
+	}
+	return entries
+}
+
+var _ = Describe("Stripping replies and signatures", func() {
+	DescribeTable("the fixture corpus", func(path string) {
+		body, err := os.ReadFile(path)
+		Ω(err).ShouldNot(HaveOccurred())
+		expected, err := os.ReadFile(path + ".expected")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		if filepath.Ext(path) == ".html" {
+			Ω(mail.ExtractTopMostPortionFromHTML(string(body))).Should(Equal(string(expected)))
+		} else {
+			Ω(mail.ExtractTopMostPortion(string(body))).Should(Equal(string(expected)))
+		}
+	}, replyFixtureEntries())
+
+	It("has a corpus", func() {
+		Ω(len(replyFixtureEntries())).Should(BeNumerically(">", 10))
+	})
+
+	It("leaves lines that merely mention the markers alone", func() {
+		body := "On Saturday I'll bring the cones.\nWe should have a -- big -- turnout.\nAm I in? Yes.\n"
+		Ω(mail.ExtractTopMostPortion(body)).Should(Equal(body))
+	})
+
+	It("decodes HTML entities", func() {
+		Ω(mail.ExtractTopMostPortionFromHTML("<p>Tom &amp; Jerry&#39;s both in &lt;3</p>")).Should(Equal("Tom & Jerry's both in <3\n"))
+	})
+})
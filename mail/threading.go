@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// we keep References from growing without bound on long threads
+const MAX_REFERENCES = 20
+
+var messageIDRegex = regexp.MustCompile(`<[^<>\s]+>`)
+var messageIDUnsafeRegex = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// MessageIDFor makes a Message-ID out of key in the sender's domain - the same key always makes the same Message-ID, so a
+// re-sent e-mail keeps its identity
+func MessageIDFor(key string, from EmailAddress) string {
+	domain := domainOf(from.Address())
+	if domain == "" {
+		domain = "localhost"
+	}
+	return "<" + strings.Trim(messageIDUnsafeRegex.ReplaceAllString(key, "."), ".") + "@" + domain + ">"
+}
+
+// TransitionKey is the idempotency key for the sequence'th e-mail a disco sends during the week of t, moving it to state.  The sequence
+// must only ever go up - reuse one and a durable outbox will swallow the new e-mail as a repeat of the old one.
+func TransitionKey(disco string, t time.Time, sequence int, state string) string {
+	return fmt.Sprintf("%s/%s/%d/%s", disco, t.Format("2006-01-02"), sequence, state)
+}
+
+// IsMessageIDFor is true if id is a Message-ID MessageIDFor made for an e-mail from from, with a key that starts with prefix
+func IsMessageIDFor(id string, prefix string, from EmailAddress) bool {
+	domain := domainOf(from.Address())
+	if domain == "" {
+		domain = "localhost"
+	}
+	prefix = strings.Trim(messageIDUnsafeRegex.ReplaceAllString(prefix, "."), ".")
+	id = strings.ToLower(id)
+	return strings.HasPrefix(id, strings.ToLower("<"+prefix+".")) && strings.HasSuffix(id, strings.ToLower("@"+domain+">"))
+}
+
+// parseMessageIDs pulls the <id>s out of an In-Reply-To or References header
+func parseMessageIDs(header string) []string {
+	return messageIDRegex.FindAllString(header, -1)
+}
+
+// InThreadOf makes e a reply to parent: In-Reply-To parent, and References parent's thread followed by parent
+func (e Email) InThreadOf(parent Email) Email {
+	e.InReplyTo = parent.MessageID
+	e.References = slices.Clone(parent.References)
+	if len(e.References) == 0 && parent.InReplyTo != "" {
+		// some clients only send In-Reply-To
+		e.References = []string{parent.InReplyTo}
+	}
+	if parent.MessageID != "" {
+		e.References = append(e.References, parent.MessageID)
+	}
+	if len(e.References) > MAX_REFERENCES {
+		// RFC 5322 suggests keeping the thread's root and trimming from the middle
+		e.References = append([]string{e.References[0]}, e.References[len(e.References)-MAX_REFERENCES+1:]...)
+	}
+	return e
+}
+
+// Thread is every Message-ID e is a reply to, most recent first
+func (e Email) Thread() []string {
+	out := []string{}
+	add := func(id string) {
+		if id != "" && !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	add(e.InReplyTo)
+	for i := len(e.References) - 1; i >= 0; i-- {
+		add(e.References[i])
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package mail_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/onsi/disco/mail"
+)
+
+var _ = Describe("Threading", func() {
+	Describe("MessageIDFor", func() {
+		It("makes a deterministic Message-ID in the sender's domain", func() {
+			id := MessageIDFor("saturday/2025-04-12/3/invite_sent", "Saturday Disco <saturday-disco@sedenverultimate.net>")
+			Ω(id).Should(Equal("<saturday.2025-04-12.3.invite_sent@sedenverultimate.net>"))
+			Ω(MessageIDFor("saturday/2025-04-12/3/invite_sent", "saturday-disco@sedenverultimate.net")).Should(Equal(id))
+		})
+
+		It("falls back to localhost when the sender has no domain", func() {
+			Ω(MessageIDFor("a key", "")).Should(Equal("<a.key@localhost>"))
+		})
+	})
+
+	Describe("TransitionKey", func() {
+		It("keys the e-mail by disco, week, sequence, and state - and makes a Message-ID to match", func() {
+			key := TransitionKey("saturday", time.Date(2025, time.April, 12, 10, 0, 0, 0, time.UTC), 3, "invite_sent")
+			Ω(key).Should(Equal("saturday/2025-04-12/3/invite_sent"))
+			email := E().WithFrom("saturday-disco@sedenverultimate.net").WithIdempotencyKey(key)
+			Ω(email.IdempotencyKey).Should(Equal(key))
+			Ω(email.MessageID).Should(Equal("<saturday.2025-04-12.3.invite_sent@sedenverultimate.net>"))
+		})
+	})
+
+	Describe("IsMessageIDFor", func() {
+		It("recognizes Message-IDs MessageIDFor made with a given prefix, for a given sender", func() {
+			id := MessageIDFor("saturday/2025-04-12/3/invite_sent", "saturday-disco@sedenverultimate.net")
+			Ω(IsMessageIDFor(id, "saturday", "Saturday Disco <saturday-disco@sedenverultimate.net>")).Should(BeTrue())
+			Ω(IsMessageIDFor(id, "lunchtime", "saturday-disco@sedenverultimate.net")).Should(BeFalse())
+			Ω(IsMessageIDFor(id, "saturday", "saturday-disco@example.com")).Should(BeFalse())
+			Ω(IsMessageIDFor("<saturdayish@sedenverultimate.net>", "saturday", "saturday-disco@sedenverultimate.net")).Should(BeFalse())
+			Ω(IsMessageIDFor("", "saturday", "saturday-disco@sedenverultimate.net")).Should(BeFalse())
+		})
+	})
+
+	Describe("InThreadOf", func() {
+		It("replies to the parent and extends its References", func() {
+			parent := Email{MessageID: "<c>", InReplyTo: "<b>", References: []string{"<a>", "<b>"}}
+			email := E().InThreadOf(parent)
+			Ω(email.InReplyTo).Should(Equal("<c>"))
+			Ω(email.References).Should(Equal([]string{"<a>", "<b>", "<c>"}))
+			Ω(parent.References).Should(Equal([]string{"<a>", "<b>"}))
+		})
+
+		It("uses the parent's In-Reply-To when it has no References", func() {
+			email := E().InThreadOf(Email{MessageID: "<b>", InReplyTo: "<a>"})
+			Ω(email.References).Should(Equal([]string{"<a>", "<b>"}))
+		})
+
+		It("keeps the thread's root when trimming long threads", func() {
+			parent := Email{MessageID: "<last>"}
+			for i := 0; i < 30; i++ {
+				parent.References = append(parent.References, fmt.Sprintf("<%d>", i))
+			}
+			email := E().InThreadOf(parent)
+			Ω(email.References).Should(HaveLen(MAX_REFERENCES))
+			Ω(email.References[0]).Should(Equal("<0>"))
+			Ω(email.References[1]).Should(Equal("<12>"))
+			Ω(email.References[MAX_REFERENCES-1]).Should(Equal("<last>"))
+		})
+	})
+
+	Describe("Thread", func() {
+		It("lists the e-mails this one replies to, most recent first", func() {
+			email := Email{InReplyTo: "<c>", References: []string{"<a>", "<b>", "<c>"}}
+			Ω(email.Thread()).Should(Equal([]string{"<c>", "<b>", "<a>"}))
+			Ω(Email{}.Thread()).Should(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,257 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Transport interface {
+	Send(Email) error
+}
+
+const (
+	TransportForwardEmail = "forwardemail"
+	TransportSMTP         = "smtp"
+	TransportMaildir      = "maildir"
+)
+
+const (
+	SMTPSecurityStartTLS = "starttls"
+	SMTPSecurityTLS      = "tls"
+	SMTPSecurityNone     = "none"
+)
+
+const FORWARD_EMAIL_ENDPOINT = "https://api.forwardemail.net/v1/emails"
+
+// TransportConfig describes a route and the transport it uses.  Only the fields relevant to Transport need to be set.
+type TransportConfig struct {
+	From      string `json:"from"`
+	Transport string `json:"transport"`
+
+	// forwardemail
+	Key      string `json:"key,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// smtp
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Security string `json:"security,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// maildir
+	Dir string `json:"dir,omitempty"`
+}
+
+func NewTransport(config TransportConfig) (Transport, error) {
+	if config.From == "" {
+		return nil, fmt.Errorf("missing from")
+	}
+	switch config.Transport {
+	case TransportForwardEmail:
+		endpoint := config.Endpoint
+		if endpoint == "" {
+			endpoint = FORWARD_EMAIL_ENDPOINT
+		}
+		return ForwardEmailTransport{Key: config.Key, Endpoint: endpoint}, nil
+	case TransportSMTP:
+		if config.Host == "" || config.Port == 0 {
+			return nil, fmt.Errorf("smtp needs a host and port")
+		}
+		security := config.Security
+		if security == "" {
+			security = SMTPSecurityStartTLS
+		}
+		if security != SMTPSecurityStartTLS && security != SMTPSecurityTLS && security != SMTPSecurityNone {
+			return nil, fmt.Errorf("unknown smtp security %q - use starttls, tls, or none", config.Security)
+		}
+		return SMTPTransport{
+			Host:     config.Host,
+			Port:     config.Port,
+			Security: security,
+			Username: config.Username,
+			Password: config.Password,
+		}, nil
+	case TransportMaildir:
+		if config.Dir == "" {
+			return nil, fmt.Errorf("maildir needs a dir")
+		}
+		return MaildirTransport{Dir: config.Dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", config.Transport)
+	}
+}
+
+type ForwardEmailTransport struct {
+	Key      string
+	Endpoint string
+}
+
+func (t ForwardEmailTransport) Send(email Email) error {
+	form := url.Values{}
+	form.Add("from", email.From.String())
+	for _, to := range email.To {
+		form.Add("to", to.String())
+	}
+	for _, cc := range email.CC {
+		form.Add("cc", cc.String())
+	}
+	if email.Subject != "" {
+		form.Add("subject", email.Subject)
+	}
+	if email.MessageID != "" {
+		form.Add("messageId", email.MessageID)
+	}
+	if email.InReplyTo != "" {
+		form.Add("inReplyTo", email.InReplyTo)
+	}
+	for _, reference := range email.References {
+		form.Add("references", reference)
+	}
+	if email.Text != "" {
+		form.Add("text", email.Text)
+	}
+	if email.HTML != "" {
+		form.Add("html", email.HTML)
+	}
+	for i, attachment := range email.Attachments {
+		prefix := fmt.Sprintf("attachments[%d]", i)
+		form.Add(prefix+"[filename]", attachment.Filename)
+		form.Add(prefix+"[contentType]", attachment.ContentType)
+		form.Add(prefix+"[content]", base64.StdEncoding.EncodeToString(attachment.Content))
+		form.Add(prefix+"[encoding]", "base64")
+		if attachment.IsInline() {
+			form.Add(prefix+"[cid]", attachment.CID)
+			form.Add(prefix+"[contentDisposition]", "inline")
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_TIMEOUT)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", t.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.Key, "")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		issue, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("failed to send e-mail: %d - %s", resp.StatusCode, string(issue))
+	}
+	return nil
+}
+
+// SMTPTransport speaks SMTP to any server.  With starttls the server must support STARTTLS - we won't quietly fall back to plaintext.
+type SMTPTransport struct {
+	Host     string
+	Port     int
+	Security string
+	Username string
+	Password string
+}
+
+func (t SMTPTransport) Send(email Email) error {
+	data := &bytes.Buffer{}
+	if _, err := buildMessage(email).WriteTo(data); err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	tlsConfig := &tls.Config{ServerName: t.Host}
+	dialer := &net.Dialer{Timeout: DEFAULT_TIMEOUT}
+	var conn net.Conn
+	var err error
+	if t.Security == SMTPSecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(DEFAULT_TIMEOUT))
+
+	c, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if t.Security == SMTPSecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if t.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(email.From.Address()); err != nil {
+		return err
+	}
+	for _, recipient := range email.Recipients() {
+		if err := c.Rcpt(recipient.Address()); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// MaildirTransport doesn't send anything - it drops each e-mail into Dir/new so you can see what would have gone out
+type MaildirTransport struct {
+	Dir string
+}
+
+func (t MaildirTransport) Send(email Email) error {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(t.Dir, sub), 0700); err != nil {
+			return err
+		}
+	}
+	data := &bytes.Buffer{}
+	if _, err := buildMessage(email).WriteTo(data); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.%s.disco", time.Now().UnixNano(), uuid.New().String())
+	tmp := filepath.Join(t.Dir, "tmp", name)
+	if err := os.WriteFile(tmp, data.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(t.Dir, "new", name))
+}
@@ -0,0 +1,189 @@
+package mail_test
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/mail"
+)
+
+type recordingTransport struct {
+	name   string
+	emails *[]string
+}
+
+func (t recordingTransport) Send(email mail.Email) error {
+	*t.emails = append(*t.emails, t.name+": "+email.From.String())
+	return nil
+}
+
+var _ = Describe("Transports", func() {
+	var email mail.Email
+	BeforeEach(func() {
+		email = mail.E().WithFrom("Saturday Disco <saturday-disco@sedenverultimate.net>").
+			WithTo("Onsi Fakhouri <onsijoe@gmail.com>").
+			AndCC("Jane Player <jane@example.com>").
+			WithSubject("Game on!").
+			WithBody(mail.Markdown("See you **Saturday**")).
+			WithAttachment(mail.Attachment{Filename: "game.ics", ContentType: "text/calendar; charset=utf-8", Content: []byte("BEGIN:VCALENDAR")})
+	})
+
+	Describe("routing", func() {
+		It("sends via the first route that matches the sender", func() {
+			sent := []string{}
+			outbox := mail.NewOutboxWithRoutes(
+				mail.Route{From: "onsijoe@gmail.com", Transport: recordingTransport{"gmail", &sent}},
+				mail.Route{From: "@sedenverultimate.net", Transport: recordingTransport{"forwardemail", &sent}},
+				mail.Route{From: "*", Transport: recordingTransport{"sink", &sent}},
+			)
+			Ω(outbox.SendEmail(mail.E().WithFrom("Onsi <OnsiJoe@gmail.com>"))).Should(Succeed())
+			Ω(outbox.SendEmail(mail.E().WithFrom("Disco <saturday-disco@SEDenverUltimate.net>"))).Should(Succeed())
+			Ω(outbox.SendEmail(mail.E().WithFrom("someone@example.com"))).Should(Succeed())
+			Ω(sent).Should(Equal([]string{
+				"gmail: Onsi <OnsiJoe@gmail.com>",
+				"forwardemail: Disco <saturday-disco@SEDenverUltimate.net>",
+				"sink: someone@example.com",
+			}))
+		})
+
+		It("fails for senders with no route", func() {
+			outbox := mail.NewOutboxWithRoutes(mail.Route{From: "@sedenverultimate.net", Transport: recordingTransport{"forwardemail", &[]string{}}})
+			Ω(outbox.SendEmail(mail.E().WithFrom("example@gmail.com"))).Should(MatchError("unknown e-mail address: example@gmail.com"))
+		})
+
+		It("validates transport configs", func() {
+			_, err := mail.NewOutbox([]mail.TransportConfig{{From: "*", Transport: "pigeon"}})
+			Ω(err).Should(MatchError(`invalid route for *: unknown transport "pigeon"`))
+			_, err = mail.NewOutbox([]mail.TransportConfig{{From: "*", Transport: mail.TransportSMTP, Host: "localhost"}})
+			Ω(err).Should(MatchError("invalid route for *: smtp needs a host and port"))
+			_, err = mail.NewOutbox([]mail.TransportConfig{{From: "*", Transport: mail.TransportSMTP, Host: "localhost", Port: 25, Security: "ssl"}})
+			Ω(err).Should(MatchError(`invalid route for *: unknown smtp security "ssl" - use starttls, tls, or none`))
+			_, err = mail.NewOutbox([]mail.TransportConfig{{From: "*", Transport: mail.TransportMaildir}})
+			Ω(err).Should(MatchError("invalid route for *: maildir needs a dir"))
+		})
+	})
+
+	Describe("smtp", func() {
+		var server *mail.FakeSMTPServer
+		BeforeEach(func() {
+			var err error
+			server, err = mail.NewFakeSMTPServer()
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(server.Close)
+		})
+
+		It("delivers the e-mail as a multipart message to every recipient", func() {
+			config := server.TransportConfig("@sedenverultimate.net")
+			config.Username, config.Password = "disco", "sekret"
+			email.MessageID = "<game-on@sedenverultimate.net>"
+			email = email.InThreadOf(mail.Email{MessageID: "<invite@sedenverultimate.net>", InReplyTo: "<root@sedenverultimate.net>"})
+			outbox, err := mail.NewOutbox([]mail.TransportConfig{config})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(outbox.SendEmail(email)).Should(Succeed())
+
+			Ω(server.Messages()).Should(HaveLen(1))
+			received := server.Messages()[0]
+			Ω(received.Username).Should(Equal("disco"))
+			Ω(received.From).Should(Equal("saturday-disco@sedenverultimate.net"))
+			Ω(received.To).Should(Equal([]string{"onsijoe@gmail.com", "jane@example.com"}))
+
+			m, err := received.Parse()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(m.Header.Get("Subject")).Should(Equal("Game on!"))
+			Ω(m.Header.Get("Cc")).Should(Equal("Jane Player <jane@example.com>"))
+			Ω(m.Header.Get("Content-Type")).Should(HavePrefix("multipart/mixed"))
+			Ω(m.Header.Get("Message-Id")).Should(Equal("<game-on@sedenverultimate.net>"))
+			Ω(m.Header.Get("In-Reply-To")).Should(Equal("<invite@sedenverultimate.net>"))
+			Ω(m.Header.Get("References")).Should(Equal("<root@sedenverultimate.net> <invite@sedenverultimate.net>"))
+			body, err := io.ReadAll(m.Body)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(body)).Should(ContainSubstring("See you Saturday"))
+			Ω(string(body)).Should(ContainSubstring(`Content-Disposition: attachment; filename="game.ics"`))
+			Ω(string(body)).Should(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("BEGIN:VCALENDAR"))))
+		})
+
+		It("embeds inline attachments with their content id", func() {
+			outbox, err := mail.NewOutbox([]mail.TransportConfig{server.TransportConfig("*")})
+			Ω(err).ShouldNot(HaveOccurred())
+			email = email.WithInlineAttachment("field-map", mail.Attachment{Filename: "map.png", ContentType: "image/png", Content: []byte{0x89, 0x50}})
+			Ω(outbox.SendEmail(email)).Should(Succeed())
+			Ω(string(server.Messages()[0].Data)).Should(ContainSubstring("Content-ID: <field-map>"))
+		})
+
+		It("refuses to send in the clear when STARTTLS is required but not offered", func() {
+			config := server.TransportConfig("*")
+			config.Security = mail.SMTPSecurityStartTLS
+			outbox, err := mail.NewOutbox([]mail.TransportConfig{config})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(outbox.SendEmail(email)).Should(MatchError(ContainSubstring("does not support STARTTLS")))
+			Ω(server.Messages()).Should(BeEmpty())
+		})
+	})
+
+	Describe("forwardemail", func() {
+		It("posts the e-mail, attachments included, to the forwardemail API", func() {
+			var form url.Values
+			var key string
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				key, _, _ = r.BasicAuth()
+				r.ParseForm()
+				form = r.PostForm
+			}))
+			DeferCleanup(api.Close)
+
+			outbox, err := mail.NewOutbox([]mail.TransportConfig{{From: "@sedenverultimate.net", Transport: mail.TransportForwardEmail, Key: "the-key", Endpoint: api.URL}})
+			Ω(err).ShouldNot(HaveOccurred())
+			email.MessageID = "<game-on@sedenverultimate.net>"
+			email = email.InThreadOf(mail.Email{MessageID: "<invite@sedenverultimate.net>", InReplyTo: "<root@sedenverultimate.net>"})
+			Ω(outbox.SendEmail(email)).Should(Succeed())
+			Ω(key).Should(Equal("the-key"))
+			Ω(form.Get("messageId")).Should(Equal("<game-on@sedenverultimate.net>"))
+			Ω(form.Get("inReplyTo")).Should(Equal("<invite@sedenverultimate.net>"))
+			Ω(form["references"]).Should(Equal([]string{"<root@sedenverultimate.net>", "<invite@sedenverultimate.net>"}))
+			Ω(form.Get("from")).Should(Equal("Saturday Disco <saturday-disco@sedenverultimate.net>"))
+			Ω(form["to"]).Should(Equal([]string{"Onsi Fakhouri <onsijoe@gmail.com>"}))
+			Ω(form["cc"]).Should(Equal([]string{"Jane Player <jane@example.com>"}))
+			Ω(form.Get("subject")).Should(Equal("Game on!"))
+			Ω(form.Get("attachments[0][filename]")).Should(Equal("game.ics"))
+			Ω(form.Get("attachments[0][content]")).Should(Equal(base64.StdEncoding.EncodeToString([]byte("BEGIN:VCALENDAR"))))
+		})
+
+		It("returns an error when the API does", func() {
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("bad key"))
+			}))
+			DeferCleanup(api.Close)
+			outbox, err := mail.NewOutbox([]mail.TransportConfig{{From: "*", Transport: mail.TransportForwardEmail, Endpoint: api.URL}})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(outbox.SendEmail(email)).Should(MatchError("failed to send e-mail: 401 - bad key"))
+		})
+	})
+
+	Describe("maildir", func() {
+		It("drops each e-mail into the maildir's new folder", func() {
+			dir := GinkgoT().TempDir()
+			outbox, err := mail.NewOutbox([]mail.TransportConfig{{From: "*", Transport: mail.TransportMaildir, Dir: dir}})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(outbox.SendEmail(email)).Should(Succeed())
+			Ω(outbox.SendEmail(email.WithSubject("Reminder"))).Should(Succeed())
+
+			files, err := filepath.Glob(filepath.Join(dir, "new", "*"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(files).Should(HaveLen(2))
+			Ω(filepath.Join(dir, "tmp")).Should(BeADirectory())
+			data, err := os.ReadFile(files[0])
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(data)).Should(ContainSubstring("Subject: "))
+			Ω(string(data)).Should(ContainSubstring("To: Onsi Fakhouri <onsijoe@gmail.com>"))
+		})
+	})
+})
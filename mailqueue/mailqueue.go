@@ -0,0 +1,376 @@
+package mailqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	"github.com/onsi/say"
+)
+
+const KEY = "mail-queue"
+
+const MAX_ATTEMPTS = 8
+const BASE_BACKOFF = time.Minute
+const MAX_BACKOFF = 2 * time.Hour
+
+// we remember the idempotency keys of delivered e-mail for this long
+const SENT_RETENTION = 30 * 24 * time.Hour
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusDead    Status = "dead"
+)
+
+type Message struct {
+	ID             string     `json:"id"`
+	IdempotencyKey string     `json:"idempotency_key,omitempty"`
+	Email          mail.Email `json:"email"`
+	Status         Status     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttempt    time.Time  `json:"next_attempt"`
+	LastError      string     `json:"last_error,omitempty"`
+	EnqueuedAt     time.Time  `json:"enqueued_at"`
+}
+
+// IsStuck is true for messages that have failed at least once - they're either waiting to be retried or dead
+func (m Message) IsStuck() bool {
+	return m.Attempts > 0
+}
+
+type Messages []Message
+
+func (m Messages) index(id string) int {
+	for i, message := range m {
+		if message.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m Messages) without(id string) Messages {
+	out := Messages{}
+	for _, message := range m {
+		if message.ID != id {
+			out = append(out, message)
+		}
+	}
+	return out
+}
+
+func (m Messages) dup() Messages {
+	out := make(Messages, len(m))
+	for i, message := range m {
+		message.Email = message.Email.Dup()
+		out[i] = message
+	}
+	return out
+}
+
+type persistedQueue struct {
+	Messages Messages             `json:"messages"`
+	Sent     map[string]time.Time `json:"sent"`
+}
+
+// Queue is a durable outbox.  SendEmail persists each e-mail to the db before trying to deliver it; failed deliveries are retried with
+// exponential backoff until MAX_ATTEMPTS, at which point the message is dead and the boss hears about it.
+//
+// E-mails with an IdempotencyKey are only ever delivered once: enqueueing the same key again is a no-op.
+type Queue struct {
+	outbox mail.OutboxInt
+	db     s3db.S3DBInt
+	clock  clock.AlarmClockInt
+	boss   mail.EmailAddress
+	w      io.Writer
+
+	messages   Messages
+	sent       map[string]time.Time
+	delivering map[string]bool
+	lock       *sync.Mutex
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewQueue(outbox mail.OutboxInt, db s3db.S3DBInt, alarmClock clock.AlarmClockInt, boss mail.EmailAddress, w io.Writer) (*Queue, error) {
+	q := &Queue{
+		outbox: outbox,
+		db:     db,
+		clock:  alarmClock,
+		boss:   boss,
+		w:      w,
+
+		messages:   Messages{},
+		sent:       map[string]time.Time{},
+		delivering: map[string]bool{},
+		lock:       &sync.Mutex{},
+		done:       make(chan struct{}),
+	}
+
+	data, err := db.FetchObject(KEY)
+	if err != nil && !errors.Is(err, s3db.ErrObjectNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		persisted := persistedQueue{}
+		if err := json.Unmarshal(data, &persisted); err != nil {
+			return nil, err
+		}
+		if persisted.Messages != nil {
+			q.messages = persisted.Messages
+		}
+		if persisted.Sent != nil {
+			q.sent = persisted.Sent
+		}
+		say.Fplni(q.w, 0, "{{green}}Mail Queue: loaded %d queued messages{{/}}", len(q.messages))
+	}
+
+	q.lock.Lock()
+	q.arm()
+	q.lock.Unlock()
+
+	var ctx context.Context
+	ctx, q.cancel = context.WithCancel(context.Background())
+	go q.run(ctx)
+	return q, nil
+}
+
+func (q *Queue) Stop() {
+	q.cancel()
+	<-q.done
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.clock.Stop()
+}
+
+// SendEmail enqueues the e-mail and then tries to deliver it right away.  It only fails if the e-mail couldn't be persisted - delivery
+// failures are retried in the background.
+func (q *Queue) SendEmail(email mail.Email) error {
+	q.lock.Lock()
+	now := q.clock.Time()
+	key := email.IdempotencyKey
+	if key != "" && q.knows(key, now) {
+		q.lock.Unlock()
+		say.Fplni(q.w, 1, "{{yellow}}Mail Queue: already sent %s, skipping{{/}}", key)
+		return nil
+	}
+	message := Message{
+		ID:             uuid.New().String(),
+		IdempotencyKey: key,
+		Email:          email.Dup(),
+		Status:         StatusPending,
+		NextAttempt:    now,
+		EnqueuedAt:     now,
+	}
+	q.messages = append(q.messages, message)
+	if err := q.persist(); err != nil {
+		q.messages = q.messages.without(message.ID)
+		q.lock.Unlock()
+		return fmt.Errorf("failed to queue e-mail: %w", err)
+	}
+	q.delivering[message.ID] = true
+	q.lock.Unlock()
+
+	q.deliver(message)
+	return nil
+}
+
+// Queued returns everything that has yet to be delivered, oldest first
+func (q *Queue) Queued() Messages {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.messages.dup()
+}
+
+// Stuck returns the messages that have failed to send at least once
+func (q *Queue) Stuck() Messages {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	out := Messages{}
+	for _, message := range q.messages {
+		if message.IsStuck() {
+			message.Email = message.Email.Dup()
+			out = append(out, message)
+		}
+	}
+	return out
+}
+
+// Retry resets a stuck (or dead) message and tries again right away
+func (q *Queue) Retry(id string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	idx := q.messages.index(id)
+	if idx == -1 {
+		return fmt.Errorf("no queued message with id %s", id)
+	}
+	q.messages[idx].Status = StatusPending
+	q.messages[idx].Attempts = 0
+	q.messages[idx].NextAttempt = q.clock.Time()
+	q.arm()
+	return q.persist()
+}
+
+// Discard gives up on a message for good
+func (q *Queue) Discard(id string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.messages.index(id) == -1 {
+		return fmt.Errorf("no queued message with id %s", id)
+	}
+	q.messages = q.messages.without(id)
+	q.arm()
+	return q.persist()
+}
+
+func (q *Queue) run(ctx context.Context) {
+	defer close(q.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.clock.C():
+			q.lock.Lock()
+			now := q.clock.Time()
+			due := Messages{}
+			for _, message := range q.messages {
+				if message.Status == StatusPending && !q.delivering[message.ID] && !message.NextAttempt.After(now) {
+					q.delivering[message.ID] = true
+					due = append(due, message)
+				}
+			}
+			q.lock.Unlock()
+			for _, message := range due {
+				q.deliver(message)
+			}
+			q.lock.Lock()
+			q.arm()
+			q.lock.Unlock()
+		}
+	}
+}
+
+func (q *Queue) deliver(message Message) {
+	err := q.outbox.SendEmail(message.Email)
+
+	q.lock.Lock()
+	delete(q.delivering, message.ID)
+	idx := q.messages.index(message.ID)
+	if idx == -1 {
+		// discarded while we were sending it
+		q.lock.Unlock()
+		return
+	}
+	now := q.clock.Time()
+	var dead Message
+	if err == nil {
+		q.messages = q.messages.without(message.ID)
+		if message.IdempotencyKey != "" {
+			q.sent[message.IdempotencyKey] = now
+		}
+		q.prune(now)
+	} else {
+		m := &q.messages[idx]
+		m.Attempts += 1
+		m.LastError = err.Error()
+		if m.Attempts >= MAX_ATTEMPTS {
+			say.Fplni(q.w, 1, "{{red}}Mail Queue: giving up on %q to %s: %s{{/}}", m.Email.Subject, m.Email.Recipients(), err.Error())
+			m.Status = StatusDead
+			dead = *m
+		} else {
+			m.NextAttempt = now.Add(backoff(m.Attempts))
+			say.Fplni(q.w, 1, "{{red}}Mail Queue: failed to send %q to %s (attempt %d), retrying at %s: %s{{/}}", m.Email.Subject, m.Email.Recipients(), m.Attempts, m.NextAttempt.Format("1/2 3:04pm"), err.Error())
+		}
+		q.arm()
+	}
+	if err := q.persist(); err != nil {
+		say.Fplni(q.w, 1, "{{red}}Mail Queue: failed to persist queue: %s{{/}}", err.Error())
+	}
+	q.lock.Unlock()
+
+	if dead.ID != "" {
+		q.notifyBoss(dead)
+	}
+}
+
+// the boss hears about dead messages directly from the outbox - if that fails too there's nothing more we can do
+func (q *Queue) notifyBoss(message Message) {
+	err := q.outbox.SendEmail(mail.Email{
+		From:    message.Email.From,
+		To:      mail.EmailAddresses{q.boss},
+		Subject: "Help!",
+		Text:    fmt.Sprintf("Disco gave up on sending an e-mail after %d attempts.\n\n%s\n\nTrying to send:\n\n%s\n\nYou can retry or discard it from the stuck mail page.", message.Attempts, message.LastError, message.Email.String()),
+	})
+	if err != nil {
+		say.Fplni(q.w, 1, "{{red}}Mail Queue: failed to tell the boss: %s{{/}}", err.Error())
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	delay := BASE_BACKOFF
+	for i := 1; i < attempts && delay < MAX_BACKOFF; i++ {
+		delay *= 2
+	}
+	if delay > MAX_BACKOFF {
+		delay = MAX_BACKOFF
+	}
+	return delay
+}
+
+func (q *Queue) knows(key string, now time.Time) bool {
+	if sentAt, ok := q.sent[key]; ok && now.Sub(sentAt) <= SENT_RETENTION {
+		return true
+	}
+	for _, message := range q.messages {
+		if message.IdempotencyKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *Queue) prune(now time.Time) {
+	for key, sentAt := range q.sent {
+		if now.Sub(sentAt) > SENT_RETENTION {
+			delete(q.sent, key)
+		}
+	}
+}
+
+// arm sets the alarm for the next retry
+func (q *Queue) arm() {
+	pending := Messages{}
+	for _, message := range q.messages {
+		if message.Status == StatusPending && !q.delivering[message.ID] {
+			pending = append(pending, message)
+		}
+	}
+	if len(pending) == 0 {
+		q.clock.Stop()
+		return
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].NextAttempt.Before(pending[j].NextAttempt)
+	})
+	q.clock.SetAlarm(pending[0].NextAttempt)
+}
+
+func (q *Queue) persist() error {
+	data, err := json.Marshal(persistedQueue{Messages: q.messages, Sent: q.sent})
+	if err != nil {
+		return err
+	}
+	return q.db.PutObject(KEY, data)
+}
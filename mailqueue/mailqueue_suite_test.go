@@ -0,0 +1,13 @@
+package mailqueue_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMailqueue(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mailqueue Suite")
+}
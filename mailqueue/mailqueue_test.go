@@ -0,0 +1,164 @@
+package mailqueue_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/mailqueue"
+	"github.com/onsi/disco/s3db"
+)
+
+var errBoom = errors.New("boom")
+
+var _ = Describe("Queue", func() {
+	var fake *clock.FakeAlarmClock
+	var db *s3db.FakeS3DB
+	var outbox *mail.FakeOutbox
+	var queue *mailqueue.Queue
+	var now time.Time
+	var boss mail.EmailAddress
+	var email mail.Email
+
+	start := func(at time.Time) {
+		GinkgoHelper()
+		fake = clock.NewFakeAlarmClock()
+		fake.SetTime(at)
+		var err error
+		queue, err = mailqueue.NewQueue(outbox, db, fake, boss, GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+		outbox = mail.NewFakeOutbox()
+		boss = mail.EmailAddress("Boss <boss@example.com>")
+		now = time.Date(2023, time.September, 24, 0, 0, 0, 0, clock.Timezone)
+		email = mail.E().WithFrom("Disco <saturday-disco@sedenverultimate.net>").WithTo("list@googlegroups.com").WithSubject("GAME ON!").WithBody("see you there")
+		start(now)
+		DeferCleanup(func() { queue.Stop() })
+	})
+
+	It("delivers e-mail right away", func() {
+		Ω(queue.SendEmail(email)).Should(Succeed())
+		Ω(outbox.Emails()).Should(HaveLen(1))
+		Ω(outbox.LastEmail().Subject).Should(Equal("GAME ON!"))
+		Ω(queue.Queued()).Should(BeEmpty())
+	})
+
+	Context("when delivery fails", func() {
+		BeforeEach(func() {
+			outbox.SetError(errBoom)
+			Ω(queue.SendEmail(email)).Should(Succeed())
+		})
+
+		It("keeps the message and retries it with exponential backoff", func() {
+			stuck := queue.Stuck()
+			Ω(stuck).Should(HaveLen(1))
+			Ω(stuck[0].Attempts).Should(Equal(1))
+			Ω(stuck[0].LastError).Should(Equal("boom"))
+			Ω(stuck[0].NextAttempt).Should(Equal(now.Add(time.Minute)))
+			Ω(fake.Time()).Should(Equal(now))
+
+			fake.Fire()
+			Eventually(func() int { return queue.Stuck()[0].Attempts }).Should(Equal(2))
+			Ω(queue.Stuck()[0].NextAttempt).Should(Equal(now.Add(time.Minute + 2*time.Minute)))
+
+			outbox.SetError(nil)
+			outbox.Clear()
+			fake.Fire()
+			Eventually(queue.Queued).Should(BeEmpty())
+			Ω(outbox.Emails()).Should(HaveLen(1))
+			Ω(outbox.LastEmail().Subject).Should(Equal("GAME ON!"))
+		})
+
+		It("gives up after MAX_ATTEMPTS and tells the boss", func() {
+			for attempt := 2; attempt <= mailqueue.MAX_ATTEMPTS; attempt++ {
+				fake.Fire()
+				Eventually(func() int { return queue.Stuck()[0].Attempts }).Should(Equal(attempt))
+			}
+			Ω(queue.Stuck()[0].Status).Should(Equal(mailqueue.StatusDead))
+			help := outbox.LastEmail()
+			Ω(help.To).Should(Equal(mail.EmailAddresses{boss}))
+			Ω(help.Subject).Should(Equal("Help!"))
+			Ω(help.Text).Should(ContainSubstring("GAME ON!"))
+
+			By("not retrying dead messages")
+			outbox.SetError(nil)
+			outbox.Clear()
+			fake.Fire()
+			Consistently(outbox.Emails, 100*time.Millisecond).Should(BeEmpty())
+
+			By("letting the boss retry them")
+			Ω(queue.Retry(queue.Stuck()[0].ID)).Should(Succeed())
+			fake.Fire()
+			Eventually(queue.Queued).Should(BeEmpty())
+			Ω(outbox.LastEmail().Subject).Should(Equal("GAME ON!"))
+		})
+
+		It("lets the boss discard stuck messages", func() {
+			Ω(queue.Discard(queue.Stuck()[0].ID)).Should(Succeed())
+			Ω(queue.Queued()).Should(BeEmpty())
+			Ω(queue.Discard("nope")).Should(MatchError("no queued message with id nope"))
+		})
+
+		It("picks up where it left off after a restart", func() {
+			queue.Stop()
+			outbox.SetError(nil)
+			outbox.Clear()
+			start(now.Add(time.Hour))
+			Ω(queue.Stuck()).Should(HaveLen(1))
+			fake.Fire()
+			Eventually(queue.Queued).Should(BeEmpty())
+			Ω(outbox.LastEmail().Subject).Should(Equal("GAME ON!"))
+		})
+	})
+
+	It("returns an error, and doesn't send anything, when it can't persist the e-mail", func() {
+		db.SetPutError(errBoom)
+		Ω(queue.SendEmail(email)).Should(MatchError("failed to queue e-mail: boom"))
+		Ω(outbox.Emails()).Should(BeEmpty())
+		Ω(queue.Queued()).Should(BeEmpty())
+	})
+
+	Describe("idempotency keys", func() {
+		BeforeEach(func() {
+			email.IdempotencyKey = "saturday/2023-09-30/3/game_on_sent"
+		})
+
+		It("never sends the same key twice - even across restarts", func() {
+			Ω(queue.SendEmail(email)).Should(Succeed())
+			Ω(queue.SendEmail(email)).Should(Succeed())
+			Ω(outbox.Emails()).Should(HaveLen(1))
+
+			queue.Stop()
+			start(now.Add(time.Hour))
+			Ω(queue.SendEmail(email)).Should(Succeed())
+			Ω(outbox.Emails()).Should(HaveLen(1))
+
+			other := email.Dup()
+			other.IdempotencyKey = "saturday/2023-09-30/4/no_game_sent"
+			Ω(queue.SendEmail(other)).Should(Succeed())
+			Ω(outbox.Emails()).Should(HaveLen(2))
+		})
+
+		It("doesn't double-queue an e-mail that's still waiting to be delivered", func() {
+			outbox.SetError(errBoom)
+			Ω(queue.SendEmail(email)).Should(Succeed())
+			Ω(queue.SendEmail(email)).Should(Succeed())
+			Ω(queue.Queued()).Should(HaveLen(1))
+		})
+
+		It("forgets keys after a while", func() {
+			Ω(queue.SendEmail(email)).Should(Succeed())
+			queue.Stop()
+			start(now.Add(mailqueue.SENT_RETENTION + time.Hour))
+			Ω(queue.SendEmail(email)).Should(Succeed())
+			Ω(outbox.Emails()).Should(HaveLen(2))
+		})
+	})
+})
@@ -6,14 +6,19 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
+	"github.com/onsi/disco/archive"
 	"github.com/onsi/disco/clock"
 	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/imappoll"
 	"github.com/onsi/disco/lunchtimedisco"
 	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/mailqueue"
 	"github.com/onsi/disco/s3db"
 	"github.com/onsi/disco/saturdaydisco"
 	"github.com/onsi/disco/server"
+	"github.com/onsi/disco/smtpd"
 	"github.com/onsi/disco/weather"
+	"github.com/onsi/disco/webhook"
 	"github.com/onsi/say"
 )
 
@@ -54,9 +59,8 @@ func main() {
 
 		// some fake data just so we can better inspect the web page
 		blob, _ = json.Marshal(lunchtimedisco.LunchtimeDiscoSnapshot{
-			BossGUID: "boss",
-			GUID:     "dev",
-			State:    lunchtimedisco.StatePending,
+			GUID:  "dev",
+			State: lunchtimedisco.StatePending,
 			Participants: lunchtimedisco.LunchtimeParticipants{
 				{Address: "Onsi Fakhouri <onsijoe@gmail.com>", GameKeys: []string{"A", "E", "F", "G", "I", "L", "M", "N"}},
 				{Address: "Jane Player <jane@example.com>", GameKeys: []string{"A"}},
@@ -85,10 +89,16 @@ func main() {
 	} else {
 		db, err = s3db.NewS3DB()
 		say.ExitIfError("could not build S3 DB", err)
-		outbox = mail.NewOutbox(conf.ForwardEmailKey, conf.GmailUser, conf.GmailPassword)
+		outbox, err = mail.NewOutbox(conf.MailRoutes)
+		say.ExitIfError("could not build outbox", err)
 		forecaster = weather.NewForecaster(db)
 	}
 
+	// everything goes out via the durable queue so a flaky transport doesn't lose e-mail
+	mailQueue, err := mailqueue.NewQueue(outbox, db, clock.NewAlarmClock(), conf.BossEmail, e.Logger.Output())
+	say.ExitIfError("could not build mail queue", err)
+	outbox = mailQueue
+
 	saturdayDisco, err = saturdaydisco.NewSaturdayDisco(
 		conf,
 		e.Logger.Output(),
@@ -110,5 +120,40 @@ func main() {
 	)
 	say.ExitIfError("could not build Lunchtime Disco", err)
 
-	log.Fatal(server.NewServer(e, "./", conf, outbox, db, saturdayDisco, lunchtimeDisco).Start())
+	// every incoming e-mail is stored for debugging - the boss can browse it, and we only keep it for so long
+	emailArchive := archive.NewArchive(db, e.Logger.Output())
+	emailArchive.Route(config.DiscoSaturday, saturdayDisco)
+	emailArchive.Route(config.DiscoLunchtime, lunchtimeDisco)
+	pruneScheduler, err := clock.NewScheduler(clock.NewAlarmClock(), db, archive.SCHEDULER_KEY)
+	say.ExitIfError("could not build archive scheduler", err)
+	say.ExitIfError("could not start pruning the archive", archive.NewPruner(emailArchive, pruneScheduler, conf.EmailRetention, e.Logger.Output()).Start())
+
+	// the webhooks, the SMTP server, and the IMAP poller all share one record of what each disco has been given
+	webhookGuard := webhook.NewGuard(conf.IncomingWebhookSecret, conf.IsPROD(), db, e.Logger.Output())
+
+	if conf.SMTPPort != "" {
+		hostname := conf.SMTPHostname
+		if hostname == "" {
+			hostname = "localhost"
+		}
+		smtpServer := smtpd.NewServer(hostname, conf.SMTPMaxMessageBytes, db, e.Logger.Output())
+		smtpServer.Route(conf.SaturdayDiscoEmail, webhookGuard.Dedupe(config.DiscoSaturday, saturdayDisco))
+		smtpServer.Route(conf.LunchtimeDiscoEmail, webhookGuard.Dedupe(config.DiscoLunchtime, lunchtimeDisco))
+		go func() {
+			log.Fatal(smtpServer.ListenAndServe(":" + conf.SMTPPort))
+		}()
+	}
+
+	if conf.IMAPAddress != "" {
+		poller, err := imappoll.NewPoller(imappoll.IMAPDialer(conf.IMAPAddress, conf.IMAPUser, conf.IMAPPassword), conf.IMAPPollInterval, db, clock.NewAlarmClock(), e.Logger.Output())
+		say.ExitIfError("could not build IMAP poller", err)
+		// the discos are on their lists, so list e-mail counts as addressed to them - just as it does when forwardemail delivers it
+		poller.Route(conf.SaturdayDiscoEmail, webhookGuard.Dedupe(config.DiscoSaturday, saturdayDisco))
+		poller.Route(conf.SaturdayDiscoList, webhookGuard.Dedupe(config.DiscoSaturday, saturdayDisco))
+		poller.Route(conf.LunchtimeDiscoEmail, webhookGuard.Dedupe(config.DiscoLunchtime, lunchtimeDisco))
+		poller.Route(conf.LunchtimeDiscoList, webhookGuard.Dedupe(config.DiscoLunchtime, lunchtimeDisco))
+		poller.Start()
+	}
+
+	log.Fatal(server.NewServer(e, "./", conf, outbox, mailQueue, emailArchive, webhookGuard, db, saturdayDisco, lunchtimeDisco).Start())
 }
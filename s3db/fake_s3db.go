@@ -1,18 +1,24 @@
 package s3db
 
 import (
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 type FakeS3DB struct {
 	objects  map[string][]byte
+	modified map[string]time.Time
 	mutex    sync.Mutex
 	fetchErr error
+	putErr   error
 }
 
 func NewFakeS3DB() *FakeS3DB {
 	return &FakeS3DB{
-		objects: make(map[string][]byte),
+		objects:  make(map[string][]byte),
+		modified: make(map[string]time.Time),
 	}
 }
 
@@ -23,6 +29,21 @@ func (f *FakeS3DB) SetFetchError(err error) {
 	f.fetchErr = err
 }
 
+func (f *FakeS3DB) SetPutError(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.putErr = err
+}
+
+// SetLastModified backdates an object - e.g. to test retention policies
+func (f *FakeS3DB) SetLastModified(key string, t time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.modified[key] = t
+}
+
 func (f *FakeS3DB) FetchObject(key string) ([]byte, error) {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
@@ -41,6 +62,42 @@ func (f *FakeS3DB) PutObject(key string, data []byte) error {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
+	if f.putErr != nil {
+		return f.putErr
+	}
+
 	f.objects[key] = data
+	f.modified[key] = time.Now()
+	return nil
+}
+
+func (f *FakeS3DB) ListObjects(prefix string) ([]ObjectInfo, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.fetchErr != nil {
+		return nil, f.fetchErr
+	}
+
+	out := []ObjectInfo{}
+	for key, data := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			out = append(out, ObjectInfo{Key: key, Size: int64(len(data)), LastModified: f.modified[key]})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+func (f *FakeS3DB) DeleteObject(key string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.putErr != nil {
+		return f.putErr
+	}
+
+	delete(f.objects, key)
+	delete(f.modified, key)
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -23,6 +24,15 @@ var ErrTimeout = errors.New("timed out")
 type S3DBInt interface {
 	FetchObject(key string) ([]byte, error)
 	PutObject(key string, data []byte) error
+	// ListObjects returns every object whose key starts with prefix, in key order
+	ListObjects(prefix string) ([]ObjectInfo, error)
+	DeleteObject(key string) error
+}
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
 }
 
 type S3DB struct {
@@ -88,3 +98,50 @@ func (s3db *S3DB) PutObject(okey string, data []byte) error {
 	}
 	return err
 }
+
+func (s3db *S3DB) ListObjects(prefix string) ([]ObjectInfo, error) {
+	envPrefix := s3db.env + "/"
+	ctx, cancel := context.WithTimeout(context.Background(), TIMEOUT)
+	defer cancel()
+	out := []ObjectInfo{}
+	err := s3db.svc.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s3db.bucket),
+		Prefix: aws.String(envPrefix + prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			out = append(out, ObjectInfo{
+				Key:          strings.TrimPrefix(aws.StringValue(object.Key), envPrefix),
+				Size:         aws.Int64Value(object.Size),
+				LastModified: aws.TimeValue(object.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			if aerr.Code() == request.CanceledErrorCode {
+				return nil, ErrTimeout
+			}
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s3db *S3DB) DeleteObject(okey string) error {
+	key := s3db.env + "/" + okey
+	ctx, cancel := context.WithTimeout(context.Background(), TIMEOUT)
+	defer cancel()
+	_, err := s3db.svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s3db.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			if aerr.Code() == request.CanceledErrorCode {
+				return ErrTimeout
+			}
+		}
+	}
+	return err
+}
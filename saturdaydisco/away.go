@@ -0,0 +1,198 @@
+package saturdaydisco
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/mail"
+)
+
+type ApprovalKind string
+
+const (
+	ApprovalInvite ApprovalKind = "invite"
+	ApprovalBadger ApprovalKind = "badger"
+	ApprovalGameOn ApprovalKind = "game-on"
+	ApprovalNoGame ApprovalKind = "no-game"
+)
+
+var approvalKinds = []ApprovalKind{ApprovalInvite, ApprovalBadger, ApprovalGameOn, ApprovalNoGame}
+
+type AwayAction string
+
+const (
+	AwayActionWait     AwayAction = "wait"
+	AwayActionApprove  AwayAction = "approve"
+	AwayActionDeny     AwayAction = "deny"
+	AwayActionDelegate AwayAction = "delegate"
+)
+
+type AwayPolicy struct {
+	Action   AwayAction        `json:"action"`
+	Delegate mail.EmailAddress `json:"delegate,omitempty"`
+}
+
+func (p AwayPolicy) String() string {
+	switch p.Action {
+	case AwayActionApprove:
+		return "auto-approve"
+	case AwayActionDeny:
+		return "auto-deny"
+	case AwayActionDelegate:
+		return "ask " + p.Delegate.String()
+	default:
+		return "wait for approval"
+	}
+}
+
+// AwayMode lets the boss decide, ahead of time, what happens to approval requests while they're away
+type AwayMode struct {
+	Start    time.Time                   `json:"start"`
+	End      time.Time                   `json:"end"`
+	SetBy    mail.EmailAddress           `json:"set_by"`
+	Policies map[ApprovalKind]AwayPolicy `json:"policies"`
+}
+
+func (a AwayMode) IsZero() bool {
+	return a.Start.IsZero() && a.End.IsZero()
+}
+
+func (a AwayMode) IsActive(t time.Time) bool {
+	return !a.IsZero() && !t.Before(a.Start) && t.Before(a.End)
+}
+
+func (a AwayMode) HasEnded(t time.Time) bool {
+	return !a.IsZero() && !t.Before(a.End)
+}
+
+func (a AwayMode) PolicyFor(kind ApprovalKind, t time.Time) AwayPolicy {
+	if !a.IsActive(t) {
+		return AwayPolicy{Action: AwayActionWait}
+	}
+	policy, ok := a.Policies[kind]
+	if !ok {
+		return AwayPolicy{Action: AwayActionWait}
+	}
+	return policy
+}
+
+// Status is empty unless away mode is active or upcoming
+func (a AwayMode) Status(t time.Time) string {
+	if a.IsZero() || a.HasEnded(t) {
+		return ""
+	}
+	out := &strings.Builder{}
+	if a.IsActive(t) {
+		out.WriteString("Away mode is ON")
+	} else {
+		out.WriteString("Away mode is scheduled")
+	}
+	fmt.Fprintf(out, " (%s through %s, set by %s): ",
+		a.Start.In(clock.Timezone).Format("Mon 1/2"),
+		a.End.Add(-time.Second).In(clock.Timezone).Format("Mon 1/2"),
+		a.SetBy)
+	policies := []string{}
+	for _, kind := range approvalKinds {
+		policies = append(policies, fmt.Sprintf("%s: %s", kind, a.PolicyFor(kind, a.Start)))
+	}
+	out.WriteString(strings.Join(policies, ", "))
+	return out.String()
+}
+
+func (a AwayMode) dup() AwayMode {
+	out := a
+	if a.Policies != nil {
+		out.Policies = map[ApprovalKind]AwayPolicy{}
+		for kind, policy := range a.Policies {
+			out.Policies[kind] = policy
+		}
+	}
+	return out
+}
+
+// ParseAwayCommand parses
+//
+//	/away <start> <end> [invite|badger|game-on|no-game]=[approve|deny|wait|delegate:<organizer@example.com>]...
+//
+// dates are M/D or YYYY-MM-DD and the range is inclusive
+func ParseAwayCommand(commandLine string, setBy mail.EmailAddress, now time.Time, organizers config.Organizers) (AwayMode, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) < 3 || fields[0] != "/away" {
+		return AwayMode{}, fmt.Errorf("usage: /away <start> <end> [invite|badger|game-on|no-game]=[approve|deny|wait|delegate:<organizer email>]...")
+	}
+	start, err := parseAwayDate(fields[1], now)
+	if err != nil {
+		return AwayMode{}, err
+	}
+	end, err := parseAwayDate(fields[2], now)
+	if err != nil {
+		return AwayMode{}, err
+	}
+	if end.Before(start) && !strings.Contains(fields[2], "-") {
+		end = end.AddDate(1, 0, 0)
+	}
+	if end.Before(start) {
+		return AwayMode{}, fmt.Errorf("invalid away range: %s is before %s", fields[2], fields[1])
+	}
+	end = end.AddDate(0, 0, 1) // the range is inclusive
+	if !end.After(now) {
+		return AwayMode{}, fmt.Errorf("invalid away range: %s - %s is in the past", fields[1], fields[2])
+	}
+
+	away := AwayMode{
+		Start:    start,
+		End:      end,
+		SetBy:    setBy,
+		Policies: map[ApprovalKind]AwayPolicy{},
+	}
+	for _, field := range fields[3:] {
+		kind, rawPolicy, ok := strings.Cut(field, "=")
+		if !ok {
+			return AwayMode{}, fmt.Errorf("invalid away policy: %s - must look like invite=approve", field)
+		}
+		if !isApprovalKind(ApprovalKind(kind)) {
+			return AwayMode{}, fmt.Errorf("invalid away policy: %s - must be one of invite, badger, game-on, or no-game", kind)
+		}
+		policy := AwayPolicy{Action: AwayAction(rawPolicy)}
+		if action, delegate, ok := strings.Cut(rawPolicy, ":"); ok && AwayAction(action) == AwayActionDelegate {
+			organizer, found := organizers.Find(mail.EmailAddress(delegate))
+			if !found {
+				return AwayMode{}, fmt.Errorf("invalid away policy: %s is not an organizer who can run Saturday Disco", delegate)
+			}
+			policy = AwayPolicy{Action: AwayActionDelegate, Delegate: organizer.Address}
+		} else if policy.Action != AwayActionApprove && policy.Action != AwayActionDeny && policy.Action != AwayActionWait {
+			return AwayMode{}, fmt.Errorf("invalid away policy: %s - must be one of approve, deny, wait, or delegate:<organizer email>", rawPolicy)
+		}
+		away.Policies[ApprovalKind(kind)] = policy
+	}
+	return away, nil
+}
+
+func isApprovalKind(kind ApprovalKind) bool {
+	for _, k := range approvalKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAwayDate(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, clock.Timezone); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("1/2", s, clock.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid away date: %s - must be M/D or YYYY-MM-DD", s)
+	}
+	now = now.In(clock.Timezone)
+	t = time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, clock.Timezone)
+	// dates more than a month in the past are assumed to refer to next year
+	if t.Before(now.AddDate(0, -1, 0)) {
+		t = t.AddDate(1, 0, 0)
+	}
+	return t, nil
+}
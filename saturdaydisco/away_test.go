@@ -0,0 +1,73 @@
+package saturdaydisco_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	clockpkg "github.com/onsi/disco/clock"
+	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/mail"
+	. "github.com/onsi/disco/saturdaydisco"
+)
+
+var _ = Describe("Away mode", func() {
+	var now time.Time
+	var organizers config.Organizers
+	var boss, helper mail.EmailAddress
+
+	BeforeEach(func() {
+		now = time.Date(2023, time.September, 24, 9, 0, 0, 0, clockpkg.Timezone)
+		boss = mail.EmailAddress("Boss <boss@example.com>")
+		helper = mail.EmailAddress("Helper <helper@example.com>")
+		organizers = config.Organizers{{Address: boss, Role: config.RoleBoss}, {Address: helper, Role: config.RoleOrganizer}}
+	})
+
+	It("parses an inclusive date range and per-approval policies", func() {
+		away, err := ParseAwayCommand("/away 9/25 10/1 invite=approve badger=deny game-on=delegate:HELPER@example.com", boss, now, organizers)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(away.Start).Should(Equal(time.Date(2023, time.September, 25, 0, 0, 0, 0, clockpkg.Timezone)))
+		Ω(away.End).Should(Equal(time.Date(2023, time.October, 2, 0, 0, 0, 0, clockpkg.Timezone)))
+		Ω(away.SetBy).Should(Equal(boss))
+
+		during := time.Date(2023, time.October, 1, 23, 0, 0, 0, clockpkg.Timezone)
+		Ω(away.IsActive(now)).Should(BeFalse())
+		Ω(away.IsActive(during)).Should(BeTrue())
+		Ω(away.PolicyFor(ApprovalInvite, during)).Should(Equal(AwayPolicy{Action: AwayActionApprove}))
+		Ω(away.PolicyFor(ApprovalBadger, during)).Should(Equal(AwayPolicy{Action: AwayActionDeny}))
+		Ω(away.PolicyFor(ApprovalGameOn, during)).Should(Equal(AwayPolicy{Action: AwayActionDelegate, Delegate: helper}))
+		Ω(away.PolicyFor(ApprovalNoGame, during)).Should(Equal(AwayPolicy{Action: AwayActionWait}))
+		Ω(away.PolicyFor(ApprovalInvite, now)).Should(Equal(AwayPolicy{Action: AwayActionWait}))
+		Ω(away.PolicyFor(ApprovalInvite, away.End)).Should(Equal(AwayPolicy{Action: AwayActionWait}))
+
+		Ω(away.Status(now)).Should(Equal("Away mode is scheduled (Mon 9/25 through Sun 10/1, set by Boss <boss@example.com>): invite: auto-approve, badger: auto-deny, game-on: ask Helper <helper@example.com>, no-game: wait for approval"))
+		Ω(away.Status(during)).Should(HavePrefix("Away mode is ON"))
+		Ω(away.Status(away.End)).Should(BeEmpty())
+	})
+
+	It("supports ISO dates and ranges that span the new year", func() {
+		away, err := ParseAwayCommand("/away 2023-12-30 2024-01-02", boss, now, organizers)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(away.End).Should(Equal(time.Date(2024, time.January, 3, 0, 0, 0, 0, clockpkg.Timezone)))
+
+		away, err = ParseAwayCommand("/away 12/30 1/2", boss, now, organizers)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(away.Start).Should(Equal(time.Date(2023, time.December, 30, 0, 0, 0, 0, clockpkg.Timezone)))
+		Ω(away.End).Should(Equal(time.Date(2024, time.January, 3, 0, 0, 0, 0, clockpkg.Timezone)))
+	})
+
+	DescribeTable("rejecting malformed commands", func(commandLine string, expectedError string) {
+		_, err := ParseAwayCommand(commandLine, boss, now, organizers)
+		Ω(err).Should(MatchError(ContainSubstring(expectedError)))
+	},
+		Entry(nil, "/away", "usage: /away"),
+		Entry(nil, "/away 9/25", "usage: /away"),
+		Entry(nil, "/away tomorrow 9/30", "invalid away date: tomorrow"),
+		Entry(nil, "/away 2023-09-30 2023-09-25", "is before"),
+		Entry(nil, "/away 9/25 9/30 invite", "must look like invite=approve"),
+		Entry(nil, "/away 9/25 9/30 party=approve", "must be one of invite, badger, game-on, or no-game"),
+		Entry(nil, "/away 9/25 9/30 invite=maybe", "must be one of approve, deny, wait, or delegate"),
+		Entry(nil, "/away 9/25 9/30 invite=delegate:stranger@example.com", "stranger@example.com is not an organizer"),
+	)
+})
@@ -0,0 +1,53 @@
+package saturdaydisco
+
+import (
+	"time"
+
+	"github.com/onsi/disco/calendar"
+	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/mail"
+)
+
+const GAME_DURATION = 2 * time.Hour
+const CALENDAR_FILENAME = "saturday-frisbee.ics"
+
+// calendarEvent describes this week's game to calendar apps.  The UID is derived from the game date so invites and cancellations for the
+// same game line up, and sequence should go up with each update.
+func calendarEvent(snapshot SaturdayDiscoSnapshot, organizer mail.EmailAddress, sequence int) calendar.Event {
+	return calendar.Event{
+		UID:         "saturday-" + snapshot.T.In(clock.Timezone).Format(config.BLACKOUT_DATE_FORMAT) + "@sedenverultimate.net",
+		Sequence:    sequence,
+		Start:       snapshot.T,
+		End:         snapshot.T.Add(GAME_DURATION),
+		Summary:     "Saturday Bible Park Frisbee",
+		Location:    "James Bible Park",
+		Description: "Bring a red shirt, a blue shirt, and a white shirt if you have them",
+		URL:         "https://maps.app.goo.gl/P1vm2nkZdYLGZbxb9",
+		Status:      calendar.StatusConfirmed,
+		Organizer:   organizer,
+	}
+}
+
+// calendarInvite is attached to game-on e-mails (and to no-game e-mails that follow one)
+func (s *SaturdayDisco) calendarInvite(method calendar.Method) mail.Attachment {
+	return calendar.Calendar{
+		Method: method,
+		Events: []calendar.Event{calendarEvent(s.SaturdayDiscoSnapshot, s.config.SaturdayDiscoEmail, s.CalendarSequence)},
+	}.Attachment(CALENDAR_FILENAME, s.alarmClock.Time())
+}
+
+// CalendarFeed lists this week's game once it's been called - or cancelled after being called - for folks who subscribe to /calendar/saturday.ics
+func (s *SaturdayDisco) CalendarFeed() calendar.Calendar {
+	feed := calendar.Calendar{Name: "Saturday Bible Park Frisbee", Method: calendar.MethodPublish, Events: []calendar.Event{}}
+	snapshot := s.GetSnapshot()
+	if snapshot.CalendarSequence == 0 {
+		return feed
+	}
+	event := calendarEvent(snapshot, s.config.SaturdayDiscoEmail, snapshot.CalendarSequence-1)
+	if snapshot.State != StateGameOnSent && snapshot.State != StateReminderSent {
+		event.Status = calendar.StatusCancelled
+	}
+	feed.Events = append(feed.Events, event)
+	return feed
+}
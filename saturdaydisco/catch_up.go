@@ -0,0 +1,110 @@
+package saturdaydisco
+
+import (
+	"time"
+
+	"github.com/onsi/disco/commands"
+)
+
+// MissedTransition is a transition Saturday Disco would have made (or a scheduled action it would have run) had it been running
+type MissedTransition = commands.MissedTransition[SaturdayDiscoState]
+
+type MissedTransitions = commands.MissedTransitions[SaturdayDiscoState]
+
+func isApprovalState(state SaturdayDiscoState) bool {
+	switch state {
+	case StateRequestedInviteApproval, StateRequestedBadgerApproval, StateRequestedGameOnApproval, StateRequestedNoGameApproval:
+		return true
+	}
+	return false
+}
+
+// nextStateAfter is the state performNextEvent would move us to if nobody answered any approval requests.
+// It returns false for the end states - we never catch up across a reset; a backup from a previous week is simply discarded.
+func nextStateAfter(state SaturdayDiscoState, hasQuorum bool) (SaturdayDiscoState, bool) {
+	switch state {
+	case StatePending:
+		return StateRequestedInviteApproval, true
+	case StateRequestedInviteApproval:
+		return StateInviteSent, true
+	case StateInviteSent:
+		if hasQuorum {
+			return StateRequestedGameOnApproval, true
+		}
+		return StateRequestedBadgerApproval, true
+	case StateRequestedBadgerApproval:
+		if hasQuorum {
+			return StateRequestedGameOnApproval, true
+		}
+		return StateBadgerSent, true
+	case StateRequestedGameOnApproval:
+		if hasQuorum {
+			return StateGameOnSent, true
+		}
+		return StateRequestedNoGameApproval, true
+	case StateBadgerSent, StateBadgerNotSent:
+		if hasQuorum {
+			return StateRequestedGameOnApproval, true
+		}
+		return StateRequestedNoGameApproval, true
+	case StateRequestedNoGameApproval:
+		if hasQuorum {
+			return StateRequestedGameOnApproval, true
+		}
+		return StateNoGameSent, true
+	case StateGameOnSent:
+		return StateReminderSent, true
+	}
+	return "", false
+}
+
+// missedTransitions works out which transitions we slept through between NextEvent and now
+func (s *SaturdayDisco) missedTransitions(now time.Time) MissedTransitions {
+	missed := MissedTransitions{}
+	state, at := s.State, s.NextEvent
+	hasQuorum := s.hasQuorum()
+	for !at.IsZero() && !at.After(now) {
+		next, ok := nextStateAfter(state, hasQuorum)
+		if !ok {
+			break
+		}
+		missed = append(missed, MissedTransition{At: at, From: state, To: next})
+		state, at = next, s.nextEventFor(next, at)
+	}
+	return missed
+}
+
+// resumeMissedTransitions runs what we missed in the dance loop's order: held actions first, then NextEvent if it's still due
+func (s *SaturdayDisco) resumeMissedTransitions() {
+	actionIDs := s.CatchUp.ActionIDs()
+	s.CatchUp = nil
+	for _, id := range actionIDs {
+		if action, ok := s.Deferred.Find(id); ok {
+			s.performDeferredAction(action.AlarmName())
+		}
+	}
+	if !s.NextEvent.IsZero() && !s.NextEvent.After(s.alarmClock.Time()) {
+		s.performNextEvent()
+	}
+	s.syncAlarms()
+}
+
+// skipMissedTransitions is /skip, worked out afresh since the counts may have changed - and any approval we'd be waiting on gets asked now
+func (s *SaturdayDisco) skipMissedTransitions() {
+	for _, id := range s.CatchUp.ActionIDs() {
+		s.Deferred = s.Deferred.Remove(id)
+	}
+	missed := s.missedTransitions(s.alarmClock.Time())
+	if len(missed) == 0 {
+		s.CatchUp = nil
+		s.syncAlarms()
+		return
+	}
+	last := missed[len(missed)-1]
+	if isApprovalState(last.To) {
+		s.transitionTo(last.From)
+		s.performNextEvent()
+	} else {
+		s.transitionTo(last.To)
+	}
+}
@@ -6,13 +6,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"text/template"
 	"time"
 
+	"github.com/onsi/disco/audit"
+	"github.com/onsi/disco/calendar"
 	"github.com/onsi/disco/clock"
+	"github.com/onsi/disco/commands"
 	"github.com/onsi/disco/config"
 	"github.com/onsi/disco/mail"
 	"github.com/onsi/disco/s3db"
@@ -40,6 +43,7 @@ const RETRY_DELAY = 5 * time.Minute
 const ApprovalTime = 4 * time.Hour
 
 const KEY = "saturday-disco"
+const NEXT_EVENT_ALARM = "next-event"
 
 type SaturdayDiscoState string
 
@@ -77,52 +81,131 @@ const (
 	CommandRequestedGameOnApprovalReply CommandType = "requested_game_on_approval_reply"
 	CommandRequestedNoGameApprovalReply CommandType = "requested_no_game_approval_reply"
 	CommandInvalidReply                 CommandType = "invalid_reply"
-
-	CommandAdminStatus   CommandType = "admin_status"
-	CommandAdminAbort    CommandType = "admin_abort"
-	CommandAdminReset    CommandType = "admin_reset"
-	CommandAdminGameOn   CommandType = "admin_game_on"
-	CommandAdminNoGame   CommandType = "admin_no_game"
-	CommandAdminSetCount CommandType = "admin_set_count"
-	CommandAdminDebug    CommandType = "admin_debug"
-	CommandAdminInvalid  CommandType = "admin_invalid"
+	CommandQuarantineReply              CommandType = "quarantine_reply"
+
+	CommandAdminStatus     CommandType = "admin_status"
+	CommandAdminAbort      CommandType = "admin_abort"
+	CommandAdminReset      CommandType = "admin_reset"
+	CommandAdminGameOn     CommandType = "admin_game_on"
+	CommandAdminNoGame     CommandType = "admin_no_game"
+	CommandAdminSetCount   CommandType = "admin_set_count"
+	CommandAdminDebug      CommandType = "admin_debug"
+	CommandAdminInvalid    CommandType = "admin_invalid"
+	CommandAdminAway       CommandType = "admin_away"
+	CommandAdminBack       CommandType = "admin_back"
+	CommandAdminHelp       CommandType = "admin_help"
+	CommandAdminUndo       CommandType = "admin_undo"
+	CommandAdminBatch      CommandType = "admin_batch"
+	CommandAdminSchedule   CommandType = "admin_schedule"
+	CommandAdminCancel     CommandType = "admin_cancel"
+	CommandAdminResume     CommandType = "admin_resume"
+	CommandAdminSkip       CommandType = "admin_skip"
+	CommandAdminBlackout   CommandType = "admin_blackout"
+	CommandAdminUnblackout CommandType = "admin_unblackout"
+	CommandAdminQuarantine CommandType = "admin_quarantine"
 
 	CommandPlayerSetCount CommandType = "player_set_count"
 	CommandPlayerIgnore   CommandType = "player_ignore"
 	CommandPlayerError    CommandType = "player_error"
+
+	CommandBounce CommandType = "bounce"
 )
 
 type Command struct {
-	CommandType CommandType
+	CommandType CommandType `json:"commandType"`
 	Email       mail.Email
 
-	Approved          bool
-	Delay             int
-	AdditionalContent string
-
-	EmailAddress mail.EmailAddress
-	Count        int
+	Approved          bool   `json:"approved"`
+	Delay             int    `json:"delay"`
+	AdditionalContent string `json:"additionalContent"`
+
+	EmailAddress mail.EmailAddress `json:"emailAddress"`
+	Count        int               `json:"count"`
+
+	Away AwayMode `json:"-"`
+	// set when away mode answers an approval request on the boss' behalf
+	Automatic bool `json:"-"`
+	// commands sent together in one e-mail, one per line
+	Batch []Command `json:"-"`
+	// set by --dry-run: reply with what would happen instead of doing it
+	DryRun bool `json:"-"`
+	// for /schedule and /cancel
+	Deferred   commands.DeferredAction `json:"-"`
+	DeferredID int                     `json:"-"`
+	// for /blackout and /unblackout
+	Blackout   config.Blackout `json:"-"`
+	BlackoutID int             `json:"-"`
+	// for the boss' reply to a quarantine confirmation request - or their click on the dashboard
+	QuarantineID int `json:"quarantineID"`
+	// which of our e-mails a player was replying to, if any
+	RepliedTo ThreadRef `json:"-"`
 
 	Error error
 }
 
-type ProcessedEmailIDs []string
-
-func (p ProcessedEmailIDs) Contains(id string) bool {
-	for _, processedID := range p {
-		if processedID == id {
-			return true
+// dashboardCommandLine renders a command submitted via the dashboard as the e-mail the boss would have sent
+func (c Command) dashboardCommandLine() (string, error) {
+	line := ""
+	switch c.CommandType {
+	case CommandRequestedInviteApprovalReply, CommandRequestedBadgerApprovalReply, CommandRequestedGameOnApprovalReply, CommandRequestedNoGameApprovalReply:
+		if c.Delay > 0 {
+			line = fmt.Sprintf("/delay %d", c.Delay)
+		} else if c.Approved {
+			line = "/approve"
+		} else {
+			line = "/deny"
+		}
+	case CommandAdminSetCount:
+		if c.EmailAddress.Address() == "" || c.Count < 0 {
+			return "", fmt.Errorf("invalid /set command: %s %d", c.EmailAddress, c.Count)
 		}
+		line = fmt.Sprintf("/set %s %d", c.EmailAddress, c.Count)
+	case CommandAdminAbort:
+		line = "/abort"
+	case CommandAdminReset:
+		line = "/RESET-RESET-RESET"
+	case CommandAdminGameOn:
+		line = "/game-on"
+	case CommandAdminNoGame:
+		line = "/no-game"
+	case CommandQuarantineReply:
+		if c.QuarantineID <= 0 {
+			return "", fmt.Errorf("invalid quarantine id: %d", c.QuarantineID)
+		}
+		line = "/reject"
+		if c.Approved {
+			line = "/confirm"
+		}
+	default:
+		return "", fmt.Errorf("invalid dashboard command: %s", c.CommandType)
 	}
-	return false
+	if c.AdditionalContent != "" {
+		line += "\n\n" + c.AdditionalContent
+	}
+	return line, nil
 }
 
 type SaturdayDiscoSnapshot struct {
-	State             SaturdayDiscoState `json:"state"`
-	Participants      Participants       `json:"participants"`
-	NextEvent         time.Time          `json:"next_event"`
-	T                 time.Time          `json:"reference_time"`
-	ProcessedEmailIDs ProcessedEmailIDs  `json:"processed_email_ids"`
+	State             SaturdayDiscoState         `json:"state"`
+	Participants      Participants               `json:"participants"`
+	NextEvent         time.Time                  `json:"next_event"`
+	T                 time.Time                  `json:"reference_time"`
+	ProcessedEmailIDs commands.ProcessedEmailIDs `json:"processed_email_ids"`
+	AuditLog          audit.Log                  `json:"audit_log"`
+	Away              AwayMode                   `json:"away"`
+	Deferred          commands.DeferredActions   `json:"deferred"`
+	// see commands.MissedTransitions
+	CatchUp MissedTransitions `json:"catch_up,omitempty"`
+	// blackouts added by e-mail - the rest come from the calendar file in config
+	Blackouts config.Blackouts `json:"blackouts"`
+	// how many calendar updates (invites and cancellations) we've sent for this week's game
+	CalendarSequence int `json:"calendar_sequence,omitempty"`
+	// the sequence for mail.TransitionKey - carried through resets and /undo
+	SentEmails int `json:"sent_emails,omitempty"`
+	// unauthenticated admin commands waiting for the boss to confirm them
+	Quarantine commands.Quarantine `json:"quarantine,omitempty"`
+	// addresses our e-mail has bounced for - unlike the participants, these carry over from week to week
+	Undeliverable commands.Undeliverable `json:"undeliverable,omitempty"`
 }
 
 func (s SaturdayDiscoSnapshot) dup() SaturdayDiscoSnapshot {
@@ -131,6 +214,17 @@ func (s SaturdayDiscoSnapshot) dup() SaturdayDiscoSnapshot {
 		Participants: s.Participants.dup(),
 		NextEvent:    s.NextEvent,
 		T:            s.T,
+		AuditLog:     s.AuditLog.Dup(),
+		Away:         s.Away.dup(),
+		Deferred:     s.Deferred.Dup(),
+		CatchUp:      s.CatchUp.Dup(),
+		Blackouts:    s.Blackouts.Dup(),
+		Quarantine:   s.Quarantine.Dup(),
+
+		Undeliverable: s.Undeliverable.Dup(),
+
+		CalendarSequence: s.CalendarSequence,
+		SentEmails:       s.SentEmails,
 	}
 }
 
@@ -143,12 +237,35 @@ type SaturdayDisco struct {
 	interpreter InterpreterInt
 	forecaster  weather.ForecasterInt
 	db          s3db.S3DBInt
+	alarms      *clock.Alarms
 	commandC    chan Command
 	snapshotC   chan chan<- SaturdayDiscoSnapshot
 	templateC   chan chan<- TemplateData
 	config      config.Config
 	ctx         context.Context
 	cancel      func()
+
+	// in-memory only - a restart (or the next scheduled event) clears the undo history
+	undoStack     []undoEntry
+	recentActions []audit.Entry
+
+	// out-of-office and other autoresponders we've dropped since we started - just for the logs
+	droppedAutoReplies atomic.Int64
+}
+
+const MAX_UNDO = 10
+
+type undoEntry struct {
+	snapshot SaturdayDiscoSnapshot
+	actions  []audit.Entry
+}
+
+func (u undoEntry) description() string {
+	actions := []string{}
+	for _, entry := range u.actions {
+		actions = append(actions, entry.Action)
+	}
+	return strings.Join(actions, ", ")
 }
 
 type TemplateData struct {
@@ -161,6 +278,10 @@ type TemplateData struct {
 	GameOff           bool
 	Forecast          weather.Forecast
 	DiscoEmailAddress string
+	AwayStatus        string
+	// set when next week is blacked out and the blackout should be announced
+	UpcomingBlackout  config.Blackout
+	UpcomingBlackouts config.Blackouts
 
 	Message       string
 	Error         error
@@ -197,9 +318,70 @@ func (e TemplateData) WithEmailDebugKey(key string) TemplateData {
 	return e
 }
 
+func (e TemplateData) BossURL() string {
+	return "https://www.sedenverultimate.net/saturday/boss"
+}
+
+func (e TemplateData) JSONForBoss() string {
+	participants := []map[string]any{}
+	for _, participant := range e.Participants {
+		relevantEmails := []map[string]any{}
+		for _, email := range participant.RelevantEmails {
+			relevantEmails = append(relevantEmails, map[string]any{
+				"from":    email.From,
+				"date":    email.Date,
+				"subject": email.Subject,
+				"text":    email.Text,
+			})
+		}
+		participants = append(participants, map[string]any{
+			"address":        participant.Address,
+			"count":          participant.Count,
+			"relevantEmails": relevantEmails,
+		})
+	}
+
+	deferred := []string{}
+	for _, action := range e.Deferred {
+		deferred = append(deferred, action.String())
+	}
+
+	blackouts := []string{}
+	for _, blackout := range e.UpcomingBlackouts {
+		blackouts = append(blackouts, blackout.String())
+	}
+
+	quarantine := []map[string]any{}
+	for _, quarantined := range e.Quarantine {
+		quarantine = append(quarantine, map[string]any{
+			"id":          quarantined.ID,
+			"description": quarantined.String(),
+			"text":        quarantined.Email.Text,
+		})
+	}
+
+	out, _ := json.Marshal(map[string]any{
+		"state":        e.State,
+		"gameDate":     e.GameDate,
+		"gameTime":     e.GameTime,
+		"nextEvent":    e.NextEvent,
+		"forecast":     e.Forecast.String(),
+		"count":        e.Participants.Count(),
+		"hasQuorum":    e.HasQuorum,
+		"participants": participants,
+		"auditLog":     e.AuditLog.Recent(20),
+		"awayStatus":   e.AwayStatus,
+		"deferred":     deferred,
+		"blackouts":    blackouts,
+		"quarantine":   quarantine,
+	})
+	return string(out)
+}
+
 func NewSaturdayDisco(config config.Config, w io.Writer, alarmClock clock.AlarmClockInt, outbox mail.OutboxInt, interpreter InterpreterInt, forecaster weather.ForecasterInt, db s3db.S3DBInt) (*SaturdayDisco, error) {
 	saturdayDisco := &SaturdayDisco{
 		alarmClock:  alarmClock,
+		alarms:      clock.NewAlarms(alarmClock),
 		outbox:      outbox,
 		interpreter: interpreter,
 		forecaster:  forecaster,
@@ -235,18 +417,27 @@ func NewSaturdayDisco(config config.Config, w io.Writer, alarmClock clock.AlarmC
 			if nextSaturday.After(snapshot.T) {
 				startupMessage = "Backup is from a previous week.  Resetting."
 				saturdayDisco.logi(0, "{{red}}%s{{/}}", startupMessage)
+				saturdayDisco.AuditLog = snapshot.AuditLog
+				saturdayDisco.Away = snapshot.Away
+				saturdayDisco.Deferred = snapshot.Deferred
+				saturdayDisco.Blackouts = snapshot.Blackouts
+				saturdayDisco.Quarantine = snapshot.Quarantine
+				saturdayDisco.SentEmails = snapshot.SentEmails
+				saturdayDisco.Undeliverable = snapshot.Undeliverable
+				saturdayDisco.pruneBlackouts()
 				saturdayDisco.reset()
+				saturdayDisco.syncAlarms()
 			} else {
 				startupMessage = "Backup is good.  Spinning up..."
 				saturdayDisco.logi(0, "{{green}}%s{{/}}", startupMessage)
 				saturdayDisco.SaturdayDiscoSnapshot = snapshot
-				alarmClock.SetAlarm(snapshot.NextEvent)
+				saturdayDisco.syncAlarms()
 			}
 		}
 	}
 
 	if err != nil {
-		outbox.SendEmail(saturdayDisco.emailForBoss("startup_error", TemplateData{
+		outbox.SendEmail(saturdayDisco.emailForOrganizers("startup_error", TemplateData{
 			Error: fmt.Errorf(startupMessage),
 		}))
 		return nil, err
@@ -262,7 +453,21 @@ func NewSaturdayDisco(config config.Config, w io.Writer, alarmClock clock.AlarmC
 		}
 	}
 
-	outbox.SendEmail(saturdayDisco.emailForBoss("startup", saturdayDisco.emailData().WithMessage(startupMessage)))
+	if lastBackup != nil {
+		// rather than fire off stale e-mails, ask the boss
+		now := saturdayDisco.alarmClock.Time()
+		if missed := commands.CatchUp(saturdayDisco.missedTransitions(now), saturdayDisco.Deferred, now); len(missed) > 0 {
+			saturdayDisco.logi(0, "{{red}}I missed %d event(s) while I was down.  Pausing until the boss tells me how to proceed.{{/}}", len(missed))
+			startupMessage += fmt.Sprintf("\nI missed %d event(s) while I was down.  I've paused until you tell me how to proceed.", len(missed))
+			saturdayDisco.CatchUp = missed
+			saturdayDisco.syncAlarms()
+		}
+	}
+
+	outbox.SendEmail(saturdayDisco.emailForOrganizers("startup", saturdayDisco.emailData().WithMessage(startupMessage)))
+	if len(saturdayDisco.CatchUp) > 0 {
+		outbox.SendEmail(saturdayDisco.emailForOrganizers("catch_up", saturdayDisco.emailData()))
+	}
 
 	go saturdayDisco.dance()
 	return saturdayDisco, nil
@@ -280,6 +485,32 @@ func (s *SaturdayDisco) HandleIncomingEmail(email mail.Email) {
 	}()
 }
 
+// command from an organizer's dashboard
+func (s *SaturdayDisco) HandleCommand(actor mail.EmailAddress, command Command) error {
+	// the dashboard is the one place the boss can confirm a quarantined command when e-mail can't be authenticated (e.g. over SMTP or IMAP)
+	if command.CommandType == CommandQuarantineReply && !actor.Equals(s.config.BossEmail) {
+		return fmt.Errorf("only the boss can confirm quarantined commands")
+	}
+	commandLine, err := command.dashboardCommandLine()
+	if err != nil {
+		return err
+	}
+	command.Email = mail.E().
+		WithFrom(actor).
+		WithTo(s.config.SaturdayDiscoEmail).
+		WithSubject("Saturday Disco Dashboard").
+		WithBody(commandLine)
+	command.Email.Date = s.alarmClock.Time().Format("Mon, 2 Jan 2006 15:04:05 -0700")
+	go func() {
+		s.commandC <- command
+	}()
+	return nil
+}
+
+func (s *SaturdayDisco) DroppedAutoReplies() int {
+	return int(s.droppedAutoReplies.Load())
+}
+
 func (s *SaturdayDisco) GetSnapshot() SaturdayDiscoSnapshot {
 	c := make(chan SaturdayDiscoSnapshot)
 	s.snapshotC <- c
@@ -325,6 +556,9 @@ func (s *SaturdayDisco) emailData() TemplateData {
 		GameOn:                s.State == StateGameOnSent || s.State == StateReminderSent,
 		GameOff:               s.State == StateNoInviteSent || s.State == StateNoGameSent,
 		Forecast:              forecast,
+		AwayStatus:            s.Away.Status(s.alarmClock.Time()),
+		UpcomingBlackout:      s.announcedBlackout(),
+		UpcomingBlackouts:     s.blackouts().Upcoming(config.DiscoSaturday, blackoutDate(s.alarmClock.Time())),
 	}.WithNextEvent(s.NextEvent)
 }
 
@@ -340,20 +574,33 @@ func (s *SaturdayDisco) emailBody(name string, data TemplateData) string {
 	return b.String()
 }
 
-func (s *SaturdayDisco) emailForBoss(name string, data TemplateData) mail.Email {
+// organizers returns everyone who can run the Saturday game
+func (s *SaturdayDisco) organizers() config.Organizers {
+	return s.config.AllOrganizers().Managers(config.DiscoSaturday)
+}
+
+func (s *SaturdayDisco) emailForOrganizers(name string, data TemplateData) mail.Email {
 	return mail.E().
 		WithFrom(s.config.SaturdayDiscoEmail).
-		WithTo(s.config.BossEmail).
+		WithTo(s.organizers().Addresses()...).
 		WithSubject(s.emailSubject(name, data)).
 		WithBody(s.emailBody(name, data))
 }
 
 func (s *SaturdayDisco) emailForList(name string, data TemplateData) mail.Email {
-	return mail.E().
+	email := mail.E().
 		WithFrom(s.config.SaturdayDiscoEmail).
 		WithTo(s.config.SaturdayDiscoList).
 		WithSubject(s.emailSubject(name, data)).
 		WithBody(mail.Markdown(s.emailBody(name, data)))
+	switch {
+	case name == "game_on":
+		email = email.WithAttachment(s.calendarInvite(calendar.MethodRequest))
+	case name == "no_game" && s.CalendarSequence > 0:
+		// folks may have already added the game to their calendars
+		email = email.WithAttachment(s.calendarInvite(calendar.MethodCancel))
+	}
+	return email
 }
 
 func (s *SaturdayDisco) dance() {
@@ -365,7 +612,14 @@ func (s *SaturdayDisco) dance() {
 			return
 		case <-s.alarmClock.C():
 			s.log("{{yellow}}alarm clock triggered{{/}}")
-			s.performNextEvent()
+			for _, name := range s.alarms.Due() {
+				if name != NEXT_EVENT_ALARM {
+					s.performDeferredAction(name)
+				} else if s.alarms.Get(NEXT_EVENT_ALARM).IsZero() {
+					// (a deferred action that ran first may have already moved us along and rescheduled the next event)
+					s.performNextEvent()
+				}
+			}
 			s.backup()
 		case command := <-s.commandC:
 			s.log("{{yellow}}received a command{{/}}")
@@ -394,8 +648,32 @@ func (s *SaturdayDisco) backup() {
 	s.log("{{green}}backed up{{/}}")
 }
 
-var setCommandRegex = regexp.MustCompile(`^/set\s+(.+)+\s+(\d+)$`)
-var delayCommandRegex = regexp.MustCompile(`^/delay\s+(\d+)$`)
+var adminCommands = commands.NewParser(
+	commands.Spec{Name: "status", Description: "reply with the current status"},
+	commands.Spec{Name: "set", Args: "<player> <count>", MinArgs: 2, MaxArgs: -1, Description: "set a player's count, e.g. /set Player Name <player@example.com> 2"},
+	commands.Spec{Name: "game-on", TakesBody: true, Description: "send the game-on e-mail right away - anything below this line is included in the e-mail"},
+	commands.Spec{Name: "no-game", TakesBody: true, Description: "send the no-game e-mail right away - anything below this line is included in the e-mail"},
+	commands.Spec{Name: "abort", Description: "stop the scheduler but keep tracking players so you can send /game-on and /no-game yourself"},
+	commands.Spec{Name: "away", Args: "<start> <end> [invite|badger|game-on|no-game]=[approve|deny|wait|delegate:<organizer email>]...", MinArgs: 2, MaxArgs: -1, Description: "turn on away mode (dates are M/D, inclusive)"},
+	commands.Spec{Name: "back", Description: "turn off away mode"},
+	commands.Spec{Name: "undo", Description: "undo the last change an organizer made (e-mails that were already sent can't be unsent)"},
+	commands.Spec{Name: "schedule", Args: "<when> [if-quorum|unless-quorum] /<command>", MinArgs: 2, MaxArgs: -1, TakesBody: true, Description: "run a command later, e.g. /schedule friday 6pm if-quorum /game-on - anything below this line goes along with the command"},
+	commands.Spec{Name: "cancel", Args: "<id>", MinArgs: 1, MaxArgs: 1, Description: "cancel a scheduled command (/status lists them)"},
+	commands.Spec{Name: "blackout", Args: "<start> [end] [silent] [announce] [reason]", MinArgs: 1, MaxArgs: -1, Description: "no games from <start> to [end] (dates are M/D, inclusive) - I'll send the no-invitation e-mail with the reason, or nothing at all if silent.  announce mentions it the week before"},
+	commands.Spec{Name: "unblackout", Args: "<id>", MinArgs: 1, MaxArgs: 1, Description: "remove a blackout added by e-mail (/status lists them)"},
+	commands.Spec{Name: "resume", Description: "after downtime: pick up where I left off, running the first event I missed right away"},
+	commands.Spec{Name: "skip", Description: "after downtime: jump ahead to where I'd be now without sending anything I missed"},
+	commands.Spec{Name: "debug", Description: "reply with an e-mail for debugging templates"},
+	commands.Spec{Name: "RESET-RESET-RESET", Description: "reset the system to pending and drop all the data.  Beware!"},
+)
+
+var replyCommands = commands.NewParser(
+	commands.Spec{Name: "approve", Aliases: []string{"yes", "shipit"}, MaxArgs: -1, TakesBody: true, Description: "approve the request - anything below this line is added to the top of the e-mail"},
+	commands.Spec{Name: "deny", Aliases: []string{"no"}, MaxArgs: -1, TakesBody: true, Description: "deny the request - anything below this line is added to the top of the e-mail"},
+	commands.Spec{Name: "delay", Args: "<hours>", MinArgs: 1, MaxArgs: 1, Description: "delay the request by <hours> hours"},
+	commands.Spec{Name: "abort", Description: "turn off the scheduler and enter manual mode"},
+	commands.Spec{Name: "RESET-RESET-RESET", Description: "reset the system to pending and drop all the data.  Beware!"},
+)
 
 func (s *SaturdayDisco) processEmail(email mail.Email) {
 	s.logi(0, "{{yellow}}Processing Email:{{/}}")
@@ -405,92 +683,38 @@ func (s *SaturdayDisco) processEmail(email mail.Email) {
 	if isFromSelf {
 		return
 	}
+	if email.IsBounce() && !mail.IsMessageIDFor(email.Bounce.OriginalMessageID, "saturday", s.config.SaturdayDiscoEmail) {
+		// only mail servers send bounces, but that doesn't make them honest - we only believe the ones about e-mail we actually sent
+		s.logi(1, "{{red}}ignoring a bounce for an e-mail I didn't send (%q){{/}}", email.Bounce.OriginalMessageID)
+		return
+	}
+	if email.IsAutoReply() && !email.IsBounce() {
+		// an out-of-office isn't an answer - and asking the interpreter costs money and might read it as "can't make it"
+		s.logi(1, "{{gray}}dropping an auto-reply (%s) - that's %d since I started{{/}}", email.AutoReply, s.droppedAutoReplies.Add(1))
+		return
+	}
 
-	isAdminCommand := email.From.Equals(s.config.BossEmail) &&
-		len(email.To) == 1 &&
-		len(email.CC) == 0 &&
-		email.To[0].Equals(s.config.SaturdayDiscoEmail)
-	isAdminReply := isAdminCommand && strings.HasPrefix(email.Subject, "Re: [")
+	isAdminCommand := s.organizers().IsAdminEmail(email, s.config.SaturdayDiscoEmail)
+	quarantineID, isQuarantineReply := commands.ParseQuarantineReply(email.Subject)
+	isQuarantineReply = isAdminCommand && isQuarantineReply
+	// organizers play too - only the boss is never a player
 	isPotentialPlayerCommand := !email.From.Equals(s.config.BossEmail)
 
-	var err error
-	if isAdminReply {
-		if strings.HasPrefix(email.Subject, "Re: [invite-approval-request]") {
-			c.CommandType = CommandRequestedInviteApprovalReply
-		} else if strings.HasPrefix(email.Subject, "Re: [badger-approval-request]") {
-			c.CommandType = CommandRequestedBadgerApprovalReply
-		} else if strings.HasPrefix(email.Subject, "Re: [game-on-approval-request]") {
-			c.CommandType = CommandRequestedGameOnApprovalReply
-		} else if strings.HasPrefix(email.Subject, "Re: [no-game-approval-request]") {
-			c.CommandType = CommandRequestedNoGameApprovalReply
-		} else {
-			c.Error = fmt.Errorf("invalid reply subject: %s", email.Subject)
-		}
-		if c.Error == nil {
-			commandLine := strings.Split(strings.TrimSpace(email.Text), "\n")[0]
-			if strings.HasPrefix(commandLine, "/approve") || strings.HasPrefix(commandLine, "/yes") || strings.HasPrefix(commandLine, "/shipit") {
-				c.Approved = true
-			} else if strings.HasPrefix(commandLine, "/deny") || strings.HasPrefix(commandLine, "/no") {
-				c.Approved = false
-			} else if match := delayCommandRegex.FindAllStringSubmatch(commandLine, -1); match != nil {
-				c.Delay, err = strconv.Atoi(match[0][1])
-				if c.Delay <= 0 {
-					c.Error = fmt.Errorf("invalid delay count for /delay command: %s - must be > 0", match[0][1])
-				}
-				if err != nil {
-					c.Error = fmt.Errorf("invalid delay count for /delay command: %s", match[0][1])
-				}
-			} else if strings.HasPrefix(commandLine, "/RESET-RESET-RESET") {
-				c.CommandType = CommandAdminReset
-			} else if strings.HasPrefix(commandLine, "/abort") {
-				c.CommandType = CommandAdminAbort
-			} else {
-				c.Error = fmt.Errorf("invalid command in reply, must be one of /approve, /yes, /shipit, /deny, /no, /delay <int>, /abort, or /RESET-RESET-RESET")
-			}
-		}
-		if c.Error == nil {
-			idxFirstNewline := strings.Index(email.Text, "\n")
-			if idxFirstNewline > -1 {
-				c.AdditionalContent = strings.Trim(email.Text[idxFirstNewline:], "\n")
-			}
-		} else {
-			c.CommandType = CommandInvalidReply
+	if email.IsBounce() {
+		// mailer daemons aren't players - there's no point asking the interpreter what they meant
+		c.CommandType = CommandBounce
+	} else if isAdminCommand && !s.isAuthenticated(email) {
+		if isQuarantineReply {
+			// confirming a quarantined command with another unauthenticated e-mail would defeat the point - the boss can use the dashboard
+			s.logi(1, "{{red}}ignoring an unauthenticated reply to a quarantine confirmation request: %s{{/}}", email.Authentication)
+			return
 		}
+		s.logi(1, "{{red}}admin command failed authentication (%s) - quarantining it{{/}}", email.Authentication)
+		c.CommandType = CommandAdminQuarantine
+	} else if isQuarantineReply {
+		c = s.quarantineReplyCommand(email, quarantineID)
 	} else if isAdminCommand {
-		commandLine := strings.Split(strings.TrimSpace(email.Text), "\n")[0]
-		if match := setCommandRegex.FindAllStringSubmatch(commandLine, -1); match != nil {
-			c.CommandType = CommandAdminSetCount
-			c.EmailAddress = mail.EmailAddress(match[0][1])
-			c.Count, err = strconv.Atoi(match[0][2])
-			if err != nil {
-				c.Error = fmt.Errorf("invalid count for /set command: %s", match[0][2])
-			}
-		} else if strings.HasPrefix(commandLine, "/status") {
-			c.CommandType = CommandAdminStatus
-		} else if strings.HasPrefix(commandLine, "/debug") {
-			c.CommandType = CommandAdminDebug
-		} else if strings.HasPrefix(commandLine, "/abort") {
-			c.CommandType = CommandAdminAbort
-		} else if strings.HasPrefix(email.Text, "/RESET-RESET-RESET") {
-			c.CommandType = CommandAdminReset
-		} else if strings.HasPrefix(commandLine, "/game-on") {
-			c.CommandType = CommandAdminGameOn
-			idxFirstNewline := strings.Index(email.Text, "\n")
-			if idxFirstNewline > -1 {
-				c.AdditionalContent = strings.Trim(email.Text[idxFirstNewline:], "\n")
-			}
-		} else if strings.HasPrefix(commandLine, "/no-game") {
-			c.CommandType = CommandAdminNoGame
-			idxFirstNewline := strings.Index(email.Text, "\n")
-			if idxFirstNewline > -1 {
-				c.AdditionalContent = strings.Trim(email.Text[idxFirstNewline:], "\n")
-			}
-		} else {
-			c.Error = fmt.Errorf("invalid command: %s", commandLine)
-		}
-		if c.Error != nil {
-			c.CommandType = CommandAdminInvalid
-		}
+		c = s.adminEmailCommand(email)
 	} else if isPotentialPlayerCommand {
 		potentialCommand, err := s.interpreter.InterpretEmail(email, s.Participants.CountFor(email.From))
 		if err != nil {
@@ -499,6 +723,7 @@ func (s *SaturdayDisco) processEmail(email mail.Email) {
 		} else {
 			c = potentialCommand
 		}
+		c.RepliedTo = repliedTo(email)
 	} else {
 		//this is not a command - do nothing
 		return
@@ -511,34 +736,226 @@ func (s *SaturdayDisco) processEmail(email mail.Email) {
 	s.commandC <- c
 }
 
+// isAuthenticated is true if the e-mail passed DKIM/SPF alignment for its sender - or if we've been told not to care
+func (s *SaturdayDisco) isAuthenticated(email mail.Email) bool {
+	return !s.config.RequireAuthenticatedAdmins || email.Authentication.AlignedWith(email.From)
+}
+
+// adminEmailCommand turns an organizer's e-mail to disco into a command - either a reply to an approval request or a fresh admin command
+func (s *SaturdayDisco) adminEmailCommand(email mail.Email) Command {
+	if !strings.HasPrefix(email.Subject, "Re: [") {
+		return s.adminCommand(email)
+	}
+	c := Command{Email: email}
+	if strings.HasPrefix(email.Subject, "Re: [invite-approval-request]") {
+		c.CommandType = CommandRequestedInviteApprovalReply
+	} else if strings.HasPrefix(email.Subject, "Re: [badger-approval-request]") {
+		c.CommandType = CommandRequestedBadgerApprovalReply
+	} else if strings.HasPrefix(email.Subject, "Re: [game-on-approval-request]") {
+		c.CommandType = CommandRequestedGameOnApprovalReply
+	} else if strings.HasPrefix(email.Subject, "Re: [no-game-approval-request]") {
+		c.CommandType = CommandRequestedNoGameApprovalReply
+	} else {
+		c.Error = fmt.Errorf("invalid reply subject: %s", email.Subject)
+	}
+	if c.Error == nil {
+		c = s.replyCommand(c)
+	}
+	if c.Error != nil {
+		c.CommandType = CommandInvalidReply
+	}
+	return c
+}
+
+// quarantineReplyCommand turns the boss' reply to a quarantine confirmation request into a command
+func (s *SaturdayDisco) quarantineReplyCommand(email mail.Email, id int) Command {
+	c := Command{CommandType: CommandQuarantineReply, Email: email, QuarantineID: id}
+	if !email.From.Equals(s.config.BossEmail) {
+		c.CommandType = CommandInvalidReply
+		c.Error = fmt.Errorf("only the boss can confirm quarantined commands")
+		return c
+	}
+	invocations, err := commands.QuarantineReplyCommands.Parse(email.Text)
+	if err != nil {
+		c.CommandType = CommandInvalidReply
+		c.Error = err
+		return c
+	}
+	switch invocations[0].Name {
+	case "help":
+		c.CommandType = CommandAdminHelp
+		c.AdditionalContent = commands.QuarantineReplyCommands.Help()
+	case "confirm":
+		c.Approved = true
+	}
+	return c
+}
+
+// replyCommand fills in c from the first command in an approval reply
+func (s *SaturdayDisco) replyCommand(c Command) Command {
+	invocations, err := replyCommands.Parse(c.Email.Text)
+	if err != nil {
+		c.Error = err
+		return c
+	}
+	invocation := invocations[0]
+	c.DryRun = invocation.DryRun
+	c.AdditionalContent = invocation.Body
+	switch invocation.Name {
+	case "help":
+		c.CommandType = CommandAdminHelp
+		c.AdditionalContent = replyCommands.Help()
+	case "approve":
+		c.Approved = true
+	case "deny":
+		c.Approved = false
+	case "delay":
+		c.Delay, err = strconv.Atoi(invocation.Args[0])
+		if err != nil {
+			c.Error = fmt.Errorf("invalid delay count for /delay command: %s", invocation.Args[0])
+		} else if c.Delay <= 0 {
+			c.Error = fmt.Errorf("invalid delay count for /delay command: %s - must be > 0", invocation.Args[0])
+		}
+	case "abort":
+		c.CommandType = CommandAdminAbort
+	case "RESET-RESET-RESET":
+		c.CommandType = CommandAdminReset
+	}
+	return c
+}
+
+// adminCommand turns an organizer's e-mail into a command - or a batch of commands if there's more than one line of them
+func (s *SaturdayDisco) adminCommand(email mail.Email) Command {
+	invocations, err := adminCommands.Parse(email.Text)
+	if err != nil {
+		return Command{CommandType: CommandAdminInvalid, Email: email, Error: err}
+	}
+	batch := Command{CommandType: CommandAdminBatch, Email: email}
+	for _, invocation := range invocations {
+		c := Command{Email: email, DryRun: invocation.DryRun, AdditionalContent: invocation.Body}
+		switch invocation.Name {
+		case "help":
+			c.CommandType = CommandAdminHelp
+			c.AdditionalContent = adminCommands.Help()
+		case "status":
+			c.CommandType = CommandAdminStatus
+		case "set":
+			c.CommandType = CommandAdminSetCount
+			count := invocation.Args[len(invocation.Args)-1]
+			c.EmailAddress = mail.EmailAddress(strings.Join(invocation.Args[:len(invocation.Args)-1], " "))
+			c.Count, err = strconv.Atoi(count)
+			if err != nil || c.Count < 0 {
+				c.Error = fmt.Errorf("invalid count for /set command: %s", count)
+			}
+		case "game-on":
+			c.CommandType = CommandAdminGameOn
+		case "no-game":
+			c.CommandType = CommandAdminNoGame
+		case "abort":
+			c.CommandType = CommandAdminAbort
+		case "away":
+			c.CommandType = CommandAdminAway
+			c.Away, c.Error = ParseAwayCommand("/away "+invocation.RawArgs(), email.From, s.alarmClock.Time(), s.organizers())
+		case "back":
+			c.CommandType = CommandAdminBack
+		case "undo":
+			c.CommandType = CommandAdminUndo
+		case "schedule":
+			c.CommandType = CommandAdminSchedule
+			c.Deferred, c.Error = commands.ParseSchedule(adminCommands, invocation.Args, invocation.Body, email.From, s.alarmClock.Time())
+		case "cancel":
+			c.CommandType = CommandAdminCancel
+			c.DeferredID, err = strconv.Atoi(strings.TrimPrefix(invocation.Args[0], "#"))
+			if err != nil {
+				c.Error = fmt.Errorf("invalid id for /cancel command: %s", invocation.Args[0])
+			}
+		case "blackout":
+			c.CommandType = CommandAdminBlackout
+			c.Blackout, c.Error = commands.ParseBlackout(invocation.Args, config.DiscoSaturday, email.From, s.alarmClock.Time())
+		case "unblackout":
+			c.CommandType = CommandAdminUnblackout
+			c.BlackoutID, err = strconv.Atoi(strings.TrimPrefix(invocation.Args[0], "#"))
+			if err != nil {
+				c.Error = fmt.Errorf("invalid id for /unblackout command: %s", invocation.Args[0])
+			}
+		case "resume":
+			c.CommandType = CommandAdminResume
+		case "skip":
+			c.CommandType = CommandAdminSkip
+		case "debug":
+			c.CommandType = CommandAdminDebug
+		case "RESET-RESET-RESET":
+			c.CommandType = CommandAdminReset
+		}
+		if c.Error != nil {
+			// don't run half a batch
+			return Command{CommandType: CommandAdminInvalid, Email: email, Error: c.Error}
+		}
+		batch.DryRun = batch.DryRun || c.DryRun
+		batch.Batch = append(batch.Batch, c)
+	}
+	if len(batch.Batch) == 1 {
+		return batch.Batch[0]
+	}
+	return batch
+}
+
 func (s *SaturdayDisco) transitionTo(state SaturdayDiscoState) {
+	if state == StatePending {
+		if blackout, ok := s.blackout(); ok && blackout.Silent {
+			s.logi(1, "{{yellow}}this week is blacked out (%s) - skipping it quietly{{/}}", blackout)
+			state = StateNoInviteSent
+		}
+	}
+	s.NextEvent = s.nextEventFor(state, s.alarmClock.Time())
+	if state == StateGameOnSent || (state == StateNoGameSent && s.CalendarSequence > 0) {
+		// we've just sent a calendar invite or cancellation
+		s.CalendarSequence += 1
+	}
+	s.State = state
+	if len(s.CatchUp) > 0 {
+		// moving again ends the catch-up
+		s.CatchUp = nil
+		s.syncAlarms()
+	}
+	if !s.NextEvent.IsZero() {
+		s.alarms.Set(NEXT_EVENT_ALARM, s.NextEvent)
+	}
+}
+
+// nextEventFor returns when the next event fires if we enter state at time t
+func (s *SaturdayDisco) nextEventFor(state SaturdayDiscoState, t time.Time) time.Time {
 	switch state {
 	case StatePending:
-		s.NextEvent = s.T.Add(-4*day - 4*time.Hour) //Tuesday, 6am
+		return s.T.Add(-4*day - 4*time.Hour) //Tuesday, 6am
 	case StateInviteSent:
-		s.NextEvent = s.T.Add(-2*day + 4*time.Hour) //Thursday, 2pm
+		return s.T.Add(-2*day + 4*time.Hour) //Thursday, 2pm
 	case StateBadgerSent, StateBadgerNotSent:
-		s.NextEvent = s.T.Add(-day - 4*time.Hour) //Friday, 6am
+		return s.T.Add(-day - 4*time.Hour) //Friday, 6am
 	case StateRequestedInviteApproval, StateRequestedBadgerApproval, StateRequestedGameOnApproval, StateRequestedNoGameApproval:
-		s.NextEvent = s.alarmClock.Time().Add(ApprovalTime) //you get 4 hours to reply, Boss
+		return t.Add(ApprovalTime) //you get 4 hours to reply, Boss
 	case StateGameOnSent:
-		s.NextEvent = s.T.Add(-4 * time.Hour) //Saturday, 6am
+		return s.T.Add(-4 * time.Hour) //Saturday, 6am
 	case StateNoInviteSent, StateNoGameSent, StateReminderSent, StateAbort:
-		s.NextEvent = s.T.Add(2 * time.Hour) //Saturday, 12pm is when we reset
-	}
-	s.State = state
-	if !s.NextEvent.IsZero() {
-		s.alarmClock.SetAlarm(s.NextEvent)
+		return s.T.Add(2 * time.Hour) //Saturday, 12pm is when we reset
 	}
+	return s.NextEvent
 }
 
 func (s *SaturdayDisco) performNextEvent() {
+	// once time moves on, undoing an admin change would rewind more than the admin's change
+	s.undoStack = nil
 	data := s.emailData()
 	switch s.State {
 	case StatePending:
+		if blackout, ok := s.blackout(); ok {
+			s.logi(1, "{{yellow}}this week is blacked out (%s) - sending the no-invitation e-mail{{/}}", blackout)
+			s.sendEmail(s.emailForList("no_invitation", data.WithMessage("%s", blackout.Reason)),
+				StateNoInviteSent, s.retryNextEventErrorHandler)
+			return
+		}
 		s.logi(1, "{{coral}}sending invite approval request to boss{{/}}")
-		s.sendEmail(s.emailForBoss("request_invite_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-			StateRequestedInviteApproval, s.retryNextEventErrorHandler)
+		s.requestApproval(ApprovalInvite, data, s.retryNextEventErrorHandler)
 
 	case StateRequestedInviteApproval:
 		s.logi(1, "{{green}}time's up, sending invitation e-mail{{/}}")
@@ -547,18 +964,15 @@ func (s *SaturdayDisco) performNextEvent() {
 	case StateInviteSent:
 		if s.hasQuorum() {
 			s.logi(1, "{{coral}}we have quorum!  asking for permission to send game-on{{/}}")
-			s.sendEmail(s.emailForBoss("request_game_on_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-				StateRequestedGameOnApproval, s.retryNextEventErrorHandler)
+			s.requestApproval(ApprovalGameOn, data, s.retryNextEventErrorHandler)
 		} else {
 			s.logi(1, "{{coral}}sending badger approval request to boss{{/}}")
-			s.sendEmail(s.emailForBoss("request_badger_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-				StateRequestedBadgerApproval, s.retryNextEventErrorHandler)
+			s.requestApproval(ApprovalBadger, data, s.retryNextEventErrorHandler)
 		}
 	case StateRequestedBadgerApproval:
 		if s.hasQuorum() {
 			s.logi(1, "{{coral}}we have quorum!  asking for permission to send game-on{{/}}")
-			s.sendEmail(s.emailForBoss("request_game_on_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-				StateRequestedGameOnApproval, s.retryNextEventErrorHandler)
+			s.requestApproval(ApprovalGameOn, data, s.retryNextEventErrorHandler)
 		} else {
 			s.logi(1, "{{green}}time's up, sending badger e-mail{{/}}")
 			s.sendEmail(s.emailForList("badger", data),
@@ -567,12 +981,10 @@ func (s *SaturdayDisco) performNextEvent() {
 	case StateBadgerSent, StateBadgerNotSent:
 		if s.hasQuorum() {
 			s.logi(1, "{{coral}}we have quorum!  asking for permission to send game-on{{/}}")
-			s.sendEmail(s.emailForBoss("request_game_on_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-				StateRequestedGameOnApproval, s.retryNextEventErrorHandler)
+			s.requestApproval(ApprovalGameOn, data, s.retryNextEventErrorHandler)
 		} else {
 			s.logi(1, "{{coral}}we still don't have quorum.  asking for permission to send no-game{{/}}")
-			s.sendEmail(s.emailForBoss("request_no_game_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-				StateRequestedNoGameApproval, s.retryNextEventErrorHandler)
+			s.requestApproval(ApprovalNoGame, data, s.retryNextEventErrorHandler)
 		}
 	case StateRequestedGameOnApproval:
 		if s.hasQuorum() {
@@ -581,14 +993,12 @@ func (s *SaturdayDisco) performNextEvent() {
 				StateGameOnSent, s.retryNextEventErrorHandler)
 		} else {
 			s.logi(1, "{{coral}}we lost quorum.  asking for permission to send no-game{{/}}")
-			s.sendEmail(s.emailForBoss("request_no_game_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-				StateRequestedNoGameApproval, s.retryNextEventErrorHandler)
+			s.requestApproval(ApprovalNoGame, data, s.retryNextEventErrorHandler)
 		}
 	case StateRequestedNoGameApproval:
 		if s.hasQuorum() {
 			s.logi(1, "{{coral}}we have quorum!  asking for permission to send game-on{{/}}")
-			s.sendEmail(s.emailForBoss("request_game_on_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-				StateRequestedGameOnApproval, s.retryNextEventErrorHandler)
+			s.requestApproval(ApprovalGameOn, data, s.retryNextEventErrorHandler)
 		} else {
 			s.logi(1, "{{green}}time's up, sending no-game e-mail{{/}}")
 			s.sendEmail(s.emailForList("no_game", data),
@@ -602,13 +1012,91 @@ func (s *SaturdayDisco) performNextEvent() {
 }
 
 func (s *SaturdayDisco) handleCommand(command Command) {
-	if s.ProcessedEmailIDs.Contains(command.Email.MessageID) {
-		s.logi(1, "{{coral}}I've already processed this email (id: %s).  Ignoring.{{/}}", command.Email.MessageID)
+	// dashboard commands don't come with a message id
+	if command.Email.MessageID != "" {
+		if s.ProcessedEmailIDs.Contains(command.Email.MessageID) {
+			s.logi(1, "{{coral}}I've already processed this email (id: %s).  Ignoring.{{/}}", command.Email.MessageID)
+			return
+		}
+		defer func() {
+			s.ProcessedEmailIDs = append(s.ProcessedEmailIDs, command.Email.MessageID)
+		}()
+	}
+	if command.Email.From != "" && !command.Email.IsBounce() {
+		var heardFrom bool
+		if s.Undeliverable, heardFrom = s.Undeliverable.Remove(command.Email.From); heardFrom {
+			s.logi(1, "{{green}}heard from %s - I'll start sending to them again{{/}}", command.Email.From)
+		}
+	}
+	if command.DryRun {
+		s.dryRun(command)
 		return
 	}
-	defer func() {
-		s.ProcessedEmailIDs = append(s.ProcessedEmailIDs, command.Email.MessageID)
-	}()
+	s.performCommand(command)
+}
+
+// performCommand runs the command and remembers how to undo any admin changes it made
+func (s *SaturdayDisco) performCommand(command Command) {
+	if command.CommandType == CommandAdminBatch {
+		s.logi(1, "{{green}}boss sent %d commands{{/}}", len(command.Batch))
+		for _, c := range command.Batch {
+			s.performCommand(c)
+		}
+		return
+	}
+	if command.CommandType == CommandQuarantineReply {
+		s.handleQuarantineReply(command)
+		return
+	}
+	snapshot := s.SaturdayDiscoSnapshot.dup()
+	s.recentActions = nil
+	s.dispatchCommand(command)
+	if len(s.recentActions) > 0 && command.CommandType != CommandAdminUndo {
+		s.undoStack = append(s.undoStack, undoEntry{snapshot: snapshot, actions: s.recentActions})
+		if len(s.undoStack) > MAX_UNDO {
+			s.undoStack = s.undoStack[len(s.undoStack)-MAX_UNDO:]
+		}
+	}
+	s.recentActions = nil
+}
+
+// dryRun runs the command against a scratch outbox, then puts everything back and tells the boss what would have happened
+func (s *SaturdayDisco) dryRun(command Command) {
+	s.logi(1, "{{yellow}}dry run - nothing will actually change{{/}}")
+	snapshot := s.SaturdayDiscoSnapshot.dup()
+	processedEmailIDs := s.ProcessedEmailIDs
+	undoStack := s.undoStack
+	outbox := s.outbox
+	preview := mail.NewFakeOutbox()
+	s.outbox = preview
+
+	s.performCommand(command)
+	finalState := s.State
+
+	s.outbox = outbox
+	s.SaturdayDiscoSnapshot = snapshot
+	s.ProcessedEmailIDs = processedEmailIDs
+	s.undoStack = undoStack
+	s.syncAlarms()
+
+	s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+		s.emailBody("dry_run", s.emailData().WithMessage(commands.DryRunSummary(snapshot.State, finalState, preview.Emails())))))
+}
+
+// syncAlarms re-arms everything after the snapshot has been swapped out (an /undo, a dry run), leaving alone whatever CatchUp holds
+func (s *SaturdayDisco) syncAlarms() {
+	s.alarms.ClearAll()
+	if !s.NextEvent.IsZero() && len(s.CatchUp) == 0 {
+		s.alarms.Set(NEXT_EVENT_ALARM, s.NextEvent)
+	}
+	for _, action := range s.Deferred {
+		if !s.CatchUp.Holds(action) {
+			s.alarms.Set(action.AlarmName(), action.At)
+		}
+	}
+}
+
+func (s *SaturdayDisco) dispatchCommand(command Command) {
 	switch command.CommandType {
 	case CommandRequestedInviteApprovalReply, CommandRequestedBadgerApprovalReply, CommandRequestedGameOnApprovalReply, CommandRequestedNoGameApprovalReply:
 		s.handleReplyCommand(command)
@@ -616,12 +1104,22 @@ func (s *SaturdayDisco) handleCommand(command Command) {
 		s.logi(1, "{{red}}boss sent me an invalid reply{{/}}")
 		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
 			s.emailBody("invalid_admin_email", s.emailData().WithError(command.Error))))
+	case CommandAdminQuarantine:
+		var quarantined commands.QuarantinedEmail
+		s.Quarantine, quarantined = s.Quarantine.Add(commands.QuarantinedEmail{At: s.alarmClock.Time(), Email: command.Email})
+		s.logi(1, "{{red}}quarantined %s - asking the boss to confirm it{{/}}", quarantined)
+		s.sendEmailWithNoTransition(mail.E().
+			WithFrom(s.config.SaturdayDiscoEmail).
+			WithTo(s.config.BossEmail).
+			WithSubject(quarantined.Subject()).
+			WithBody(s.emailBody("quarantine_confirmation_request", s.emailData().WithAttachment(quarantined))))
 	case CommandAdminStatus:
 		s.logi(1, "{{green}}boss is asking for status{{/}}")
 		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
 			s.emailBody("boss_status", s.emailData())))
 	case CommandAdminReset:
 		s.logi(1, "{{red}}BOSS IS RESETTING THE SYSTEM.  HOLD ON TO YOUR BUTTS.{{/}}")
+		s.recordAdminAction(command.Email.From, "reset the system")
 		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
 			s.emailBody("reset", s.emailData())))
 		s.reset()
@@ -635,44 +1133,184 @@ func (s *SaturdayDisco) handleCommand(command Command) {
 			)))
 	case CommandAdminAbort:
 		s.logi(1, "{{red}}boss has asked me to abort{{/}}")
+		s.recordAdminAction(command.Email.From, "aborted")
 		s.sendEmail(command.Email.Reply(s.config.SaturdayDiscoEmail,
 			s.emailBody("abort", s.emailData())),
 			StateAbort, s.replyWithFailureErrorHandler)
 	case CommandAdminGameOn:
 		s.logi(1, "{{green}}boss has asked me to send game-on{{/}}")
+		s.recordAdminAction(command.Email.From, "sent game on")
 		s.sendEmail(s.emailForList("game_on",
 			s.emailData().WithMessage(command.AdditionalContent)),
 			StateGameOnSent, s.replyWithFailureErrorHandler)
 	case CommandAdminNoGame:
 		s.logi(1, "{{red}}boss has asked me to send no-game{{/}}")
+		s.recordAdminAction(command.Email.From, "sent no game")
 		s.sendEmail(s.emailForList("no_game",
 			s.emailData().WithMessage(command.AdditionalContent)),
 			StateNoGameSent, s.replyWithFailureErrorHandler)
 	case CommandAdminSetCount:
 		s.logi(1, "{{green}}boss has asked me to adjust a participant count{{/}}")
 		s.logi(2, "{{gray}}Setting %s to %d{{/}}", command.EmailAddress, command.Count)
+		s.recordAdminAction(command.Email.From, "set %s to %d", command.EmailAddress, command.Count)
 		s.Participants = s.Participants.UpdateCount(command.EmailAddress, command.Count, command.Email)
 		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
 			s.emailBody("acknowledge_admin_set_count",
 				s.emailData().WithMessage("%s to %d", command.EmailAddress, command.Count))))
+	case CommandAdminAway:
+		s.logi(1, "{{green}}boss is going away{{/}}")
+		s.Away = command.Away
+		s.recordAdminAction(command.Email.From, "turned on away mode: %s", s.Away.Status(s.Away.Start))
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("acknowledge_away", s.emailData())))
+	case CommandAdminBack:
+		s.logi(1, "{{green}}boss is back{{/}}")
+		s.Away = AwayMode{}
+		s.recordAdminAction(command.Email.From, "turned off away mode")
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("acknowledge_back", s.emailData())))
+	case CommandAdminBlackout:
+		var blackout config.Blackout
+		s.Blackouts, blackout = s.Blackouts.Add(command.Blackout)
+		s.logi(1, "{{green}}boss added a blackout: %s{{/}}", blackout)
+		s.recordAdminAction(command.Email.From, "added blackout %s", blackout)
+		if s.State == StatePending {
+			// in case this week is now a silent blackout
+			s.transitionTo(StatePending)
+		}
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("acknowledge_blackout", s.emailData().WithMessage("added %s", blackout))))
+	case CommandAdminUnblackout:
+		var blackout config.Blackout
+		var ok bool
+		s.Blackouts, blackout, ok = s.Blackouts.Remove(command.BlackoutID)
+		if !ok {
+			s.logi(1, "{{red}}boss asked me to remove a blackout that doesn't exist: #%d{{/}}", command.BlackoutID)
+			s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+				s.emailBody("invalid_admin_email",
+					s.emailData().WithError(fmt.Errorf("there's no blackout #%d - /status lists them (blackouts from the calendar file can't be removed by e-mail)", command.BlackoutID)))))
+			return
+		}
+		s.logi(1, "{{yellow}}boss removed a blackout: %s{{/}}", blackout)
+		s.recordAdminAction(command.Email.From, "removed blackout %s", blackout)
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("acknowledge_blackout", s.emailData().WithMessage("removed %s", blackout))))
+	case CommandAdminResume, CommandAdminSkip:
+		if len(s.CatchUp) == 0 {
+			s.logi(1, "{{red}}boss asked me to catch up, but I'm not behind{{/}}")
+			s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+				s.emailBody("invalid_admin_email",
+					s.emailData().WithError(fmt.Errorf("I haven't missed any events - there's nothing to catch up on")))))
+			return
+		}
+		message := ""
+		if command.CommandType == CommandAdminResume {
+			s.logi(1, "{{green}}boss asked me to pick up where I left off{{/}}")
+			s.recordAdminAction(command.Email.From, "resumed after missing %d event(s)", len(s.CatchUp))
+			s.resumeMissedTransitions()
+			message = "I've picked up where I left off."
+		} else {
+			s.logi(1, "{{green}}boss asked me to skip the events I missed{{/}}")
+			s.recordAdminAction(command.Email.From, "skipped %d missed event(s)", len(s.CatchUp))
+			s.skipMissedTransitions()
+			message = "I've skipped ahead without sending anything I missed."
+		}
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("acknowledge_catch_up", s.emailData().WithMessage(message))))
+	case CommandAdminHelp:
+		s.logi(1, "{{green}}boss is asking for help{{/}}")
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("admin_help", s.emailData().WithMessage(command.AdditionalContent))))
+	case CommandAdminUndo:
+		if len(s.undoStack) == 0 {
+			s.logi(1, "{{red}}boss asked me to undo, but there's nothing to undo{{/}}")
+			s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+				s.emailBody("invalid_admin_email",
+					s.emailData().WithError(fmt.Errorf("there's nothing to undo - I can only undo admin changes made since the last scheduled event")))))
+			return
+		}
+		entry := s.undoStack[len(s.undoStack)-1]
+		s.undoStack = s.undoStack[:len(s.undoStack)-1]
+		s.logi(1, "{{yellow}}boss asked me to undo: %s{{/}}", entry.description())
+		auditLog, processedEmailIDs, sentEmails := s.AuditLog, s.ProcessedEmailIDs, s.SentEmails
+		quarantine, undeliverable := s.Quarantine, s.Undeliverable
+		s.SaturdayDiscoSnapshot = entry.snapshot
+		s.AuditLog, s.ProcessedEmailIDs, s.SentEmails = auditLog, processedEmailIDs, sentEmails
+		s.Quarantine, s.Undeliverable = quarantine, undeliverable
+		s.syncAlarms()
+		s.recordAdminAction(command.Email.From, "undid: %s", entry.description())
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("acknowledge_undo", s.emailData().WithMessage(entry.description()))))
+	case CommandAdminSchedule:
+		var action commands.DeferredAction
+		s.Deferred, action = s.Deferred.Add(command.Deferred)
+		s.logi(1, "{{green}}boss scheduled %s{{/}}", action)
+		s.alarms.Set(action.AlarmName(), action.At)
+		s.recordAdminAction(command.Email.From, "scheduled %s", action)
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("acknowledge_schedule", s.emailData().WithMessage(action.String()))))
+	case CommandAdminCancel:
+		action, ok := s.Deferred.Find(command.DeferredID)
+		if !ok {
+			s.logi(1, "{{red}}boss asked me to cancel a scheduled action that doesn't exist: #%d{{/}}", command.DeferredID)
+			s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+				s.emailBody("invalid_admin_email",
+					s.emailData().WithError(fmt.Errorf("there's no scheduled action #%d - /status lists them", command.DeferredID)))))
+			return
+		}
+		s.logi(1, "{{yellow}}boss cancelled %s{{/}}", action)
+		s.Deferred = s.Deferred.Remove(action.ID)
+		s.alarms.Clear(action.AlarmName())
+		s.recordAdminAction(command.Email.From, "cancelled %s", action)
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("acknowledge_cancel", s.emailData().WithMessage(action.String()))))
 	case CommandAdminInvalid:
 		s.logi(1, "{{red}}boss sent me an invalid command{{/}}")
 		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
 			s.emailBody("invalid_admin_email",
 				s.emailData().WithError(command.Error))))
 	case CommandPlayerSetCount:
+		if !command.RepliedTo.IsZero() {
+			s.logi(1, "{{gray}}player is replying to %s for %s{{/}}", command.RepliedTo.Description(), command.RepliedTo.Week)
+		}
+		if !command.RepliedTo.IsZero() && command.RepliedTo.Week != s.T.Format("2006-01-02") {
+			s.logi(1, "{{yellow}}player replied to an old thread - not changing this week's counts{{/}}")
+			s.sendEmailWithNoTransition(command.Email.Forward(s.config.SaturdayDiscoEmail, s.config.BossEmail,
+				mail.Markdown(s.emailBody("late_reply", s.emailData().WithMessage("%s", command.RepliedTo.Week).WithAttachment(command).WithEmailDebugKey(command.Email.DebugKey)))).
+				AndCC(s.organizers().Except(s.config.BossEmail).Addresses()...))
+			return
+		}
 		s.logi(1, "{{green}}player sent a message signing up.{{/}}")
+		// undoing an earlier admin change would also drop this player's change
+		s.undoStack = nil
 		s.logi(2, "{{gray}}Setting %s to %d{{/}}", command.EmailAddress, command.Count)
 		s.Participants = s.Participants.UpdateCount(command.EmailAddress, command.Count, command.Email)
 		s.sendEmailWithNoTransition(command.Email.Forward(s.config.SaturdayDiscoEmail, s.config.BossEmail,
-			mail.Markdown(s.emailBody("acknowledge_player_set_count", s.emailData().WithMessage("%d", command.Count).WithAttachment(command.EmailAddress).WithEmailDebugKey(command.Email.DebugKey)))))
+			mail.Markdown(s.emailBody("acknowledge_player_set_count", s.emailData().WithMessage("%d", command.Count).WithAttachment(command.EmailAddress).WithEmailDebugKey(command.Email.DebugKey)))).
+			AndCC(s.organizers().Except(s.config.BossEmail).Addresses()...))
 	case CommandPlayerIgnore:
 		s.logi(1, "{{yellow}}ignoring this e-mail{{/}}")
+	case CommandBounce:
+		s.handleBounce(command)
 	case CommandPlayerError:
 		s.logi(1, "{{red}}encountered an error while processing a player command: %s{{/}}", command.Error.Error())
 		s.sendEmailWithNoTransition(command.Email.Forward(s.config.SaturdayDiscoEmail, s.config.BossEmail,
-			s.emailBody("error_player_command", s.emailData().WithError(command.Error))))
+			s.emailBody("error_player_command", s.emailData().WithError(command.Error))).
+			AndCC(s.organizers().Except(s.config.BossEmail).Addresses()...))
+	}
+}
+
+// handleBounce keeps track of who our e-mail is bouncing for and tells the boss
+func (s *SaturdayDisco) handleBounce(command Command) {
+	bounce := command.Email.Bounce
+	if bounce.Delayed {
+		s.logi(1, "{{yellow}}got a delivery delay notification - the mail server is still trying, so ignoring it{{/}}")
+		return
 	}
+	s.logi(1, "{{red}}an e-mail bounced for %s: %s{{/}}", bounce.FailedRecipients, bounce.Reason())
+	s.Undeliverable = s.Undeliverable.Record(bounce, s.alarmClock.Time())
+	s.sendEmailWithNoTransition(command.Email.Forward(s.config.SaturdayDiscoEmail, s.config.BossEmail,
+		s.emailBody("bounce_report", s.emailData().WithAttachment(bounce).WithEmailDebugKey(command.Email.DebugKey))))
 }
 
 func (s *SaturdayDisco) handleReplyCommand(command Command) {
@@ -695,6 +1333,9 @@ func (s *SaturdayDisco) handleReplyCommand(command Command) {
 	}
 	if s.State != expectedState {
 		s.logi(1, "{{red}}boss sent me a reply command: %s, but i'm in the wrong state: %s{{/}}", command.CommandType, s.State)
+		if entry, ok := s.AuditLog.Last(); ok {
+			data = data.WithMessage(entry.String())
+		}
 		s.sendEmailWithNoTransition(command.Email.Reply(
 			s.config.SaturdayDiscoEmail,
 			s.emailBody("invalid_reply_state_email", data),
@@ -703,13 +1344,25 @@ func (s *SaturdayDisco) handleReplyCommand(command Command) {
 	}
 	if command.Delay > 0 {
 		s.logi(1, "{{green}}boss says to delay the next event by %d hours{{/}}", command.Delay)
+		defer s.notifyOtherOrganizers(s.recordAdminAction(command.Email.From, "delayed the %s email by %d hours", requestedApproval, command.Delay))
 		s.NextEvent = s.NextEvent.Add(time.Duration(command.Delay) * time.Hour)
-		s.alarmClock.SetAlarm(s.NextEvent)
+		s.alarms.Set(NEXT_EVENT_ALARM, s.NextEvent)
 		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
 			s.emailBody("acknowledge_delay", s.emailData().WithMessage("the %s email by %d hours", requestedApproval, command.Delay))))
 		return
 	}
 
+	// first reply wins - any later replies will find us in a different state
+	decision := "denied"
+	if command.Approved {
+		decision = "approved"
+	}
+	if command.Automatic {
+		defer s.notifyOtherOrganizers(s.recordAdminAction(s.config.SaturdayDiscoEmail, "automatically %s the %s (away mode)", decision, requestedApproval))
+	} else {
+		defer s.notifyOtherOrganizers(s.recordAdminAction(command.Email.From, "%s the %s", decision, requestedApproval))
+	}
+
 	switch command.CommandType {
 	case CommandRequestedInviteApprovalReply:
 		if command.Approved {
@@ -742,8 +1395,7 @@ func (s *SaturdayDisco) handleReplyCommand(command Command) {
 					s.config.SaturdayDiscoEmail,
 					s.emailBody("invalid_admin_email", data.WithError(fmt.Errorf("Quorum was lost before this approval came in.  Starting the No-Game flow soon."))),
 				))
-				s.sendEmail(s.emailForBoss("request_no_game_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-					StateRequestedNoGameApproval, s.replyWithFailureErrorHandler)
+				s.requestApproval(ApprovalNoGame, data, s.replyWithFailureErrorHandler)
 			}
 		} else {
 			s.logi(1, "{{green}}boss says it's not ok to send game on, sending no-game e-mail{{/}}")
@@ -757,8 +1409,7 @@ func (s *SaturdayDisco) handleReplyCommand(command Command) {
 				s.config.SaturdayDiscoEmail,
 				s.emailBody("invalid_admin_email", data.WithError(fmt.Errorf("Quorum was gained before this came in.  Starting the Game-On flow soon."))),
 			))
-			s.sendEmail(s.emailForBoss("request_game_on_approval", data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))),
-				StateRequestedGameOnApproval, s.replyWithFailureErrorHandler)
+			s.requestApproval(ApprovalGameOn, data, s.replyWithFailureErrorHandler)
 		} else {
 			if command.Approved {
 				s.logi(1, "{{green}}boss says it's ok to send no game, sending no-game e-mail{{/}}")
@@ -776,27 +1427,162 @@ func (s *SaturdayDisco) handleReplyCommand(command Command) {
 	}
 }
 
+// handleQuarantineReply runs (or throws away) the quarantined command the boss is replying about
+func (s *SaturdayDisco) handleQuarantineReply(command Command) {
+	var quarantined commands.QuarantinedEmail
+	var ok bool
+	s.Quarantine, quarantined, ok = s.Quarantine.Remove(command.QuarantineID)
+	if !ok {
+		s.logi(1, "{{red}}boss replied about a quarantined e-mail that doesn't exist: #%d{{/}}", command.QuarantineID)
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("invalid_admin_email",
+				s.emailData().WithError(fmt.Errorf("there's no quarantined e-mail #%d - it may have already been confirmed or rejected", command.QuarantineID)))))
+		return
+	}
+	if !command.Approved {
+		s.logi(1, "{{yellow}}boss rejected quarantined e-mail %s{{/}}", quarantined)
+		s.recordAdminAction(command.Email.From, "rejected quarantined e-mail %s", quarantined)
+		s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("acknowledge_quarantine", s.emailData().WithMessage("I threw away #%d.", quarantined.ID))))
+		return
+	}
+	s.logi(1, "{{green}}boss confirmed quarantined e-mail %s{{/}}", quarantined)
+	s.recordAdminAction(command.Email.From, "confirmed quarantined e-mail %s", quarantined)
+	s.sendEmailWithNoTransition(command.Email.Reply(s.config.SaturdayDiscoEmail,
+		s.emailBody("acknowledge_quarantine", s.emailData().WithMessage("I'm running #%d now.", quarantined.ID))))
+	c := s.adminEmailCommand(quarantined.Email)
+	if c.DryRun {
+		s.dryRun(c)
+	} else {
+		s.performCommand(c)
+	}
+}
+
+// requestApproval asks the organizers for approval - unless the boss is away and has left instructions
+func (s *SaturdayDisco) requestApproval(kind ApprovalKind, data TemplateData, onFailure func(mail.Email, error)) {
+	var name string
+	var state SaturdayDiscoState
+	var replyType CommandType
+	switch kind {
+	case ApprovalInvite:
+		name, state, replyType = "request_invite_approval", StateRequestedInviteApproval, CommandRequestedInviteApprovalReply
+	case ApprovalBadger:
+		name, state, replyType = "request_badger_approval", StateRequestedBadgerApproval, CommandRequestedBadgerApprovalReply
+	case ApprovalGameOn:
+		name, state, replyType = "request_game_on_approval", StateRequestedGameOnApproval, CommandRequestedGameOnApprovalReply
+	case ApprovalNoGame:
+		name, state, replyType = "request_no_game_approval", StateRequestedNoGameApproval, CommandRequestedNoGameApprovalReply
+	}
+	data = data.WithNextEvent(s.alarmClock.Time().Add(ApprovalTime))
+	email := s.emailForOrganizers(name, data)
+
+	policy := s.Away.PolicyFor(kind, s.alarmClock.Time())
+	switch policy.Action {
+	case AwayActionApprove, AwayActionDeny:
+		s.logi(1, "{{yellow}}boss is away - handling the %s approval automatically: %s{{/}}", kind, policy)
+		s.transitionTo(state)
+		s.handleReplyCommand(Command{
+			CommandType: replyType,
+			Approved:    policy.Action == AwayActionApprove,
+			Automatic:   true,
+			Email:       email.WithFrom(s.Away.SetBy).WithTo(s.config.SaturdayDiscoEmail),
+		})
+	case AwayActionDelegate:
+		s.logi(1, "{{yellow}}boss is away - asking %s for %s approval{{/}}", policy.Delegate, kind)
+		s.sendEmail(email.WithTo(policy.Delegate), state, onFailure)
+	default:
+		s.sendEmail(email, state, onFailure)
+	}
+}
+
+// performDeferredAction runs a scheduled command as though the organizer who scheduled it had just e-mailed it in
+func (s *SaturdayDisco) performDeferredAction(name string) {
+	action, ok := s.Deferred.FindByAlarmName(name)
+	if !ok {
+		return
+	}
+	s.Deferred = s.Deferred.Remove(action.ID)
+	s.undoStack = nil
+	email := mail.E().
+		WithFrom(action.SetBy).
+		WithTo(s.config.SaturdayDiscoEmail).
+		WithSubject("Scheduled: " + action.CommandLine).
+		WithBody(action.Text())
+	email.Date = s.alarmClock.Time().Format("Mon, 2 Jan 2006 15:04:05 -0700")
+
+	skipReason := ""
+	if !s.organizers().Includes(action.SetBy) {
+		skipReason = fmt.Sprintf("%s is no longer an organizer", action.SetBy)
+	} else if action.Condition == commands.ConditionIfQuorum && !s.hasQuorum() {
+		skipReason = "we don't have quorum"
+	} else if action.Condition == commands.ConditionUnlessQuorum && s.hasQuorum() {
+		skipReason = "we have quorum"
+	}
+	if skipReason != "" {
+		s.logi(1, "{{yellow}}skipping scheduled action %s - %s{{/}}", action, skipReason)
+		s.recordAdminAction(s.config.SaturdayDiscoEmail, "skipped scheduled action %s - %s", action, skipReason)
+		s.sendEmailWithNoTransition(email.Reply(s.config.SaturdayDiscoEmail,
+			s.emailBody("deferred_skipped", s.emailData().WithMessage("%s - %s", action, skipReason))))
+		return
+	}
+
+	s.logi(1, "{{green}}running scheduled action %s{{/}}", action)
+	s.recordAdminAction(action.SetBy, "ran scheduled action %s", action)
+	s.dispatchCommand(s.adminCommand(email))
+}
+
+func (s *SaturdayDisco) recordAdminAction(actor mail.EmailAddress, format string, args ...any) audit.Entry {
+	s.AuditLog = s.AuditLog.Record(s.alarmClock.Time(), actor, format, args...)
+	entry, _ := s.AuditLog.Last()
+	s.recentActions = append(s.recentActions, entry)
+	s.logi(2, "{{gray}}audit: %s{{/}}", entry)
+	return entry
+}
+
+func (s *SaturdayDisco) notifyOtherOrganizers(entry audit.Entry) {
+	others := s.organizers().Except(entry.Actor)
+	if len(others) == 0 {
+		return
+	}
+	s.logi(1, "{{gray}}letting the other organizers know{{/}}")
+	data := s.emailData().WithMessage("%s %s", entry.Actor.Name(), entry.Action)
+	s.sendEmailWithNoTransition(mail.E().
+		WithFrom(s.config.SaturdayDiscoEmail).
+		WithTo(others.Addresses()...).
+		WithSubject(s.emailSubject("organizer_decision", data)).
+		WithBody(s.emailBody("organizer_decision", data)))
+}
+
 func (s *SaturdayDisco) retryNextEventErrorHandler(email mail.Email, err error) {
 	s.outbox.SendEmail(mail.Email{
 		From:    s.config.SaturdayDiscoEmail,
-		To:      []mail.EmailAddress{s.config.BossEmail},
+		To:      s.organizers().Addresses(),
 		Subject: "Help!",
 		Text:    fmt.Sprintf("Saturday Disco failed to send an e-mail during an event transition.\n\n%s\n\nTrying to send:\n\n%s\n\nPlease help!", err.Error(), email.String()),
 	})
-	s.alarmClock.SetAlarm(s.alarmClock.Time().Add(RETRY_DELAY))
+	// NextEvent has to move too - otherwise syncAlarms (or a restart) would put the alarm straight back on the event we just failed
+	s.NextEvent = s.alarmClock.Time().Add(RETRY_DELAY)
+	s.alarms.Set(NEXT_EVENT_ALARM, s.NextEvent)
 }
 
 func (s *SaturdayDisco) replyWithFailureErrorHandler(email mail.Email, err error) {
 	s.logi(1, "{{red}}failed while handling a command: %s{{/}}", err.Error())
 	s.outbox.SendEmail(mail.Email{
 		From:    s.config.SaturdayDiscoEmail,
-		To:      []mail.EmailAddress{s.config.BossEmail},
+		To:      s.organizers().Addresses(),
 		Subject: "Help!",
 		Text:    fmt.Sprintf("Saturday Disco failed while trying to handle a command.\n\n%s\n\nTrying to send:\n\n%s\n\nPlease help!", err.Error(), email.String()),
 	})
 }
 
 func (s *SaturdayDisco) sendEmail(email mail.Email, successState SaturdayDiscoState, onFailure func(mail.Email, error)) {
+	email = email.WithIdempotencyKey(mail.TransitionKey("saturday", s.T, s.SentEmails, string(successState)))
+	email, deliverable := s.Undeliverable.Deliverable(email, s.logi, s.config.SaturdayDiscoList, s.config.BossEmail)
+	if !deliverable {
+		s.SentEmails += 1
+		s.transitionTo(successState)
+		return
+	}
 	err := s.outbox.SendEmail(email)
 	if err != nil {
 		s.logi(1, "{{red}}failed to send e-mail: %s{{/}}", err.Error())
@@ -806,11 +1592,16 @@ func (s *SaturdayDisco) sendEmail(email mail.Email, successState SaturdayDiscoSt
 
 		onFailure(email, err)
 	} else {
+		s.SentEmails += 1
 		s.transitionTo(successState)
 	}
 }
 
 func (s *SaturdayDisco) sendEmailWithNoTransition(email mail.Email) {
+	email, deliverable := s.Undeliverable.Deliverable(email, s.logi, s.config.SaturdayDiscoList, s.config.BossEmail)
+	if !deliverable {
+		return
+	}
 	err := s.outbox.SendEmail(email)
 	if err != nil {
 		s.logi(1, "{{red}}failed to send e-mail: %s{{/}}", err.Error())
@@ -818,12 +1609,42 @@ func (s *SaturdayDisco) sendEmailWithNoTransition(email mail.Email) {
 	}
 }
 
+func blackoutDate(t time.Time) string {
+	return t.In(clock.Timezone).Format(config.BLACKOUT_DATE_FORMAT)
+}
+
+// blackouts combines the calendar file with the blackouts added by e-mail
+func (s *SaturdayDisco) blackouts() config.Blackouts {
+	return append(s.config.Blackouts.Dup(), s.Blackouts...)
+}
+
+// pruneBlackouts forgets the blackouts added by e-mail that are over
+func (s *SaturdayDisco) pruneBlackouts() {
+	s.Blackouts = s.Blackouts.Upcoming(config.DiscoSaturday, blackoutDate(s.alarmClock.Time()))
+}
+
+// blackout returns the blackout covering this week's game, if any
+func (s *SaturdayDisco) blackout() (config.Blackout, bool) {
+	return s.blackouts().Find(config.DiscoSaturday, blackoutDate(s.T), blackoutDate(s.T))
+}
+
+// announcedBlackout returns the blackout covering next week's game, if it should be announced this week
+func (s *SaturdayDisco) announcedBlackout() config.Blackout {
+	nextWeek := blackoutDate(s.T.AddDate(0, 0, 7))
+	blackout, ok := s.blackouts().Find(config.DiscoSaturday, nextWeek, nextWeek)
+	if !ok || !blackout.Announce {
+		return config.Blackout{}
+	}
+	return blackout
+}
+
 func (s *SaturdayDisco) reset() {
-	s.alarmClock.Stop()
+	s.alarms.Clear(NEXT_EVENT_ALARM)
 	s.State = StateInvalid
 	s.Participants = Participants{}
 	s.T = clock.NextSaturdayAt10Or1030(s.alarmClock.Time())
 	s.NextEvent = time.Time{}
-	s.ProcessedEmailIDs = ProcessedEmailIDs{}
+	s.ProcessedEmailIDs = commands.ProcessedEmailIDs{}
+	s.CalendarSequence = 0
 	s.transitionTo(StatePending)
 }
@@ -10,7 +10,9 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
 
+	"github.com/onsi/disco/calendar"
 	clockpkg "github.com/onsi/disco/clock"
+	"github.com/onsi/disco/commands"
 	"github.com/onsi/disco/config"
 	"github.com/onsi/disco/mail"
 	"github.com/onsi/disco/s3db"
@@ -55,6 +57,7 @@ var _ = Describe("SaturdayDisco", func() {
 			var now time.Time
 			var gameDate string
 			var playerEmail mail.EmailAddress
+			var helper, lunchtimeOnly, viewer mail.EmailAddress
 
 			var le func() mail.Email
 			var handleIncomingEmail = func(m mail.Email) mail.Email {
@@ -80,6 +83,14 @@ var _ = Describe("SaturdayDisco", func() {
 					WithSubject(subject).
 					WithBody(body))
 			}
+			// the Describes that need organizers set them up before the disco starts
+			var withOrganizers = func() {
+				conf.Organizers = config.Organizers{
+					{Address: helper, Role: config.RoleOrganizer, Discos: []string{config.DiscoSaturday}},
+					{Address: lunchtimeOnly, Role: config.RoleOrganizer, Discos: []string{config.DiscoLunchtime}},
+					{Address: viewer, Role: config.RoleViewer},
+				}
+			}
 
 			BeforeEach(func() {
 				outbox = mail.NewFakeOutbox()
@@ -100,11 +111,20 @@ var _ = Describe("SaturdayDisco", func() {
 				conf.SaturdayDiscoEmail = mail.EmailAddress("Disco <saturday-disco@sedenverultimate.net>")
 				conf.SaturdayDiscoList = mail.EmailAddress("Saturday-List <saturday-se-denver-ultimate@googlegroups.com>")
 				playerEmail = mail.EmailAddress("player@example.com")
+				helper = mail.EmailAddress("Helper <helper@example.com>")
+				lunchtimeOnly = mail.EmailAddress("Lunch <lunch@example.com>")
+				viewer = mail.EmailAddress("Lurker <lurker@example.com>")
+				conf.Organizers = nil
+				conf.Blackouts = nil
+				conf.RequireAuthenticatedAdmins = false
 
 				now = testConfig.Now
 				gameDate = testConfig.GameDate
 				clock.SetTime(now)
+			})
 
+			// the disco starts after every BeforeEach has had its say about conf
+			JustBeforeEach(func() {
 				isStartup, _ := CurrentSpecReport().MatchesLabelFilter("startup")
 				if !isStartup {
 					var err error
@@ -208,9 +228,19 @@ var _ = Describe("SaturdayDisco", func() {
 							},
 							T:         clockpkg.NextSaturdayAt10Or1030(now.Add(-time.Hour * 24 * 7)),
 							NextEvent: clockpkg.NextSaturdayAt10Or1030(now.Add(-time.Hour * 24 * 7)).Add(-2*time.Hour*24 + 8*time.Hour),
+							Undeliverable: commands.Undeliverable{
+								{Address: "gone@example.com", Bounces: 1, Permanent: true},
+							},
 						})
 					})
 
+					It("keeps track of who our e-mail bounces for", func() {
+						var err error
+						disco, err = NewSaturdayDisco(conf, GinkgoWriter, clock, outbox, interpreter, forecaster, db)
+						Ω(err).ShouldNot(HaveOccurred())
+						Ω(disco.GetSnapshot().Undeliverable.IsDead("gone@example.com")).Should(BeTrue())
+					})
+
 					It("discards the backup, starts afresh, and sends an eamil", func() {
 						var err error
 						disco, err = NewSaturdayDisco(conf, GinkgoWriter, clock, outbox, interpreter, forecaster, db)
@@ -269,7 +299,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if it's already past time for the next event", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							clock.SetTime(clockpkg.NextSaturdayAt10Or1030(now).Add(-2*time.Hour*24 + 3*time.Hour))
 							go clock.Fire() //basically what happens irl
 						})
@@ -297,6 +327,136 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 				})
 
+				Context("if it was down long enough to miss several events", func() {
+					JustBeforeEach(func() {
+						put(SaturdayDiscoSnapshot{
+							State: StatePending,
+							Participants: Participants{
+								Participant{Address: playerEmail, Count: 2},
+							},
+							T:         clockpkg.NextSaturdayAt10Or1030(now),
+							NextEvent: clockpkg.NextSaturdayAt10Or1030(now).Add(-4*time.Hour*24 - 4*time.Hour), //Tuesday, 6am
+						})
+						clock.SetTime(clockpkg.NextSaturdayAt10Or1030(now).Add(-3*time.Hour*24 + 2*time.Hour)) //Wednesday, noon
+						var err error
+						disco, err = NewSaturdayDisco(conf, GinkgoWriter, clock, outbox, interpreter, forecaster, db)
+						Ω(err).ShouldNot(HaveOccurred())
+						DeferCleanup(disco.Stop)
+					})
+
+					It("pauses instead of sending stale e-mails, and asks the boss how to proceed", func() {
+						Ω(outbox.Emails()).Should(HaveLen(2))
+						Ω(outbox.Emails()[0]).Should(HaveSubject("SaturdayDisco Joined the Dance Floor"))
+						Ω(outbox.Emails()[0]).Should(HaveText(ContainSubstring("Backup is good.  Spinning up...\nI missed 2 event(s) while I was down.  I've paused until you tell me how to proceed.")))
+						Ω(le()).Should(HaveSubject("SaturdayDisco Missed Some Events"))
+						Ω(le()).Should(BeSentTo(conf.BossEmail))
+						Ω(le()).Should(HaveText(ContainSubstring(": pending → requested_invite_approval\n- ")))
+						Ω(le()).Should(HaveText(ContainSubstring(": requested_invite_approval → invite_sent")))
+						Ω(le()).Should(HaveText(ContainSubstring("⏸️ Paused after missing 2 event(s) - send /resume or /skip")))
+						Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+						Ω(disco.GetSnapshot().CatchUp).Should(HaveLen(2))
+					})
+
+					It("picks up where it left off on /resume", func() {
+						outbox.Clear()
+						bossToDisco("/resume")
+						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
+						Ω(outbox.Emails()).Should(HaveLen(2))
+						Ω(outbox.Emails()[0]).Should(HaveSubject(HavePrefix("[invite-approval-request]")))
+						Ω(le()).Should(HaveText(HavePrefix("Got it - I've picked up where I left off.")))
+						Ω(disco.GetSnapshot().CatchUp).Should(BeEmpty())
+						Ω(disco.GetSnapshot().NextEvent).Should(Equal(clock.Time().Add(ApprovalTime)))
+					})
+
+					It("jumps ahead without sending anything it missed on /skip", func() {
+						outbox.Clear()
+						bossToDisco("/skip")
+						Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+						Ω(outbox.Emails()).Should(HaveLen(1))
+						Ω(le()).Should(HaveText(HavePrefix("Got it - I've skipped ahead without sending anything I missed.")))
+						Ω(disco.GetSnapshot().CatchUp).Should(BeEmpty())
+
+						outbox.Clear()
+						clock.Fire()
+						Ω(clock.Time()).Should(BeOn(time.Thursday, 14, testConfig.Offset))
+						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedBadgerApproval))
+					})
+
+					It("asks for approval right away if skipping ahead lands on an approval request the boss never saw", func() {
+						bossToDisco("/set onsijoe@gmail.com 6") // we have quorum, so we'd be waiting on game-on approval by now
+						Eventually(disco.GetSnapshot).Should(HaveCount(8))
+						clock.SetTime(clockpkg.NextSaturdayAt10Or1030(now).Add(-2*time.Hour*24 + 6*time.Hour)) //Thursday, 4pm
+						outbox.Clear()
+						bossToDisco("/skip")
+						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedGameOnApproval))
+						Ω(outbox.Emails()[0]).Should(HaveSubject(HavePrefix("[game-on-approval-request]")))
+						Ω(disco.GetSnapshot().NextEvent).Should(Equal(clock.Time().Add(ApprovalTime)))
+					})
+
+					It("complains if there's nothing to catch up on", func() {
+						bossToDisco("/skip")
+						Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+						bossToDisco("/resume")
+						Eventually(le).Should(HaveText(ContainSubstring("I haven't missed any events - there's nothing to catch up on")))
+						Ω(disco.GetSnapshot()).Should(HaveState(StateInviteSent))
+					})
+				})
+
+				Context("if it was down long enough to miss a scheduled action", func() {
+					JustBeforeEach(func() {
+						put(SaturdayDiscoSnapshot{
+							State: StateInviteSent,
+							Participants: Participants{
+								Participant{Address: playerEmail, Count: 2},
+							},
+							T:         clockpkg.NextSaturdayAt10Or1030(now),
+							NextEvent: clockpkg.NextSaturdayAt10Or1030(now).Add(-2*time.Hour*24 + 4*time.Hour), //Thursday, 2pm
+							Deferred: commands.DeferredActions{
+								{ID: 1, At: clockpkg.NextSaturdayAt10Or1030(now).Add(-3*time.Hour*24 - 4*time.Hour), CommandLine: "/abort", SetBy: conf.BossEmail},     //Wednesday, 6am
+								{ID: 2, At: clockpkg.NextSaturdayAt10Or1030(now).Add(-time.Hour * 24), CommandLine: "/set onsijoe@gmail.com 3", SetBy: conf.BossEmail}, //Friday
+							},
+						})
+						clock.SetTime(clockpkg.NextSaturdayAt10Or1030(now).Add(-3*time.Hour*24 + 2*time.Hour)) //Wednesday, noon
+						var err error
+						disco, err = NewSaturdayDisco(conf, GinkgoWriter, clock, outbox, interpreter, forecaster, db)
+						Ω(err).ShouldNot(HaveOccurred())
+						DeferCleanup(disco.Stop)
+					})
+
+					It("holds on to the action and pauses instead of running it late", func() {
+						Ω(outbox.Emails()).Should(HaveLen(2))
+						Ω(outbox.Emails()[0]).Should(HaveText(ContainSubstring("I missed 1 event(s) while I was down.  I've paused until you tell me how to proceed.")))
+						Ω(le()).Should(HaveSubject("SaturdayDisco Missed Some Events"))
+						Ω(le()).Should(HaveText(ContainSubstring(": scheduled #1 /abort")))
+						Ω(disco.GetSnapshot()).Should(HaveState(StateInviteSent))
+						Ω(disco.GetSnapshot().CatchUp).Should(HaveLen(1))
+						Ω(disco.GetSnapshot().Deferred).Should(HaveLen(2))
+					})
+
+					It("runs the action it missed on /resume", func() {
+						outbox.Clear()
+						bossToDisco("/resume")
+						Eventually(disco.GetSnapshot).Should(HaveState(StateAbort))
+						Ω(le()).Should(HaveText(HavePrefix("Got it - I've picked up where I left off.")))
+						Ω(disco.GetSnapshot().CatchUp).Should(BeEmpty())
+						Ω(disco.GetSnapshot().Deferred).Should(HaveLen(1))
+					})
+
+					It("drops the action it missed on /skip, and carries on with the rest of the schedule", func() {
+						outbox.Clear()
+						bossToDisco("/skip")
+						Eventually(le).Should(HaveText(HavePrefix("Got it - I've skipped ahead without sending anything I missed.")))
+						Ω(disco.GetSnapshot()).Should(HaveState(StateInviteSent))
+						Ω(disco.GetSnapshot().CatchUp).Should(BeEmpty())
+						Ω(disco.GetSnapshot().Deferred).Should(HaveLen(1))
+
+						outbox.Clear()
+						clock.Fire()
+						Ω(clock.Time()).Should(BeOn(time.Thursday, 14, testConfig.Offset))
+						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedBadgerApproval))
+					})
+				})
+
 				for _, state := range []SaturdayDiscoState{StatePending, StateRequestedInviteApproval} {
 					state := state
 					Context("if the invite hasn't been sent yet ("+string(state)+") and its after thursday 2pm", func() {
@@ -334,7 +494,307 @@ var _ = Describe("SaturdayDisco", func() {
 					It("replies with an error e-mail", func() {
 						bossToDisco("/floop")
 						Eventually(le).Should(HaveSubject("Re: hey"))
-						Ω(le()).Should(HaveText(ContainSubstring("invalid command: /floop")))
+						Ω(le()).Should(HaveText(ContainSubstring("unknown command: /floop - send /help for a list of commands")))
+					})
+				})
+
+				Describe("when the boss makes a typo", func() {
+					It("suggests the command they probably meant", func() {
+						bossToDisco("/stauts")
+						Eventually(le).Should(HaveSubject("Re: hey"))
+						Ω(le()).Should(HaveText(ContainSubstring("unknown command: /stauts - did you mean /status?")))
+					})
+				})
+
+				Describe("asking for help", func() {
+					It("replies with the list of commands", func() {
+						bossToDisco("/help")
+						Eventually(le).Should(HaveSubject("Re: hey"))
+						Ω(le()).Should(BeSentTo(conf.BossEmail))
+						Ω(le()).Should(HaveText(ContainSubstring("/set <player> <count> - set a player's count")))
+						Ω(le()).Should(HaveText(ContainSubstring("/undo - undo the last change an organizer made")))
+						Ω(le()).Should(HaveText(ContainSubstring("Add --dry-run to a command")))
+					})
+				})
+
+				Describe("sending multiple commands in one e-mail", func() {
+					It("runs each command in order", func() {
+						bossToDisco("/set onsijoe@gmail.com 2\n/set player@example.com 3\n/status\n\nThanks!\nOnsi")
+						Eventually(outbox.Emails).Should(HaveLen(3))
+						Ω(disco.GetSnapshot()).Should(HaveCount(5))
+						Ω(le()).Should(HaveText(ContainSubstring("Here's the status report.")))
+						Ω(le()).Should(HaveText(ContainSubstring("Total Count: 5")))
+					})
+
+					It("doesn't run any of them if one is invalid", func() {
+						bossToDisco("/set onsijoe@gmail.com 2\n/floop")
+						Eventually(le).Should(HaveText(ContainSubstring("unknown command: /floop")))
+						Consistently(outbox.Emails).Should(HaveLen(1))
+						Ω(disco.GetSnapshot()).Should(HaveCount(0))
+					})
+				})
+
+				Describe("undoing admin changes", func() {
+					It("undoes the most recent change first", func() {
+						bossToDisco("/set onsijoe@gmail.com 2")
+						Eventually(disco.GetSnapshot).Should(HaveCount(2))
+						bossToDisco("/set player@example.com 3")
+						Eventually(disco.GetSnapshot).Should(HaveCount(5))
+
+						bossToDisco("/undo")
+						Eventually(disco.GetSnapshot).Should(HaveCount(2))
+						Ω(le()).Should(HaveText(ContainSubstring("Done - I've undone: set player@example.com to 3")))
+						Ω(le()).Should(HaveText(ContainSubstring("e-mails I already sent can't be unsent")))
+						entry, _ := disco.GetSnapshot().AuditLog.Last()
+						Ω(entry.Action).Should(Equal("undid: set player@example.com to 3"))
+
+						bossToDisco("/undo")
+						Eventually(disco.GetSnapshot).Should(HaveCount(0))
+
+						outbox.Clear()
+						bossToDisco("/undo")
+						Eventually(le).Should(HaveText(ContainSubstring("there's nothing to undo")))
+					})
+
+					It("restores the state and the schedule", func() {
+						bossToDisco("/abort")
+						Eventually(disco.GetSnapshot).Should(HaveState(StateAbort))
+						bossToDisco("/undo")
+						Eventually(disco.GetSnapshot).Should(HaveState(StatePending))
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("[invite-approval-request] Can I send this week's invite?"))
+						Ω(clock.Time()).Should(BeOn(time.Tuesday, 6, testConfig.Offset))
+					})
+
+					It("keeps the bounces that arrived since the change", func() {
+						bossToDisco("/set onsijoe@gmail.com 2")
+						Eventually(disco.GetSnapshot).Should(HaveCount(2))
+						bounce := mail.E().WithFrom("Mail Delivery System <MAILER-DAEMON@mx1.forwardemail.net>").WithTo(conf.SaturdayDiscoEmail).
+							WithSubject("Undelivered Mail Returned to Sender").WithBody("I'm sorry to have to inform you that your message could not be delivered.")
+						bounce.Bounce = mail.Bounce{FailedRecipients: []mail.EmailAddress{playerEmail}, Status: "5.1.1", Diagnostic: "550 no such user",
+							OriginalMessageID: mail.MessageIDFor("saturday/2023-09-30/0/requested_invite_approval", conf.SaturdayDiscoEmail)}
+						handleIncomingEmail(bounce)
+						Eventually(disco.GetSnapshot).Should(HaveField("Undeliverable", HaveLen(1)))
+
+						bossToDisco("/undo")
+						Eventually(disco.GetSnapshot).Should(HaveCount(0))
+						Ω(disco.GetSnapshot().Undeliverable.IsDead(playerEmail)).Should(BeTrue())
+					})
+
+					It("won't undo once a player has changed things since", func() {
+						bossToDisco("/set onsijoe@gmail.com 2")
+						Eventually(disco.GetSnapshot).Should(HaveCount(2))
+						interpreter.SetCommand(Command{CommandType: CommandPlayerSetCount, EmailAddress: playerEmail, Count: 1})
+						handleIncomingEmail(mail.E().WithFrom(playerEmail).WithTo(conf.SaturdayDiscoList).WithSubject("hey").WithBody("I'm in!"))
+						Eventually(disco.GetSnapshot).Should(HaveCount(3))
+
+						outbox.Clear()
+						bossToDisco("/undo")
+						Eventually(le).Should(HaveText(ContainSubstring("there's nothing to undo")))
+						Ω(disco.GetSnapshot()).Should(HaveCount(3))
+					})
+				})
+
+				Describe("dry runs", func() {
+					It("replies with what would happen without doing it", func() {
+						bossToDisco("/game-on --dry-run\nSee you there!")
+						Eventually(le).Should(HaveSubject("Re: hey"))
+						Ω(outbox.Emails()).Should(HaveLen(1))
+						Ω(le()).Should(BeSentTo(conf.BossEmail))
+						Ω(le()).Should(HaveText(ContainSubstring("This was a dry run")))
+						Ω(le()).Should(HaveText(ContainSubstring("I would move from the pending state to the game_on_sent state.")))
+						Ω(le()).Should(HaveText(ContainSubstring("To: " + conf.SaturdayDiscoList.String())))
+						Ω(le()).Should(HaveText(ContainSubstring("Subject: GAME ON THIS SATURDAY! " + gameDate)))
+						Ω(le()).Should(HaveText(ContainSubstring("See you there!")))
+
+						Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+						Ω(disco.GetSnapshot().AuditLog).Should(BeEmpty())
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("[invite-approval-request] Can I send this week's invite?"))
+					})
+
+					It("previews every command in the e-mail", func() {
+						bossToDisco("/set onsijoe@gmail.com 2\n/abort --dry-run")
+						Eventually(le).Should(HaveText(ContainSubstring("I would move from the pending state to the abort state.")))
+						Ω(outbox.Emails()).Should(HaveLen(1))
+						Ω(disco.GetSnapshot()).Should(HaveCount(0))
+						Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+
+						bossToDisco("/undo")
+						Eventually(le).Should(HaveText(ContainSubstring("there's nothing to undo")))
+					})
+				})
+
+				Describe("scheduling commands for later", func() {
+					var mondayNoon string
+					BeforeEach(func() {
+						mondayNoon = now.Add(36 * time.Hour).Format("Mon 1/2 3:04pm")
+					})
+
+					It("runs the command at the scheduled time and lists it in the status until then", func() {
+						bossToDisco("/schedule monday noon /set onsijoe@gmail.com 3")
+						Eventually(le).Should(HaveText(ContainSubstring("Got it - I've scheduled #1: /set onsijoe@gmail.com 3 on " + mondayNoon)))
+						bossToDisco("/status")
+						Eventually(le).Should(HaveText(ContainSubstring("Scheduled actions:\n- #1: /set onsijoe@gmail.com 3 on " + mondayNoon + " (set by Boss)")))
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(disco.GetSnapshot).Should(HaveCount(3))
+						Ω(clock.Time()).Should(BeOn(time.Monday, 12))
+						Ω(le()).Should(HaveSubject("Re: Scheduled: /set onsijoe@gmail.com 3"))
+						Ω(le()).Should(BeSentTo(conf.BossEmail))
+						Ω(disco.GetSnapshot().Deferred).Should(BeEmpty())
+						Ω(disco.GetSnapshot().AuditLog.Recent(2).String()).Should(And(
+							ContainSubstring("Boss <boss@example.com> ran scheduled action #1: /set onsijoe@gmail.com 3"),
+							ContainSubstring("Boss <boss@example.com> set onsijoe@gmail.com to 3"),
+						))
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("[invite-approval-request] Can I send this week's invite?"))
+						Ω(clock.Time()).Should(BeOn(time.Tuesday, 6, testConfig.Offset))
+					})
+
+					It("skips the command if its condition isn't met", func() {
+						bossToDisco("/schedule monday noon if-quorum /game-on")
+						Eventually(le).Should(HaveText(ContainSubstring("#1: /game-on on " + mondayNoon + " if we have quorum")))
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveText(ContainSubstring("I didn't run this scheduled action: #1: /game-on on " + mondayNoon + " if we have quorum - we don't have quorum")))
+						Ω(outbox.Emails()).Should(HaveLen(1))
+						Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
+					})
+
+					It("runs the command, content and all, if its condition is met - and picks up the schedule from there", func() {
+						bossToDisco("/set onsijoe@gmail.com 8")
+						Eventually(disco.GetSnapshot).Should(HaveCount(8))
+						bossToDisco("/schedule monday noon if-quorum /game-on\nSee you there!")
+						Eventually(le).Should(HaveText(ContainSubstring("Got it - I've scheduled #1")))
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("GAME ON THIS SATURDAY! " + gameDate))
+						Ω(le()).Should(BeSentTo(conf.SaturdayDiscoList))
+						Ω(le()).Should(HaveText(ContainSubstring("See you there!")))
+						Ω(disco.GetSnapshot()).Should(HaveState(StateGameOnSent))
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("Reminder: GAME ON TODAY! " + gameDate))
+						Ω(clock.Time()).Should(BeOn(time.Saturday, 6, testConfig.Offset))
+					})
+
+					It("lets organizers cancel scheduled commands", func() {
+						bossToDisco("/schedule monday noon /abort")
+						Eventually(le).Should(HaveText(ContainSubstring("Got it - I've scheduled #1")))
+						bossToDisco("/cancel 7")
+						Eventually(le).Should(HaveText(ContainSubstring("there's no scheduled action #7")))
+						bossToDisco("/cancel #1")
+						Eventually(le).Should(HaveText(ContainSubstring("Got it - I've cancelled #1: /abort on " + mondayNoon)))
+						Ω(disco.GetSnapshot().Deferred).Should(BeEmpty())
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("[invite-approval-request] Can I send this week's invite?"))
+						Ω(disco.GetSnapshot()).Should(HaveState(StateRequestedInviteApproval))
+					})
+
+					It("catches mistakes when the command is scheduled", func() {
+						bossToDisco("/schedule someday /abort")
+						Eventually(le).Should(HaveText(ContainSubstring("invalid time for /schedule: someday")))
+						bossToDisco("/schedule friday /abrt")
+						Eventually(le).Should(HaveText(ContainSubstring("invalid command to schedule: unknown command: /abrt - did you mean /abort?")))
+						Ω(disco.GetSnapshot().Deferred).Should(BeEmpty())
+					})
+				})
+
+				Describe("blacking out weeks", func() {
+					var thisWeek, nextWeek string
+					JustBeforeEach(func() {
+						thisWeek = disco.GetSnapshot().T.In(clockpkg.Timezone).Format("1/2")
+						nextWeek = disco.GetSnapshot().T.In(clockpkg.Timezone).AddDate(0, 0, 7).Format("1/2")
+					})
+
+					It("sends the no-invitation e-mail with the reason instead of asking to send the invite", func() {
+						bossToDisco("/blackout " + thisWeek + " Field is closed")
+						Eventually(le).Should(HaveText(ContainSubstring("Got it - I've added #1: " + thisWeek + " (Field is closed)")))
+						bossToDisco("status", "/status")
+						Eventually(le).Should(HaveSubject("Re: status"))
+						Ω(le()).Should(HaveText(ContainSubstring("Upcoming blackouts:\n- #1: " + thisWeek + " (Field is closed)")))
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("No Saturday Bible Park Frisbee This Week"))
+						Ω(le()).Should(BeSentTo(conf.SaturdayDiscoList))
+						Ω(le()).Should(HaveText(HavePrefix("Field is closed")))
+						Ω(outbox.Emails()).Should(HaveLen(1))
+						Ω(disco.GetSnapshot()).Should(HaveState(StateNoInviteSent))
+						Ω(disco.GetSnapshot().AuditLog.Recent(1).String()).Should(ContainSubstring("Boss <boss@example.com> added blackout #1: " + thisWeek + " (Field is closed)"))
+					})
+
+					It("skips the week without sending anything if the blackout is silent", func() {
+						bossToDisco("/blackout " + thisWeek + " silent")
+						Eventually(le).Should(HaveText(ContainSubstring("Got it - I've added #1: " + thisWeek + ", silent")))
+						Eventually(disco.GetSnapshot).Should(HaveState(StateNoInviteSent))
+
+						outbox.Clear()
+						clock.Fire()
+						Consistently(outbox.Emails).Should(BeEmpty())
+					})
+
+					It("announces blackouts in the previous week's e-mails", func() {
+						bossToDisco("/blackout " + nextWeek + " announce Thanksgiving")
+						Eventually(le).Should(HaveText(ContainSubstring("Got it - I've added #1: " + nextWeek + " (Thanksgiving), announced the week before")))
+
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("[invite-approval-request] Can I send this week's invite?"))
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("Saturday Bible Park Frisbee " + gameDate))
+						Ω(le()).Should(HaveText(ContainSubstring("Heads up: there's no Saturday game next week (" + nextWeek + ") - Thanksgiving.")))
+					})
+
+					It("lets the boss remove blackouts", func() {
+						bossToDisco("/blackout " + thisWeek)
+						Eventually(le).Should(HaveText(ContainSubstring("Got it - I've added #1")))
+						bossToDisco("/unblackout 2")
+						Eventually(le).Should(HaveText(ContainSubstring("there's no blackout #2")))
+						bossToDisco("/unblackout #1")
+						Eventually(le).Should(HaveText(ContainSubstring("Got it - I've removed #1: " + thisWeek)))
+						Ω(disco.GetSnapshot().Blackouts).Should(BeEmpty())
+
+						outbox.Clear()
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("[invite-approval-request] Can I send this week's invite?"))
+					})
+
+					It("catches mistakes", func() {
+						bossToDisco("/blackout turkey-day")
+						Eventually(le).Should(HaveText(ContainSubstring("invalid date for /blackout: turkey-day - use M/D")))
+						Ω(disco.GetSnapshot().Blackouts).Should(BeEmpty())
+					})
+
+					It("honors the blackout calendar from the config, for this disco only", func() {
+						t := disco.GetSnapshot().T.In(clockpkg.Timezone).Format(config.BLACKOUT_DATE_FORMAT)
+						disco.Stop()
+						conf.Blackouts = config.Blackouts{
+							{Start: t, Discos: []string{config.DiscoLunchtime}, Silent: true},
+							{Start: t, Discos: []string{config.DiscoSaturday}, Reason: "Tournament weekend"},
+						}
+						var err error
+						disco, err = NewSaturdayDisco(conf, GinkgoWriter, clock, outbox, interpreter, forecaster, db)
+						Ω(err).ShouldNot(HaveOccurred())
+						DeferCleanup(disco.Stop)
+						outbox.Clear()
+
+						clock.Fire()
+						Eventually(le).Should(HaveSubject("No Saturday Bible Park Frisbee This Week"))
+						Ω(le()).Should(HaveText(HavePrefix("Tournament weekend")))
 					})
 				})
 
@@ -376,6 +836,29 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 				})
 
+				Describe("when an autoresponder replies", func() {
+					It("drops the e-mail without asking the interpreter about it", func() {
+						interpreter.SetCommand(Command{CommandType: CommandPlayerSetCount, EmailAddress: playerEmail, Count: 0})
+						email := mail.E().WithFrom(playerEmail).WithTo(conf.SaturdayDiscoList).WithSubject("Automatic reply: Saturday Bible Park Frisbee").WithBody("I'm out of the office until Monday.")
+						email.AutoReply = "Subject: Automatic reply: Saturday Bible Park Frisbee"
+						handleIncomingEmail(email)
+						handleIncomingEmail(email)
+						Eventually(disco.DroppedAutoReplies).Should(Equal(2))
+						Consistently(le).Should(BeZero())
+						Ω(disco.GetSnapshot()).Should(HaveCount(0))
+					})
+
+					It("still handles bounces, which are auto-submitted too", func() {
+						email := mail.E().WithFrom("MAILER-DAEMON@mx1.forwardemail.net").WithTo(conf.SaturdayDiscoEmail).WithSubject("Undelivered Mail Returned to Sender").WithBody("Sorry.")
+						email.AutoReply = "Auto-Submitted: auto-replied"
+						email.Bounce = mail.Bounce{FailedRecipients: mail.EmailAddresses{playerEmail}, Status: "5.1.1",
+							OriginalMessageID: mail.MessageIDFor("saturday/2023-09-30/3/invite_sent", conf.SaturdayDiscoEmail)}
+						handleIncomingEmail(email)
+						Eventually(le).Should(HaveSubject("Fwd: Undelivered Mail Returned to Sender"))
+						Ω(disco.DroppedAutoReplies()).Should(Equal(0))
+					})
+				})
+
 				Describe("when an e-mail comes from disco itself", func() {
 					It("completely ignores the e-mail", func() {
 						interpreter.SetCommand(Command{CommandType: CommandPlayerSetCount, Count: 2})
@@ -406,7 +889,7 @@ var _ = Describe("SaturdayDisco", func() {
 				})
 
 				Describe("registering players", func() {
-					BeforeEach(func() {
+					JustBeforeEach(func() {
 						Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
 						clock.Fire()
 						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
@@ -461,11 +944,12 @@ var _ = Describe("SaturdayDisco", func() {
 
 						It("send back an error if the admin messes up", func() {
 							bossToDisco("/set")
-							Eventually(le).Should(HaveText(ContainSubstring("invalid command: /set")))
+							Eventually(le).Should(HaveText(ContainSubstring("invalid arguments for /set - usage: /set <player> <count>")))
+							outbox.Clear()
 							bossToDisco("/set 2")
-							Eventually(le).Should(HaveText(ContainSubstring("invalid command: /set 2")))
+							Eventually(le).Should(HaveText(ContainSubstring("invalid arguments for /set - usage: /set <player> <count>")))
 							bossToDisco("/set onsijoe@gmail.com two")
-							Eventually(le).Should(HaveText(ContainSubstring("invalid command: /set onsijoe@gmail.com two")))
+							Eventually(le).Should(HaveText(ContainSubstring("invalid count for /set command: two")))
 						})
 
 						It("keeps track of all the emails associated with the player", func() {
@@ -501,7 +985,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Describe("the user-facing interface for setting players", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							bossToDisco("/set player@example.com 1")
 							Eventually(disco.GetSnapshot).Should(HaveCount(1))
 						})
@@ -518,11 +1002,39 @@ var _ = Describe("SaturdayDisco", func() {
 
 							Ω(le()).Should(HaveHTML(ContainSubstring(`<a href="mailto:Disco &lt;saturday-disco@sedenverultimate.net&gt;?subject=Set Player&amp;body=/set player@example.com N" target="_blank">/set player@example.com N</a>`)))
 						})
+
+						Describe("replies to our own e-mails", func() {
+							var inviteFor = func(week string) mail.Email {
+								invite := mail.E().WithFrom(conf.SaturdayDiscoEmail).WithTo(conf.SaturdayDiscoList).WithSubject("Saturday Bible Park Frisbee")
+								invite.MessageID = mail.MessageIDFor("saturday/"+week+"/0/invite_sent", conf.SaturdayDiscoEmail)
+								return invite
+							}
+
+							It("counts replies to this week's e-mails", func() {
+								interpreter.SetCommand(Command{CommandType: CommandPlayerSetCount, EmailAddress: playerEmail, Count: 2})
+								handleIncomingEmail(inviteFor(disco.GetSnapshot().T.Format("2006-01-02")).ReplyAll(playerEmail, "I'm in!"))
+
+								Eventually(disco.GetSnapshot).Should(HaveParticipantWithCount(playerEmail, 2))
+								Ω(le()).Should(HaveText(ContainSubstring("I've set the player's count to 2.")))
+							})
+
+							It("doesn't let replies to last week's thread change this week's counts, but lets the boss know", func() {
+								lastWeek := disco.GetSnapshot().T.AddDate(0, 0, -7).Format("2006-01-02")
+								interpreter.SetCommand(Command{CommandType: CommandPlayerSetCount, EmailAddress: playerEmail, Count: 2})
+								handleIncomingEmail(inviteFor(lastWeek).ReplyAll(playerEmail, "I'm in!"))
+
+								Eventually(le).Should(HaveSubject("Fwd: Re: Saturday Bible Park Frisbee"))
+								Ω(le()).Should(BeSentTo(conf.BossEmail))
+								Ω(le()).Should(HaveText(ContainSubstring("It's a reply to the invite for " + lastWeek + " - not this week's game - so I haven't changed any counts.")))
+								Ω(le()).Should(HaveText(ContainSubstring("/set player@example.com 2")))
+								Consistently(disco.GetSnapshot).Should(HaveCount(1))
+							})
+						})
 					})
 				})
 
 				Describe("getting status", func() {
-					BeforeEach(func() {
+					JustBeforeEach(func() {
 						clock.Fire() // invite approval
 						clock.Fire() // invite
 						bossToDisco("/set random@example.com 0")
@@ -534,7 +1046,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Describe("the boss' interface", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							bossToDisco("/status")
 							Eventually(le).Should(HaveSubject("Re: hey"))
 						})
@@ -557,7 +1069,7 @@ var _ = Describe("SaturdayDisco", func() {
 				})
 
 				Describe("aborting the scheduler", func() {
-					BeforeEach(func() {
+					JustBeforeEach(func() {
 						bossToDisco("/abort")
 						Eventually(disco.GetSnapshot).Should(HaveState(StateAbort))
 					})
@@ -575,7 +1087,7 @@ var _ = Describe("SaturdayDisco", func() {
 				})
 
 				Describe("resetting the system", func() {
-					BeforeEach(func() {
+					JustBeforeEach(func() {
 						clock.Fire() // invite approval
 						clock.Fire() // invite
 						Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
@@ -596,7 +1108,7 @@ var _ = Describe("SaturdayDisco", func() {
 
 				Describe("forcibly calling game on", func() {
 					Context("with no additional content", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							bossToDisco("/game-on")
 							Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
 						})
@@ -609,7 +1121,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("with additional content", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							bossToDisco("/game-on\n\nLETS **GO**")
 							Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
 						})
@@ -625,7 +1137,7 @@ var _ = Describe("SaturdayDisco", func() {
 
 				Describe("forcibly calling game on", func() {
 					Context("with no additional content", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							bossToDisco("/no-game")
 							Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
 						})
@@ -638,7 +1150,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("with additional content", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							bossToDisco("/no-game\n\nTOO MUCH **SNOW**")
 							Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
 						})
@@ -653,10 +1165,491 @@ var _ = Describe("SaturdayDisco", func() {
 				})
 			})
 
+			Describe("calendar invites", func() {
+				It("attaches an invite to the game-on e-mail and a cancellation to a no-game e-mail that follows it", func() {
+					Ω(disco.CalendarFeed().Events).Should(BeEmpty())
+					start := disco.GetSnapshot().T.UTC().Format(calendar.ICS_TIME_FORMAT)
+
+					bossToDisco("/game-on")
+					Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
+					Ω(le()).Should(HaveSubject("GAME ON THIS SATURDAY! " + gameDate))
+					Ω(le().Attachments).Should(HaveLen(1))
+					invite := le().Attachments[0]
+					Ω(invite.Filename).Should(Equal("saturday-frisbee.ics"))
+					Ω(invite.ContentType).Should(Equal("text/calendar; method=REQUEST; charset=utf-8"))
+					Ω(string(invite.Content)).Should(And(
+						ContainSubstring("METHOD:REQUEST\r\n"),
+						ContainSubstring("SEQUENCE:0\r\n"),
+						ContainSubstring("DTSTART:"+start+"\r\n"),
+						ContainSubstring("LOCATION:James Bible Park\r\n"),
+						ContainSubstring("STATUS:CONFIRMED\r\n"),
+					))
+
+					feed := disco.CalendarFeed()
+					Ω(feed.Method).Should(Equal(calendar.MethodPublish))
+					Ω(feed.Events).Should(HaveExactElements(And(HaveField("Sequence", 0), HaveField("Status", calendar.StatusConfirmed))))
+
+					bossToDisco("/no-game")
+					Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
+					Ω(le()).Should(HaveSubject("No Saturday Game This Week " + gameDate))
+					Ω(le().Attachments).Should(HaveLen(1))
+					cancellation := string(le().Attachments[0].Content)
+					Ω(cancellation).Should(And(
+						ContainSubstring("METHOD:CANCEL\r\n"),
+						ContainSubstring("SEQUENCE:1\r\n"),
+						ContainSubstring("STATUS:CANCELLED\r\n"),
+					))
+					Ω(disco.CalendarFeed().Events).Should(HaveExactElements(And(HaveField("Sequence", 1), HaveField("Status", calendar.StatusCancelled))))
+				})
+
+				It("doesn't attach anything to a no-game e-mail if the game was never called", func() {
+					bossToDisco("/no-game")
+					Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
+					Ω(le().Attachments).Should(BeEmpty())
+					Ω(disco.CalendarFeed().Events).Should(BeEmpty())
+				})
+			})
+
+			Describe("idempotency keys", func() {
+				It("gives each transition e-mail a key that's stable until the transition goes through", func() {
+					date := disco.GetSnapshot().T.Format("2006-01-02")
+					bossToDisco("/game-on")
+					Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
+					Ω(le().IdempotencyKey).Should(Equal("saturday/" + date + "/0/game_on_sent"))
+					Ω(le().MessageID).Should(Equal("<saturday." + date + ".0.game_on_sent@sedenverultimate.net>"))
+
+					bossToDisco("/no-game")
+					Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
+					Ω(le().IdempotencyKey).Should(Equal("saturday/" + date + "/1/no_game_sent"))
+
+					bossToDisco("/game-on")
+					Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
+					Ω(le().IdempotencyKey).Should(Equal("saturday/" + date + "/2/game_on_sent"))
+				})
+
+				It("never reuses a key after an undo", func() {
+					bossToDisco("/game-on")
+					Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
+					undone := le()
+
+					bossToDisco("/undo")
+					Eventually(disco.GetSnapshot).ShouldNot(HaveState(StateGameOnSent))
+					bossToDisco("/game-on\n\nNow with the right field!")
+					Eventually(disco.GetSnapshot).Should(HaveState(StateGameOnSent))
+					Ω(le().IdempotencyKey).ShouldNot(Equal(undone.IdempotencyKey))
+					Ω(le().MessageID).ShouldNot(Equal(undone.MessageID))
+				})
+
+				It("never reuses a key after a reset", func() {
+					clock.Fire()
+					Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
+					request := le()
+
+					bossToDisco("/RESET-RESET-RESET")
+					Eventually(disco.GetSnapshot).Should(HaveState(StatePending))
+					clock.Fire()
+					Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
+					Ω(le()).Should(HaveSubject(request.Subject))
+					Ω(le().IdempotencyKey).ShouldNot(Equal(request.IdempotencyKey))
+					Ω(le().MessageID).ShouldNot(Equal(request.MessageID))
+				})
+			})
+
+			Describe("authenticating admin commands", func() {
+				BeforeEach(func() {
+					conf.RequireAuthenticatedAdmins = true
+				})
+
+				authenticated := func(m mail.Email) mail.Email {
+					m.Authentication = mail.AuthenticationResults{
+						AuthServID: "mx1.forwardemail.net",
+						Results: []mail.AuthenticationResult{
+							{Method: "dkim", Result: "pass", Properties: map[string]string{"header.d": "example.com"}},
+						},
+					}
+					return m
+				}
+				JustBeforeEach(func() {
+					handleIncomingEmail(mail.E().
+						WithFrom(conf.BossEmail).
+						WithTo(conf.SaturdayDiscoEmail).
+						WithSubject("hey").
+						WithBody("/set player@example.com 3"))
+					Eventually(le).Should(HaveSubject("[quarantine-confirmation-request] #1: hey"))
+				})
+
+				It("runs authenticated commands right away", func() {
+					handleIncomingEmail(authenticated(mail.E().
+						WithFrom(conf.BossEmail).
+						WithTo(conf.SaturdayDiscoEmail).
+						WithSubject("hey").
+						WithBody("/set player@example.com 2")))
+					Eventually(disco.GetSnapshot).Should(HaveCount(2))
+				})
+
+				It("quarantines unauthenticated commands and asks the boss to confirm them", func() {
+					Ω(le()).Should(BeSentTo(conf.BossEmail))
+					Ω(le()).Should(HaveText(ContainSubstring("It claims to be from Boss <boss@example.com>, but our mail server reported: no authentication results")))
+					Ω(le()).Should(HaveText(ContainSubstring("/set player@example.com 3")))
+					Ω(outbox.Emails()).Should(HaveLen(1))
+					Ω(disco.GetSnapshot()).Should(HaveCount(0))
+					Ω(disco.GetSnapshot().Quarantine).Should(HaveLen(1))
+
+					handleIncomingEmail(authenticated(mail.E().
+						WithFrom(conf.BossEmail).
+						WithTo(conf.SaturdayDiscoEmail).
+						WithSubject("status").
+						WithBody("/status")))
+					Eventually(le).Should(HaveSubject("Re: status"))
+					Ω(le()).Should(HaveText(ContainSubstring("🔒 Waiting for you to /confirm or /reject these unauthenticated commands (or use the dashboard):\n- #1: \"hey\" from Boss <boss@example.com>")))
+				})
+
+				It("runs the command once the boss confirms it", func() {
+					handleIncomingEmail(authenticated(le().ReplyWithoutQuote(conf.BossEmail, "/confirm")))
+					Eventually(disco.GetSnapshot).Should(HaveCount(3))
+					Ω(disco.GetSnapshot().Quarantine).Should(BeEmpty())
+					Ω(outbox.Emails()).Should(ContainElement(HaveText(ContainSubstring("Got it - I'm running #1 now."))))
+					Ω(le()).Should(HaveSubject("Re: hey"))
+					Ω(le()).Should(HaveText(ContainSubstring("I've set player@example.com to 3")))
+					Ω(disco.GetSnapshot().AuditLog.Recent(2).String()).Should(ContainSubstring("confirmed quarantined e-mail #1"))
+				})
+
+				It("throws the command away if the boss rejects it", func() {
+					handleIncomingEmail(authenticated(le().ReplyWithoutQuote(conf.BossEmail, "/reject")))
+					Eventually(le).Should(HaveText(ContainSubstring("Got it - I threw away #1.")))
+					Ω(disco.GetSnapshot().Quarantine).Should(BeEmpty())
+					Ω(disco.GetSnapshot()).Should(HaveCount(0))
+				})
+
+				It("ignores confirmations that aren't authenticated either", func() {
+					outbox.Clear()
+					handleIncomingEmail(le().ReplyWithoutQuote(conf.BossEmail, "/confirm"))
+					Consistently(outbox.Emails).Should(BeEmpty())
+					Ω(disco.GetSnapshot().Quarantine).Should(HaveLen(1))
+					Ω(disco.GetSnapshot()).Should(HaveCount(0))
+				})
+
+				It("lets the boss confirm or reject from the dashboard, where e-mail can't be authenticated (e.g. over SMTP or IMAP)", func() {
+					Ω(le()).Should(HaveText(ContainSubstring("confirm or reject it on the dashboard instead: https://www.sedenverultimate.net/saturday/boss")))
+					Ω(disco.TemplateData().JSONForBoss()).Should(ContainSubstring(`"quarantine":[{"description":"#1: \"hey\" from Boss \u003cboss@example.com\u003e`))
+
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandQuarantineReply, QuarantineID: 1, Approved: true})).Should(Succeed())
+					Eventually(disco.GetSnapshot).Should(HaveCount(3))
+					Ω(disco.GetSnapshot().Quarantine).Should(BeEmpty())
+					Ω(outbox.Emails()).Should(ContainElement(HaveText(ContainSubstring("Got it - I'm running #1 now."))))
+					Ω(disco.GetSnapshot().AuditLog.Recent(2).String()).Should(ContainSubstring("confirmed quarantined e-mail #1"))
+
+					handleIncomingEmail(mail.E().WithFrom(conf.BossEmail).WithTo(conf.SaturdayDiscoEmail).WithSubject("again").WithBody("/set player@example.com 1"))
+					Eventually(disco.GetSnapshot).Should(HaveField("Quarantine", HaveLen(1)))
+					id := disco.GetSnapshot().Quarantine[0].ID
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandQuarantineReply, QuarantineID: id})).Should(Succeed())
+					Eventually(le).Should(HaveText(ContainSubstring(fmt.Sprintf("Got it - I threw away #%d.", id))))
+					Ω(disco.GetSnapshot()).Should(HaveCount(3))
+				})
+
+				It("only lets the boss confirm from the dashboard", func() {
+					Ω(disco.HandleCommand(helper, Command{CommandType: CommandQuarantineReply, QuarantineID: 1, Approved: true})).Should(MatchError("only the boss can confirm quarantined commands"))
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandQuarantineReply, Approved: true})).ShouldNot(Succeed())
+					Consistently(disco.GetSnapshot, "100ms").Should(HaveField("Quarantine", HaveLen(1)))
+				})
+
+				It("complains if the quarantined e-mail is already gone", func() {
+					request := le()
+					handleIncomingEmail(authenticated(request.ReplyWithoutQuote(conf.BossEmail, "/reject")))
+					Eventually(le).Should(HaveText(ContainSubstring("Got it - I threw away #1.")))
+					handleIncomingEmail(authenticated(request.ReplyWithoutQuote(conf.BossEmail, "/confirm")))
+					Eventually(le).Should(HaveText(ContainSubstring("there's no quarantined e-mail #1")))
+					Ω(disco.GetSnapshot()).Should(HaveCount(0))
+				})
+			})
+
+			Describe("the dashboard", func() {
+				It("includes a link to the dashboard in the status e-mail", func() {
+					bossToDisco("/status")
+					Eventually(le).Should(HaveText(ContainSubstring("Dashboard: https://www.sedenverultimate.net/saturday/boss")))
+				})
+
+				It("allows the boss to set counts, replying with an acknowledgement", func() {
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandAdminSetCount, EmailAddress: "Onsi Fakhouri <onsijoe@gmail.com>", Count: 2})).Should(Succeed())
+					Eventually(disco.GetSnapshot).Should(HaveCount(2))
+					Eventually(le).Should(HaveSubject("Re: Saturday Disco Dashboard"))
+					Ω(le()).Should(BeSentTo(conf.BossEmail))
+					Ω(le()).Should(HaveText(ContainSubstring("I've set Onsi Fakhouri <onsijoe@gmail.com> to 2")))
+
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandAdminSetCount, EmailAddress: "onsijoe@gmail.com", Count: 3})).Should(Succeed())
+					Eventually(disco.GetSnapshot).Should(HaveCount(3))
+					Ω(disco.GetSnapshot().Participants[0].RelevantEmails).Should(HaveLen(2))
+					Ω(disco.GetSnapshot().Participants[0].RelevantEmails[1].Text).Should(Equal("/set onsijoe@gmail.com 3"))
+				})
+
+				It("allows the boss to respond to approval requests", func() {
+					clock.Fire()
+					Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
+					outbox.Clear()
+
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandRequestedInviteApprovalReply, Approved: true, AdditionalContent: "Bring **cleats**"})).Should(Succeed())
+					Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+					Ω(le()).Should(HaveSubject("Saturday Bible Park Frisbee " + gameDate))
+					Ω(le()).Should(HaveHTML(ContainSubstring("Bring <strong>cleats</strong>")))
+				})
+
+				It("allows the boss to delay approval requests", func() {
+					clock.Fire()
+					Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
+					nextEvent := disco.GetSnapshot().NextEvent
+
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandRequestedInviteApprovalReply, Delay: 2})).Should(Succeed())
+					Eventually(le).Should(HaveText(ContainSubstring("I've delayed sending the invite email by 2 hours")))
+					Ω(disco.GetSnapshot().NextEvent).Should(Equal(nextEvent.Add(2 * time.Hour)))
+				})
+
+				It("allows the boss to abort", func() {
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandAdminAbort})).Should(Succeed())
+					Eventually(disco.GetSnapshot).Should(HaveState(StateAbort))
+				})
+
+				It("rejects commands that don't make sense from the dashboard", func() {
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandPlayerSetCount})).Should(MatchError("invalid dashboard command: player_set_count"))
+					Ω(disco.HandleCommand(conf.BossEmail, Command{CommandType: CommandAdminSetCount, Count: 2})).ShouldNot(Succeed())
+					Consistently(le).Should(BeZero())
+				})
+			})
+
+			Describe("bounces", func() {
+				BeforeEach(withOrganizers)
+
+				var bounceFor = func(status string, recipients ...mail.EmailAddress) mail.Email {
+					email := mail.E().WithFrom("Mail Delivery System <MAILER-DAEMON@mx1.forwardemail.net>").WithTo(conf.SaturdayDiscoEmail).
+						WithSubject("Undelivered Mail Returned to Sender").WithBody("I'm sorry to have to inform you that your message could not be delivered.")
+					email.Bounce = mail.Bounce{FailedRecipients: recipients, Status: status, Diagnostic: "550 no such user",
+						OriginalMessageID: mail.MessageIDFor("saturday/2023-09-30/0/requested_invite_approval", conf.SaturdayDiscoEmail)}
+					return email
+				}
+
+				It("tells the boss who the e-mail bounced for, without asking the interpreter about it", func() {
+					interpreter.SetCommand(Command{CommandType: CommandPlayerSetCount, EmailAddress: playerEmail, Count: 2})
+					handleIncomingEmail(bounceFor("5.1.1", helper))
+					Eventually(le).Should(HaveSubject("Fwd: Undelivered Mail Returned to Sender"))
+					Ω(le()).Should(BeSentTo(conf.BossEmail))
+					Ω(le()).Should(HaveText(ContainSubstring("It couldn't be delivered to:\n\n- Helper <helper@example.com>")))
+					Ω(le()).Should(HaveText(ContainSubstring("The mail server said: 5.1.1 550 no such user")))
+					Ω(le()).Should(HaveText(ContainSubstring("E-mail has bounced for:\n- Helper <helper@example.com>: bounced 1 time(s)")))
+					Ω(disco.GetSnapshot()).Should(HaveCount(0))
+					Ω(disco.GetSnapshot().Undeliverable).Should(HaveLen(1))
+					Ω(disco.GetSnapshot().Undeliverable.IsDead(helper)).Should(BeTrue())
+				})
+
+				It("stops sending to dead addresses until we hear from them again", func() {
+					handleIncomingEmail(bounceFor("5.1.1", helper))
+					Eventually(le).Should(HaveSubject("Fwd: Undelivered Mail Returned to Sender"))
+
+					clock.Fire()
+					Eventually(le).Should(HaveSubject("[invite-approval-request] Can I send this week's invite?"))
+					Ω(le()).Should(BeSentTo(conf.BossEmail))
+
+					handleIncomingEmail(mail.E().WithFrom(helper).WithTo(conf.SaturdayDiscoEmail).WithSubject("hey").WithBody("/status"))
+					Eventually(le).Should(BeSentTo(helper))
+					Ω(disco.GetSnapshot().Undeliverable).Should(BeEmpty())
+				})
+
+				It("only gives up on an address after a few temporary failures", func() {
+					for i := 1; i <= commands.MAX_TEMPORARY_BOUNCES; i++ {
+						Ω(disco.GetSnapshot().Undeliverable.IsDead(helper)).Should(BeFalse())
+						handleIncomingEmail(bounceFor("4.2.2", helper))
+						Eventually(func() int { return len(outbox.Emails()) }).Should(Equal(i))
+					}
+					Ω(disco.GetSnapshot().Undeliverable.IsDead(helper)).Should(BeTrue())
+					Ω(disco.GetSnapshot().Undeliverable[0].Bounces).Should(Equal(commands.MAX_TEMPORARY_BOUNCES))
+				})
+
+				It("never stops sending to the list or the boss", func() {
+					handleIncomingEmail(bounceFor("5.1.1", conf.SaturdayDiscoList, conf.BossEmail))
+					Eventually(le).Should(HaveSubject("Fwd: Undelivered Mail Returned to Sender"))
+					Ω(le()).Should(BeSentTo(conf.BossEmail))
+
+					clock.Fire()
+					Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
+					Ω(le()).Should(BeSentTo(conf.BossEmail, helper))
+					clock.Fire()
+					Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+					Ω(le()).Should(BeSentTo(conf.SaturdayDiscoList))
+				})
+
+				It("ignores bounces for e-mails it didn't send, so nobody can get a player cut off with a forged one", func() {
+					forged := bounceFor("5.1.1", playerEmail)
+					forged.Bounce.OriginalMessageID = "<made-up@example.com>"
+					handleIncomingEmail(forged)
+					forged.Bounce.OriginalMessageID = ""
+					handleIncomingEmail(forged)
+					forged.Bounce.OriginalMessageID = mail.MessageIDFor("saturday/2023-09-30/0/invite_sent", "someone@example.com")
+					handleIncomingEmail(forged)
+					Consistently(le).Should(BeZero())
+					Ω(disco.GetSnapshot().Undeliverable).Should(BeEmpty())
+				})
+
+				It("ignores delay notifications", func() {
+					email := bounceFor("")
+					email.Bounce = mail.Bounce{Delayed: true}
+					handleIncomingEmail(email)
+					Consistently(le).Should(BeZero())
+					Ω(disco.GetSnapshot().Undeliverable).Should(BeEmpty())
+				})
+			})
+
+			Describe("multiple organizers", func() {
+				BeforeEach(withOrganizers)
+
+				It("lets any organizer who can manage Saturday run commands, and logs who did it", func() {
+					handleIncomingEmail(mail.E().WithFrom(helper).WithTo(conf.SaturdayDiscoEmail).WithSubject("hey").WithBody("/set onsijoe@gmail.com 2"))
+					Eventually(disco.GetSnapshot).Should(HaveCount(2))
+					Ω(le()).Should(BeSentTo(helper))
+
+					Ω(disco.GetSnapshot().AuditLog).Should(HaveLen(1))
+					Ω(disco.GetSnapshot().AuditLog[0].Actor).Should(Equal(helper))
+					Ω(disco.GetSnapshot().AuditLog[0].Action).Should(Equal("set onsijoe@gmail.com to 2"))
+
+					Ω(disco.HandleCommand(helper, Command{CommandType: CommandAdminAbort})).Should(Succeed())
+					Eventually(disco.GetSnapshot).Should(HaveState(StateAbort))
+					Ω(disco.GetSnapshot().AuditLog[1].Actor).Should(Equal(helper))
+					Ω(disco.GetSnapshot().AuditLog[1].Action).Should(Equal("aborted"))
+
+					bossToDisco("/status")
+					Eventually(le).Should(HaveText(ContainSubstring("Recent admin actions:")))
+					Ω(le()).Should(HaveText(ContainSubstring("Helper <helper@example.com> aborted")))
+				})
+
+				It("doesn't treat viewers or organizers of other discos as admins", func() {
+					for _, address := range []mail.EmailAddress{lunchtimeOnly, viewer} {
+						handleIncomingEmail(mail.E().WithFrom(address).WithTo(conf.SaturdayDiscoEmail).WithSubject("hey").WithBody("/abort"))
+					}
+					Consistently(disco.GetSnapshot, "100ms").Should(HaveState(StatePending))
+					Ω(disco.GetSnapshot().AuditLog).Should(BeEmpty())
+				})
+
+				It("sends approval requests to every organizer who can manage Saturday", func() {
+					clock.Fire()
+					Eventually(le).Should(HaveSubject("[invite-approval-request] Can I send this week's invite?"))
+					Ω(le()).Should(BeSentTo(conf.BossEmail, helper))
+				})
+
+				It("accepts an approval that replies to every organizer", func() {
+					clock.Fire()
+					Eventually(le).Should(HaveSubject("[invite-approval-request] Can I send this week's invite?"))
+					reply := le().ReplyAllWithoutQuote(helper, "/approve")
+					Ω(reply.Recipients()).Should(HaveLen(2))
+
+					handleIncomingEmail(reply)
+					Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+					Ω(interpreter.GetEmails()).Should(BeEmpty())
+				})
+
+				It("treats an organizer's e-mail as a player's when it copies anyone but organizers", func() {
+					interpreter.SetCommand(Command{CommandType: CommandPlayerSetCount, Count: 1})
+					handleIncomingEmail(mail.E().WithFrom(helper).WithTo(conf.SaturdayDiscoEmail).AndCC(conf.SaturdayDiscoList).WithSubject("hey").WithBody("I'm in"))
+					Eventually(disco.GetSnapshot).Should(HaveParticipantWithCount(helper, 1))
+					Ω(interpreter.GetMostRecentEmail().From).Should(Equal(helper))
+					Ω(disco.GetSnapshot().AuditLog).Should(BeEmpty())
+				})
+
+				Describe("away mode", func() {
+					var awayRange string
+					BeforeEach(func() {
+						awayRange = now.Format("1/2") + " " + now.AddDate(0, 0, 13).Format("1/2")
+					})
+
+					It("handles approval requests according to the boss' away policies", func() {
+						bossToDisco("/away " + awayRange + " invite=approve badger=deny no-game=delegate:helper@example.com")
+						Eventually(le).Should(HaveText(ContainSubstring("Have a great trip!")))
+						Ω(le()).Should(HaveText(ContainSubstring("Away mode is ON")))
+						Ω(le()).Should(HaveText(ContainSubstring("invite: auto-approve, badger: auto-deny, game-on: wait for approval, no-game: ask Helper <helper@example.com>")))
+						outbox.Clear()
+
+						clock.Fire()
+						Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+						Ω(outbox.Emails()).Should(HaveLen(2))
+						Ω(outbox.Emails()[0]).Should(HaveSubject("Saturday Bible Park Frisbee " + gameDate))
+						Ω(le()).Should(HaveSubject("[Saturday Disco] Disco automatically approved the invite (away mode)"))
+						Ω(le()).Should(BeSentTo(conf.BossEmail, helper))
+						outbox.Clear()
+
+						clock.Fire()
+						Eventually(disco.GetSnapshot).Should(HaveState(StateBadgerNotSent))
+						Ω(le()).Should(HaveSubject("[Saturday Disco] Disco automatically denied the badger (away mode)"))
+						outbox.Clear()
+
+						clock.Fire()
+						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedNoGameApproval))
+						Ω(le()).Should(HaveSubject(ContainSubstring("[no-game-approval-request]")))
+						Ω(le()).Should(BeSentTo(helper))
+						Ω(le()).Should(HaveText(ContainSubstring("🏝️ Away mode is ON")))
+
+						auditLog := disco.GetSnapshot().AuditLog
+						Ω(auditLog).Should(HaveLen(3))
+						Ω(auditLog[0].Actor).Should(Equal(conf.BossEmail))
+						Ω(auditLog[0].Action).Should(HavePrefix("turned on away mode"))
+						Ω(auditLog[1].Actor).Should(Equal(conf.SaturdayDiscoEmail))
+						Ω(auditLog[1].Action).Should(Equal("automatically approved the invite (away mode)"))
+					})
+
+					It("shows away mode in the status e-mail and can be turned off with /back", func() {
+						bossToDisco("/away " + awayRange + " invite=deny")
+						Eventually(le).Should(HaveText(ContainSubstring("Have a great trip!")))
+
+						bossToDisco("/status")
+						Eventually(le).Should(HaveText(ContainSubstring("🏝️ Away mode is ON")))
+
+						bossToDisco("/back")
+						Eventually(le).Should(HaveText(ContainSubstring("Welcome back!")))
+						Ω(disco.GetSnapshot().Away.IsZero()).Should(BeTrue())
+						outbox.Clear()
+
+						clock.Fire()
+						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
+						Ω(le()).Should(BeSentTo(conf.BossEmail, helper))
+						Ω(le()).ShouldNot(HaveText(ContainSubstring("Away mode")))
+					})
+
+					It("tells the boss when the away command is malformed", func() {
+						bossToDisco("/away " + awayRange + " invite=maybe")
+						Eventually(le).Should(HaveText(ContainSubstring("must be one of approve, deny, wait, or delegate")))
+						Ω(disco.GetSnapshot().Away.IsZero()).Should(BeTrue())
+					})
+				})
+
+				Context("when several organizers reply to an approval request", func() {
+					var approvalRequest mail.Email
+					JustBeforeEach(func() {
+						clock.Fire()
+						Eventually(le).ShouldNot(BeZero())
+						approvalRequest = le()
+						outbox.Clear()
+
+						handleIncomingEmail(approvalRequest.ReplyWithoutQuote(helper, "/approve"))
+						Eventually(disco.GetSnapshot).Should(HaveState(StateInviteSent))
+					})
+
+					It("lets the first reply win and tells everyone else who decided", func() {
+						Eventually(outbox.Emails).Should(HaveLen(2))
+						Ω(outbox.Emails()[0]).Should(HaveSubject("Saturday Bible Park Frisbee " + gameDate))
+						Ω(le()).Should(HaveSubject("[Saturday Disco] Helper approved the invite"))
+						Ω(le()).Should(BeSentTo(conf.BossEmail))
+						Ω(le()).Should(HaveText(ContainSubstring("there's no need to reply to it")))
+
+						outbox.Clear()
+						handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/deny"))
+						Eventually(le).Should(HaveText(ContainSubstring("You sent me this e-mail, but my current state is: invite_sent")))
+						Ω(le()).Should(BeSentTo(conf.BossEmail))
+						Ω(le()).Should(HaveText(ContainSubstring("Latest admin action: ")))
+						Ω(le()).Should(HaveText(ContainSubstring("Helper <helper@example.com> approved the invite")))
+						Ω(disco.GetSnapshot()).Should(HaveState(StateInviteSent))
+					})
+				})
+			})
+
 			Describe("the flow of the state machine/scheduler", func() {
 				Describe("sending invitations", func() {
 					var approvalRequest mail.Email
-					BeforeEach(func() {
+					JustBeforeEach(func() {
 						clock.Fire()
 						Eventually(le).ShouldNot(BeZero())
 
@@ -677,7 +1670,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss doesn't reply", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							outbox.Clear()
 							clock.Fire()
 							Eventually(le).ShouldNot(BeZero())
@@ -696,7 +1689,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("if the boss then replies", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve"))
 								Eventually(le).ShouldNot(BeZero())
@@ -716,7 +1709,7 @@ var _ = Describe("SaturdayDisco", func() {
 
 					Context("if the boss asks for a delay", func() {
 						Context("and the delay is malformed", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/delay"))
 							})
@@ -724,7 +1717,7 @@ var _ = Describe("SaturdayDisco", func() {
 							It("returns an error and doesn't change the timer", func() {
 								Eventually(le).ShouldNot(BeZero())
 								Ω(le()).Should(BeSentTo(conf.BossEmail))
-								Ω(le()).Should(HaveText(ContainSubstring("invalid command in reply, must be one of /approve, /yes, /shipit, /deny, /no, /delay <int>, /abort, or /RESET-RESET-RESET")))
+								Ω(le()).Should(HaveText(ContainSubstring("invalid arguments for /delay - usage: /delay <hours>")))
 
 								outbox.Clear()
 								clock.Fire()
@@ -734,7 +1727,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("and the delay is for 0 hours", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/delay 0"))
 							})
@@ -752,7 +1745,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("and the delay is for some positive number of hours", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/delay 1"))
 							})
@@ -773,7 +1766,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss replies in the affirmative", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							outbox.Clear()
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve"))
 							Eventually(le).ShouldNot(BeZero())
@@ -790,7 +1783,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss replies with /abort", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							outbox.Clear()
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/abort"))
 							Eventually(le).ShouldNot(BeZero())
@@ -808,7 +1801,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss replies in the affirmative with an additional message", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							outbox.Clear()
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve\n\nLets **GO!**"))
 							Eventually(le).ShouldNot(BeZero())
@@ -826,7 +1819,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss replies in the negative", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							outbox.Clear()
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/no"))
 							Eventually(le).ShouldNot(BeZero())
@@ -849,7 +1842,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss replies in the negative with an additional message", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							outbox.Clear()
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/no\n\nOn account of **weather**...\n\n:("))
 							Eventually(le).ShouldNot(BeZero())
@@ -879,14 +1872,14 @@ var _ = Describe("SaturdayDisco", func() {
 						It("tells the boss", func() {
 							bossToDisco("Re: [invite-approval-request] hey", "/yeppers")
 							Eventually(le).Should(HaveSubject("Re: [invite-approval-request] hey"))
-							Ω(le()).Should(HaveText(ContainSubstring("invalid command in reply, must be one of /approve, /yes, /shipit, /deny, /no, /delay <int>, /abort, or /RESET-RESET-RESET")))
+							Ω(le()).Should(HaveText(ContainSubstring("unknown command: /yeppers - send /help for a list of commands")))
 						})
 					})
 				})
 
 				Describe("badgering users: after the invite is sent - when there is no quorum yet", func() {
 					var approvalRequest mail.Email
-					BeforeEach(func() {
+					JustBeforeEach(func() {
 						Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
 						clock.Fire()
 						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
@@ -915,7 +1908,7 @@ var _ = Describe("SaturdayDisco", func() {
 
 					Context("if there is still no quorum after time has elapsed", func() {
 						Context("if the boss doesn't reply", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								clock.Fire()
 								Eventually(le).ShouldNot(BeZero())
@@ -937,7 +1930,7 @@ var _ = Describe("SaturdayDisco", func() {
 							})
 
 							Context("if the boss then replies", func() {
-								BeforeEach(func() {
+								JustBeforeEach(func() {
 									outbox.Clear()
 									handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve"))
 									Eventually(le).ShouldNot(BeZero())
@@ -956,7 +1949,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("if the boss replies in the affirmative", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve"))
 								Eventually(le).ShouldNot(BeZero())
@@ -980,7 +1973,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("if the boss replies in the affirmative with an additional message", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve\n\nWe only have **FIVE**."))
 								Eventually(le).ShouldNot(BeZero())
@@ -1003,7 +1996,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("if the boss asks for a delay", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/delay 1"))
 							})
@@ -1023,7 +2016,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("if the boss replies in the negative", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/no"))
 								Eventually(disco.GetSnapshot).Should(HaveState(StateBadgerNotSent))
@@ -1041,7 +2034,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if quorum is attained before the boss replies or the badger is sent", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							clock.SetTime(clock.Time().Add(time.Hour)) // an hour later, i.e. the badger hasn't triggered yet
 							outbox.Clear()
 							bossToDisco("/set onsijoe@gmail.com 1")
@@ -1049,7 +2042,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("when its time to send the badger", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								clock.Fire()
 							})
@@ -1068,7 +2061,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("when the boss approves the badger", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve"))
 								Eventually(le).Should(HaveSubject("Last Call! " + gameDate))
@@ -1090,7 +2083,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Describe("if, after the badger is sent, there is quorum", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve"))
 							Eventually(le).Should(HaveSubject("Last Call! " + gameDate))
 							bossToDisco("/set onsijoe@gmail.com 1")
@@ -1107,7 +2100,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Describe("if, after the badger is sent, there is still no quorum", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve"))
 							Eventually(le).Should(HaveSubject("Last Call! " + gameDate))
 						})
@@ -1122,7 +2115,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Describe("if, the badger is not sent, but there comes to be quorum", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/deny"))
 							Eventually(disco.GetSnapshot).Should(HaveState(StateBadgerNotSent))
 							bossToDisco("/set onsijoe@gmail.com 1")
@@ -1139,7 +2132,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Describe("if, the badger is not sent and there is still no quorum", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/deny"))
 							Eventually(disco.GetSnapshot).Should(HaveState(StateBadgerNotSent))
 						})
@@ -1156,7 +2149,7 @@ var _ = Describe("SaturdayDisco", func() {
 
 				Describe("after the invite is sent - when there is quorum", func() {
 					var approvalRequest mail.Email
-					BeforeEach(func() {
+					JustBeforeEach(func() {
 						Ω(disco.GetSnapshot()).Should(HaveState(StatePending))
 						clock.Fire()
 						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
@@ -1189,7 +2182,7 @@ var _ = Describe("SaturdayDisco", func() {
 
 					Context("if there is still quorum after time has elapsed", func() {
 						Context("if the boss doesn't reply", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								clock.Fire()
 								Eventually(le).ShouldNot(BeZero())
@@ -1220,7 +2213,7 @@ var _ = Describe("SaturdayDisco", func() {
 							})
 
 							Context("if the boss then replies", func() {
-								BeforeEach(func() {
+								JustBeforeEach(func() {
 									outbox.Clear()
 									handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve"))
 									Eventually(le).ShouldNot(BeZero())
@@ -1240,7 +2233,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("if the boss replies in the affirmative", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve"))
 								Eventually(le).ShouldNot(BeZero())
@@ -1268,7 +2261,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("if the boss replies in the affirmative with an additional message", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/approve\n\nWe have a **solid** group this week!"))
 								Eventually(le).ShouldNot(BeZero())
@@ -1296,7 +2289,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("if the boss asks for a delay", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/delay 1"))
 							})
@@ -1316,7 +2309,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("if the boss replies in the negative", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								outbox.Clear()
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/no\nWe have the numbers but the **weather** has turned :("))
 								Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
@@ -1341,13 +2334,13 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if quorum is lost before its time", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							bossToDisco("/set Onsi Fakhouri <onsijoe@gmail.com> 0")
 							Eventually(disco.GetSnapshot).Should(HaveCount(7))
 						})
 
 						Context("and the boss doesn't reply before the timer goes off", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								clock.Fire()
 								Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedNoGameApproval))
 							})
@@ -1364,7 +2357,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("and the boss approves", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/yes"))
 								Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedNoGameApproval))
 							})
@@ -1385,7 +2378,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						Context("and the boss declines", func() {
-							BeforeEach(func() {
+							JustBeforeEach(func() {
 								handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/no"))
 								Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
 							})
@@ -1403,7 +2396,7 @@ var _ = Describe("SaturdayDisco", func() {
 
 				Describe("the no game flow", func() {
 					var approvalRequest mail.Email
-					BeforeEach(func() {
+					JustBeforeEach(func() {
 						clock.Fire()
 						Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedInviteApproval))
 						clock.Fire()
@@ -1428,7 +2421,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss doesn't respond in time", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							clock.Fire()
 						})
 
@@ -1451,7 +2444,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss approves", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/yes"))
 							Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
 						})
@@ -1465,7 +2458,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss approves with additional content", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/yes\nWe did **not** manage to get to quorum."))
 							Eventually(disco.GetSnapshot).Should(HaveState(StateNoGameSent))
 						})
@@ -1480,7 +2473,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss disapproves", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/no"))
 							Eventually(disco.GetSnapshot).Should(HaveState(StateAbort))
 						})
@@ -1494,7 +2487,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("if the boss asks for a delay", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							outbox.Clear()
 							handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, "/delay 1"))
 						})
@@ -1514,7 +2507,7 @@ var _ = Describe("SaturdayDisco", func() {
 					})
 
 					Context("quorum is attained before no-game is resolved", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							bossToDisco("/set player@example.com 1")
 							Eventually(disco.GetSnapshot).Should(HaveCount(8)) // quorum!
 						})
@@ -1535,7 +2528,7 @@ var _ = Describe("SaturdayDisco", func() {
 						for _, response := range []string{"/yes", "/no"} {
 							response := response
 							Context("if the boss responds with "+response, func() {
-								BeforeEach(func() {
+								JustBeforeEach(func() {
 									handleIncomingEmail(approvalRequest.ReplyWithoutQuote(conf.BossEmail, response))
 									Eventually(disco.GetSnapshot).Should(HaveState(StateRequestedGameOnApproval))
 								})
@@ -1559,7 +2552,7 @@ var _ = Describe("SaturdayDisco", func() {
 
 				Describe("spot-checking retry logic", func() {
 					Context("when an email for a scheduled event is supposed to be sent, but it fails to send", func() {
-						BeforeEach(func() {
+						JustBeforeEach(func() {
 							outbox.SetError(fmt.Errorf("boom"))
 							clock.Fire()
 							Eventually(le).Should(HaveSubject("Help!"))
@@ -1575,6 +2568,7 @@ var _ = Describe("SaturdayDisco", func() {
 						})
 
 						It("retries in five minutes", func() {
+							Ω(disco.GetSnapshot().NextEvent).Should(BeOn(time.Tuesday, 6, 5+testConfig.Offset))
 							outbox.SetError(nil)
 							clock.Fire()
 							Ω(clock.Time()).Should(BeOn(time.Tuesday, 6, 5+testConfig.Offset))
@@ -0,0 +1,58 @@
+package saturdaydisco
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/onsi/disco/mail"
+)
+
+// ThreadRef identifies one of our own e-mails from its Message-ID - sendEmail derives the Message-ID from the e-mail's idempotency
+// key, so the ID tells us which week the e-mail was for and what it was (the invite, the game on, etc.)
+type ThreadRef struct {
+	Week  string
+	State SaturdayDiscoState
+}
+
+func (r ThreadRef) IsZero() bool {
+	return r.Week == ""
+}
+
+// Description is what the e-mail was, for the logs and the boss
+func (r ThreadRef) Description() string {
+	switch r.State {
+	case StateInviteSent:
+		return "the invite"
+	case StateNoInviteSent:
+		return "the no-invite announcement"
+	case StateBadgerSent:
+		return "the badger"
+	case StateGameOnSent:
+		return "the game on"
+	case StateNoGameSent:
+		return "the no game"
+	case StateReminderSent:
+		return "the reminder"
+	}
+	return "an e-mail"
+}
+
+var threadRefRegex = regexp.MustCompile(`^<saturday\.(\d{4}-\d{2}-\d{2})\.\d+\.([a-z_]+)@`)
+
+func parseThreadRef(messageID string) (ThreadRef, bool) {
+	match := threadRefRegex.FindStringSubmatch(strings.ToLower(messageID))
+	if match == nil {
+		return ThreadRef{}, false
+	}
+	return ThreadRef{Week: match[1], State: SaturdayDiscoState(match[2])}, true
+}
+
+// repliedTo finds the most recent of our e-mails that email is a reply to
+func repliedTo(email mail.Email) ThreadRef {
+	for _, id := range email.Thread() {
+		if ref, ok := parseThreadRef(id); ok {
+			return ref
+		}
+	}
+	return ThreadRef{}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/onsi/disco/archive"
+	"github.com/onsi/disco/mail"
+)
+
+const ARCHIVE_PAGE_SIZE = 25
+
+type ArchiveAction struct {
+	Action string `json:"action" form:"action"`
+	Key    string `json:"key" form:"key"`
+	Disco  string `json:"disco" form:"disco"`
+}
+
+func archivedEmailJSON(email mail.Email) map[string]any {
+	return map[string]any{
+		"from":      email.From,
+		"to":        email.To.String(),
+		"cc":        email.CC.String(),
+		"subject":   email.Subject,
+		"date":      email.Date,
+		"messageID": email.MessageID,
+		"text":      email.Text,
+		"bounce":    email.Bounce.Reason(),
+		"isBounce":  email.IsBounce(),
+		"autoReply": email.AutoReply,
+	}
+}
+
+func (s *Server) ArchiveBoss(c echo.Context) error {
+	if s.archive == nil {
+		return c.String(http.StatusNotFound, "not found")
+	}
+	query := c.QueryParam("q")
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	entries := []map[string]any{}
+	results, err := s.archive.Search(query, offset, ARCHIVE_PAGE_SIZE)
+	listError := ""
+	if err != nil {
+		listError = err.Error()
+	}
+	for _, entry := range results.Entries {
+		out := map[string]any{
+			"key":      entry.Key,
+			"storedAt": entry.StoredAt,
+			"size":     entry.Size,
+		}
+		if entry.Error != nil {
+			out["error"] = entry.Error.Error()
+		} else {
+			out["email"] = archivedEmailJSON(entry.Email)
+		}
+		entries = append(entries, out)
+	}
+	out, _ := json.Marshal(map[string]any{
+		"entries":   entries,
+		"query":     query,
+		"offset":    offset,
+		"pageSize":  ARCHIVE_PAGE_SIZE,
+		"more":      results.More,
+		"stored":    results.Stored,
+		"maxScan":   archive.MAX_SCAN,
+		"discos":    s.archive.Discos(),
+		"retention": int(s.config.EmailRetention / (24 * time.Hour)),
+		"error":     listError,
+	})
+	return c.Render(http.StatusOK, "mail_archive", map[string]any{"JSON": string(out)})
+}
+
+func (s *Server) ArchiveBossSubmit(c echo.Context) error {
+	if s.archive == nil {
+		return c.String(http.StatusNotFound, "not found")
+	}
+	var action ArchiveAction
+	if err := c.Bind(&action); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	switch action.Action {
+	case "reparse":
+		email, err := s.archive.Reparse(action.Key)
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.JSON(http.StatusOK, archivedEmailJSON(email))
+	case "dispatch":
+		email, err := s.archive.Redispatch(action.Key, action.Disco)
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.JSON(http.StatusOK, archivedEmailJSON(email))
+	case "prune":
+		deleted, err := s.archive.Prune(time.Now().Add(-s.config.EmailRetention))
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		return c.JSON(http.StatusOK, map[string]any{"deleted": deleted})
+	default:
+		return c.String(http.StatusBadRequest, "unknown action: "+action.Action)
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/onsi/disco/auth"
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/say"
+)
+
+const ORGANIZER_KEY = "organizer"
+
+var loginTemplate = template.Must(template.New("login").Parse(`Hey {{.Name}},
+
+Click here to log in to Disco: {{.URL}}
+
+This link expires in 15 minutes.  If you didn't ask for it, just ignore this e-mail.
+
+Disco 🪩`))
+
+type LoginRequest struct {
+	Email string `form:"email" json:"email"`
+	Next  string `form:"next" json:"next"`
+}
+
+// RequireOrganizer guards every admin endpoint for disco.  Any organizer with access to disco can look, only those who can manage it can make changes.
+func (s *Server) RequireOrganizer(disco string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			organizer, err := s.auth.Authenticate(c.Request(), time.Now())
+			if err != nil {
+				if c.Request().Method == http.MethodGet {
+					return c.Redirect(http.StatusSeeOther, "/login?next="+url.QueryEscape(c.Request().URL.RequestURI()))
+				}
+				return c.String(http.StatusUnauthorized, "please log in")
+			}
+			if !organizer.CanView(disco) {
+				say.Fplni(s.e.Logger.Output(), 0, "{{red}}%s tried to access %s{{/}}", organizer.Address, disco)
+				return c.String(http.StatusForbidden, "you don't have access to this disco")
+			}
+			if c.Request().Method != http.MethodGet && !organizer.CanManage(disco) {
+				say.Fplni(s.e.Logger.Output(), 0, "{{red}}%s (%s) tried to make changes to %s{{/}}", organizer.Address, organizer.Role, disco)
+				return c.String(http.StatusForbidden, "you don't have permission to make changes")
+			}
+			c.Set(ORGANIZER_KEY, organizer)
+			return next(c)
+		}
+	}
+}
+
+func (s *Server) LoginForm(c echo.Context) error {
+	return c.Render(http.StatusOK, "login", map[string]any{"Next": auth.SafeNext(c.QueryParam("next"))})
+}
+
+func (s *Server) Login(c echo.Context) error {
+	say.Fplni(s.e.Logger.Output(), 0, "{{green}}Got a login request{{/}}")
+	var request LoginRequest
+	if err := c.Bind(&request); err != nil {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Failed to bind request %s{{/}}", err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	address := mail.EmailAddress(truncate(strings.TrimSpace(request.Email), 100))
+
+	now := time.Now()
+	if !s.loginLimiter.Allow(strings.ToLower(address.Address()), now) {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Too many login requests for %s{{/}}", address)
+		return c.String(http.StatusTooManyRequests, "Too many requests")
+	}
+
+	// we don't tell strangers whether or not they're an organizer
+	organizer, token, err := s.auth.LoginToken(address, request.Next, now)
+	if err == auth.ErrNotAnOrganizer {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}%s is not an organizer{{/}}", address)
+		return c.Render(http.StatusOK, "login", map[string]any{"Sent": true})
+	} else if err != nil {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Failed to sign login token %s{{/}}", err.Error())
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	body := &strings.Builder{}
+	err = loginTemplate.Execute(body, map[string]string{
+		"Name": organizer.Address.Name(),
+		"URL":  "https://www.sedenverultimate.net/login/" + token,
+	})
+	if err != nil {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Failed to render email body %s{{/}}", err.Error())
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	err = s.outbox.SendEmail(mail.E().
+		WithFrom(s.config.SaturdayDiscoEmail).
+		WithTo(organizer.Address).
+		WithSubject("Your Disco login link").WithBody(body.String()))
+	if err != nil {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Failed to send email %s{{/}}", err.Error())
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	say.Fplni(s.e.Logger.Output(), 1, "{{green}}Sent login link to %s{{/}}", organizer.Address)
+	return c.Render(http.StatusOK, "login", map[string]any{"Sent": true})
+}
+
+func (s *Server) LoginWithToken(c echo.Context) error {
+	cookie, next, err := s.auth.Login(c.Param("token"), time.Now())
+	if err != nil {
+		say.Fplni(s.e.Logger.Output(), 0, "{{red}}Invalid login token: %s{{/}}", err.Error())
+		return c.Render(http.StatusBadRequest, "login", map[string]any{"Error": err, "Next": auth.SafeNext("")})
+	}
+	c.SetCookie(cookie)
+	return c.Redirect(http.StatusSeeOther, next)
+}
+
+func (s *Server) Logout(c echo.Context) error {
+	c.SetCookie(s.auth.LogoutCookie())
+	return c.Redirect(http.StatusSeeOther, "/")
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/onsi/disco/calendar"
+)
+
+const CALENDAR_CONTENT_TYPE = "text/calendar; charset=utf-8"
+
+// calendar feeds are public - they only ever hold the called game, which we've already e-mailed to the list
+
+func (s *Server) SaturdayCalendar(c echo.Context) error {
+	if s.saturdayDisco == nil {
+		return c.String(http.StatusNotFound, "not found")
+	}
+	return s.renderCalendar(c, s.saturdayDisco.CalendarFeed())
+}
+
+func (s *Server) LunchtimeCalendar(c echo.Context) error {
+	if s.lunchtimeDisco == nil {
+		return c.String(http.StatusNotFound, "not found")
+	}
+	return s.renderCalendar(c, s.lunchtimeDisco.CalendarFeed())
+}
+
+func (s *Server) renderCalendar(c echo.Context, feed calendar.Calendar) error {
+	// calendar apps poll feeds, so don't let anything in between hold on to a stale copy
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	return c.Blob(http.StatusOK, CALENDAR_CONTENT_TYPE, feed.Render(time.Now()))
+}
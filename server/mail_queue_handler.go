@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/onsi/disco/config"
+	"github.com/onsi/disco/mailqueue"
+	"github.com/onsi/say"
+)
+
+type MailQueueAction struct {
+	Action string `json:"action" form:"action"`
+	ID     string `json:"id" form:"id"`
+}
+
+// RequireBoss guards endpoints that span every disco - like the mail page
+func (s *Server) RequireBoss() echo.MiddlewareFunc {
+	requireOrganizer := s.RequireOrganizer("")
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return requireOrganizer(func(c echo.Context) error {
+			organizer := c.Get(ORGANIZER_KEY).(config.Organizer)
+			if organizer.Role != config.RoleBoss {
+				say.Fplni(s.e.Logger.Output(), 0, "{{red}}%s tried to access the mail page{{/}}", organizer.Address)
+				return c.String(http.StatusForbidden, "only the boss can do that")
+			}
+			return next(c)
+		})
+	}
+}
+
+func (s *Server) MailQueueBoss(c echo.Context) error {
+	messages := []map[string]any{}
+	stuck := mailqueue.Messages{}
+	if s.mailQueue != nil {
+		stuck = s.mailQueue.Stuck()
+	}
+	for _, message := range stuck {
+		messages = append(messages, map[string]any{
+			"id":          message.ID,
+			"status":      message.Status,
+			"attempts":    message.Attempts,
+			"lastError":   message.LastError,
+			"nextAttempt": message.NextAttempt,
+			"enqueuedAt":  message.EnqueuedAt,
+			"from":        message.Email.From,
+			"to":          message.Email.Recipients().String(),
+			"subject":     message.Email.Subject,
+			"text":        message.Email.Text,
+		})
+	}
+	out, _ := json.Marshal(map[string]any{
+		"messages":    messages,
+		"maxAttempts": mailqueue.MAX_ATTEMPTS,
+		"incoming":    s.webhookGuard.Metrics().String(),
+	})
+	return c.Render(http.StatusOK, "mail_boss", map[string]any{"JSON": string(out)})
+}
+
+func (s *Server) MailQueueBossSubmit(c echo.Context) error {
+	if s.mailQueue == nil {
+		return c.String(http.StatusNotFound, "not found")
+	}
+	var action MailQueueAction
+	if err := c.Bind(&action); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	var err error
+	switch action.Action {
+	case "retry":
+		err = s.mailQueue.Retry(action.ID)
+	case "discard":
+		err = s.mailQueue.Discard(action.ID)
+	default:
+		return c.String(http.StatusBadRequest, "unknown action: "+action.Action)
+	}
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// how often Allow sweeps out keys that haven't been hit within the window, so the maps don't grow forever
+const RATE_LIMITER_SWEEP_INTERVAL = time.Minute
+
+// RateLimiter is a simple in-memory sliding-window limiter.  It's plenty for a single-instance deployment.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+	swept  time.Time
+	lock   *sync.Mutex
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		hits:   map[string][]time.Time{},
+		lock:   &sync.Mutex{},
+	}
+}
+
+// Allow records a hit for key and returns false if key has exceeded the limit within the window
+func (r *RateLimiter) Allow(key string, now time.Time) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sweep(now)
+	recent := r.recent(key, now)
+	if len(recent) >= r.limit {
+		r.hits[key] = recent
+		return false
+	}
+	r.hits[key] = append(recent, now)
+	return true
+}
+
+func (r *RateLimiter) recent(key string, now time.Time) []time.Time {
+	recent := []time.Time{}
+	for _, t := range r.hits[key] {
+		if now.Sub(t) < r.window {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// sweep forgets every key whose hits have all aged out of the window
+func (r *RateLimiter) sweep(now time.Time) {
+	if now.Sub(r.swept) < RATE_LIMITER_SWEEP_INTERVAL {
+		return
+	}
+	r.swept = now
+	for key := range r.hits {
+		if len(r.recent(key, now)) == 0 {
+			delete(r.hits, key)
+		}
+	}
+}
@@ -3,15 +3,21 @@ package server
 import (
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
+	"github.com/onsi/disco/archive"
+	"github.com/onsi/disco/auth"
 	"github.com/onsi/disco/config"
 	"github.com/onsi/disco/lunchtimedisco"
 	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/mailqueue"
 	"github.com/onsi/disco/s3db"
 	"github.com/onsi/disco/saturdaydisco"
+	"github.com/onsi/disco/signing"
+	"github.com/onsi/disco/webhook"
 )
 
 type TemplateData struct {
@@ -24,20 +30,44 @@ type Server struct {
 	rootPath       string
 	config         config.Config
 	outbox         mail.OutboxInt
+	mailQueue      *mailqueue.Queue
+	archive        *archive.Archive
 	db             s3db.S3DBInt
 	saturdayDisco  *saturdaydisco.SaturdayDisco
 	lunchtimeDisco *lunchtimedisco.LunchtimeDisco
+
+	signer                  signing.Signer
+	webhookGuard            *webhook.Guard
+	auth                    auth.Authenticator
+	loginLimiter            *RateLimiter
+	subscribeIPLimiter      *RateLimiter
+	subscribeAddressLimiter *RateLimiter
+	confirmedSubscriptions  *RateLimiter
 }
 
-func NewServer(e *echo.Echo, rootPath string, conf config.Config, outbox mail.OutboxInt, db s3db.S3DBInt, saturdayDisco *saturdaydisco.SaturdayDisco, lunchtimeDisco *lunchtimedisco.LunchtimeDisco) *Server {
+func NewServer(e *echo.Echo, rootPath string, conf config.Config, outbox mail.OutboxInt, mailQueue *mailqueue.Queue, archive *archive.Archive, webhookGuard *webhook.Guard, db s3db.S3DBInt, saturdayDisco *saturdaydisco.SaturdayDisco, lunchtimeDisco *lunchtimedisco.LunchtimeDisco) *Server {
+	signer := signing.NewSigner(conf.SigningSecret)
+	if conf.SigningSecret == "" {
+		signer = signing.NewRandomSigner()
+	}
 	return &Server{
 		e:              e,
 		rootPath:       rootPath,
 		config:         conf,
 		outbox:         outbox,
+		mailQueue:      mailQueue,
+		archive:        archive,
 		db:             db,
 		saturdayDisco:  saturdayDisco,
 		lunchtimeDisco: lunchtimeDisco,
+
+		signer:                  signer,
+		webhookGuard:            webhookGuard,
+		auth:                    auth.NewAuthenticator(conf, signer),
+		loginLimiter:            NewRateLimiter(5, time.Hour),
+		subscribeIPLimiter:      NewRateLimiter(5, time.Hour),
+		subscribeAddressLimiter: NewRateLimiter(3, 24*time.Hour),
+		confirmedSubscriptions:  NewRateLimiter(1, SUBSCRIPTION_TOKEN_TTL),
 	}
 }
 
@@ -45,6 +75,9 @@ func (s *Server) Start() error {
 	t := NewTemplateRenderer(s.rootPath, s.config.IsDev())
 	s.e.Renderer = t
 	s.e.Logger.SetLevel(log.INFO)
+	// fly.io's proxy sits in front of us and appends the caller to X-Forwarded-For - only believe that header when it comes from the
+	// proxy's private network, otherwise anyone could dodge the rate limits by making up an IP
+	s.e.IPExtractor = echo.ExtractIPFromXFFHeader()
 	if s.config.IsDev() {
 		s.e.Debug = true
 	}
@@ -59,8 +92,26 @@ func (s *Server) RegisterRoutes() {
 	s.e.POST("/incoming/"+s.config.IncomingSaturdayEmailGUID, s.IncomingSaturdayEmail)
 	s.e.POST("/incoming/"+s.config.IncomingLunchtimeEmailGUID, s.IncomingLunchtimeEmail)
 	s.e.POST("/subscribe", s.Subscribe)
+	s.e.GET("/subscribe/confirm/:token", s.ConfirmSubscriptionForm)
+	s.e.POST("/subscribe/confirm/:token", s.ConfirmSubscription)
+	s.e.GET("/login", s.LoginForm)
+	s.e.POST("/login", s.Login)
+	s.e.GET("/login/:token", s.LoginWithToken)
+	s.e.GET("/logout", s.Logout)
 	s.e.GET("/lunchtime/:guid", s.Lunchtime)
 	s.e.POST("/lunchtime/:guid", s.LunchtimeSubmit)
+	s.e.GET("/calendar/saturday.ics", s.SaturdayCalendar)
+	s.e.GET("/calendar/lunchtime.ics", s.LunchtimeCalendar)
+
+	// admin endpoints
+	s.e.GET("/saturday/boss", s.SaturdayBoss, s.RequireOrganizer(config.DiscoSaturday))
+	s.e.POST("/saturday/boss", s.SaturdayBossSubmit, s.RequireOrganizer(config.DiscoSaturday))
+	s.e.GET("/lunchtime/boss", s.LunchtimeBoss, s.RequireOrganizer(config.DiscoLunchtime))
+	s.e.POST("/lunchtime/boss", s.LunchtimeBossSubmit, s.RequireOrganizer(config.DiscoLunchtime))
+	s.e.GET("/mail/boss", s.MailQueueBoss, s.RequireBoss())
+	s.e.POST("/mail/boss", s.MailQueueBossSubmit, s.RequireBoss())
+	s.e.GET("/mail/archive", s.ArchiveBoss, s.RequireBoss())
+	s.e.POST("/mail/archive", s.ArchiveBossSubmit, s.RequireBoss())
 }
 
 func (s *Server) Index(c echo.Context) error {
@@ -79,13 +130,18 @@ func (s *Server) IncomingSaturdayEmail(c echo.Context) error {
 	if err != nil {
 		return c.NoContent(http.StatusInternalServerError)
 	}
+	if err := s.webhookGuard.Check(config.DiscoSaturday, c.Request().Header.Get(webhook.SIGNATURE_HEADER), data, time.Now()); err != nil {
+		return rejectIncomingEmail(c, err)
+	}
 	email, err := mail.ParseIncomingEmail(s.db, data, s.e.Logger.Output())
 	if err != nil {
 		s.e.Logger.Errorf("failed to parse incoming email: %s", err.Error())
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
-
-	s.saturdayDisco.HandleIncomingEmail(email)
+	// only now that it's safely parsed - if we'd remembered it before, forwardemail's retry of a failure would look like a replay
+	if s.webhookGuard.MarkDelivered(config.DiscoSaturday, email.MessageID, time.Now()) {
+		s.saturdayDisco.HandleIncomingEmail(email)
+	}
 	return c.NoContent(http.StatusOK)
 }
 
@@ -94,12 +150,66 @@ func (s *Server) IncomingLunchtimeEmail(c echo.Context) error {
 	if err != nil {
 		return c.NoContent(http.StatusInternalServerError)
 	}
+	if err := s.webhookGuard.Check(config.DiscoLunchtime, c.Request().Header.Get(webhook.SIGNATURE_HEADER), data, time.Now()); err != nil {
+		return rejectIncomingEmail(c, err)
+	}
 	email, err := mail.ParseIncomingEmail(s.db, data, s.e.Logger.Output())
 	if err != nil {
 		s.e.Logger.Errorf("failed to parse incoming email: %s", err.Error())
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
-	s.lunchtimeDisco.HandleIncomingEmail(email)
+	if s.webhookGuard.MarkDelivered(config.DiscoLunchtime, email.MessageID, time.Now()) {
+		s.lunchtimeDisco.HandleIncomingEmail(email)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// duplicates get a 200 so forwardemail doesn't keep retrying them - everything else is refused
+func rejectIncomingEmail(c echo.Context, err error) error {
+	rejection, _ := webhook.IsRejection(err)
+	switch rejection.Reason {
+	case webhook.ReasonDuplicate:
+		return c.NoContent(http.StatusOK)
+	case webhook.ReasonMalformed, webhook.ReasonStale:
+		return c.String(http.StatusBadRequest, err.Error())
+	default:
+		return c.String(http.StatusUnauthorized, err.Error())
+	}
+}
+
+func (s *Server) SaturdayBoss(c echo.Context) error {
+	return c.Render(http.StatusOK, "saturday_boss", TemplateData{
+		Saturday: s.saturdayDisco.TemplateData(),
+	})
+}
+
+func (s *Server) SaturdayBossSubmit(c echo.Context) error {
+	var command saturdaydisco.Command
+	if err := c.Bind(&command); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	organizer := c.Get(ORGANIZER_KEY).(config.Organizer)
+	if err := s.saturdayDisco.HandleCommand(organizer.Address, command); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) LunchtimeBoss(c echo.Context) error {
+	return c.Render(http.StatusOK, "lunchtime_boss", TemplateData{
+		Lunchtime: s.lunchtimeDisco.TemplateData(),
+	})
+}
+
+func (s *Server) LunchtimeBossSubmit(c echo.Context) error {
+	var command lunchtimedisco.Command
+	if err := c.Bind(&command); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	organizer := c.Get(ORGANIZER_KEY).(config.Organizer)
+	if err := s.lunchtimeDisco.HandleCommand(organizer.Address, command); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
 	return c.NoContent(http.StatusOK)
 }
 
@@ -110,10 +220,6 @@ func (s *Server) Lunchtime(c echo.Context) error {
 		return c.Render(http.StatusOK, "lunchtime_player", TemplateData{
 			Lunchtime: data,
 		})
-	} else if guid == data.BossGUID {
-		return c.Render(http.StatusOK, "lunchtime_boss", TemplateData{
-			Lunchtime: data,
-		})
 	}
 	return c.String(http.StatusNotFound, "not found - check your inbox for the latest game link")
 }
@@ -128,13 +234,6 @@ func (s *Server) LunchtimeSubmit(c echo.Context) error {
 		}
 		s.lunchtimeDisco.HandleParticipant(participant)
 		return c.NoContent(http.StatusOK)
-	} else if guid == data.BossGUID {
-		var command lunchtimedisco.Command
-		if err := c.Bind(&command); err != nil {
-			return c.String(http.StatusBadRequest, err.Error())
-		}
-		s.lunchtimeDisco.HandleCommand(command)
-		return c.NoContent(http.StatusOK)
 	}
 	return c.String(http.StatusNotFound, "not found - check your inbox for the latest game link")
 }
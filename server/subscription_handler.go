@@ -4,12 +4,16 @@ import (
 	"html/template"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/onsi/disco/mail"
 	"github.com/onsi/say"
 )
 
+const SUBSCRIPTION_TOKEN_PURPOSE = "subscribe"
+const SUBSCRIPTION_TOKEN_TTL = 48 * time.Hour
+
 var subscribeTemplate = template.Must(template.New("subscribe").Parse(`Hey boss,
 
 We just got a subscription request:
@@ -24,11 +28,24 @@ Thanks,
 
 Disco 🪩`))
 
+var confirmSubscriptionTemplate = template.Must(template.New("confirm-subscription").Parse(`Hey there,
+
+Someone (hopefully you!) asked to join the Southeast Denver Ultimate mailing lists with this e-mail address.
+
+Click here to confirm: {{.}}
+
+If this wasn't you, just ignore this e-mail and you won't hear from us again.
+
+Disco 🪩`))
+
 type SubscriptionRequest struct {
 	Email          string `json:"email"`
 	WantsSaturday  bool   `json:"wantsSaturday"`
 	WantsLunchtime bool   `json:"wantsLunchtime"`
 	Message        string `json:"message"`
+
+	// Honeypot: this field is hidden from humans.  Bots that fill it out get a fake success.
+	Website string `json:"website"`
 }
 
 func truncate(input string, maxLength int) string {
@@ -46,6 +63,11 @@ func (s *Server) Subscribe(c echo.Context) error {
 		return c.String(http.StatusBadRequest, err.Error())
 	}
 
+	if request.Website != "" {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Honeypot field was filled in - pretending all is well{{/}}")
+		return c.NoContent(http.StatusOK)
+	}
+
 	if !request.WantsSaturday && !request.WantsLunchtime {
 		say.Fplni(s.e.Logger.Output(), 1, "{{red}}No subscription type selected - ignoring{{/}}")
 		return c.String(http.StatusBadRequest, "No subscription type selected")
@@ -54,11 +76,71 @@ func (s *Server) Subscribe(c echo.Context) error {
 	request.Email = truncate(strings.TrimSpace(request.Email), 100)
 	request.Message = truncate(strings.TrimSpace(request.Message), 1000)
 
-	if request.Email == "" {
+	if request.Email == "" || !strings.Contains(request.Email, "@") {
 		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Email is required but missing{{/}}")
 		return c.String(http.StatusBadRequest, "Email is required")
 	}
 
+	now := time.Now()
+	ip := c.RealIP()
+	if !s.subscribeIPLimiter.Allow(ip, now) {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Too many requests from %s{{/}}", ip)
+		return c.String(http.StatusTooManyRequests, "Too many requests")
+	}
+	if !s.subscribeAddressLimiter.Allow(strings.ToLower(mail.EmailAddress(request.Email).Address()), now) {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Too many requests for %s{{/}}", request.Email)
+		return c.String(http.StatusTooManyRequests, "Too many requests")
+	}
+
+	token, err := s.signer.Sign(SUBSCRIPTION_TOKEN_PURPOSE, request, now.Add(SUBSCRIPTION_TOKEN_TTL))
+	if err != nil {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Failed to sign request %s{{/}}", err.Error())
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	body := &strings.Builder{}
+	err = confirmSubscriptionTemplate.Execute(body, "https://www.sedenverultimate.net/subscribe/confirm/"+token)
+	if err != nil {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Failed to render email body %s{{/}}", err.Error())
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	err = s.outbox.SendEmail(mail.E().
+		WithFrom(s.config.SaturdayDiscoEmail).
+		WithTo(mail.EmailAddress(request.Email)).
+		WithSubject("Please confirm your Southeast Denver Ultimate subscription").WithBody(body.String()))
+	if err != nil {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Failed to send email %s{{/}}", err.Error())
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	say.Fplni(s.e.Logger.Output(), 1, "{{green}}Sent confirmation email{{/}}")
+	return c.NoContent(http.StatusOK)
+}
+
+// ConfirmSubscriptionForm only shows a confirm button: mail scanners prefetch the links in e-mails, so a GET mustn't confirm anything.
+func (s *Server) ConfirmSubscriptionForm(c echo.Context) error {
+	token := c.Param("token")
+	var request SubscriptionRequest
+	if err := s.signer.Verify(SUBSCRIPTION_TOKEN_PURPOSE, token, time.Now(), &request); err != nil {
+		say.Fplni(s.e.Logger.Output(), 0, "{{red}}Invalid confirmation token: %s{{/}}", err.Error())
+		return c.Render(http.StatusBadRequest, "subscribe_confirm", map[string]any{"Error": err})
+	}
+	return c.Render(http.StatusOK, "subscribe_confirm", map[string]any{"Request": request, "Token": token})
+}
+
+func (s *Server) ConfirmSubscription(c echo.Context) error {
+	say.Fplni(s.e.Logger.Output(), 0, "{{green}}Got a subscription confirmation{{/}}")
+	token := c.Param("token")
+	var request SubscriptionRequest
+	if err := s.signer.Verify(SUBSCRIPTION_TOKEN_PURPOSE, token, time.Now(), &request); err != nil {
+		say.Fplni(s.e.Logger.Output(), 1, "{{red}}Invalid confirmation token: %s{{/}}", err.Error())
+		return c.Render(http.StatusBadRequest, "subscribe_confirm", map[string]any{"Error": err})
+	}
+
+	if !s.confirmedSubscriptions.Allow(token, time.Now()) {
+		say.Fplni(s.e.Logger.Output(), 1, "{{yellow}}Already confirmed - not bugging the boss again{{/}}")
+		return c.Render(http.StatusOK, "subscribe_confirm", map[string]any{"Request": request, "Confirmed": true})
+	}
+
 	body := &strings.Builder{}
 	err := subscribeTemplate.Execute(body, request)
 	if err != nil {
@@ -74,5 +156,5 @@ func (s *Server) Subscribe(c echo.Context) error {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
 	say.Fplni(s.e.Logger.Output(), 1, "{{green}}Sent email{{/}}")
-	return c.NoContent(http.StatusOK)
+	return c.Render(http.StatusOK, "subscribe_confirm", map[string]any{"Request": request, "Confirmed": true})
 }
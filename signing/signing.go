@@ -0,0 +1,96 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+var ErrExpiredToken = errors.New("token has expired")
+
+type envelope struct {
+	Purpose   string          `json:"p"`
+	ExpiresAt time.Time       `json:"e"`
+	Payload   json.RawMessage `json:"d"`
+}
+
+// Signer produces and verifies tamper-proof, expiring tokens.  The purpose is baked into the signature
+// so a token minted for one flow (e.g. confirming a subscription) can't be replayed against another.
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret string) Signer {
+	return Signer{secret: []byte(secret)}
+}
+
+// NewRandomSigner is useful in development, when no secret is configured.  Tokens won't survive a restart.
+func NewRandomSigner() Signer {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(fmt.Sprintf("failed to generate a signing secret: %s", err.Error()))
+	}
+	return Signer{secret: []byte(hex.EncodeToString(secret))}
+}
+
+func (s Signer) Sign(purpose string, payload any, expiresAt time.Time) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	data, err = json.Marshal(envelope{
+		Purpose:   purpose,
+		ExpiresAt: expiresAt.UTC(),
+		Payload:   data,
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return encoded + "." + s.mac(encoded), nil
+}
+
+func (s Signer) Verify(purpose string, token string, now time.Time, payload any) error {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.mac(encoded))) {
+		return ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	e := envelope{}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return ErrInvalidToken
+	}
+	if e.Purpose != purpose {
+		return ErrInvalidToken
+	}
+	if now.After(e.ExpiresAt) {
+		return ErrExpiredToken
+	}
+	if payload == nil {
+		return nil
+	}
+	if err := json.Unmarshal(e.Payload, payload); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (s Signer) mac(encoded string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package signing_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/signing"
+)
+
+type payload struct {
+	Email string
+	Count int
+}
+
+var _ = Describe("Signing", func() {
+	var signer signing.Signer
+	var now time.Time
+
+	BeforeEach(func() {
+		signer = signing.NewSigner("sekrit")
+		now = time.Date(2023, time.September, 24, 10, 0, 0, 0, time.UTC)
+	})
+
+	It("roundtrips payloads", func() {
+		token, err := signer.Sign("subscribe", payload{Email: "player@example.com", Count: 2}, now.Add(time.Hour))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		var out payload
+		Ω(signer.Verify("subscribe", token, now, &out)).Should(Succeed())
+		Ω(out).Should(Equal(payload{Email: "player@example.com", Count: 2}))
+	})
+
+	It("rejects expired tokens", func() {
+		token, err := signer.Sign("subscribe", payload{}, now.Add(time.Hour))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(signer.Verify("subscribe", token, now.Add(time.Hour+time.Second), nil)).Should(MatchError(signing.ErrExpiredToken))
+	})
+
+	It("rejects tokens minted for a different purpose", func() {
+		token, err := signer.Sign("subscribe", payload{}, now.Add(time.Hour))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(signer.Verify("login", token, now, nil)).Should(MatchError(signing.ErrInvalidToken))
+	})
+
+	It("rejects tokens signed with a different secret", func() {
+		token, err := signing.NewSigner("other").Sign("subscribe", payload{}, now.Add(time.Hour))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(signer.Verify("subscribe", token, now, nil)).Should(MatchError(signing.ErrInvalidToken))
+	})
+
+	It("rejects tampered tokens", func() {
+		token, err := signer.Sign("subscribe", payload{Email: "player@example.com"}, now.Add(time.Hour))
+		Ω(err).ShouldNot(HaveOccurred())
+		tampered, err := signer.Sign("subscribe", payload{Email: "evil@example.com"}, now.Add(time.Hour))
+		Ω(err).ShouldNot(HaveOccurred())
+		tampered = strings.Split(tampered, ".")[0] + "." + strings.Split(token, ".")[1]
+
+		Ω(signer.Verify("subscribe", tampered, now, nil)).Should(MatchError(signing.ErrInvalidToken))
+		Ω(signer.Verify("subscribe", "garbage", now, nil)).Should(MatchError(signing.ErrInvalidToken))
+	})
+})
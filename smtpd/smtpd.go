@@ -0,0 +1,351 @@
+package smtpd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	"github.com/onsi/say"
+)
+
+const DEFAULT_MAX_MESSAGE_BYTES = 10 * 1024 * 1024
+
+// we hang up on clients that go quiet for longer than this - RFC 5321 suggests 5 minutes for most commands
+const TIMEOUT = 5 * time.Minute
+
+const MAX_RECIPIENTS = 100
+
+// command lines are limited to 512 octets by RFC 5321 - we're a bit more forgiving
+const MAX_LINE_LENGTH = 4096
+
+// Disco is anything we can hand an incoming e-mail to - i.e. SaturdayDisco and LunchtimeDisco
+type Disco interface {
+	HandleIncomingEmail(email mail.Email)
+}
+
+// Server is a minimal inbound-only SMTP server.  It accepts mail addressed to the disco addresses it has routes for, parses it, and
+// hands it to the matching disco.  It does not relay, authenticate, or speak STARTTLS.
+//
+// Messages that arrive this way have not been vetted by forwardemail, so any Authentication-Results headers on them were written by
+// the sender - we drop them.  Admin commands that arrive via SMTP will be quarantined for the boss to confirm on the dashboard.
+type Server struct {
+	hostname        string
+	maxMessageBytes int64
+	db              s3db.S3DBInt
+	w               io.Writer
+
+	routes   map[string]Disco
+	listener net.Listener
+	closed   bool
+	lock     *sync.Mutex
+}
+
+func NewServer(hostname string, maxMessageBytes int64, db s3db.S3DBInt, w io.Writer) *Server {
+	if maxMessageBytes <= 0 {
+		maxMessageBytes = DEFAULT_MAX_MESSAGE_BYTES
+	}
+	return &Server{
+		hostname:        hostname,
+		maxMessageBytes: maxMessageBytes,
+		db:              db,
+		w:               w,
+		routes:          map[string]Disco{},
+		lock:            &sync.Mutex{},
+	}
+}
+
+// Route delivers mail sent to address (compared case-insensitively, ignoring any display name) to disco
+func (s *Server) Route(address mail.EmailAddress, disco Disco) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.routes[strings.ToLower(address.Address())] = disco
+}
+
+func (s *Server) discoFor(address string) (Disco, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	disco, ok := s.routes[strings.ToLower(address)]
+	return disco, ok
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on listener until Close is called
+func (s *Server) Serve(listener net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		listener.Close()
+		return nil
+	}
+	s.listener = listener
+	s.lock.Unlock()
+	say.Fplni(s.w, 0, "{{green}}SMTP: listening on %s{{/}}", listener.Addr())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			s.lock.Unlock()
+			if closed {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+// deliver parses the message once and hands it to every disco it was addressed to
+func (s *Server) deliver(data []byte, discos []Disco) error {
+	email, err := mail.ParseRawEmail(s.db, data, s.w)
+	if err != nil {
+		return err
+	}
+	email.Authentication = mail.AuthenticationResults{}
+	say.Fplni(s.w, 0, "{{green}}SMTP: accepted %q from %s{{/}}", email.Subject, email.From)
+	for _, disco := range discos {
+		disco.HandleIncomingEmail(email)
+	}
+	return nil
+}
+
+type session struct {
+	server *Server
+	conn   net.Conn
+	reader *bufio.Reader
+	text   *textproto.Writer
+
+	helo       string
+	from       string
+	hasFrom    bool
+	recipients []string
+	discos     []Disco
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	session := &session{
+		server: s,
+		conn:   conn,
+		reader: bufio.NewReaderSize(conn, MAX_LINE_LENGTH),
+		text:   textproto.NewWriter(bufio.NewWriter(conn)),
+	}
+	session.serve()
+}
+
+func (c *session) reply(code int, format string, args ...any) error {
+	return c.text.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+}
+
+func (c *session) reset() {
+	c.from, c.hasFrom = "", false
+	c.recipients, c.discos = nil, nil
+}
+
+func (c *session) readLine() (string, error) {
+	c.conn.SetReadDeadline(time.Now().Add(TIMEOUT))
+	line, err := c.reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// swallow the rest of the line so we can carry on
+		for err == bufio.ErrBufferFull {
+			_, err = c.reader.ReadSlice('\n')
+		}
+		if err != nil {
+			return "", err
+		}
+		return "", errLineTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+var errLineTooLong = errors.New("line too long")
+
+func (c *session) serve() {
+	c.conn.SetWriteDeadline(time.Now().Add(TIMEOUT))
+	if c.reply(220, "%s ESMTP disco", c.server.hostname) != nil {
+		return
+	}
+	for {
+		line, err := c.readLine()
+		if err == errLineTooLong {
+			c.reply(500, "5.5.2 line too long")
+			continue
+		}
+		if err != nil {
+			return
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(TIMEOUT))
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			c.reset()
+			c.helo = arg
+			err = c.reply(250, "%s", c.server.hostname)
+		case "EHLO":
+			c.reset()
+			c.helo = arg
+			err = c.text.PrintfLine("250-%s\r\n250-SIZE %d\r\n250-8BITMIME\r\n250 PIPELINING", c.server.hostname, c.server.maxMessageBytes)
+		case "MAIL":
+			err = c.mail(arg)
+		case "RCPT":
+			err = c.rcpt(arg)
+		case "DATA":
+			err = c.data()
+		case "RSET":
+			c.reset()
+			err = c.reply(250, "2.0.0 OK")
+		case "NOOP":
+			err = c.reply(250, "2.0.0 OK")
+		case "VRFY":
+			err = c.reply(252, "2.5.0 send some mail and we'll see")
+		case "QUIT":
+			c.reply(221, "2.0.0 bye")
+			return
+		default:
+			err = c.reply(502, "5.5.1 command not implemented")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *session) mail(arg string) error {
+	if c.helo == "" {
+		return c.reply(503, "5.5.1 say HELO first")
+	}
+	if c.hasFrom {
+		return c.reply(503, "5.5.1 nested MAIL command")
+	}
+	path, params, ok := parsePath(arg, "FROM:")
+	if !ok {
+		return c.reply(501, "5.5.4 syntax: MAIL FROM:<address>")
+	}
+	for _, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(key, "SIZE") {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return c.reply(501, "5.5.4 bad SIZE")
+			}
+			if size > c.server.maxMessageBytes {
+				return c.reply(552, "5.3.4 message too big - the limit is %d bytes", c.server.maxMessageBytes)
+			}
+		}
+	}
+	// the null reverse-path (<>) is how bounces arrive, so it's fine
+	c.from, c.hasFrom = path, true
+	return c.reply(250, "2.1.0 OK")
+}
+
+func (c *session) rcpt(arg string) error {
+	if !c.hasFrom {
+		return c.reply(503, "5.5.1 need MAIL first")
+	}
+	path, _, ok := parsePath(arg, "TO:")
+	if !ok || path == "" {
+		return c.reply(501, "5.5.4 syntax: RCPT TO:<address>")
+	}
+	if len(c.recipients) >= MAX_RECIPIENTS {
+		return c.reply(452, "4.5.3 too many recipients")
+	}
+	disco, ok := c.server.discoFor(path)
+	if !ok {
+		return c.reply(550, "5.1.1 no disco here by that name")
+	}
+	c.recipients = append(c.recipients, path)
+	for _, existing := range c.discos {
+		if existing == disco {
+			return c.reply(250, "2.1.5 OK")
+		}
+	}
+	c.discos = append(c.discos, disco)
+	return c.reply(250, "2.1.5 OK")
+}
+
+func (c *session) data() error {
+	if !c.hasFrom || len(c.recipients) == 0 {
+		return c.reply(503, "5.5.1 need MAIL and RCPT first")
+	}
+	if err := c.reply(354, "go ahead, end with <CRLF>.<CRLF>"); err != nil {
+		return err
+	}
+	c.conn.SetReadDeadline(time.Now().Add(TIMEOUT))
+	dot := textproto.NewReader(c.reader).DotReader()
+	data, err := io.ReadAll(io.LimitReader(dot, c.server.maxMessageBytes+1))
+	if err != nil {
+		return err
+	}
+	discos := c.discos
+	c.reset()
+	if int64(len(data)) > c.server.maxMessageBytes {
+		// read (and throw away) the rest so we're back in sync with the client
+		if _, err := io.Copy(io.Discard, dot); err != nil {
+			return err
+		}
+		return c.reply(552, "5.3.4 message too big - the limit is %d bytes", c.server.maxMessageBytes)
+	}
+	// DotReader hands us \n line endings - put the CRLFs back so what we store is the message as it was sent
+	data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	if err := c.server.deliver(data, discos); err != nil {
+		say.Fplni(c.server.w, 0, "{{red}}SMTP: failed to parse incoming email: %s{{/}}", err.Error())
+		return c.reply(554, "5.6.0 could not parse message: %s", err.Error())
+	}
+	return c.reply(250, "2.0.0 OK, delivered")
+}
+
+// parsePath pulls the address out of e.g. "FROM:<a@b.com> SIZE=123", along with any trailing parameters
+func parsePath(arg string, prefix string) (string, []string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	fields := strings.Fields(strings.TrimSpace(arg[len(prefix):]))
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	path := fields[0]
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", nil, false
+	}
+	path = strings.Trim(path, "<>")
+	// drop any (long obsolete) source route: <@relay1,@relay2:user@example.com>
+	if i := strings.LastIndex(path, ":"); i >= 0 {
+		path = path[i+1:]
+	}
+	return path, fields[1:], true
+}
@@ -0,0 +1,181 @@
+package smtpd_test
+
+import (
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	"github.com/onsi/disco/smtpd"
+)
+
+type fakeDisco struct {
+	emails []mail.Email
+	lock   *sync.Mutex
+}
+
+func newFakeDisco() *fakeDisco {
+	return &fakeDisco{lock: &sync.Mutex{}}
+}
+
+func (f *fakeDisco) HandleIncomingEmail(email mail.Email) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.emails = append(f.emails, email)
+}
+
+func (f *fakeDisco) Emails() []mail.Email {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]mail.Email{}, f.emails...)
+}
+
+func fixture(name string) []byte {
+	GinkgoHelper()
+	data, err := os.ReadFile("../mail/fixtures/" + name)
+	Ω(err).ShouldNot(HaveOccurred())
+	return data
+}
+
+var _ = Describe("Server", func() {
+	var db *s3db.FakeS3DB
+	var server *smtpd.Server
+	var saturday, lunchtime *fakeDisco
+	var addr string
+
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+		saturday, lunchtime = newFakeDisco(), newFakeDisco()
+		server = smtpd.NewServer("disco.test", 2048, db, GinkgoWriter)
+		server.Route("Saturday Disco <saturday-disco@sedenverultimate.net>", saturday)
+		server.Route("lunchtime-disco@sedenverultimate.net", lunchtime)
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Ω(err).ShouldNot(HaveOccurred())
+		addr = listener.Addr().String()
+		done := make(chan error)
+		go func() {
+			done <- server.Serve(listener)
+		}()
+		DeferCleanup(func() {
+			Ω(server.Close()).Should(Succeed())
+			Eventually(done).Should(Receive(BeNil()))
+		})
+	})
+
+	send := func(from string, to []string, data []byte) error {
+		return smtp.SendMail(addr, nil, from, to, data)
+	}
+
+	It("parses the message and hands it to the disco it was addressed to", func() {
+		raw := fixture("raw_multipart_alternative.eml")
+		Ω(send("onsijoe@gmail.com", []string{"Saturday-Disco@sedenverultimate.net"}, raw)).Should(Succeed())
+
+		Ω(saturday.Emails()).Should(HaveLen(1))
+		Ω(lunchtime.Emails()).Should(BeEmpty())
+		email := saturday.Emails()[0]
+		Ω(email.From).Should(Equal(mail.EmailAddress("Onsi Fakhouri 🥏 <onsijoe@gmail.com>")))
+		Ω(email.Subject).Should(Equal("Re: Saturday Bible Park Frisbee 🥏"))
+		Ω(email.Text).Should(Equal("I'm in! Bringing a friend — so that's 2.\n"))
+		Eventually(db.FetchObject).WithArguments(email.DebugKey).Should(Equal(raw))
+	})
+
+	It("never trusts the sender's authentication results", func() {
+		Ω(send("onsijoe@gmail.com", []string{"saturday-disco@sedenverultimate.net"}, fixture("raw_multipart_alternative.eml"))).Should(Succeed())
+		Ω(saturday.Emails()[0].Authentication.IsZero()).Should(BeTrue())
+	})
+
+	It("hands a message addressed to both discos to each of them once", func() {
+		Ω(send("onsijoe@gmail.com", []string{"saturday-disco@sedenverultimate.net", "lunchtime-disco@sedenverultimate.net", "SATURDAY-DISCO@sedenverultimate.net"}, fixture("raw_mailing_list.eml"))).Should(Succeed())
+		Ω(saturday.Emails()).Should(HaveLen(1))
+		Ω(lunchtime.Emails()).Should(HaveLen(1))
+		Ω(lunchtime.Emails()[0].From).Should(Equal(mail.EmailAddress("Onsi Fakhouri <onsijoe@gmail.com>")))
+	})
+
+	It("accepts bounces, which have a null reverse-path", func() {
+		Ω(send("", []string{"saturday-disco@sedenverultimate.net"}, fixture("raw_mailing_list.eml"))).Should(Succeed())
+		Ω(saturday.Emails()).Should(HaveLen(1))
+	})
+
+	It("refuses recipients it has no disco for", func() {
+		err := send("onsijoe@gmail.com", []string{"someone-else@sedenverultimate.net"}, fixture("raw_mailing_list.eml"))
+		Ω(err).Should(MatchError(ContainSubstring("550")))
+		Ω(saturday.Emails()).Should(BeEmpty())
+	})
+
+	It("refuses messages that aren't e-mails it can read", func() {
+		err := send("onsijoe@gmail.com", []string{"saturday-disco@sedenverultimate.net"}, []byte("From: a@example.com\r\nContent-Type: image/png\r\n\r\nxyz\r\n"))
+		Ω(err).Should(MatchError(ContainSubstring("554")))
+		Ω(saturday.Emails()).Should(BeEmpty())
+	})
+
+	Describe("size limits", func() {
+		It("refuses messages that are too big, and stays in sync with the client", func() {
+			big := "From: a@example.com\r\nSubject: big\r\n\r\n" + strings.Repeat("I'm in!\r\n", 1000)
+			client, err := smtp.Dial(addr)
+			Ω(err).ShouldNot(HaveOccurred())
+			defer client.Close()
+			Ω(client.Mail("a@example.com")).Should(Succeed())
+			Ω(client.Rcpt("saturday-disco@sedenverultimate.net")).Should(Succeed())
+			w, err := client.Data()
+			Ω(err).ShouldNot(HaveOccurred())
+			w.Write([]byte(big))
+			err = w.Close()
+			Ω(err).Should(MatchError(ContainSubstring("552")))
+
+			Ω(client.Mail("a@example.com")).Should(Succeed())
+			Ω(client.Rcpt("saturday-disco@sedenverultimate.net")).Should(Succeed())
+			w, err = client.Data()
+			Ω(err).ShouldNot(HaveOccurred())
+			w.Write(fixture("raw_mailing_list.eml"))
+			Ω(w.Close()).Should(Succeed())
+			Ω(client.Quit()).Should(Succeed())
+
+			Ω(saturday.Emails()).Should(HaveLen(1))
+		})
+
+		It("advertises its limit and refuses a declared size over it up front", func() {
+			conn, err := textproto.Dial("tcp", addr)
+			Ω(err).ShouldNot(HaveOccurred())
+			defer conn.Close()
+			_, _, err = conn.ReadResponse(220)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(conn.PrintfLine("EHLO client.test")).Should(Succeed())
+			_, message, err := conn.ReadResponse(250)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(message).Should(ContainSubstring("SIZE 2048"))
+
+			Ω(conn.PrintfLine("MAIL FROM:<a@example.com> SIZE=4096")).Should(Succeed())
+			_, _, err = conn.ReadResponse(250)
+			Ω(err).Should(MatchError(ContainSubstring("552")))
+		})
+	})
+
+	It("insists on the commands coming in order", func() {
+		conn, err := textproto.Dial("tcp", addr)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer conn.Close()
+		_, _, err = conn.ReadResponse(220)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(conn.PrintfLine("MAIL FROM:<a@example.com>")).Should(Succeed())
+		_, _, err = conn.ReadResponse(250)
+		Ω(err).Should(MatchError(ContainSubstring("503")))
+
+		Ω(conn.PrintfLine("HELO client.test")).Should(Succeed())
+		_, _, err = conn.ReadResponse(250)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(conn.PrintfLine("DATA")).Should(Succeed())
+		_, _, err = conn.ReadResponse(354)
+		Ω(err).Should(MatchError(ContainSubstring("503")))
+	})
+})
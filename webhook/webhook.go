@@ -0,0 +1,265 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	"github.com/onsi/say"
+)
+
+const KEY = "incoming-webhooks"
+
+// forwardemail signs each webhook with an HMAC-SHA256 of the body, hex encoded, in this header
+const SIGNATURE_HEADER = "X-Webhook-Signature"
+
+// we refuse payloads that arrived at forwardemail longer ago than this (or too far in the future).  Anything older can't be
+// replayed, so that's also how long we need to remember Message-IDs.
+const MAX_AGE = 24 * time.Hour
+const MAX_SKEW = 5 * time.Minute
+
+type Reason string
+
+const (
+	ReasonNoSecret         Reason = "no_secret"
+	ReasonMissingSignature Reason = "missing_signature"
+	ReasonBadSignature     Reason = "bad_signature"
+	ReasonMalformed        Reason = "malformed"
+	ReasonStale            Reason = "stale"
+	ReasonDuplicate        Reason = "duplicate"
+)
+
+type Rejection struct {
+	Reason Reason
+	Detail string
+}
+
+func (r Rejection) Error() string {
+	return fmt.Sprintf("rejected incoming e-mail (%s): %s", r.Reason, r.Detail)
+}
+
+// IsRejection pulls the Rejection out of err, if there is one
+func IsRejection(err error) (Rejection, bool) {
+	var rejection Rejection
+	ok := errors.As(err, &rejection)
+	return rejection, ok
+}
+
+type Metrics struct {
+	Accepted int            `json:"accepted"`
+	Rejected map[Reason]int `json:"rejected"`
+}
+
+func (m Metrics) dup() Metrics {
+	out := Metrics{Accepted: m.Accepted, Rejected: map[Reason]int{}}
+	for reason, count := range m.Rejected {
+		out.Rejected[reason] = count
+	}
+	return out
+}
+
+func (m Metrics) String() string {
+	out := []string{fmt.Sprintf("%d accepted", m.Accepted)}
+	for _, reason := range []Reason{ReasonNoSecret, ReasonMissingSignature, ReasonBadSignature, ReasonMalformed, ReasonStale, ReasonDuplicate} {
+		if m.Rejected[reason] > 0 {
+			out = append(out, fmt.Sprintf("%d %s", m.Rejected[reason], strings.ReplaceAll(string(reason), "_", " ")))
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+type persistedGuard struct {
+	Seen    map[string]time.Time `json:"seen"`
+	Metrics Metrics              `json:"metrics"`
+}
+
+// the bits of the forwardemail payload we need before we trust it
+type payload struct {
+	MessageID string `json:"messageId"`
+	Session   struct {
+		ArrivalTime int64 `json:"arrivalTime"`
+	} `json:"session"`
+}
+
+// Guard sits in front of the incoming e-mail webhooks for every disco.  It checks the signature, refuses stale payloads, and refuses
+// Message-IDs it has already delivered to that disco - however they arrived (see Dedupe).
+type Guard struct {
+	secret           string
+	requireSignature bool
+	db               s3db.S3DBInt
+	w                io.Writer
+
+	seen    map[string]time.Time
+	metrics Metrics
+	lock    *sync.Mutex
+}
+
+// NewGuard never fails - if we can't load the Message-IDs we've seen we start fresh and lean on the timestamp check.  Without a secret
+// signatures can't be checked: that's only OK if requireSignature is false (i.e. in dev).
+func NewGuard(secret string, requireSignature bool, db s3db.S3DBInt, w io.Writer) *Guard {
+	g := &Guard{
+		secret:           secret,
+		requireSignature: requireSignature,
+		db:               db,
+		w:                w,
+		seen:             map[string]time.Time{},
+		metrics:          Metrics{Rejected: map[Reason]int{}},
+		lock:             &sync.Mutex{},
+	}
+	if secret == "" && requireSignature {
+		say.Fplni(w, 0, "{{red}}Webhook Guard: no webhook secret is configured - all incoming e-mail will be rejected{{/}}")
+	}
+	data, err := db.FetchObject(KEY)
+	if err != nil && !errors.Is(err, s3db.ErrObjectNotFound) {
+		say.Fplni(w, 0, "{{red}}Webhook Guard: failed to load seen Message-IDs, starting fresh: %s{{/}}", err.Error())
+	} else if err == nil {
+		persisted := persistedGuard{}
+		if err := json.Unmarshal(data, &persisted); err != nil {
+			say.Fplni(w, 0, "{{red}}Webhook Guard: failed to unmarshal seen Message-IDs, starting fresh: %s{{/}}", err.Error())
+		} else {
+			if persisted.Seen != nil {
+				g.seen = persisted.Seen
+			}
+			if persisted.Metrics.Rejected != nil {
+				g.metrics = persisted.Metrics
+			}
+		}
+	}
+	return g
+}
+
+// Sign returns the signature forwardemail would send for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Check returns nil if the payload, posted to disco's webhook, is authentic and new.  Otherwise it returns a Rejection.  It doesn't
+// remember the Message-ID - call MarkDelivered once the e-mail has made it to the disco, so a payload we fail to parse can be retried.
+func (g *Guard) Check(disco string, signature string, body []byte, now time.Time) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	err := g.check(disco, signature, body, now)
+	if rejection, ok := IsRejection(err); ok {
+		g.metrics.Rejected[rejection.Reason] += 1
+		say.Fplni(g.w, 0, "{{red}}Webhook Guard: %s: %s{{/}}", disco, rejection.Error())
+	} else {
+		g.metrics.Accepted += 1
+	}
+	if err := g.persist(); err != nil {
+		say.Fplni(g.w, 0, "{{red}}Webhook Guard: failed to persist: %s{{/}}", err.Error())
+	}
+	return err
+}
+
+func (g *Guard) check(disco string, signature string, body []byte, now time.Time) error {
+	if g.secret == "" && g.requireSignature {
+		return Rejection{ReasonNoSecret, "no webhook secret is configured"}
+	}
+	if g.secret != "" {
+		if signature == "" {
+			return Rejection{ReasonMissingSignature, "no " + SIGNATURE_HEADER + " header"}
+		}
+		expected, _ := hex.DecodeString(Sign(g.secret, body))
+		provided, err := hex.DecodeString(strings.TrimSpace(signature))
+		if err != nil || !hmac.Equal(expected, provided) {
+			return Rejection{ReasonBadSignature, "signature does not match"}
+		}
+	}
+
+	p := payload{}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return Rejection{ReasonMalformed, err.Error()}
+	}
+	if p.MessageID == "" {
+		return Rejection{ReasonMalformed, "no messageId"}
+	}
+	if p.Session.ArrivalTime == 0 {
+		return Rejection{ReasonMalformed, "no session.arrivalTime"}
+	}
+	arrival := time.UnixMilli(p.Session.ArrivalTime)
+	if now.Sub(arrival) > MAX_AGE || arrival.Sub(now) > MAX_SKEW {
+		return Rejection{ReasonStale, fmt.Sprintf("%s arrived at %s", p.MessageID, arrival.UTC().Format(time.RFC3339))}
+	}
+
+	if _, ok := g.seen[disco+"/"+p.MessageID]; ok {
+		return Rejection{ReasonDuplicate, "already received " + p.MessageID}
+	}
+	return nil
+}
+
+// MarkDelivered records that the e-mail with messageID has been handed to disco.  It returns false - and counts a duplicate - if it
+// already had been, e.g. because forwardemail retried while we were still handling the first attempt, or the same e-mail also came in
+// over SMTP or IMAP.  E-mails without a Message-ID can't be deduplicated, so they're always delivered.
+func (g *Guard) MarkDelivered(disco string, messageID string, now time.Time) bool {
+	if messageID == "" {
+		return true
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	key := disco + "/" + messageID
+	if _, ok := g.seen[key]; ok {
+		g.metrics.Rejected[ReasonDuplicate] += 1
+		say.Fplni(g.w, 0, "{{red}}Webhook Guard: %s: already delivered %s{{/}}", disco, messageID)
+		return false
+	}
+	g.seen[key] = now
+	for key, seenAt := range g.seen {
+		if now.Sub(seenAt) > MAX_AGE+MAX_SKEW {
+			delete(g.seen, key)
+		}
+	}
+	if err := g.persist(); err != nil {
+		say.Fplni(g.w, 0, "{{red}}Webhook Guard: failed to persist: %s{{/}}", err.Error())
+	}
+	return true
+}
+
+// Disco is anything we deliver incoming e-mail to
+type Disco interface {
+	HandleIncomingEmail(email mail.Email)
+}
+
+type dedupedDisco struct {
+	guard *Guard
+	name  string
+	disco Disco
+}
+
+func (d dedupedDisco) HandleIncomingEmail(email mail.Email) {
+	if d.guard.MarkDelivered(d.name, email.MessageID, time.Now()) {
+		d.disco.HandleIncomingEmail(email)
+	}
+}
+
+// Dedupe wraps disco so that e-mail that reaches it some other way (the SMTP server, the IMAP poller) shares the webhooks' record of
+// which Message-IDs it's already been given
+func (g *Guard) Dedupe(name string, disco Disco) Disco {
+	return dedupedDisco{guard: g, name: name, disco: disco}
+}
+
+func (g *Guard) Metrics() Metrics {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.metrics.dup()
+}
+
+func (g *Guard) persist() error {
+	data, err := json.Marshal(persistedGuard{Seen: g.seen, Metrics: g.metrics})
+	if err != nil {
+		return err
+	}
+	return g.db.PutObject(KEY, data)
+}
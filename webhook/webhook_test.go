@@ -0,0 +1,148 @@
+package webhook_test
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/onsi/disco/mail"
+	"github.com/onsi/disco/s3db"
+	"github.com/onsi/disco/webhook"
+)
+
+const SECRET = "shh"
+
+func body(messageID string, arrival time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"from":{"value":[{"address":"boss@example.com","name":"Boss"}]},"subject":"uh oh","text":"/RESET-RESET-RESET","messageId":%q,"session":{"arrivalTime":%d}}`, messageID, arrival.UnixMilli()))
+}
+
+func HaveBeenRejectedFor(reason webhook.Reason) OmegaMatcher {
+	return WithTransform(func(err error) webhook.Reason {
+		rejection, _ := webhook.IsRejection(err)
+		return rejection.Reason
+	}, Equal(reason))
+}
+
+type recordingDisco struct {
+	emails []mail.Email
+}
+
+func (r *recordingDisco) HandleIncomingEmail(email mail.Email) {
+	r.emails = append(r.emails, email)
+}
+
+var _ = Describe("Guard", func() {
+	var db *s3db.FakeS3DB
+	var guard *webhook.Guard
+	var now time.Time
+
+	BeforeEach(func() {
+		db = s3db.NewFakeS3DB()
+		now = time.Date(2023, time.September, 24, 10, 0, 0, 0, time.UTC)
+		guard = webhook.NewGuard(SECRET, true, db, GinkgoWriter)
+	})
+
+	It("accepts signed, fresh payloads", func() {
+		b := body("<1@example.com>", now.Add(-time.Minute))
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, now)).Should(Succeed())
+		Ω(guard.Metrics().Accepted).Should(Equal(1))
+	})
+
+	It("refuses a spoofed e-mail from the boss that isn't signed, or is signed with the wrong secret", func() {
+		b := body("<1@example.com>", now.Add(-time.Minute))
+		Ω(guard.Check("saturday", "", b, now)).Should(HaveBeenRejectedFor(webhook.ReasonMissingSignature))
+		Ω(guard.Check("saturday", webhook.Sign("guess", b), b, now)).Should(HaveBeenRejectedFor(webhook.ReasonBadSignature))
+		Ω(guard.Check("saturday", "not-even-hex", b, now)).Should(HaveBeenRejectedFor(webhook.ReasonBadSignature))
+
+		tampered := body("<2@example.com>", now.Add(-time.Minute))
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), tampered, now)).Should(HaveBeenRejectedFor(webhook.ReasonBadSignature))
+	})
+
+	It("refuses stale payloads and payloads from the future", func() {
+		old := body("<1@example.com>", now.Add(-webhook.MAX_AGE-time.Minute))
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, old), old, now)).Should(HaveBeenRejectedFor(webhook.ReasonStale))
+		future := body("<2@example.com>", now.Add(time.Hour))
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, future), future, now)).Should(HaveBeenRejectedFor(webhook.ReasonStale))
+	})
+
+	It("refuses malformed payloads", func() {
+		b := []byte(`{"messageId":"<1@example.com>"}`)
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, now)).Should(HaveBeenRejectedFor(webhook.ReasonMalformed))
+		b = []byte(`nope`)
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, now)).Should(HaveBeenRejectedFor(webhook.ReasonMalformed))
+	})
+
+	It("refuses replays of a Message-ID already delivered to the same disco - even across restarts", func() {
+		b := body("<1@example.com>", now.Add(-time.Minute))
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, now)).Should(Succeed())
+		Ω(guard.MarkDelivered("saturday", "<1@example.com>", now)).Should(BeTrue())
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, now.Add(time.Minute))).Should(HaveBeenRejectedFor(webhook.ReasonDuplicate))
+		Ω(guard.Check("lunchtime", webhook.Sign(SECRET, b), b, now.Add(time.Minute))).Should(Succeed())
+
+		guard = webhook.NewGuard(SECRET, true, db, GinkgoWriter)
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, now.Add(time.Hour))).Should(HaveBeenRejectedFor(webhook.ReasonDuplicate))
+		Ω(guard.Metrics()).Should(Equal(webhook.Metrics{Accepted: 2, Rejected: map[webhook.Reason]int{webhook.ReasonDuplicate: 2}}))
+		Ω(guard.Metrics().String()).Should(Equal("2 accepted, 2 duplicate"))
+	})
+
+	It("lets forwardemail retry an e-mail that never made it to the disco (e.g. because we failed to parse it)", func() {
+		b := body("<1@example.com>", now.Add(-time.Minute))
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, now)).Should(Succeed())
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, now.Add(time.Minute))).Should(Succeed())
+		Ω(guard.MarkDelivered("saturday", "<1@example.com>", now.Add(time.Minute))).Should(BeTrue())
+	})
+
+	It("only delivers a Message-ID once, even if two attempts pass the check at the same time", func() {
+		Ω(guard.MarkDelivered("saturday", "<1@example.com>", now)).Should(BeTrue())
+		Ω(guard.MarkDelivered("saturday", "<1@example.com>", now)).Should(BeFalse())
+		Ω(guard.MarkDelivered("lunchtime", "<1@example.com>", now)).Should(BeTrue())
+		Ω(guard.Metrics().Rejected).Should(Equal(map[webhook.Reason]int{webhook.ReasonDuplicate: 1}))
+	})
+
+	It("always delivers e-mails without a Message-ID - there's nothing to dedupe them by", func() {
+		Ω(guard.MarkDelivered("saturday", "", now)).Should(BeTrue())
+		Ω(guard.MarkDelivered("saturday", "", now)).Should(BeTrue())
+	})
+
+	Describe("Dedupe", func() {
+		It("shares the record of delivered Message-IDs with the webhooks, so e-mail arriving over SMTP or IMAP is only delivered once", func() {
+			disco := &recordingDisco{}
+			deduped := guard.Dedupe("saturday", disco)
+			deduped.HandleIncomingEmail(mail.Email{MessageID: "<1@example.com>", Subject: "first"})
+			deduped.HandleIncomingEmail(mail.Email{MessageID: "<1@example.com>", Subject: "again"})
+			deduped.HandleIncomingEmail(mail.Email{MessageID: "<2@example.com>", Subject: "second"})
+			Ω(disco.emails).Should(HaveLen(2))
+			Ω(disco.emails[0].Subject).Should(Equal("first"))
+			Ω(disco.emails[1].Subject).Should(Equal("second"))
+
+			b := body("<2@example.com>", time.Now().Add(-time.Minute))
+			Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, time.Now())).Should(HaveBeenRejectedFor(webhook.ReasonDuplicate))
+		})
+	})
+
+	Context("when no secret is configured", func() {
+		It("refuses everything if signatures are required", func() {
+			b := body("<1@example.com>", now.Add(-time.Minute))
+			guard = webhook.NewGuard("", true, db, GinkgoWriter)
+			Ω(guard.Check("saturday", "", b, now)).Should(HaveBeenRejectedFor(webhook.ReasonNoSecret))
+		})
+
+		It("skips the signature check if they aren't (e.g. in dev)", func() {
+			b := body("<1@example.com>", now.Add(-time.Minute))
+			guard = webhook.NewGuard("", false, db, GinkgoWriter)
+			Ω(guard.Check("saturday", "", b, now)).Should(Succeed())
+			guard.MarkDelivered("saturday", "<1@example.com>", now)
+			Ω(guard.Check("saturday", "", b, now)).Should(HaveBeenRejectedFor(webhook.ReasonDuplicate))
+		})
+	})
+
+	It("starts fresh if it can't load what it's seen", func() {
+		db.SetFetchError(errors.New("boom"))
+		guard = webhook.NewGuard(SECRET, true, db, GinkgoWriter)
+		b := body("<1@example.com>", now.Add(-time.Minute))
+		Ω(guard.Check("saturday", webhook.Sign(SECRET, b), b, now)).Should(Succeed())
+	})
+})